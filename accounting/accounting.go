@@ -0,0 +1,569 @@
+// Package accounting derives a position's state from its orders, transfers
+// and events.
+package accounting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/fx"
+	"github.com/shopspring/decimal"
+)
+
+// ErrOversold is returned when a sell order, a transfer's fee or an outgoing
+// event exceeds the amount held at that time.
+var ErrOversold = errors.New("sell amount exceeds position amount")
+
+// Decimal places kept when dividing, enough for 18 decimals crypto quantities
+const divisionPrecision = 18
+
+// HoldingPeriod tells whether a realized gain is taxed as short or long term.
+type HoldingPeriod string
+
+const (
+	HoldingPeriodShort HoldingPeriod = "short"
+	HoldingPeriodLong  HoldingPeriod = "long"
+)
+
+// Gain is the part of a sell order, of a transfer's network fee or of lost
+// coins that consumed a single lot, opened by the LotOrderID order or the
+// LotEventID event. Fees have a TransferID and lost coins an EventID instead
+// of a SellOrderID, and no proceeds. Proceeds of sells are net of their share
+// of the order's fee.
+type Gain struct {
+	LotOrderID    int             `json:"lotOrder,omitempty"`
+	LotEventID    int             `json:"lotEvent,omitempty"`
+	SellOrderID   int             `json:"sellOrder,omitempty"`
+	TransferID    int             `json:"transfer,omitempty"`
+	EventID       int             `json:"event,omitempty"`
+	Amount        decimal.Decimal `json:"amount"`
+	CostBasis     decimal.Decimal `json:"costBasis"`
+	Proceeds      decimal.Decimal `json:"proceeds"`
+	Profit        decimal.Decimal `json:"profit"`
+	AcquiredAt    time.Time       `json:"acquiredAt"`
+	DisposedAt    time.Time       `json:"disposedAt"`
+	HoldingPeriod HoldingPeriod   `json:"holdingPeriod"`
+}
+
+// Result is the state of a position after replaying its orders, transfers
+// and events. Income is the value of the rewards and airdrops received, which
+// is not part of the realized profit.
+type Result struct {
+	Amount         decimal.Decimal `json:"amount"`
+	AveragePrice   decimal.Decimal `json:"averagePrice"`
+	CostBasis      decimal.Decimal `json:"costBasis"`
+	RealizedProfit decimal.Decimal `json:"realizedProfit"`
+	Income         decimal.Decimal `json:"income"`
+	Fees           decimal.Decimal `json:"fees"`
+	Lots           []gaivota.Lot   `json:"lots"`
+	Gains          []Gain          `json:"gains"`
+}
+
+// Replay applies the orders, the events and the transfers' fees in execution
+// order. Each buy opens a lot and each sell consumes lots according to
+// method. Without fees, costs and proceeds are amount times unit price, so
+// they add up exactly.
+//
+// Orders' fees must be in the position's currency (see ConvertOrders): buy
+// fees add to the cost of their lot, and sell fees are shared by the lots
+// consumed, in proportion to the amount taken from each, reducing their
+// proceeds. Network fees are disposed of with no proceeds: the lots paying
+// them realize their cost as a loss. Transfers without fee leave the position
+// as it is.
+//
+// Events must be in the position's currency too (see ConvertEvents). Incoming
+// events open lots like buys, outgoing ones consume lots like sells, as
+// described by gaivota.Event. The slices are not modified.
+func Replay(orders []gaivota.Order, transfers []gaivota.Transfer, events []gaivota.Event, method gaivota.CostBasisMethod) (*Result, error) {
+	result := &Result{Lots: []gaivota.Lot{}, Gains: []Gain{}}
+
+	for _, step := range sortSteps(orders, transfers, events) {
+		if step.transfer != nil {
+			fee := step.transfer.Fee
+			if !fee.IsPositive() {
+				continue
+			}
+
+			if fee.GreaterThan(result.Amount) {
+				return nil, fmt.Errorf("transfer %v pays a fee of %v but position holds %v: %w", step.transfer.ID, fee, result.Amount, ErrOversold)
+			}
+
+			result.dispose(Gain{TransferID: step.transfer.ID, DisposedAt: step.transfer.ExecutedAt}, fee, decimal.Zero, decimal.Zero, method)
+			result.updateAverage()
+			continue
+		}
+
+		if step.event != nil {
+			if err := result.applyEvent(*step.event, method); err != nil {
+				return nil, err
+			}
+
+			result.updateAverage()
+			continue
+		}
+
+		order := *step.order
+		amount := order.Amount
+		price := order.UnitPrice
+
+		switch order.Operation {
+		case gaivota.OrderOperationBuy:
+			cost := amount.Mul(price).Add(order.Fee)
+
+			// Lots carry the cost per unit, fee included
+			unitCost := price
+			if order.Fee.IsPositive() && amount.IsPositive() {
+				unitCost = cost.DivRound(amount, divisionPrecision)
+			}
+
+			result.Lots = append(result.Lots, gaivota.Lot{
+				PositionID:      order.PositionID,
+				OrderID:         order.ID,
+				Amount:          amount,
+				RemainingAmount: amount,
+				UnitPrice:       unitCost,
+				AcquiredAt:      order.ExecutedAt,
+			})
+
+			result.CostBasis = result.CostBasis.Add(cost)
+			result.Amount = result.Amount.Add(amount)
+		case gaivota.OrderOperationSell:
+			if amount.GreaterThan(result.Amount) {
+				return nil, fmt.Errorf("order %v sells %v but position holds %v: %w", order.ID, amount, result.Amount, ErrOversold)
+			}
+
+			result.dispose(Gain{SellOrderID: order.ID, DisposedAt: order.ExecutedAt}, amount, price, order.Fee, method)
+		default:
+			return nil, fmt.Errorf("order %v has unknown operation %q", order.ID, order.Operation)
+		}
+
+		result.Fees = result.Fees.Add(order.Fee)
+		result.updateAverage()
+	}
+
+	return result, nil
+}
+
+func (result *Result) updateAverage() {
+	result.AveragePrice = decimal.Zero
+	if result.Amount.IsPositive() {
+		result.AveragePrice = result.CostBasis.DivRound(result.Amount, divisionPrecision)
+	} else {
+		// Avoid carrying division residue once the position is closed
+		result.CostBasis = decimal.Zero
+	}
+}
+
+// Incoming events open a lot, at zero cost for forks, and rewards and
+// airdrops are income. Lost coins are disposed of with no proceeds, while
+// withdrawals take their cost with them without realizing anything.
+func (result *Result) applyEvent(event gaivota.Event, method gaivota.CostBasisMethod) error {
+	amount := event.Amount
+
+	switch {
+	case !event.Kind.Valid():
+		return fmt.Errorf("event %v has unknown kind %q", event.ID, event.Kind)
+	case event.Kind.Incoming():
+		unitCost := event.UnitPrice
+		if event.Kind == gaivota.EventKindFork {
+			unitCost = decimal.Zero
+		}
+
+		result.Lots = append(result.Lots, gaivota.Lot{
+			PositionID:      event.PositionID,
+			EventID:         event.ID,
+			Amount:          amount,
+			RemainingAmount: amount,
+			UnitPrice:       unitCost,
+			AcquiredAt:      event.ExecutedAt,
+		})
+
+		result.CostBasis = result.CostBasis.Add(amount.Mul(unitCost))
+		result.Amount = result.Amount.Add(amount)
+
+		if event.Kind.Income() {
+			result.Income = result.Income.Add(amount.Mul(event.UnitPrice))
+		}
+	case amount.GreaterThan(result.Amount):
+		return fmt.Errorf("%s event %v takes %v out but position holds %v: %w", event.Kind, event.ID, amount, result.Amount, ErrOversold)
+	case event.Kind == gaivota.EventKindLost:
+		result.dispose(Gain{EventID: event.ID, DisposedAt: event.ExecutedAt}, amount, decimal.Zero, decimal.Zero, method)
+	default:
+		result.consume(amount, method, nil)
+	}
+
+	return nil
+}
+
+// Consumes open lots until amount is disposed of at price, recording a Gain
+// per consumed lot. The disposal tells what disposed of the lots and when.
+// The fee is shared by the gains, the last one taking what division left.
+func (result *Result) dispose(disposal Gain, amount decimal.Decimal, price decimal.Decimal, fee decimal.Decimal, method gaivota.CostBasisMethod) {
+	remaining := amount
+	remainingFee := fee
+
+	result.consume(amount, method, func(lot gaivota.Lot, taken decimal.Decimal, cost decimal.Decimal) {
+		gain := disposal
+		gain.LotOrderID = lot.OrderID
+		gain.LotEventID = lot.EventID
+		gain.Amount = taken
+		gain.CostBasis = cost
+		gain.Proceeds = taken.Mul(price)
+
+		feeShare := remainingFee
+		if taken.LessThan(remaining) {
+			feeShare = fee.Mul(taken).DivRound(amount, divisionPrecision)
+		}
+		gain.Proceeds = gain.Proceeds.Sub(feeShare)
+		remainingFee = remainingFee.Sub(feeShare)
+		remaining = remaining.Sub(taken)
+
+		gain.AcquiredAt = lot.AcquiredAt
+		gain.HoldingPeriod = holdingPeriod(lot.AcquiredAt, disposal.DisposedAt)
+		gain.Profit = gain.Proceeds.Sub(gain.CostBasis)
+
+		result.Gains = append(result.Gains, gain)
+		result.RealizedProfit = result.RealizedProfit.Add(gain.Profit)
+	})
+}
+
+// Takes amount out of the open lots, in the order given by method, along
+// with their cost. take, when not nil, is called with each lot consumed, the
+// amount taken from it and the cost of that amount.
+func (result *Result) consume(amount decimal.Decimal, method gaivota.CostBasisMethod, take func(lot gaivota.Lot, taken decimal.Decimal, cost decimal.Decimal)) {
+	averagePrice := result.AveragePrice
+	remaining := amount
+
+	for _, i := range consumptionOrder(result.Lots, method) {
+		if !remaining.IsPositive() {
+			break
+		}
+
+		lot := &result.Lots[i]
+		taken := decimal.Min(lot.RemainingAmount, remaining)
+
+		// Average cost still consumes lots first-in first-out to know the holding period
+		unitCost := lot.UnitPrice
+		if method == gaivota.CostBasisAverage {
+			unitCost = averagePrice
+		}
+
+		cost := taken.Mul(unitCost)
+		if take != nil {
+			take(*lot, taken, cost)
+		}
+
+		result.CostBasis = result.CostBasis.Sub(cost)
+		lot.RemainingAmount = lot.RemainingAmount.Sub(taken)
+		remaining = remaining.Sub(taken)
+	}
+
+	result.Amount = result.Amount.Sub(amount)
+}
+
+// UnrealizedProfit is the profit of the remaining amount if sold at price.
+func (result *Result) UnrealizedProfit(price decimal.Decimal) decimal.Decimal {
+	return result.Amount.Mul(price).Sub(result.CostBasis)
+}
+
+// RealizedByHoldingPeriod sums the realized profit of each holding period.
+func (result *Result) RealizedByHoldingPeriod() map[HoldingPeriod]decimal.Decimal {
+	totals := map[HoldingPeriod]decimal.Decimal{
+		HoldingPeriodShort: decimal.Zero,
+		HoldingPeriodLong:  decimal.Zero,
+	}
+
+	for _, gain := range result.Gains {
+		totals[gain.HoldingPeriod] = totals[gain.HoldingPeriod].Add(gain.Profit)
+	}
+
+	return totals
+}
+
+// Apply copies the derived values into the position.
+func (result *Result) Apply(position *gaivota.Position) {
+	position.Amount = result.Amount
+	position.AveragePrice = result.AveragePrice
+	position.Profit = result.RealizedProfit
+}
+
+// SortOrders returns a copy of the orders sorted by execution time.
+// Orders executed at the same time keep their insertion (ID) order.
+func SortOrders(orders []gaivota.Order) []gaivota.Order {
+	sorted := make([]gaivota.Order, len(orders))
+	copy(sorted, orders)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ExecutedAt.Equal(sorted[j].ExecutedAt) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].ExecutedAt.Before(sorted[j].ExecutedAt)
+	})
+
+	return sorted
+}
+
+// An order, a transfer or an event, replayed in execution order
+type step struct {
+	at       time.Time
+	order    *gaivota.Order
+	transfer *gaivota.Transfer
+	event    *gaivota.Event
+}
+
+// Merges the orders, events and transfers by execution time. At the same
+// time, orders come first, then events and transfers last, so coins bought or
+// deposited can pay a fee right away.
+func sortSteps(orders []gaivota.Order, transfers []gaivota.Transfer, events []gaivota.Event) []step {
+	sorted := SortOrders(orders)
+	steps := make([]step, 0, len(orders)+len(transfers)+len(events))
+
+	for i := range sorted {
+		steps = append(steps, step{at: sorted[i].ExecutedAt, order: &sorted[i]})
+	}
+
+	for _, event := range SortEvents(events) {
+		event := event
+		steps = append(steps, step{at: event.ExecutedAt, event: &event})
+	}
+
+	for _, transfer := range sortTransfers(transfers) {
+		transfer := transfer
+		steps = append(steps, step{at: transfer.ExecutedAt, transfer: &transfer})
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].at.Before(steps[j].at) })
+
+	return steps
+}
+
+// SortEvents returns a copy of the events sorted by execution time, then ID
+func SortEvents(events []gaivota.Event) []gaivota.Event {
+	sorted := make([]gaivota.Event, len(events))
+	copy(sorted, events)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ExecutedAt.Equal(sorted[j].ExecutedAt) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].ExecutedAt.Before(sorted[j].ExecutedAt)
+	})
+
+	return sorted
+}
+
+// Returns a copy of the transfers sorted by execution time, then ID
+func sortTransfers(transfers []gaivota.Transfer) []gaivota.Transfer {
+	sorted := make([]gaivota.Transfer, len(transfers))
+	copy(sorted, transfers)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ExecutedAt.Equal(sorted[j].ExecutedAt) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].ExecutedAt.Before(sorted[j].ExecutedAt)
+	})
+
+	return sorted
+}
+
+// Returns the indexes of the open lots in the order they should be consumed
+func consumptionOrder(lots []gaivota.Lot, method gaivota.CostBasisMethod) []int {
+	var open []int
+	for i, lot := range lots {
+		if lot.RemainingAmount.IsPositive() {
+			open = append(open, i)
+		}
+	}
+
+	switch method {
+	case gaivota.CostBasisLIFO:
+		for i, j := 0, len(open)-1; i < j; i, j = i+1, j-1 {
+			open[i], open[j] = open[j], open[i]
+		}
+	case gaivota.CostBasisHIFO:
+		sort.SliceStable(open, func(i, j int) bool {
+			return lots[open[i]].UnitPrice.GreaterThan(lots[open[j]].UnitPrice)
+		})
+	}
+
+	return open
+}
+
+// Gains on assets held for more than a year are long term
+func holdingPeriod(acquiredAt time.Time, disposedAt time.Time) HoldingPeriod {
+	if disposedAt.After(acquiredAt.AddDate(1, 0, 0)) {
+		return HoldingPeriodLong
+	}
+
+	return HoldingPeriodShort
+}
+
+// ConvertOrders returns copies of the orders priced in `currency`, converted at
+// the rate of their execution time. Orders already in `currency` are untouched.
+// Fees are valued in `currency` too: fees in `symbol`, the token traded, at
+// the order's unit price, and fees in any other asset at its rate or price.
+func ConvertOrders(ctx context.Context, converter *fx.Converter, orders []gaivota.Order, symbol string, currency string) ([]gaivota.Order, error) {
+	converted := make([]gaivota.Order, len(orders))
+
+	for i, order := range orders {
+		converted[i] = order
+
+		rate := decimal.NewFromInt(1)
+		if order.QuoteCurrency != "" && order.QuoteCurrency != currency {
+			var err error
+			rate, err = converter.Rate(ctx, order.QuoteCurrency, currency, order.ExecutedAt)
+			if err != nil {
+				return nil, fmt.Errorf("Could not convert order %v: %w", order.ID, missingRate(err, order.QuoteCurrency, currency, order.ExecutedAt))
+			}
+
+			converted[i].UnitPrice = order.UnitPrice.Mul(rate)
+			converted[i].TotalPrice = order.TotalPrice.Mul(rate)
+			converted[i].QuoteCurrency = currency
+		}
+
+		if order.Fee.IsZero() {
+			continue
+		}
+
+		switch {
+		case order.FeeCurrency == "" || strings.EqualFold(order.FeeCurrency, order.QuoteCurrency):
+			converted[i].Fee = order.Fee.Mul(rate)
+		case strings.EqualFold(order.FeeCurrency, symbol):
+			converted[i].Fee = order.Fee.Mul(converted[i].UnitPrice)
+		default:
+			fee, err := converter.Convert(ctx, order.Fee, order.FeeCurrency, currency, order.ExecutedAt)
+			if err != nil {
+				return nil, fmt.Errorf("Could not convert fee of order %v: %w", order.ID, missingRate(err, order.FeeCurrency, currency, order.ExecutedAt))
+			}
+			converted[i].Fee = fee
+		}
+		converted[i].FeeCurrency = currency
+	}
+
+	return converted, nil
+}
+
+// ConvertEvents returns copies of the events valued in `currency`, converted
+// at the rate of their execution time. Events already in `currency`, or
+// without value, are untouched.
+func ConvertEvents(ctx context.Context, converter *fx.Converter, events []gaivota.Event, currency string) ([]gaivota.Event, error) {
+	converted := make([]gaivota.Event, len(events))
+
+	for i, event := range events {
+		converted[i] = event
+
+		if event.UnitPrice.IsZero() || event.QuoteCurrency == "" || event.QuoteCurrency == currency {
+			continue
+		}
+
+		unitPrice, err := converter.Convert(ctx, event.UnitPrice, event.QuoteCurrency, currency, event.ExecutedAt)
+		if err != nil {
+			return nil, fmt.Errorf("Could not convert event %v: %w", event.ID, missingRate(err, event.QuoteCurrency, currency, event.ExecutedAt))
+		}
+
+		converted[i].UnitPrice = unitPrice
+		converted[i].QuoteCurrency = currency
+	}
+
+	return converted, nil
+}
+
+// Tells users which rate or price is missing to convert `from` to `to`, as a
+// gaivota.Error that is still gaivota.ErrFXRateNotFound
+func missingRate(err error, from string, to string, at time.Time) error {
+	if !errors.Is(err, gaivota.ErrFXRateNotFound) {
+		return err
+	}
+
+	return &gaivota.Error{
+		Kind:    gaivota.ErrFXRateNotFound,
+		Message: fmt.Sprintf("no exchange rate or price from %s to %s at or before %s", strings.ToUpper(from), strings.ToUpper(to), at.Format(time.RFC3339)),
+		Err:     err,
+	}
+}
+
+// ReplayPosition replays the position's orders and events, converted to the
+// position's currency, and transfers, using the cost basis method of the
+// portfolio it belongs to.
+func ReplayPosition(ctx context.Context, client *gaivota.Client, positionId int) (*Result, error) {
+	return ReplayPositionAt(ctx, client, positionId, time.Time{})
+}
+
+// ReplayPositionAt replays the orders, transfers and events executed until
+// `at`, or all of them when `at` is zero, to get the position as it was back
+// then.
+func ReplayPositionAt(ctx context.Context, client *gaivota.Client, positionId int, at time.Time) (*Result, error) {
+	position, err := client.PositionStore.Get(ctx, positionId)
+	if err != nil {
+		return nil, err
+	}
+
+	investment, err := client.InvestmentStore.Get(ctx, position.InvestmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio, err := client.PortfolioStore.Get(ctx, investment.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := client.OrderStore.GetByPositionID(ctx, positionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !at.IsZero() {
+		var executed []gaivota.Order
+		for _, order := range orders {
+			if !order.ExecutedAt.After(at) {
+				executed = append(executed, order)
+			}
+		}
+		orders = executed
+	}
+
+	converter := fx.NewConverter(fx.WithPrices(client.FXRateStore, client.PriceStore))
+
+	orders, err = ConvertOrders(ctx, converter, orders, investment.TokenSymbol, position.QuoteCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, err := client.TransferStore.GetByPositionID(ctx, positionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var executedTransfers []gaivota.Transfer
+	for _, transfer := range *transfers {
+		if at.IsZero() || !transfer.ExecutedAt.After(at) {
+			executedTransfers = append(executedTransfers, transfer)
+		}
+	}
+
+	events, err := client.EventStore.GetByPositionID(ctx, positionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var executedEvents []gaivota.Event
+	for _, event := range *events {
+		if at.IsZero() || !event.ExecutedAt.After(at) {
+			executedEvents = append(executedEvents, event)
+		}
+	}
+
+	executedEvents, err = ConvertEvents(ctx, converter, executedEvents, position.QuoteCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	return Replay(orders, executedTransfers, executedEvents, portfolio.CostBasisMethod)
+}
@@ -0,0 +1,290 @@
+package accounting_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/inmem"
+	"github.com/leoschet/gaivota/inmem/inmemtest"
+	"github.com/shopspring/decimal"
+)
+
+var day = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// An order of the test position, executed `days` after the first day, priced
+// in USD like the position
+type testOrder struct {
+	operation gaivota.OrderOperation
+	amount    string
+	unitPrice string
+	fee       string
+	days      int
+}
+
+func buy(amount string, unitPrice string, days int) testOrder {
+	return testOrder{operation: gaivota.OrderOperationBuy, amount: amount, unitPrice: unitPrice, days: days}
+}
+
+func sell(amount string, unitPrice string, days int) testOrder {
+	return testOrder{operation: gaivota.OrderOperationSell, amount: amount, unitPrice: unitPrice, days: days}
+}
+
+func (order testOrder) withFee(fee string) testOrder {
+	order.fee = fee
+	return order
+}
+
+// Creates a USD position in a portfolio using the method, in a new memory store
+func newPosition(t *testing.T, method gaivota.CostBasisMethod) (*gaivota.Client, int) {
+	t.Helper()
+	client := inmem.New().NewClient()
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{CostBasisMethod: method})
+
+	return client, fixture.Position.ID
+}
+
+func addOrder(client *gaivota.Client, positionId int, order testOrder) error {
+	amount := decimal.RequireFromString(order.amount)
+	unitPrice := decimal.RequireFromString(order.unitPrice)
+	fee := decimal.Zero
+	if order.fee != "" {
+		fee = decimal.RequireFromString(order.fee)
+	}
+
+	_, err := client.OrderStore.Add(context.Background(), &gaivota.Order{
+		PositionID:    positionId,
+		Amount:        amount,
+		UnitPrice:     unitPrice,
+		TotalPrice:    amount.Mul(unitPrice),
+		QuoteCurrency: "USD",
+		Fee:           fee,
+		Operation:     order.operation,
+		Type:          gaivota.OrderTypeMarket,
+		ExecutedAt:    day.AddDate(0, 0, order.days),
+	})
+
+	return err
+}
+
+// Adds the orders to a new position and replays it
+func replay(t *testing.T, method gaivota.CostBasisMethod, orders []testOrder) (*gaivota.Client, int, *accounting.Result) {
+	t.Helper()
+	client, positionId := newPosition(t, method)
+
+	for i, order := range orders {
+		if err := addOrder(client, positionId, order); err != nil {
+			t.Fatalf("Could not add order %d: %v", i+1, err)
+		}
+	}
+
+	result, err := accounting.ReplayPosition(context.Background(), client, positionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, positionId, result
+}
+
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, expected string) {
+	t.Helper()
+	if !got.Equal(decimal.RequireFromString(expected)) {
+		t.Errorf("%s is %v, expected %s", name, got, expected)
+	}
+}
+
+func TestReplayMethods(t *testing.T) {
+	// Three lots at 100, 200 and 150, then half of the second one is sold
+	// along with a whole one
+	orders := []testOrder{
+		buy("1", "100", 0),
+		buy("1", "200", 1),
+		buy("1", "150", 2),
+		sell("1.5", "300", 3),
+	}
+
+	tests := []struct {
+		method gaivota.CostBasisMethod
+		// Remaining amount of each lot, in acquisition order
+		remaining []string
+		costBasis string
+		profit    string
+	}{
+		{gaivota.CostBasisFIFO, []string{"0", "0.5", "1"}, "250", "250"},
+		{gaivota.CostBasisLIFO, []string{"1", "0.5", "0"}, "200", "200"},
+		{gaivota.CostBasisHIFO, []string{"1", "0", "0.5"}, "175", "175"},
+		// Sold at the average cost of 150, still consuming lots first-in first-out
+		{gaivota.CostBasisAverage, []string{"0", "0.5", "1"}, "225", "225"},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.method), func(t *testing.T) {
+			client, positionId, result := replay(t, test.method, orders)
+
+			assertDecimal(t, "Amount", result.Amount, "1.5")
+			assertDecimal(t, "CostBasis", result.CostBasis, test.costBasis)
+			assertDecimal(t, "RealizedProfit", result.RealizedProfit, test.profit)
+
+			if len(result.Lots) != len(test.remaining) {
+				t.Fatalf("Replay opened %d lots, expected %d", len(result.Lots), len(test.remaining))
+			}
+			for i, lot := range result.Lots {
+				assertDecimal(t, "RemainingAmount of lot "+lot.AcquiredAt.Format("2006-01-02"), lot.RemainingAmount, test.remaining[i])
+			}
+
+			position, err := client.PositionStore.Get(context.Background(), positionId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertDecimal(t, "Stored amount", position.Amount, "1.5")
+			assertDecimal(t, "Stored profit", position.Profit, test.profit)
+		})
+	}
+}
+
+type testGain struct {
+	amount        string
+	profit        string
+	holdingPeriod accounting.HoldingPeriod
+}
+
+func TestReplayPartialDisposals(t *testing.T) {
+	tests := []struct {
+		name   string
+		orders []testOrder
+		// Amount, profit and holding period of each gain
+		gains []testGain
+	}{
+		{
+			name:   "within a lot",
+			orders: []testOrder{buy("2", "100", 0), sell("0.5", "120", 1), sell("0.5", "80", 2)},
+			gains:  []testGain{{"0.5", "10", accounting.HoldingPeriodShort}, {"0.5", "-10", accounting.HoldingPeriodShort}},
+		},
+		{
+			name:   "across lots",
+			orders: []testOrder{buy("1", "100", 0), buy("1", "200", 400), sell("1.5", "300", 401)},
+			gains:  []testGain{{"1", "200", accounting.HoldingPeriodLong}, {"0.5", "50", accounting.HoldingPeriodShort}},
+		},
+		{
+			name:   "closing the position",
+			orders: []testOrder{buy("1", "100", 0), sell("0.25", "200", 1), sell("0.75", "200", 2)},
+			gains:  []testGain{{"0.25", "25", accounting.HoldingPeriodShort}, {"0.75", "75", accounting.HoldingPeriodShort}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, result := replay(t, gaivota.CostBasisFIFO, test.orders)
+
+			if len(result.Gains) != len(test.gains) {
+				t.Fatalf("Replay realized %d gains, expected %d", len(result.Gains), len(test.gains))
+			}
+			for i, gain := range result.Gains {
+				assertDecimal(t, "Amount", gain.Amount, test.gains[i].amount)
+				assertDecimal(t, "Profit", gain.Profit, test.gains[i].profit)
+				if gain.HoldingPeriod != test.gains[i].holdingPeriod {
+					t.Errorf("Gain %d is held %s term, expected %s", i+1, gain.HoldingPeriod, test.gains[i].holdingPeriod)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayFees(t *testing.T) {
+	tests := []struct {
+		name      string
+		orders    []testOrder
+		lotPrices []string
+		proceeds  []string
+		profit    string
+		fees      string
+	}{
+		{
+			name:      "buy fee adds to the lot's cost",
+			orders:    []testOrder{buy("2", "100", 0).withFee("10"), sell("1", "150", 1)},
+			lotPrices: []string{"105"},
+			proceeds:  []string{"150"},
+			profit:    "45",
+			fees:      "10",
+		},
+		{
+			name:      "sell fee reduces the proceeds",
+			orders:    []testOrder{buy("1", "100", 0), sell("1", "150", 1).withFee("4")},
+			lotPrices: []string{"100"},
+			proceeds:  []string{"146"},
+			profit:    "46",
+			fees:      "4",
+		},
+		{
+			name:      "sell fee is shared by the lots consumed",
+			orders:    []testOrder{buy("1", "100", 0), buy("3", "200", 1), sell("2", "300", 2).withFee("10")},
+			lotPrices: []string{"100", "200"},
+			proceeds:  []string{"295", "295"},
+			profit:    "290",
+			fees:      "10",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, result := replay(t, gaivota.CostBasisFIFO, test.orders)
+
+			assertDecimal(t, "RealizedProfit", result.RealizedProfit, test.profit)
+			assertDecimal(t, "Fees", result.Fees, test.fees)
+
+			if len(result.Lots) != len(test.lotPrices) {
+				t.Fatalf("Replay opened %d lots, expected %d", len(result.Lots), len(test.lotPrices))
+			}
+			for i, lot := range result.Lots {
+				assertDecimal(t, "UnitPrice", lot.UnitPrice, test.lotPrices[i])
+			}
+
+			if len(result.Gains) != len(test.proceeds) {
+				t.Fatalf("Replay realized %d gains, expected %d", len(result.Gains), len(test.proceeds))
+			}
+			for i, gain := range result.Gains {
+				assertDecimal(t, "Proceeds", gain.Proceeds, test.proceeds[i])
+			}
+		})
+	}
+}
+
+func TestReplayOversell(t *testing.T) {
+	tests := []struct {
+		name   string
+		orders []testOrder
+		sell   testOrder
+	}{
+		{"more than bought", []testOrder{buy("1", "100", 1)}, sell("1.5", "100", 2)},
+		{"before buying", []testOrder{buy("1", "100", 1)}, sell("1", "100", 0)},
+		{"what was sold already", []testOrder{buy("1", "100", 0), sell("0.75", "100", 1)}, sell("0.5", "100", 2)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, positionId, before := replay(t, gaivota.CostBasisFIFO, test.orders)
+
+			err := addOrder(client, positionId, test.sell)
+			if !errors.Is(err, accounting.ErrOversold) {
+				t.Fatalf("Selling %s answered %v, expected ErrOversold", test.sell.amount, err)
+			}
+
+			position, err := client.PositionStore.Get(context.Background(), positionId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertDecimal(t, "Amount after the oversell", position.Amount, before.Amount.String())
+
+			orders, err := client.OrderStore.GetByPositionID(context.Background(), positionId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != len(test.orders) {
+				t.Errorf("Position has %d orders, expected the oversell not to be stored", len(orders))
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package audit records every change made through the stores in the audit
+// log: which entity changed, who changed it and the entity before and after.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Entities recorded in the audit log, named after their tables
+var Entities = []string{
+	"users", "api_keys", "portfolios", "wallets", "investments", "positions",
+	"holdings", "transfers", "orders", "events", "prices", "fx_rates", "portfolio_snapshots",
+}
+
+type actorKey struct{}
+
+// WithActor returns a context for changes made by the actor
+func WithActor(ctx context.Context, actor gaivota.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor changes are made by, if any
+func ActorFrom(ctx context.Context) (gaivota.Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(gaivota.Actor)
+
+	return actor, ok
+}
+
+// Record wraps the client's stores so that every insert, update and delete
+// appends entries to the client's AuditStore, in the same transaction as the
+// change. Changes are attributed to the context's actor (see WithActor), or
+// to `actor` when there is none.
+//
+// Positions and lots recomputed from orders and events are not recorded
+// separately: the entries of the orders and events explain them.
+func Record(client *gaivota.Client, actor gaivota.Actor) *gaivota.Client {
+	recorder := &recorder{client: client, actor: actor}
+
+	recorded := *client
+	recorded.UserStore = &userStore{UserStore: client.UserStore, recorder: recorder}
+	recorded.APIKeyStore = &apiKeyStore{APIKeyStore: client.APIKeyStore, recorder: recorder}
+	recorded.PortfolioStore = &portfolioStore{PortfolioStore: client.PortfolioStore, recorder: recorder}
+	recorded.WalletStore = &walletStore{WalletStore: client.WalletStore, recorder: recorder}
+	recorded.InvestmentStore = &investmentStore{InvestmentStore: client.InvestmentStore, recorder: recorder}
+	recorded.PositionStore = &positionStore{PositionStore: client.PositionStore, recorder: recorder}
+	recorded.HoldingStore = &holdingStore{HoldingStore: client.HoldingStore, recorder: recorder}
+	recorded.TransferStore = &transferStore{TransferStore: client.TransferStore, recorder: recorder}
+	recorded.OrderStore = &orderStore{OrderStore: client.OrderStore, recorder: recorder}
+	recorded.EventStore = &eventStore{EventStore: client.EventStore, recorder: recorder}
+	recorded.PriceStore = &priceStore{PriceStore: client.PriceStore, recorder: recorder}
+	recorded.FXRateStore = &fxRateStore{FXRateStore: client.FXRateStore, recorder: recorder}
+	recorded.SnapshotStore = &snapshotStore{SnapshotStore: client.SnapshotStore, recorder: recorder}
+	if client.Transactor != nil {
+		recorded.Transactor = &transactor{transactor: client.Transactor, actor: actor}
+	}
+
+	return &recorded
+}
+
+// Records the changes made with the clients handed out by the transactor too
+type transactor struct {
+	transactor gaivota.Transactor
+	actor      gaivota.Actor
+}
+
+func (recorded *transactor) WithinTx(ctx context.Context, fn func(*gaivota.Client) error) error {
+	return recorded.transactor.WithinTx(ctx, func(tx *gaivota.Client) error {
+		return fn(Record(tx, recorded.actor))
+	})
+}
+
+// A change to an entity, turned into an AuditEntry
+type change struct {
+	entity string
+	id     int
+	action gaivota.AuditAction
+	// User owning the entity, 0 for shared ones
+	owner  int
+	before interface{}
+	after  interface{}
+}
+
+// Makes changes through the client's stores, which are not recorded
+type recorder struct {
+	client *gaivota.Client
+	actor  gaivota.Actor
+}
+
+// Runs fn in a transaction and appends an entry for each change it returns.
+// When an entry cannot be appended, the changes are rolled back.
+func (recorder *recorder) record(ctx context.Context, fn func(tx *gaivota.Client) ([]change, error)) error {
+	actor, ok := ActorFrom(ctx)
+	if !ok {
+		actor = recorder.actor
+	}
+
+	return recorder.client.WithinTx(ctx, func(tx *gaivota.Client) error {
+		changes, err := fn(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range changes {
+			entry := &gaivota.AuditEntry{
+				Entity:   c.entity,
+				EntityID: c.id,
+				Action:   c.action,
+				Actor:    actor,
+				UserID:   c.owner,
+			}
+
+			if c.before != nil {
+				if entry.Before, err = json.Marshal(c.before); err != nil {
+					return err
+				}
+			}
+
+			if c.after != nil {
+				if entry.After, err = json.Marshal(c.after); err != nil {
+					return err
+				}
+			}
+
+			if _, err := tx.AuditStore.Add(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Owners of the entities, looked up in the transaction. Entities whose owner
+// cannot be found, e.g. in a deleted portfolio, are recorded without owner.
+
+func portfolioOwner(ctx context.Context, tx *gaivota.Client, portfolioId int) int {
+	portfolio, err := tx.PortfolioStore.Get(ctx, portfolioId)
+	if err != nil {
+		return 0
+	}
+
+	return portfolio.UserID
+}
+
+func walletOwner(ctx context.Context, tx *gaivota.Client, walletId int) int {
+	wallet, err := tx.WalletStore.Get(ctx, walletId)
+	if err != nil {
+		return 0
+	}
+
+	return wallet.UserID
+}
+
+func investmentOwner(ctx context.Context, tx *gaivota.Client, investmentId int) int {
+	investment, err := tx.InvestmentStore.Get(ctx, investmentId)
+	if err != nil {
+		return 0
+	}
+
+	return portfolioOwner(ctx, tx, investment.PortfolioID)
+}
+
+func positionOwner(ctx context.Context, tx *gaivota.Client, positionId int) int {
+	position, err := tx.PositionStore.Get(ctx, positionId)
+	if err != nil {
+		return 0
+	}
+
+	return investmentOwner(ctx, tx, position.InvestmentID)
+}
@@ -0,0 +1,552 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Reads an entity in the transaction, with the user owning it
+type getter func(ctx context.Context, tx *gaivota.Client, id int) (entity interface{}, owner int, err error)
+
+// Records the entity added by fn
+func (recorder *recorder) insert(ctx context.Context, entity string, get getter, fn func(tx *gaivota.Client) (int, error)) error {
+	return recorder.record(ctx, func(tx *gaivota.Client) ([]change, error) {
+		id, err := fn(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		after, owner, err := get(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return []change{{entity: entity, id: id, action: gaivota.AuditActionInsert, owner: owner, after: after}}, nil
+	})
+}
+
+// Records the entity before and after fn updates it
+func (recorder *recorder) update(ctx context.Context, entity string, id int, get getter, fn func(tx *gaivota.Client) error) error {
+	return recorder.record(ctx, func(tx *gaivota.Client) ([]change, error) {
+		before, _, err := get(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := fn(tx); err != nil {
+			return nil, err
+		}
+
+		after, owner, err := get(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return []change{{entity: entity, id: id, action: gaivota.AuditActionUpdate, owner: owner, before: before, after: after}}, nil
+	})
+}
+
+// Records the entity fn deletes
+func (recorder *recorder) delete(ctx context.Context, entity string, id int, get getter, fn func(tx *gaivota.Client) error) error {
+	return recorder.record(ctx, func(tx *gaivota.Client) ([]change, error) {
+		before, owner, err := get(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := fn(tx); err != nil {
+			return nil, err
+		}
+
+		return []change{{entity: entity, id: id, action: gaivota.AuditActionDelete, owner: owner, before: before}}, nil
+	})
+}
+
+type userStore struct {
+	gaivota.UserStore
+	recorder *recorder
+}
+
+func getUser(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	user, err := tx.UserStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return user, user.ID, nil
+}
+
+func (recorded *userStore) Add(ctx context.Context, user *gaivota.User) (newUser *gaivota.User, err error) {
+	err = recorded.recorder.insert(ctx, "users", getUser, func(tx *gaivota.Client) (int, error) {
+		newUser, err = tx.UserStore.Add(ctx, user)
+		if err != nil {
+			return 0, err
+		}
+		return newUser.ID, nil
+	})
+
+	return newUser, err
+}
+
+func (recorded *userStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "users", id, getUser, func(tx *gaivota.Client) error {
+		return tx.UserStore.Delete(ctx, id)
+	})
+}
+
+// The hash is not part of the entries, only that the user changed
+func (recorded *userStore) SetPasswordHash(ctx context.Context, id int, hash string) error {
+	return recorded.recorder.update(ctx, "users", id, getUser, func(tx *gaivota.Client) error {
+		return tx.UserStore.SetPasswordHash(ctx, id, hash)
+	})
+}
+
+func (recorded *userStore) Update(ctx context.Context, user *gaivota.User) error {
+	return recorded.recorder.update(ctx, "users", user.ID, getUser, func(tx *gaivota.Client) error {
+		return tx.UserStore.Update(ctx, user)
+	})
+}
+
+type apiKeyStore struct {
+	gaivota.APIKeyStore
+	recorder *recorder
+}
+
+func getAPIKey(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	key, err := tx.APIKeyStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return key, key.UserID, nil
+}
+
+func (recorded *apiKeyStore) Add(ctx context.Context, key *gaivota.APIKey) (newKey *gaivota.APIKey, err error) {
+	err = recorded.recorder.insert(ctx, "api_keys", getAPIKey, func(tx *gaivota.Client) (int, error) {
+		newKey, err = tx.APIKeyStore.Add(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		return newKey.ID, nil
+	})
+
+	return newKey, err
+}
+
+func (recorded *apiKeyStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "api_keys", id, getAPIKey, func(tx *gaivota.Client) error {
+		return tx.APIKeyStore.Delete(ctx, id)
+	})
+}
+
+type portfolioStore struct {
+	gaivota.PortfolioStore
+	recorder *recorder
+}
+
+func getPortfolio(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	portfolio, err := tx.PortfolioStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return portfolio, portfolio.UserID, nil
+}
+
+func (recorded *portfolioStore) Add(ctx context.Context, portfolio *gaivota.Portfolio) (newPortfolio *gaivota.Portfolio, err error) {
+	err = recorded.recorder.insert(ctx, "portfolios", getPortfolio, func(tx *gaivota.Client) (int, error) {
+		newPortfolio, err = tx.PortfolioStore.Add(ctx, portfolio)
+		if err != nil {
+			return 0, err
+		}
+		return newPortfolio.ID, nil
+	})
+
+	return newPortfolio, err
+}
+
+func (recorded *portfolioStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "portfolios", id, getPortfolio, func(tx *gaivota.Client) error {
+		return tx.PortfolioStore.Delete(ctx, id)
+	})
+}
+
+func (recorded *portfolioStore) Update(ctx context.Context, portfolio *gaivota.Portfolio) error {
+	return recorded.recorder.update(ctx, "portfolios", portfolio.ID, getPortfolio, func(tx *gaivota.Client) error {
+		return tx.PortfolioStore.Update(ctx, portfolio)
+	})
+}
+
+type walletStore struct {
+	gaivota.WalletStore
+	recorder *recorder
+}
+
+func getWallet(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	wallet, err := tx.WalletStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return wallet, wallet.UserID, nil
+}
+
+func (recorded *walletStore) Add(ctx context.Context, wallet *gaivota.Wallet) (newWallet *gaivota.Wallet, err error) {
+	err = recorded.recorder.insert(ctx, "wallets", getWallet, func(tx *gaivota.Client) (int, error) {
+		newWallet, err = tx.WalletStore.Add(ctx, wallet)
+		if err != nil {
+			return 0, err
+		}
+		return newWallet.ID, nil
+	})
+
+	return newWallet, err
+}
+
+func (recorded *walletStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "wallets", id, getWallet, func(tx *gaivota.Client) error {
+		return tx.WalletStore.Delete(ctx, id)
+	})
+}
+
+func (recorded *walletStore) Update(ctx context.Context, wallet *gaivota.Wallet) error {
+	return recorded.recorder.update(ctx, "wallets", wallet.ID, getWallet, func(tx *gaivota.Client) error {
+		return tx.WalletStore.Update(ctx, wallet)
+	})
+}
+
+type investmentStore struct {
+	gaivota.InvestmentStore
+	recorder *recorder
+}
+
+func getInvestment(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	investment, err := tx.InvestmentStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return investment, portfolioOwner(ctx, tx, investment.PortfolioID), nil
+}
+
+func (recorded *investmentStore) Add(ctx context.Context, investment *gaivota.Investment) (newInvestment *gaivota.Investment, err error) {
+	err = recorded.recorder.insert(ctx, "investments", getInvestment, func(tx *gaivota.Client) (int, error) {
+		newInvestment, err = tx.InvestmentStore.Add(ctx, investment)
+		if err != nil {
+			return 0, err
+		}
+		return newInvestment.ID, nil
+	})
+
+	return newInvestment, err
+}
+
+func (recorded *investmentStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "investments", id, getInvestment, func(tx *gaivota.Client) error {
+		return tx.InvestmentStore.Delete(ctx, id)
+	})
+}
+
+func (recorded *investmentStore) Update(ctx context.Context, investment *gaivota.Investment) error {
+	return recorded.recorder.update(ctx, "investments", investment.ID, getInvestment, func(tx *gaivota.Client) error {
+		return tx.InvestmentStore.Update(ctx, investment)
+	})
+}
+
+type positionStore struct {
+	gaivota.PositionStore
+	recorder *recorder
+}
+
+func getPosition(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	position, err := tx.PositionStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return position, investmentOwner(ctx, tx, position.InvestmentID), nil
+}
+
+func (recorded *positionStore) Add(ctx context.Context, position *gaivota.Position) (newPosition *gaivota.Position, err error) {
+	err = recorded.recorder.insert(ctx, "positions", getPosition, func(tx *gaivota.Client) (int, error) {
+		newPosition, err = tx.PositionStore.Add(ctx, position)
+		if err != nil {
+			return 0, err
+		}
+		return newPosition.ID, nil
+	})
+
+	return newPosition, err
+}
+
+func (recorded *positionStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "positions", id, getPosition, func(tx *gaivota.Client) error {
+		return tx.PositionStore.Delete(ctx, id)
+	})
+}
+
+func (recorded *positionStore) Update(ctx context.Context, position *gaivota.Position) error {
+	return recorded.recorder.update(ctx, "positions", position.ID, getPosition, func(tx *gaivota.Client) error {
+		return tx.PositionStore.Update(ctx, position)
+	})
+}
+
+type holdingStore struct {
+	gaivota.HoldingStore
+	recorder *recorder
+}
+
+func getHolding(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	holding, err := tx.HoldingStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return holding, walletOwner(ctx, tx, holding.WalletID), nil
+}
+
+func (recorded *holdingStore) Add(ctx context.Context, holding *gaivota.Holding) (newHolding *gaivota.Holding, err error) {
+	err = recorded.recorder.insert(ctx, "holdings", getHolding, func(tx *gaivota.Client) (int, error) {
+		newHolding, err = tx.HoldingStore.Add(ctx, holding)
+		if err != nil {
+			return 0, err
+		}
+		return newHolding.ID, nil
+	})
+
+	return newHolding, err
+}
+
+func (recorded *holdingStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "holdings", id, getHolding, func(tx *gaivota.Client) error {
+		return tx.HoldingStore.Delete(ctx, id)
+	})
+}
+
+func (recorded *holdingStore) Update(ctx context.Context, holding *gaivota.Holding) error {
+	return recorded.recorder.update(ctx, "holdings", holding.ID, getHolding, func(tx *gaivota.Client) error {
+		return tx.HoldingStore.Update(ctx, holding)
+	})
+}
+
+type transferStore struct {
+	gaivota.TransferStore
+	recorder *recorder
+}
+
+func getTransfer(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	transfer, err := tx.TransferStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transfer, walletOwner(ctx, tx, transfer.FromWalletID), nil
+}
+
+func (recorded *transferStore) Add(ctx context.Context, transfer *gaivota.Transfer) (newTransfer *gaivota.Transfer, err error) {
+	err = recorded.recorder.insert(ctx, "transfers", getTransfer, func(tx *gaivota.Client) (int, error) {
+		newTransfer, err = tx.TransferStore.Add(ctx, transfer)
+		if err != nil {
+			return 0, err
+		}
+		return newTransfer.ID, nil
+	})
+
+	return newTransfer, err
+}
+
+type orderStore struct {
+	gaivota.OrderStore
+	recorder *recorder
+}
+
+func getOrder(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	order, err := tx.OrderStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return order, positionOwner(ctx, tx, order.PositionID), nil
+}
+
+func (recorded *orderStore) Add(ctx context.Context, order *gaivota.Order) (newOrder *gaivota.Order, err error) {
+	err = recorded.recorder.insert(ctx, "orders", getOrder, func(tx *gaivota.Client) (int, error) {
+		newOrder, err = tx.OrderStore.Add(ctx, order)
+		if err != nil {
+			return 0, err
+		}
+		return newOrder.ID, nil
+	})
+
+	return newOrder, err
+}
+
+// Records an entry per order
+func (recorded *orderStore) AddMany(ctx context.Context, orders []gaivota.Order) (newOrders []gaivota.Order, err error) {
+	err = recorded.recorder.record(ctx, func(tx *gaivota.Client) ([]change, error) {
+		newOrders, err = tx.OrderStore.AddMany(ctx, orders)
+		if err != nil {
+			return nil, err
+		}
+
+		changes := make([]change, 0, len(newOrders))
+		owners := map[int]int{}
+
+		for i := range newOrders {
+			order := &newOrders[i]
+
+			owner, ok := owners[order.PositionID]
+			if !ok {
+				owner = positionOwner(ctx, tx, order.PositionID)
+				owners[order.PositionID] = owner
+			}
+
+			changes = append(changes, change{
+				entity: "orders", id: order.ID, action: gaivota.AuditActionInsert, owner: owner, after: order,
+			})
+		}
+
+		return changes, nil
+	})
+
+	return newOrders, err
+}
+
+func (recorded *orderStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "orders", id, getOrder, func(tx *gaivota.Client) error {
+		return tx.OrderStore.Delete(ctx, id)
+	})
+}
+
+func (recorded *orderStore) Update(ctx context.Context, order *gaivota.Order) error {
+	return recorded.recorder.update(ctx, "orders", order.ID, getOrder, func(tx *gaivota.Client) error {
+		return tx.OrderStore.Update(ctx, order)
+	})
+}
+
+type eventStore struct {
+	gaivota.EventStore
+	recorder *recorder
+}
+
+func getEvent(ctx context.Context, tx *gaivota.Client, id int) (interface{}, int, error) {
+	event, err := tx.EventStore.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return event, positionOwner(ctx, tx, event.PositionID), nil
+}
+
+func (recorded *eventStore) Add(ctx context.Context, event *gaivota.Event) (newEvent *gaivota.Event, err error) {
+	err = recorded.recorder.insert(ctx, "events", getEvent, func(tx *gaivota.Client) (int, error) {
+		newEvent, err = tx.EventStore.Add(ctx, event)
+		if err != nil {
+			return 0, err
+		}
+		return newEvent.ID, nil
+	})
+
+	return newEvent, err
+}
+
+func (recorded *eventStore) Delete(ctx context.Context, id int) error {
+	return recorded.recorder.delete(ctx, "events", id, getEvent, func(tx *gaivota.Client) error {
+		return tx.EventStore.Delete(ctx, id)
+	})
+}
+
+func (recorded *eventStore) Update(ctx context.Context, event *gaivota.Event) error {
+	return recorded.recorder.update(ctx, "events", event.ID, getEvent, func(tx *gaivota.Client) error {
+		return tx.EventStore.Update(ctx, event)
+	})
+}
+
+// Prices and exchange rates replace the ones at the same time, which are
+// recorded as updates
+
+type priceStore struct {
+	gaivota.PriceStore
+	recorder *recorder
+}
+
+func (recorded *priceStore) Add(ctx context.Context, price *gaivota.Price) (newPrice *gaivota.Price, err error) {
+	err = recorded.recorder.record(ctx, func(tx *gaivota.Client) ([]change, error) {
+		var before interface{}
+		action := gaivota.AuditActionInsert
+
+		existing, err := tx.PriceStore.GetAt(ctx, price.TokenSymbol, price.QuoteCurrency, price.At)
+		if err == nil && existing.At.Equal(price.At) {
+			before = existing
+			action = gaivota.AuditActionUpdate
+		}
+
+		newPrice, err = tx.PriceStore.Add(ctx, price)
+		if err != nil {
+			return nil, err
+		}
+
+		return []change{{entity: "prices", id: newPrice.ID, action: action, before: before, after: newPrice}}, nil
+	})
+
+	return newPrice, err
+}
+
+type fxRateStore struct {
+	gaivota.FXRateStore
+	recorder *recorder
+}
+
+func (recorded *fxRateStore) Add(ctx context.Context, rate *gaivota.FXRate) (newRate *gaivota.FXRate, err error) {
+	err = recorded.recorder.record(ctx, func(tx *gaivota.Client) ([]change, error) {
+		var before interface{}
+		action := gaivota.AuditActionInsert
+
+		existing, err := tx.FXRateStore.GetAt(ctx, rate.BaseCurrency, rate.QuoteCurrency, rate.At)
+		if err == nil && existing.At.Equal(rate.At) {
+			before = existing
+			action = gaivota.AuditActionUpdate
+		}
+
+		newRate, err = tx.FXRateStore.Add(ctx, rate)
+		if err != nil {
+			return nil, err
+		}
+
+		return []change{{entity: "fx_rates", id: newRate.ID, action: action, before: before, after: newRate}}, nil
+	})
+
+	return newRate, err
+}
+
+type snapshotStore struct {
+	gaivota.SnapshotStore
+	recorder *recorder
+}
+
+// Snapshots replace the one of the portfolio on the same date
+func (recorded *snapshotStore) Add(ctx context.Context, snapshot *gaivota.PortfolioSnapshot) (newSnapshot *gaivota.PortfolioSnapshot, err error) {
+	err = recorded.recorder.record(ctx, func(tx *gaivota.Client) ([]change, error) {
+		var before interface{}
+		action := gaivota.AuditActionInsert
+
+		existing, err := tx.SnapshotStore.GetRange(ctx, snapshot.PortfolioID, snapshot.Date, snapshot.Date)
+		if err == nil && len(*existing) > 0 {
+			before = &(*existing)[0]
+			action = gaivota.AuditActionUpdate
+		}
+
+		newSnapshot, err = tx.SnapshotStore.Add(ctx, snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		return []change{{
+			entity: "portfolio_snapshots", id: newSnapshot.ID, action: action,
+			owner: portfolioOwner(ctx, tx, snapshot.PortfolioID), before: before, after: newSnapshot,
+		}}, nil
+	})
+
+	return newSnapshot, err
+}
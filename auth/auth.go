@@ -0,0 +1,159 @@
+// Package auth authenticates API callers, with passwords, signed session
+// tokens and API keys, and scopes the stores to the caller's own data.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/leoschet/gaivota"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned for unknown emails, wrong passwords,
+// invalid or expired tokens and revoked API keys alike
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Prefix of every API key, which tells them apart from session tokens
+const APIKeyPrefix = "gaivota_"
+
+// Passwords shorter than this are refused
+const MinPasswordLength = 8
+
+type userKey struct{}
+
+// WithUser returns a context for requests made by the user. Scoped stores
+// only see that user's data.
+func WithUser(ctx context.Context, userId int) context.Context {
+	return context.WithValue(ctx, userKey{}, userId)
+}
+
+// UserID returns the user requests are made for, if any
+func UserID(ctx context.Context) (int, bool) {
+	userId, ok := ctx.Value(userKey{}).(int)
+
+	return userId, ok
+}
+
+// HashPassword hashes a password with bcrypt
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		var errs gaivota.FieldErrors
+		errs.Add("password", "must have at least %d characters", MinPasswordLength)
+		return "", errs.Err("invalid password")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Login checks the user's password. Users without a password cannot log in.
+func Login(ctx context.Context, store gaivota.UserStore, email string, password string) (*gaivota.User, error) {
+	user, err := store.GetByEmail(ctx, email)
+	if errors.Is(err, gaivota.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// NewAPIKey creates a random API key for the user and stores its hash. The
+// returned key cannot be recovered later.
+func NewAPIKey(ctx context.Context, store gaivota.APIKeyStore, userId int, name string) (string, *gaivota.APIKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+
+	key := APIKeyPrefix + hex.EncodeToString(secret)
+
+	apiKey, err := store.Add(ctx, &gaivota.APIKey{
+		UserID:  userId,
+		Name:    name,
+		KeyHash: HashAPIKey(key),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return key, apiKey, nil
+}
+
+// HashAPIKey returns the hex SHA-256 of the key. Keys are random, so a slow
+// hash like bcrypt is not needed.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Authenticator resolves the user behind a session token or an API key
+type Authenticator struct {
+	tokens *Tokens
+	users  gaivota.UserStore
+	keys   gaivota.APIKeyStore
+}
+
+func NewAuthenticator(tokens *Tokens, users gaivota.UserStore, keys gaivota.APIKeyStore) *Authenticator {
+	return &Authenticator{
+		tokens: tokens,
+		users:  users,
+		keys:   keys,
+	}
+}
+
+// Authenticate returns the ID of the user the credential belongs to, and the
+// actor changes are made by: the user with a session token, or the API key.
+// Credentials of deleted users are rejected, even when not expired.
+func (authenticator *Authenticator) Authenticate(ctx context.Context, credential string) (int, gaivota.Actor, error) {
+	var userId int
+	var actor gaivota.Actor
+
+	if strings.HasPrefix(credential, APIKeyPrefix) {
+		key, err := authenticator.keys.GetByHash(ctx, HashAPIKey(credential))
+		if errors.Is(err, gaivota.ErrNotFound) {
+			return 0, gaivota.Actor{}, ErrInvalidCredentials
+		}
+		if err != nil {
+			return 0, gaivota.Actor{}, err
+		}
+
+		userId, actor = key.UserID, gaivota.Actor{Type: gaivota.ActorTypeAPIKey, ID: key.ID}
+	} else {
+		var err error
+		if userId, err = authenticator.tokens.Verify(credential); err != nil {
+			return 0, gaivota.Actor{}, err
+		}
+
+		actor = gaivota.Actor{Type: gaivota.ActorTypeUser, ID: userId}
+	}
+
+	_, err := authenticator.users.Get(ctx, userId)
+	if errors.Is(err, gaivota.ErrNotFound) {
+		return 0, gaivota.Actor{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return 0, gaivota.Actor{}, err
+	}
+
+	return userId, actor, nil
+}
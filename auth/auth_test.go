@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leoschet/gaivota/inmem"
+)
+
+func TestAuthenticateRejectsDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	client := inmem.New().NewClient()
+	user := addUserData(t, client, "alice@example.com").user
+
+	tokens, err := NewTokens("abcdefghijklmnopqrstuvwxyz0123456789", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewAuthenticator(tokens, client.UserStore, client.APIKeyStore)
+
+	token, _, err := tokens.Issue(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := NewAPIKey(ctx, client.APIKeyStore, user.ID, "CI")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, credential := range []string{token, key} {
+		userId, _, err := authenticator.Authenticate(ctx, credential)
+		if err != nil || userId != user.ID {
+			t.Fatalf("Authenticated user %d (%v), expected %d", userId, err, user.ID)
+		}
+	}
+
+	if err := client.UserStore.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, credential := range map[string]string{"token": token, "API key": key} {
+		if _, _, err := authenticator.Authenticate(ctx, credential); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("The deleted user's %s answered %v, expected ErrInvalidCredentials", name, err)
+		}
+	}
+}
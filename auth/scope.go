@@ -0,0 +1,994 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Scope wraps the client's stores so that requests made by a user (see
+// WithUser) only read and write that user's data. Other users' data is
+// reported as gaivota.ErrNotFound, so its existence is not disclosed, and
+// giving entities to other users is gaivota.ErrForbidden.
+// Requests without a user, from the CLI or background jobs, see everything.
+// Prices and exchange rates are shared by all users.
+func Scope(client *gaivota.Client) *gaivota.Client {
+	owners := &owners{client: client}
+
+	scoped := *client
+	scoped.UserStore = &userStore{store: client.UserStore}
+	scoped.PortfolioStore = &portfolioStore{store: client.PortfolioStore, owners: owners}
+	scoped.WalletStore = &walletStore{store: client.WalletStore, owners: owners}
+	scoped.InvestmentStore = &investmentStore{store: client.InvestmentStore, owners: owners}
+	scoped.PositionStore = &positionStore{store: client.PositionStore, owners: owners}
+	scoped.HoldingStore = &holdingStore{store: client.HoldingStore, owners: owners}
+	scoped.TransferStore = &transferStore{store: client.TransferStore, owners: owners}
+	scoped.OrderStore = &orderStore{store: client.OrderStore, owners: owners}
+	scoped.EventStore = &eventStore{store: client.EventStore, owners: owners}
+	scoped.LotStore = &lotStore{store: client.LotStore, owners: owners}
+	scoped.SnapshotStore = &snapshotStore{store: client.SnapshotStore, owners: owners}
+	scoped.APIKeyStore = &apiKeyStore{store: client.APIKeyStore}
+	scoped.AuditStore = &auditStore{store: client.AuditStore}
+	if client.Transactor != nil {
+		scoped.Transactor = &transactor{transactor: client.Transactor}
+	}
+
+	return &scoped
+}
+
+// Scopes the clients handed out by the transactor too
+type transactor struct {
+	transactor gaivota.Transactor
+}
+
+func (scoped *transactor) WithinTx(ctx context.Context, fn func(*gaivota.Client) error) error {
+	return scoped.transactor.WithinTx(ctx, func(tx *gaivota.Client) error {
+		return fn(Scope(tx))
+	})
+}
+
+// Returns gaivota.ErrNotFound when the entity is owned by another user
+func owned(entity string, id int, userId int, ownerId int) error {
+	if userId != ownerId {
+		return fmt.Errorf("Could not get %s %v: %w", entity, id, gaivota.ErrNotFound)
+	}
+
+	return nil
+}
+
+// Returns gaivota.ErrForbidden when an entity is given to another user than
+// the caller. Unlike reads, this discloses nothing about the other user.
+func ownedByCaller(entity string, userId int, ownerId int) error {
+	if userId != ownerId {
+		return &gaivota.Error{
+			Kind:    gaivota.ErrForbidden,
+			Message: fmt.Sprintf("cannot give a %s to another user", entity),
+			Fields:  []gaivota.FieldError{{Field: "user", Message: "must be the caller"}},
+		}
+	}
+
+	return nil
+}
+
+// Checks who owns entities through the unscoped stores
+type owners struct {
+	client *gaivota.Client
+}
+
+func (owners *owners) portfolio(ctx context.Context, userId int, portfolioId int) error {
+	portfolio, err := owners.client.PortfolioStore.Get(ctx, portfolioId)
+	if err != nil {
+		return err
+	}
+
+	return owned("portfolio", portfolioId, userId, portfolio.UserID)
+}
+
+func (owners *owners) wallet(ctx context.Context, userId int, walletId int) error {
+	wallet, err := owners.client.WalletStore.Get(ctx, walletId)
+	if err != nil {
+		return err
+	}
+
+	return owned("wallet", walletId, userId, wallet.UserID)
+}
+
+func (owners *owners) investment(ctx context.Context, userId int, investmentId int) error {
+	investment, err := owners.client.InvestmentStore.Get(ctx, investmentId)
+	if err != nil {
+		return err
+	}
+
+	return owners.portfolio(ctx, userId, investment.PortfolioID)
+}
+
+func (owners *owners) position(ctx context.Context, userId int, positionId int) error {
+	position, err := owners.client.PositionStore.Get(ctx, positionId)
+	if err != nil {
+		return err
+	}
+
+	return owners.investment(ctx, userId, position.InvestmentID)
+}
+
+func (owners *owners) holding(ctx context.Context, userId int, holdingId int) error {
+	holding, err := owners.client.HoldingStore.Get(ctx, holdingId)
+	if err != nil {
+		return err
+	}
+
+	if err := owners.wallet(ctx, userId, holding.WalletID); err != nil {
+		return err
+	}
+
+	return owners.position(ctx, userId, holding.PositionID)
+}
+
+func (owners *owners) order(ctx context.Context, userId int, orderId int) error {
+	order, err := owners.client.OrderStore.Get(ctx, orderId)
+	if err != nil {
+		return err
+	}
+
+	return owners.position(ctx, userId, order.PositionID)
+}
+
+func (owners *owners) event(ctx context.Context, userId int, eventId int) error {
+	event, err := owners.client.EventStore.Get(ctx, eventId)
+	if err != nil {
+		return err
+	}
+
+	return owners.position(ctx, userId, event.PositionID)
+}
+
+type userStore struct {
+	store gaivota.UserStore
+}
+
+// Signing up is open to anyone
+func (scoped *userStore) Add(ctx context.Context, user *gaivota.User) (*gaivota.User, error) {
+	return scoped.store.Add(ctx, user)
+}
+
+// Lists the caller's own user only
+func (scoped *userStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.User, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *userStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *userStore) Get(ctx context.Context, id int) (*gaivota.User, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Get(ctx, id)
+}
+
+func (scoped *userStore) GetByEmail(ctx context.Context, email string) (*gaivota.User, error) {
+	user, err := scoped.store.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok && user.ID != userId {
+		return nil, fmt.Errorf("Could not get user %s: %w", email, gaivota.ErrNotFound)
+	}
+
+	return user, nil
+}
+
+func (scoped *userStore) SetPasswordHash(ctx context.Context, id int, hash string) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.SetPasswordHash(ctx, id, hash)
+}
+
+func (scoped *userStore) Update(ctx context.Context, user *gaivota.User) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", user.ID, userId, user.ID); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, user)
+}
+
+type portfolioStore struct {
+	store  gaivota.PortfolioStore
+	owners *owners
+}
+
+// Portfolios without a user are created for the caller
+func (scoped *portfolioStore) Add(ctx context.Context, portfolio *gaivota.Portfolio) (*gaivota.Portfolio, error) {
+	if userId, ok := UserID(ctx); ok {
+		if portfolio.UserID == 0 {
+			portfolio.UserID = userId
+		}
+		if err := ownedByCaller("portfolio", userId, portfolio.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, portfolio)
+}
+
+func (scoped *portfolioStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Portfolio, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *portfolioStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.portfolio(ctx, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *portfolioStore) Get(ctx context.Context, id int) (*gaivota.Portfolio, error) {
+	portfolio, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("portfolio", id, userId, portfolio.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return portfolio, nil
+}
+
+func (scoped *portfolioStore) GetByUserID(ctx context.Context, id int) (*[]gaivota.Portfolio, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByUserID(ctx, id)
+}
+
+func (scoped *portfolioStore) Update(ctx context.Context, portfolio *gaivota.Portfolio) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.portfolio(ctx, userId, portfolio.ID); err != nil {
+			return err
+		}
+		if portfolio.UserID == 0 {
+			portfolio.UserID = userId
+		}
+		if err := ownedByCaller("portfolio", userId, portfolio.UserID); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, portfolio)
+}
+
+type walletStore struct {
+	store  gaivota.WalletStore
+	owners *owners
+}
+
+// Wallets without a user are created for the caller
+func (scoped *walletStore) Add(ctx context.Context, wallet *gaivota.Wallet) (*gaivota.Wallet, error) {
+	if userId, ok := UserID(ctx); ok {
+		if wallet.UserID == 0 {
+			wallet.UserID = userId
+		}
+		if err := ownedByCaller("wallet", userId, wallet.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, wallet)
+}
+
+func (scoped *walletStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Wallet, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *walletStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.wallet(ctx, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *walletStore) Get(ctx context.Context, id int) (*gaivota.Wallet, error) {
+	wallet, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("wallet", id, userId, wallet.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return wallet, nil
+}
+
+func (scoped *walletStore) GetByUserID(ctx context.Context, id int) (*[]gaivota.Wallet, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByUserID(ctx, id)
+}
+
+func (scoped *walletStore) Update(ctx context.Context, wallet *gaivota.Wallet) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.wallet(ctx, userId, wallet.ID); err != nil {
+			return err
+		}
+		if wallet.UserID == 0 {
+			wallet.UserID = userId
+		}
+		if err := ownedByCaller("wallet", userId, wallet.UserID); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, wallet)
+}
+
+type investmentStore struct {
+	store  gaivota.InvestmentStore
+	owners *owners
+}
+
+func (scoped *investmentStore) Add(ctx context.Context, investment *gaivota.Investment) (*gaivota.Investment, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.portfolio(ctx, userId, investment.PortfolioID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, investment)
+}
+
+func (scoped *investmentStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Investment, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *investmentStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.investment(ctx, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *investmentStore) Get(ctx context.Context, id int) (*gaivota.Investment, error) {
+	investment, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.portfolio(ctx, userId, investment.PortfolioID); err != nil {
+			return nil, err
+		}
+	}
+
+	return investment, nil
+}
+
+func (scoped *investmentStore) GetByUserID(ctx context.Context, id int) (*[]gaivota.Investment, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByUserID(ctx, id)
+}
+
+func (scoped *investmentStore) GetByPortfolioID(ctx context.Context, portfolioId int) (*[]gaivota.Investment, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.portfolio(ctx, userId, portfolioId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByPortfolioID(ctx, portfolioId)
+}
+
+func (scoped *investmentStore) Update(ctx context.Context, investment *gaivota.Investment) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.investment(ctx, userId, investment.ID); err != nil {
+			return err
+		}
+		if err := scoped.owners.portfolio(ctx, userId, investment.PortfolioID); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, investment)
+}
+
+type positionStore struct {
+	store  gaivota.PositionStore
+	owners *owners
+}
+
+func (scoped *positionStore) Add(ctx context.Context, position *gaivota.Position) (*gaivota.Position, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.investment(ctx, userId, position.InvestmentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, position)
+}
+
+func (scoped *positionStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Position, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *positionStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *positionStore) Get(ctx context.Context, id int) (*gaivota.Position, error) {
+	position, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.investment(ctx, userId, position.InvestmentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return position, nil
+}
+
+func (scoped *positionStore) GetByInvestmentID(ctx context.Context, investmentId int) (*[]gaivota.Position, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.investment(ctx, userId, investmentId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByInvestmentID(ctx, investmentId)
+}
+
+func (scoped *positionStore) Update(ctx context.Context, position *gaivota.Position) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, position.ID); err != nil {
+			return err
+		}
+		if err := scoped.owners.investment(ctx, userId, position.InvestmentID); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, position)
+}
+
+type holdingStore struct {
+	store  gaivota.HoldingStore
+	owners *owners
+}
+
+// Both the wallet and the position must belong to the caller
+func (scoped *holdingStore) owns(ctx context.Context, userId int, holding *gaivota.Holding) error {
+	if err := scoped.owners.wallet(ctx, userId, holding.WalletID); err != nil {
+		return err
+	}
+
+	return scoped.owners.position(ctx, userId, holding.PositionID)
+}
+
+func (scoped *holdingStore) Add(ctx context.Context, holding *gaivota.Holding) (*gaivota.Holding, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owns(ctx, userId, holding); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, holding)
+}
+
+func (scoped *holdingStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Holding, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *holdingStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.holding(ctx, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *holdingStore) Get(ctx context.Context, id int) (*gaivota.Holding, error) {
+	holding, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owns(ctx, userId, holding); err != nil {
+			return nil, err
+		}
+	}
+
+	return holding, nil
+}
+
+func (scoped *holdingStore) GetByUserID(ctx context.Context, id int) (*[]gaivota.Holding, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByUserID(ctx, id)
+}
+
+func (scoped *holdingStore) GetByWalletID(ctx context.Context, walletId int) (*[]gaivota.Holding, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.wallet(ctx, userId, walletId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByWalletID(ctx, walletId)
+}
+
+func (scoped *holdingStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Holding, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, positionId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByPositionID(ctx, positionId)
+}
+
+func (scoped *holdingStore) Update(ctx context.Context, holding *gaivota.Holding) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.holding(ctx, userId, holding.ID); err != nil {
+			return err
+		}
+		if err := scoped.owns(ctx, userId, holding); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, holding)
+}
+
+type transferStore struct {
+	store  gaivota.TransferStore
+	owners *owners
+}
+
+// Both wallets and the position must belong to the caller
+func (scoped *transferStore) Add(ctx context.Context, transfer *gaivota.Transfer) (*gaivota.Transfer, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.wallet(ctx, userId, transfer.FromWalletID); err != nil {
+			return nil, err
+		}
+		if err := scoped.owners.wallet(ctx, userId, transfer.ToWalletID); err != nil {
+			return nil, err
+		}
+		if err := scoped.owners.position(ctx, userId, transfer.PositionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, transfer)
+}
+
+func (scoped *transferStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Transfer, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *transferStore) Get(ctx context.Context, id int) (*gaivota.Transfer, error) {
+	transfer, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.wallet(ctx, userId, transfer.FromWalletID); err != nil {
+			return nil, err
+		}
+	}
+
+	return transfer, nil
+}
+
+func (scoped *transferStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Transfer, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, positionId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByPositionID(ctx, positionId)
+}
+
+func (scoped *transferStore) GetByWalletID(ctx context.Context, walletId int) (*[]gaivota.Transfer, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.wallet(ctx, userId, walletId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByWalletID(ctx, walletId)
+}
+
+type orderStore struct {
+	store  gaivota.OrderStore
+	owners *owners
+}
+
+func (scoped *orderStore) Add(ctx context.Context, order *gaivota.Order) (*gaivota.Order, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, order.PositionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, order)
+}
+
+func (scoped *orderStore) AddMany(ctx context.Context, orders []gaivota.Order) ([]gaivota.Order, error) {
+	if userId, ok := UserID(ctx); ok {
+		checked := map[int]bool{}
+		for _, order := range orders {
+			if checked[order.PositionID] {
+				continue
+			}
+			if err := scoped.owners.position(ctx, userId, order.PositionID); err != nil {
+				return nil, err
+			}
+			checked[order.PositionID] = true
+		}
+	}
+
+	return scoped.store.AddMany(ctx, orders)
+}
+
+func (scoped *orderStore) All(ctx context.Context, opts gaivota.ListOptions) ([]gaivota.Order, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *orderStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.order(ctx, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *orderStore) Get(ctx context.Context, id int) (*gaivota.Order, error) {
+	order, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, order.PositionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func (scoped *orderStore) GetByPositionID(ctx context.Context, positionId int) ([]gaivota.Order, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, positionId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByPositionID(ctx, positionId)
+}
+
+func (scoped *orderStore) Update(ctx context.Context, order *gaivota.Order) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.order(ctx, userId, order.ID); err != nil {
+			return err
+		}
+		if err := scoped.owners.position(ctx, userId, order.PositionID); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, order)
+}
+
+type eventStore struct {
+	store  gaivota.EventStore
+	owners *owners
+}
+
+func (scoped *eventStore) Add(ctx context.Context, event *gaivota.Event) (*gaivota.Event, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, event.PositionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, event)
+}
+
+func (scoped *eventStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Event, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *eventStore) Delete(ctx context.Context, id int) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.event(ctx, userId, id); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *eventStore) Get(ctx context.Context, id int) (*gaivota.Event, error) {
+	event, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, event.PositionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return event, nil
+}
+
+func (scoped *eventStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Event, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, positionId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByPositionID(ctx, positionId)
+}
+
+func (scoped *eventStore) Update(ctx context.Context, event *gaivota.Event) error {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.event(ctx, userId, event.ID); err != nil {
+			return err
+		}
+		if err := scoped.owners.position(ctx, userId, event.PositionID); err != nil {
+			return err
+		}
+	}
+
+	return scoped.store.Update(ctx, event)
+}
+
+type lotStore struct {
+	store  gaivota.LotStore
+	owners *owners
+}
+
+func (scoped *lotStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Lot, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
+
+func (scoped *lotStore) Get(ctx context.Context, id int) (*gaivota.Lot, error) {
+	lot, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, lot.PositionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return lot, nil
+}
+
+func (scoped *lotStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Lot, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.position(ctx, userId, positionId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByPositionID(ctx, positionId)
+}
+
+type snapshotStore struct {
+	store  gaivota.SnapshotStore
+	owners *owners
+}
+
+func (scoped *snapshotStore) Add(ctx context.Context, snapshot *gaivota.PortfolioSnapshot) (*gaivota.PortfolioSnapshot, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.portfolio(ctx, userId, snapshot.PortfolioID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, snapshot)
+}
+
+func (scoped *snapshotStore) GetRange(ctx context.Context, portfolioId int, from time.Time, to time.Time) (*[]gaivota.PortfolioSnapshot, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := scoped.owners.portfolio(ctx, userId, portfolioId); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetRange(ctx, portfolioId, from, to)
+}
+
+type apiKeyStore struct {
+	store gaivota.APIKeyStore
+}
+
+// Keys without a user are created for the caller
+func (scoped *apiKeyStore) Add(ctx context.Context, key *gaivota.APIKey) (*gaivota.APIKey, error) {
+	if userId, ok := UserID(ctx); ok {
+		if key.UserID == 0 {
+			key.UserID = userId
+		}
+		if err := ownedByCaller("api key", userId, key.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.Add(ctx, key)
+}
+
+func (scoped *apiKeyStore) Delete(ctx context.Context, id int) error {
+	if _, err := scoped.Get(ctx, id); err != nil {
+		return err
+	}
+
+	return scoped.store.Delete(ctx, id)
+}
+
+func (scoped *apiKeyStore) Get(ctx context.Context, id int) (*gaivota.APIKey, error) {
+	key, err := scoped.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("api key", id, userId, key.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func (scoped *apiKeyStore) GetByHash(ctx context.Context, hash string) (*gaivota.APIKey, error) {
+	key, err := scoped.store.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("api key", key.ID, userId, key.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func (scoped *apiKeyStore) GetByUserID(ctx context.Context, id int) (*[]gaivota.APIKey, error) {
+	if userId, ok := UserID(ctx); ok {
+		if err := owned("user", id, userId, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return scoped.store.GetByUserID(ctx, id)
+}
+
+type auditStore struct {
+	store gaivota.AuditStore
+}
+
+func (scoped *auditStore) Add(ctx context.Context, entry *gaivota.AuditEntry) (*gaivota.AuditEntry, error) {
+	return scoped.store.Add(ctx, entry)
+}
+
+// Lists the entries of the caller's own entities only
+func (scoped *auditStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.AuditEntry, string, error) {
+	if userId, ok := UserID(ctx); ok {
+		opts.UserID = userId
+	}
+
+	return scoped.store.All(ctx, opts)
+}
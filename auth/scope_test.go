@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/inmem"
+	"github.com/leoschet/gaivota/inmem/inmemtest"
+	"github.com/shopspring/decimal"
+)
+
+// A user's portfolio, with one position holding one order
+type userData struct {
+	user      *gaivota.User
+	portfolio *gaivota.Portfolio
+	position  *gaivota.Position
+	order     *gaivota.Order
+}
+
+// Adds a user and their data through the unscoped client
+func addUserData(t *testing.T, client *gaivota.Client, email string) userData {
+	t.Helper()
+	fixture := inmemtest.Seed(t, client, email, gaivota.Portfolio{})
+
+	order, err := client.OrderStore.Add(context.Background(), &gaivota.Order{
+		PositionID:    fixture.Position.ID,
+		Amount:        decimal.NewFromInt(1),
+		UnitPrice:     decimal.NewFromInt(100),
+		TotalPrice:    decimal.NewFromInt(100),
+		QuoteCurrency: "USD",
+		Operation:     gaivota.OrderOperationBuy,
+		Type:          gaivota.OrderTypeMarket,
+		ExecutedAt:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return userData{user: fixture.User, portfolio: fixture.Portfolio, position: fixture.Position, order: order}
+}
+
+func TestScopeIsolatesUsers(t *testing.T) {
+	client := inmem.New().NewClient()
+	alice := addUserData(t, client, "alice@example.com")
+	bob := addUserData(t, client, "bob@example.com")
+
+	scoped := Scope(client)
+	ctx := WithUser(context.Background(), alice.user.ID)
+
+	// Everything Alice may try on Bob's data, which must look like it does not exist
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"get portfolio", func() error {
+			_, err := scoped.PortfolioStore.Get(ctx, bob.portfolio.ID)
+			return err
+		}},
+		{"get portfolios of user", func() error {
+			_, err := scoped.PortfolioStore.GetByUserID(ctx, bob.user.ID)
+			return err
+		}},
+		{"update portfolio", func() error {
+			return scoped.PortfolioStore.Update(ctx, &gaivota.Portfolio{ID: bob.portfolio.ID, Name: "Mine"})
+		}},
+		{"delete portfolio", func() error {
+			return scoped.PortfolioStore.Delete(ctx, bob.portfolio.ID)
+		}},
+		{"get position", func() error {
+			_, err := scoped.PositionStore.Get(ctx, bob.position.ID)
+			return err
+		}},
+		{"get positions of investment", func() error {
+			_, err := scoped.PositionStore.GetByInvestmentID(ctx, bob.position.InvestmentID)
+			return err
+		}},
+		{"add position", func() error {
+			_, err := scoped.PositionStore.Add(ctx, &gaivota.Position{InvestmentID: bob.position.InvestmentID, QuoteCurrency: "EUR"})
+			return err
+		}},
+		{"update position", func() error {
+			return scoped.PositionStore.Update(ctx, &gaivota.Position{ID: bob.position.ID, InvestmentID: bob.position.InvestmentID, QuoteCurrency: "EUR"})
+		}},
+		{"move position to own investment", func() error {
+			return scoped.PositionStore.Update(ctx, &gaivota.Position{ID: bob.position.ID, InvestmentID: alice.position.InvestmentID, QuoteCurrency: "USD"})
+		}},
+		{"delete position", func() error {
+			return scoped.PositionStore.Delete(ctx, bob.position.ID)
+		}},
+		{"get order", func() error {
+			_, err := scoped.OrderStore.Get(ctx, bob.order.ID)
+			return err
+		}},
+		{"get orders of position", func() error {
+			_, err := scoped.OrderStore.GetByPositionID(ctx, bob.position.ID)
+			return err
+		}},
+		{"add order", func() error {
+			order := *bob.order
+			order.ID = 0
+			_, err := scoped.OrderStore.Add(ctx, &order)
+			return err
+		}},
+		{"add orders", func() error {
+			own, other := *alice.order, *bob.order
+			own.ID, other.ID = 0, 0
+			_, err := scoped.OrderStore.AddMany(ctx, []gaivota.Order{own, other})
+			return err
+		}},
+		{"update order", func() error {
+			order := *bob.order
+			order.Amount = decimal.NewFromInt(2)
+			return scoped.OrderStore.Update(ctx, &order)
+		}},
+		{"move order to own position", func() error {
+			order := *bob.order
+			order.PositionID = alice.position.ID
+			return scoped.OrderStore.Update(ctx, &order)
+		}},
+		{"delete order", func() error {
+			return scoped.OrderStore.Delete(ctx, bob.order.ID)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(); !errors.Is(err, gaivota.ErrNotFound) {
+				t.Errorf("Alice got %v, expected ErrNotFound", err)
+			}
+		})
+	}
+
+	// Bob's data is as it was, and Alice's own orders were not added
+	unscoped := context.Background()
+	portfolio, err := client.PortfolioStore.Get(unscoped, bob.portfolio.ID)
+	if err != nil || portfolio.Name != bob.portfolio.Name {
+		t.Errorf("Bob's portfolio is %+v (%v), expected it unchanged", portfolio, err)
+	}
+
+	position, err := client.PositionStore.Get(unscoped, bob.position.ID)
+	if err != nil || position.QuoteCurrency != "USD" || position.InvestmentID != bob.position.InvestmentID {
+		t.Errorf("Bob's position is %+v (%v), expected it unchanged", position, err)
+	}
+
+	for _, data := range []userData{alice, bob} {
+		orders, err := client.OrderStore.GetByPositionID(unscoped, data.position.ID)
+		if err != nil || len(orders) != 1 || !orders[0].Amount.Equal(decimal.NewFromInt(1)) {
+			t.Errorf("%s's position has orders %+v (%v), expected its one order unchanged", data.user.Email, orders, err)
+		}
+	}
+}
+
+func TestScopeListsCallerData(t *testing.T) {
+	client := inmem.New().NewClient()
+	alice := addUserData(t, client, "alice@example.com")
+	addUserData(t, client, "bob@example.com")
+
+	scoped := Scope(client)
+	ctx := WithUser(context.Background(), alice.user.ID)
+
+	portfolios, _, err := scoped.PortfolioStore.All(ctx, gaivota.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*portfolios) != 1 || (*portfolios)[0].ID != alice.portfolio.ID {
+		t.Errorf("Alice lists portfolios %+v, expected only hers", *portfolios)
+	}
+
+	positions, _, err := scoped.PositionStore.All(ctx, gaivota.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*positions) != 1 || (*positions)[0].ID != alice.position.ID {
+		t.Errorf("Alice lists positions %+v, expected only hers", *positions)
+	}
+
+	orders, _, err := scoped.OrderStore.All(ctx, gaivota.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].ID != alice.order.ID {
+		t.Errorf("Alice lists orders %+v, expected only hers", orders)
+	}
+
+	// Without a user, e.g. from the CLI, everything is listed
+	orders, _, err = scoped.OrderStore.All(context.Background(), gaivota.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 {
+		t.Errorf("Unscoped requests list %d orders, expected 2", len(orders))
+	}
+}
+
+func TestScopeForbidsGivingPortfolios(t *testing.T) {
+	client := inmem.New().NewClient()
+	alice := addUserData(t, client, "alice@example.com")
+	bob := addUserData(t, client, "bob@example.com")
+
+	scoped := Scope(client)
+	ctx := WithUser(context.Background(), alice.user.ID)
+
+	_, err := scoped.PortfolioStore.Add(ctx, &gaivota.Portfolio{UserID: bob.user.ID, Name: "Gift"})
+	if !errors.Is(err, gaivota.ErrForbidden) {
+		t.Errorf("Adding a portfolio for Bob answered %v, expected ErrForbidden", err)
+	}
+
+	err = scoped.PortfolioStore.Update(ctx, &gaivota.Portfolio{ID: alice.portfolio.ID, UserID: bob.user.ID, Name: "Main"})
+	if !errors.Is(err, gaivota.ErrForbidden) {
+		t.Errorf("Giving a portfolio to Bob answered %v, expected ErrForbidden", err)
+	}
+
+	portfolio, err := scoped.PortfolioStore.Add(ctx, &gaivota.Portfolio{Name: "Savings"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if portfolio.UserID != alice.user.ID {
+		t.Errorf("Portfolio without user belongs to %d, expected the caller %d", portfolio.UserID, alice.user.ID)
+	}
+}
+
+func TestScopeChecksHoldingPositions(t *testing.T) {
+	client := inmem.New().NewClient()
+	alice := addUserData(t, client, "alice@example.com")
+	bob := addUserData(t, client, "bob@example.com")
+	ctx := context.Background()
+
+	wallet, err := client.WalletStore.Add(ctx, &gaivota.Wallet{UserID: alice.user.ID, Name: "Ledger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice's wallet holding Bob's position, e.g. left from before a fix
+	holding, err := client.HoldingStore.Add(ctx, &gaivota.Holding{WalletID: wallet.ID, PositionID: bob.position.ID, Amount: decimal.NewFromInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped := Scope(client)
+	ctx = WithUser(ctx, alice.user.ID)
+
+	if _, err := scoped.HoldingStore.Get(ctx, holding.ID); !errors.Is(err, gaivota.ErrNotFound) {
+		t.Errorf("Getting a holding of Bob's position answered %v, expected ErrNotFound", err)
+	}
+	if err := scoped.HoldingStore.Delete(ctx, holding.ID); !errors.Is(err, gaivota.ErrNotFound) {
+		t.Errorf("Deleting a holding of Bob's position answered %v, expected ErrNotFound", err)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session tokens are valid for this long by default
+const DefaultTokenTTL = 24 * time.Hour
+
+// Tokens issues and verifies session tokens, JWTs signed with HMAC-SHA256
+type Tokens struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokens(secret string, ttl time.Duration) (*Tokens, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("auth secret must have at least 32 characters")
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+
+	return &Tokens{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}, nil
+}
+
+// Only HS256 tokens are issued, so the header never changes
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Issue returns a token for the user and when it expires
+func (tokens *Tokens) Issue(userId int) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(tokens.ttl)
+
+	payload, err := json.Marshal(claims{
+		Subject:   strconv.Itoa(userId),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", expiresAt, err
+	}
+
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return unsigned + "." + tokens.sign(unsigned), expiresAt, nil
+}
+
+// Verify checks the token's signature and expiry and returns its user ID
+func (tokens *Tokens) Verify(token string) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return 0, ErrInvalidCredentials
+	}
+
+	signature := tokens.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(signature), []byte(parts[2])) {
+		return 0, ErrInvalidCredentials
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, ErrInvalidCredentials
+	}
+
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return 0, ErrInvalidCredentials
+	}
+
+	if time.Now().Unix() >= c.ExpiresAt {
+		return 0, ErrInvalidCredentials
+	}
+
+	userId, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return 0, ErrInvalidCredentials
+	}
+
+	return userId, nil
+}
+
+func (tokens *Tokens) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, tokens.secret)
+	mac.Write([]byte(unsigned))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
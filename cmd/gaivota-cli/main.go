@@ -2,51 +2,62 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
-	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/audit"
+	"github.com/leoschet/gaivota/auth"
+	"github.com/leoschet/gaivota/fees"
+	"github.com/leoschet/gaivota/fx"
 	"github.com/leoschet/gaivota/internal/config"
 	"github.com/leoschet/gaivota/log"
+	"github.com/leoschet/gaivota/migrations"
+	"github.com/leoschet/gaivota/performance"
 	"github.com/leoschet/gaivota/postgres"
+	"github.com/leoschet/gaivota/pricing"
+	"github.com/leoschet/gaivota/snapshots"
+	"github.com/leoschet/gaivota/trades"
+	"github.com/leoschet/gaivota/valuation"
+	"github.com/shopspring/decimal"
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	var logger gaivota.Logger = log.New(os.Stdout, gaivota.LogLevelInfo, log.FormatText).With("app", "gaivota-cli")
+
+	// Settings flags come before the command, and the CLI runs on the host
+	// against docker compose's database unless told otherwise
+	settings, args, err := config.Load("gaivota-cli", os.Args[1:], "local")
+	if errors.Is(err, flag.ErrHelp) {
 		printUsage()
-		os.Exit(1)
+		os.Exit(0)
 	}
-
-	logger := log.New("Gaivota-CLI - ")
-
-	rootPath, err := os.Getwd()
 	if err != nil {
-		logger.Log(gaivota.LogLevelFatal, "Error getting root path: %v", err)
+		logger.Log(gaivota.LogLevelFatal, "Error while reading configuration: %v", err)
+	}
+
+	if len(args) < 1 {
+		printUsage()
+		os.Exit(1)
 	}
 
-	// Try to read local config first, fallback to regular config
-	configPath := path.Join(rootPath, "/config.local.json")
-	settings, err := config.ReadFile(configPath)
+	configured, err := log.Parse(os.Stdout, settings.LogLevel, settings.LogFormat)
 	if err != nil {
-		// Fallback to regular config
-		configPath = path.Join(rootPath, "/config.json")
-		settings, err = config.ReadFile(configPath)
-		if err != nil {
-			logger.Log(gaivota.LogLevelFatal, "Error while reading config file: %v", err)
-		}
-		
-		// If using regular config, check if we need to adjust the database host for local usage
-		if settings.DatabaseConnString != "" {
-			// Replace @db:5432 with @localhost:5555 for local development
-			settings.DatabaseConnString = strings.Replace(settings.DatabaseConnString, "@db:5432", "@localhost:5555", 1)
-			logger.Log(gaivota.LogLevelInfo, "Adjusted database connection for local development")
-		}
+		logger.Log(gaivota.LogLevelFatal, "Error while setting up logging: %v", err)
 	}
+	logger = configured.With("app", "gaivota-cli")
 
-	db, err := postgres.Connect(context.Background(), settings.DatabaseConnString)
+	db, err := postgres.Connect(context.Background(), settings.DatabaseConnString, postgres.Options{
+		Logger:   logger,
+		MaxConns: int32(settings.DatabaseMaxConns),
+		MinConns: int32(settings.DatabaseMinConns),
+	})
 	if err != nil {
 		logger.Log(gaivota.LogLevelFatal, "Error while connecting to Postgres: %v", err)
 	}
@@ -54,20 +65,41 @@ func main() {
 
 	pgClient := db.NewPostgresClient()
 
-	command := os.Args[1]
+	pgClient.PriceSource, err = pricing.New(pgClient.PriceStore, settings.PriceSource, settings.PriceSourceLocation, settings.PriceSourceKey)
+	if err != nil {
+		logger.Log(gaivota.LogLevelFatal, "Error while setting up price source: %v", err)
+	}
+
+	pgClient = audit.Record(pgClient, gaivota.Actor{Type: gaivota.ActorTypeCLI})
+
+	command := args[0]
 	switch command {
 	case "users":
-		handleUsers(pgClient, os.Args[2:])
+		handleUsers(pgClient, args[1:])
 	case "portfolios":
-		handlePortfolios(pgClient, os.Args[2:])
+		handlePortfolios(pgClient, args[1:], logger)
 	case "wallets":
-		handleWallets(pgClient, os.Args[2:])
+		handleWallets(pgClient, args[1:])
 	case "investments":
-		handleInvestments(pgClient, os.Args[2:])
+		handleInvestments(pgClient, args[1:])
 	case "positions":
-		handlePositions(pgClient, os.Args[2:])
+		handlePositions(pgClient, args[1:])
 	case "orders":
-		handleOrders(pgClient, os.Args[2:])
+		handleOrders(pgClient, args[1:])
+	case "transfers":
+		handleTransfers(pgClient, args[1:])
+	case "events":
+		handleEvents(pgClient, args[1:])
+	case "prices":
+		handlePrices(pgClient, args[1:])
+	case "fx":
+		handleFX(pgClient, args[1:])
+	case "keys":
+		handleKeys(pgClient, args[1:])
+	case "audit":
+		handleAudit(pgClient, args[1:])
+	case "migrate":
+		handleMigrate(db, args[1:])
 	case "health":
 		handleHealth(db)
 	default:
@@ -80,41 +112,221 @@ func main() {
 func printUsage() {
 	fmt.Println("Gaivota CLI - Portfolio Management Tool")
 	fmt.Println("")
-	fmt.Println("Usage: gaivota-cli <command> [args]")
+	fmt.Println("Usage: gaivota-cli [--config=<file>] [--profile=<name>] [--<setting>=<value>] <command> [args]")
+	fmt.Println("")
+	fmt.Println("Settings are read from flags, GAIVOTA_* environment variables, the configuration")
+	fmt.Println("file (config.json) and the profile (local by default, docker or prod), e.g.")
+	fmt.Println("--database-conn-string or GAIVOTA_DATABASE_CONN_STRING.")
 	fmt.Println("")
 	fmt.Println("Commands:")
 	fmt.Println("  health                    Check database connection")
+	fmt.Println("  audit [--entity=<name>] [--id=<id>]  List changes, with before and after for one entity ID")
+	fmt.Println("  migrate <subcommand>      Manage the database schema")
+	fmt.Println("    status                  List migrations and whether they are applied")
+	fmt.Println("    up                      Apply all pending migrations")
+	fmt.Println("    down                    Revert the latest migration")
+	fmt.Println("    to <version>            Apply or revert migrations up to version (0 reverts all)")
 	fmt.Println("  users <subcommand>        Manage users")
 	fmt.Println("    list                    List all users")
 	fmt.Println("    get <id>                Get user by ID")
-	fmt.Println("    create <email> <first> <last>  Create new user")
+	fmt.Println("    create <email> <first> <last> [currency]  Create new user")
+	fmt.Println("    password <id> <password>  Set the password the user logs in with")
 	fmt.Println("  portfolios <subcommand>   Manage portfolios")
 	fmt.Println("    list                    List all portfolios")
 	fmt.Println("    list-by-user <user_id>  List portfolios for user")
 	fmt.Println("    get <id>                Get portfolio by ID")
-	fmt.Println("    create <user_id> <name> Create new portfolio")
+	fmt.Println("    create <user_id> <name> [method] [currency]  Create new portfolio (fifo, lifo, hifo or average)")
+	fmt.Println("    summary <id> [at]       Show value, P&L and allocation per investment and wallet")
+	fmt.Println("    returns <id> [from] [to]  Show time-weighted (TWR) and money-weighted (XIRR) returns")
+	fmt.Println("    snapshot [id]           Snapshot today's value of one or all portfolios")
+	fmt.Println("    backfill <id> [from] [to]  Reconstruct daily snapshots from orders and prices")
+	fmt.Println("    history <id> [from] [to] [interval]  List snapshots per day, week or month")
+	fmt.Println("    fees <id> [from] [to] [interval]  Sum trading fees per exchange, split per day, week or month")
 	fmt.Println("  wallets <subcommand>      Manage wallets")
 	fmt.Println("    list                    List all wallets")
 	fmt.Println("    list-by-user <user_id>  List wallets for user")
 	fmt.Println("    get <id>                Get wallet by ID")
+	fmt.Println("    value <id>              Price wallet holdings and update its total value")
 	fmt.Println("  investments <subcommand>  Manage investments")
 	fmt.Println("    list                    List all investments")
 	fmt.Println("    get <id>                Get investment by ID")
+	fmt.Println("    returns <id> [from] [to]  Show time-weighted (TWR) and money-weighted (XIRR) returns")
 	fmt.Println("  positions <subcommand>    Manage positions")
 	fmt.Println("    list                    List all positions")
 	fmt.Println("    get <id>                Get position by ID")
+	fmt.Println("    profit <id> [price]     Replay orders and show realized/unrealized profit")
+	fmt.Println("    gains <id>              Show realized gains by lot and holding period")
+	fmt.Println("    value <id> [at] [currency]  Price position now or at a time (RFC 3339 or date)")
 	fmt.Println("  orders <subcommand>       Manage orders")
 	fmt.Println("    list                    List all orders")
 	fmt.Println("    get <id>                Get order by ID")
+	fmt.Println("    import --format=<exchange> --position=<id> [--dry-run] <file.csv>  Import trades from an exchange export")
+	fmt.Println("  transfers <subcommand>    Manage transfers between wallets")
+	fmt.Println("    list                    List all transfers")
+	fmt.Println("    list-by-wallet <wallet_id>  List transfers from or to wallet")
+	fmt.Println("    get <id>                Get transfer by ID")
+	fmt.Println("    create <from_wallet> <to_wallet> <position> <amount> [fee] [at]  Move holdings, the fee paid out of the amount")
+	fmt.Println("  events <subcommand>       Manage deposits, withdrawals, rewards and other position events")
+	fmt.Println("    list [--kind=<kind>]    List all events")
+	fmt.Println("    list-by-position <position_id>  List events of position")
+	fmt.Println("    get <id>                Get event by ID")
+	fmt.Println("    create <position> <kind> <amount> [unit_price] [at] [note]  Record an event (deposit, withdrawal, reward, airdrop, fork, gift or lost)")
+	fmt.Println("    delete <id>             Delete event")
+	fmt.Println("  prices <subcommand>       Manage prices")
+	fmt.Println("    get <symbol> [quote] [at]  Quote token now or at a time")
+	fmt.Println("    add <symbol> <quote> <price> [at]  Store price")
+	fmt.Println("    import <file.csv>       Store prices from CSV (symbol,quote,time,price)")
+	fmt.Println("  fx <subcommand>           Manage exchange rates")
+	fmt.Println("    get <base> <quote> [at] Get rate, inverted or crossed through USD when needed")
+	fmt.Println("    add <base> <quote> <rate> [at]  Store rate (1 base = rate quote)")
+	fmt.Println("  keys <subcommand>         Manage API keys")
+	fmt.Println("    list <user_id>          List API keys for user")
+	fmt.Println("    create <user_id> <name> Create API key, shown only once")
+	fmt.Println("    revoke <id>             Revoke API key")
+	fmt.Println("")
+	fmt.Println("List subcommands accept --limit, --cursor, --sort, --desc, --from, --to,")
+	fmt.Println("--operation, --exchange and --symbol, e.g. orders list --symbol=BTC --sort=executedAt --desc")
+}
+
+func handleHealth(db *postgres.Database) {
+	ctx := context.Background()
+
+	checkers := []gaivota.HealthChecker{db}
+
+	migrator, err := postgres.NewMigrator(db)
+	if err != nil {
+		fmt.Printf("Error loading migrations: %v\n", err)
+		os.Exit(1)
+	}
+	checkers = append(checkers, migrator)
+
+	healthy := true
+	for _, checker := range checkers {
+		if err := checker.Ping(ctx); err != nil {
+			fmt.Printf("%s: down (%v)\n", checker.Name(), err)
+			healthy = false
+			continue
+		}
+		fmt.Printf("%s: ok\n", checker.Name())
+	}
+
+	if !healthy {
+		os.Exit(1)
+	}
 }
 
-func handleHealth(db gaivota.HealthChecker) {
-	msg, err := db.Ping()
+func handleAudit(client *gaivota.Client, args []string) {
+	ctx := context.Background()
+
+	opts, ok := parseListFlags("audit", args)
+	if !ok {
+		return
+	}
+
+	if opts.EntityID != 0 && opts.Entity == "" {
+		fmt.Println("Usage: audit --entity=<name> --id=<id>")
+		return
+	}
+
+	entries, next, err := client.AuditStore.All(ctx, opts)
+	if err != nil {
+		fmt.Printf("Error listing audit entries: %v\n", err)
+		return
+	}
+
+	fmt.Println("Audit Entries:")
+	fmt.Printf("%-6s %-25s %-20s %-8s %-8s %-12s %-6s\n", "ID", "Time", "Entity", "ID", "Action", "Actor", "User")
+	fmt.Println("-----------------------------------------------------------------------------------------")
+	for _, entry := range *entries {
+		actor := string(entry.Actor.Type)
+		if entry.Actor.ID != 0 {
+			actor = fmt.Sprintf("%s %d", entry.Actor.Type, entry.Actor.ID)
+		}
+
+		fmt.Printf("%-6d %-25s %-20s %-8d %-8s %-12s %-6d\n",
+			entry.ID, entry.CreatedAt.Format(time.RFC3339), entry.Entity, entry.EntityID, entry.Action, actor, entry.UserID)
+
+		// The history of a single entity shows what changed
+		if opts.EntityID != 0 {
+			fmt.Printf("       before: %s\n", entry.Before)
+			fmt.Printf("       after:  %s\n", entry.After)
+		}
+	}
+	printNextCursor(next)
+}
+
+func handleMigrate(db *postgres.Database, args []string) {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Println("Missing subcommand for migrate")
+		return
+	}
+
+	migrator, err := postgres.NewMigrator(db)
+	if err != nil {
+		fmt.Printf("Error loading migrations: %v\n", err)
+		return
+	}
+
+	var ran []migrations.Migration
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Printf("Error getting migration status: %v\n", err)
+			return
+		}
+
+		fmt.Printf("%-8s %-30s %-10s %-25s\n", "Version", "Name", "State", "Applied")
+		fmt.Println("--------------------------------------------------------------------------")
+		for _, status := range statuses {
+			appliedAt := ""
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-8d %-30s %-10s %-25s\n", status.Version, status.Name, status.State, appliedAt)
+		}
+		return
+
+	case "up":
+		ran, err = migrator.Up(ctx)
+
+	case "down":
+		ran, err = migrator.Down(ctx)
+
+	case "to":
+		if len(args) < 2 {
+			fmt.Println("Missing version")
+			return
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			fmt.Printf("Invalid version: %s\n", args[1])
+			return
+		}
+
+		ran, err = migrator.To(ctx, version)
+
+	default:
+		fmt.Printf("Unknown migrate subcommand: %s\n", args[0])
+		return
+	}
+
+	// Migrations that ran before a failing one stay applied
+	for _, migration := range ran {
+		fmt.Printf("Ran migration %d (%s)\n", migration.Version, migration.Name)
+	}
+
 	if err != nil {
-		fmt.Printf("Health check failed: %v\n", err)
+		fmt.Printf("Error migrating: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Database connection healthy: %s\n", msg)
+
+	if len(ran) == 0 {
+		fmt.Println("Nothing to migrate")
+	}
 }
 
 func handleUsers(client *gaivota.Client, args []string) {
@@ -127,7 +339,11 @@ func handleUsers(client *gaivota.Client, args []string) {
 
 	switch args[0] {
 	case "list":
-		users, err := client.UserStore.All(ctx)
+		opts, ok := parseListFlags("users list", args[1:])
+		if !ok {
+			return
+		}
+		users, next, err := client.UserStore.All(ctx, opts)
 		if err != nil {
 			fmt.Printf("Error listing users: %v\n", err)
 			return
@@ -139,6 +355,7 @@ func handleUsers(client *gaivota.Client, args []string) {
 		for _, user := range *users {
 			fmt.Printf("%-5d %-25s %-15s %-15s\n", user.ID, user.Email, user.FirstName, user.LastName)
 		}
+		printNextCursor(next)
 
 	case "get":
 		if len(args) < 2 {
@@ -161,11 +378,12 @@ func handleUsers(client *gaivota.Client, args []string) {
 		fmt.Printf("  ID: %d\n", user.ID)
 		fmt.Printf("  Email: %s\n", user.Email)
 		fmt.Printf("  Name: %s %s\n", user.FirstName, user.LastName)
+		fmt.Printf("  Reporting Currency: %s\n", user.ReportingCurrency)
 		fmt.Printf("  Created: %s\n", user.CreatedAt)
 
 	case "create":
 		if len(args) < 4 {
-			fmt.Println("Usage: users create <email> <first_name> <last_name>")
+			fmt.Println("Usage: users create <email> <first_name> <last_name> [reporting_currency]")
 			return
 		}
 		
@@ -174,6 +392,9 @@ func handleUsers(client *gaivota.Client, args []string) {
 			FirstName: args[2],
 			LastName:  args[3],
 		}
+		if len(args) > 4 {
+			user.ReportingCurrency = strings.ToUpper(args[4])
+		}
 		
 		createdUser, err := client.UserStore.Add(ctx, user)
 		if err != nil {
@@ -185,13 +406,39 @@ func handleUsers(client *gaivota.Client, args []string) {
 		fmt.Printf("  ID: %d\n", createdUser.ID)
 		fmt.Printf("  Email: %s\n", createdUser.Email)
 		fmt.Printf("  Name: %s %s\n", createdUser.FirstName, createdUser.LastName)
+		fmt.Printf("  Reporting Currency: %s\n", createdUser.ReportingCurrency)
+
+	case "password":
+		if len(args) < 3 {
+			fmt.Println("Usage: users password <id> <password>")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid user ID: %s\n", args[1])
+			return
+		}
+
+		hash, err := auth.HashPassword(args[2])
+		if err != nil {
+			fmt.Printf("Invalid password: %v\n", err)
+			return
+		}
+
+		err = client.UserStore.SetPasswordHash(ctx, id, hash)
+		if err != nil {
+			fmt.Printf("Error setting password: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Password set for user %d\n", id)
 
 	default:
 		fmt.Printf("Unknown users subcommand: %s\n", args[0])
 	}
 }
 
-func handlePortfolios(client *gaivota.Client, args []string) {
+func handlePortfolios(client *gaivota.Client, args []string, logger gaivota.Logger) {
 	ctx := context.Background()
 	
 	if len(args) == 0 {
@@ -201,7 +448,11 @@ func handlePortfolios(client *gaivota.Client, args []string) {
 
 	switch args[0] {
 	case "list":
-		portfolios, err := client.PortfolioStore.All(ctx)
+		opts, ok := parseListFlags("portfolios list", args[1:])
+		if !ok {
+			return
+		}
+		portfolios, next, err := client.PortfolioStore.All(ctx, opts)
 		if err != nil {
 			fmt.Printf("Error listing portfolios: %v\n", err)
 			return
@@ -213,6 +464,7 @@ func handlePortfolios(client *gaivota.Client, args []string) {
 		for _, portfolio := range *portfolios {
 			fmt.Printf("%-5d %-10d %-30s\n", portfolio.ID, portfolio.UserID, portfolio.Name)
 		}
+		printNextCursor(next)
 
 	case "list-by-user":
 		if len(args) < 2 {
@@ -259,11 +511,13 @@ func handlePortfolios(client *gaivota.Client, args []string) {
 		fmt.Printf("  ID: %d\n", portfolio.ID)
 		fmt.Printf("  User ID: %d\n", portfolio.UserID)
 		fmt.Printf("  Name: %s\n", portfolio.Name)
+		fmt.Printf("  Cost Basis Method: %s\n", portfolio.CostBasisMethod)
+		fmt.Printf("  Reporting Currency: %s\n", portfolio.ReportingCurrency)
 		fmt.Printf("  Created: %s\n", portfolio.CreatedAt)
 
 	case "create":
 		if len(args) < 3 {
-			fmt.Println("Usage: portfolios create <user_id> <name>")
+			fmt.Println("Usage: portfolios create <user_id> <name> [fifo|lifo|hifo|average] [reporting_currency]")
 			return
 		}
 		
@@ -277,6 +531,12 @@ func handlePortfolios(client *gaivota.Client, args []string) {
 			UserID: userID,
 			Name:   args[2],
 		}
+		if len(args) > 3 {
+			portfolio.CostBasisMethod = gaivota.CostBasisMethod(args[3])
+		}
+		if len(args) > 4 {
+			portfolio.ReportingCurrency = strings.ToUpper(args[4])
+		}
 		
 		createdPortfolio, err := client.PortfolioStore.Add(ctx, portfolio)
 		if err != nil {
@@ -288,6 +548,219 @@ func handlePortfolios(client *gaivota.Client, args []string) {
 		fmt.Printf("  ID: %d\n", createdPortfolio.ID)
 		fmt.Printf("  User ID: %d\n", createdPortfolio.UserID)
 		fmt.Printf("  Name: %s\n", createdPortfolio.Name)
+		fmt.Printf("  Cost Basis Method: %s\n", createdPortfolio.CostBasisMethod)
+		fmt.Printf("  Reporting Currency: %s\n", createdPortfolio.ReportingCurrency)
+
+	case "summary":
+		if len(args) < 2 {
+			fmt.Println("Usage: portfolios summary <id> [at]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid portfolio ID: %s\n", args[1])
+			return
+		}
+
+		var at time.Time
+		if len(args) > 2 {
+			at, err = parseTime(args[2])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[2])
+				return
+			}
+		}
+
+		summary, err := valuation.New(client).Portfolio(ctx, id, at)
+		if err != nil {
+			fmt.Printf("Error summarizing portfolio: %v\n", err)
+			return
+		}
+
+		quote := summary.Quote
+		fmt.Printf("Portfolio %d Summary: %s (at %s)\n", summary.PortfolioID, summary.Name, summary.At)
+		fmt.Printf("  Total Value: %s\n", money(summary.Value, quote))
+		fmt.Printf("  Cost Basis: %s\n", money(summary.CostBasis, quote))
+		fmt.Printf("  Unrealized Profit: %s\n", money(summary.UnrealizedProfit, quote))
+		fmt.Printf("  Realized Profit: %s\n", money(summary.RealizedProfit, quote))
+		fmt.Println("")
+		fmt.Println("Investments:")
+		fmt.Printf("%-5s %-10s %-20s %-20s %-20s %-20s %-8s\n", "ID", "Symbol", "Amount", "Value", "Cost Basis", "Unrealized", "Alloc")
+		fmt.Println("---------------------------------------------------------------------------------------------------------")
+		for _, investment := range summary.Investments {
+			fmt.Printf("%-5d %-10s %-20s %-20s %-20s %-20s %-8s\n", investment.InvestmentID, investment.Symbol, investment.Amount,
+				money(investment.Value, quote), money(investment.CostBasis, quote), money(investment.UnrealizedProfit, quote),
+				investment.Allocation.StringFixed(2)+"%")
+		}
+		fmt.Println("")
+		fmt.Println("Wallets:")
+		fmt.Printf("%-5s %-20s %-20s %-8s\n", "ID", "Name", "Value", "Alloc")
+		fmt.Println("-------------------------------------------------------")
+		for _, wallet := range summary.Wallets {
+			fmt.Printf("%-5d %-20s %-20s %-8s\n", wallet.WalletID, wallet.Name, money(wallet.Value, quote), wallet.Allocation.StringFixed(2)+"%")
+		}
+
+	case "returns":
+		if len(args) < 2 {
+			fmt.Println("Usage: portfolios returns <id> [from] [to]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid portfolio ID: %s\n", args[1])
+			return
+		}
+
+		from, to, err := parsePeriod(args[2:])
+		if err != nil {
+			fmt.Printf("Invalid period: %v\n", err)
+			return
+		}
+
+		returns, err := performance.New(client, valuation.New(client)).Portfolio(ctx, id, from, to)
+		if err != nil {
+			fmt.Printf("Error measuring portfolio returns: %v\n", err)
+			return
+		}
+
+		printReturns(returns)
+
+	case "snapshot":
+		snapshotter := snapshots.New(client, valuation.New(client), logger)
+
+		if len(args) < 2 {
+			taken, err := snapshotter.TakeAll(ctx, time.Now())
+			if err != nil {
+				fmt.Printf("Error taking snapshots: %v\n", err)
+			}
+			fmt.Printf("Took %d portfolio snapshots\n", taken)
+			return
+		}
+
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid portfolio ID: %s\n", args[1])
+			return
+		}
+
+		snapshot, err := snapshotter.Take(ctx, id, time.Now())
+		if err != nil {
+			fmt.Printf("Error taking snapshot: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Portfolio %d snapshot for %s: %s\n", snapshot.PortfolioID, snapshot.Date.Format("2006-01-02"), money(snapshot.Value, snapshot.QuoteCurrency))
+
+	case "backfill":
+		if len(args) < 2 {
+			fmt.Println("Usage: portfolios backfill <id> [from] [to]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid portfolio ID: %s\n", args[1])
+			return
+		}
+
+		from, to, err := parsePeriod(args[2:])
+		if err != nil {
+			fmt.Printf("Invalid period: %v\n", err)
+			return
+		}
+
+		snapshotter := snapshots.New(client, valuation.New(client), logger)
+		taken, err := snapshotter.Backfill(ctx, id, from, to)
+		if err != nil {
+			fmt.Printf("Error backfilling snapshots after %d days: %v\n", taken, err)
+			return
+		}
+
+		fmt.Printf("Backfilled %d daily snapshots\n", taken)
+
+	case "history":
+		if len(args) < 2 {
+			fmt.Println("Usage: portfolios history <id> [from] [to] [day|week|month]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid portfolio ID: %s\n", args[1])
+			return
+		}
+
+		from, to, err := parsePeriod(args[2:])
+		if err != nil {
+			fmt.Printf("Invalid period: %v\n", err)
+			return
+		}
+		if to.IsZero() {
+			to = time.Now()
+		}
+		if from.IsZero() {
+			from = to.AddDate(-1, 0, 0)
+		}
+
+		interval := ""
+		if len(args) > 4 {
+			interval = args[4]
+		}
+
+		history, err := client.SnapshotStore.GetRange(ctx, id, from, to)
+		if err != nil {
+			fmt.Printf("Error getting portfolio history: %v\n", err)
+			return
+		}
+
+		sampled, err := snapshots.Sample(*history, interval)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("%-12s %-20s %-20s %-20s %-20s\n", "Date", "Value", "Cost Basis", "Unrealized", "Realized")
+		fmt.Println("------------------------------------------------------------------------------------------------")
+		for _, snapshot := range sampled {
+			quote := snapshot.QuoteCurrency
+			fmt.Printf("%-12s %-20s %-20s %-20s %-20s\n", snapshot.Date.Format("2006-01-02"), money(snapshot.Value, quote),
+				money(snapshot.CostBasis, quote), money(snapshot.UnrealizedProfit, quote), money(snapshot.RealizedProfit, quote))
+		}
+
+	case "fees":
+		if len(args) < 2 {
+			fmt.Println("Usage: portfolios fees <id> [from] [to] [day|week|month]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid portfolio ID: %s\n", args[1])
+			return
+		}
+
+		from, to, err := parsePeriod(args[2:])
+		if err != nil {
+			fmt.Printf("Invalid period: %v\n", err)
+			return
+		}
+
+		interval := ""
+		if len(args) > 4 {
+			interval = args[4]
+		}
+
+		summary, err := fees.New(client).Portfolio(ctx, id, from, to, interval)
+		if err != nil {
+			fmt.Printf("Error summing portfolio fees: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Fees of Portfolio %d from %s to %s:\n", id, summary.From.Format("2006-01-02"), summary.To.Format("2006-01-02"))
+		fmt.Printf("%-12s %-15s %-22s %-20s %-8s\n", "Period", "Exchange", "Fee", "Value", "Orders")
+		fmt.Println("-----------------------------------------------------------------------------------")
+		for _, total := range summary.Totals {
+			fmt.Printf("%-12s %-15s %-22s %-20s %-8d\n", total.Period.Format("2006-01-02"), total.Exchange,
+				total.Amount.String()+" "+total.Currency, money(total.Value, summary.Quote), total.Orders)
+		}
+		fmt.Printf("  Total: %s\n", money(summary.Value, summary.Quote))
 
 	default:
 		fmt.Printf("Unknown portfolios subcommand: %s\n", args[0])
@@ -304,18 +777,27 @@ func handleWallets(client *gaivota.Client, args []string) {
 
 	switch args[0] {
 	case "list":
-		wallets, err := client.WalletStore.All(ctx)
+		opts, ok := parseListFlags("wallets list", args[1:])
+		if !ok {
+			return
+		}
+		wallets, next, err := client.WalletStore.All(ctx, opts)
 		if err != nil {
 			fmt.Printf("Error listing wallets: %v\n", err)
 			return
 		}
 		
+		// Wallet values are in their user's reporting currency
+		currencies := userCurrencies(ctx, client)
+
 		fmt.Println("Wallets:")
-		fmt.Printf("%-5s %-10s %-20s %-15s %-40s\n", "ID", "User ID", "Name", "Total Value", "Address")
-		fmt.Println("--------------------------------------------------------------------------------")
+		fmt.Printf("%-5s %-10s %-20s %-20s %-40s\n", "ID", "User ID", "Name", "Total Value", "Address")
+		fmt.Println("-------------------------------------------------------------------------------------")
 		for _, wallet := range *wallets {
-			fmt.Printf("%-5d %-10d %-20s $%-14.2f %-40s\n", wallet.ID, wallet.UserID, wallet.Name, wallet.TotalValue, wallet.Address)
+			fmt.Printf("%-5d %-10d %-20s %-20s %-40s\n", wallet.ID, wallet.UserID, wallet.Name,
+				money(wallet.TotalValue, currencies[wallet.UserID]), wallet.Address)
 		}
+		printNextCursor(next)
 
 	case "list-by-user":
 		if len(args) < 2 {
@@ -334,11 +816,13 @@ func handleWallets(client *gaivota.Client, args []string) {
 			return
 		}
 		
+		currencies := userCurrencies(ctx, client)
+
 		fmt.Printf("Wallets for User %d:\n", userID)
-		fmt.Printf("%-5s %-20s %-15s %-40s\n", "ID", "Name", "Total Value", "Address")
-		fmt.Println("--------------------------------------------------------------------------------")
+		fmt.Printf("%-5s %-20s %-20s %-40s\n", "ID", "Name", "Total Value", "Address")
+		fmt.Println("-------------------------------------------------------------------------------------")
 		for _, wallet := range *wallets {
-			fmt.Printf("%-5d %-20s $%-14.2f %-40s\n", wallet.ID, wallet.Name, wallet.TotalValue, wallet.Address)
+			fmt.Printf("%-5d %-20s %-20s %-40s\n", wallet.ID, wallet.Name, money(wallet.TotalValue, currencies[userID]), wallet.Address)
 		}
 
 	case "get":
@@ -362,29 +846,71 @@ func handleWallets(client *gaivota.Client, args []string) {
 		fmt.Printf("  ID: %d\n", wallet.ID)
 		fmt.Printf("  User ID: %d\n", wallet.UserID)
 		fmt.Printf("  Name: %s\n", wallet.Name)
-		fmt.Printf("  Total Value: $%.2f\n", wallet.TotalValue)
+		fmt.Printf("  Total Value: %s\n", money(wallet.TotalValue, userCurrencies(ctx, client)[wallet.UserID]))
 		fmt.Printf("  Address: %s\n", wallet.Address)
 		fmt.Printf("  Location: %s\n", wallet.Location)
 		fmt.Printf("  Created: %s\n", wallet.CreatedAt)
 
-	default:
-		fmt.Printf("Unknown wallets subcommand: %s\n", args[0])
-	}
-}
-
-func handleInvestments(client *gaivota.Client, args []string) {
-	ctx := context.Background()
-	
-	if len(args) == 0 {
-		fmt.Println("Missing subcommand for investments")
-		return
-	}
-
-	switch args[0] {
-	case "list":
-		investments, err := client.InvestmentStore.All(ctx)
-		if err != nil {
-			fmt.Printf("Error listing investments: %v\n", err)
+	case "value":
+		if len(args) < 2 {
+			fmt.Println("Missing wallet ID")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid wallet ID: %s\n", args[1])
+			return
+		}
+
+		wallet, err := client.WalletStore.Get(ctx, id)
+		if err != nil {
+			fmt.Printf("Error getting wallet: %v\n", err)
+			return
+		}
+
+		walletValuation, err := valuation.New(client).Wallet(ctx, id)
+		if err != nil {
+			fmt.Printf("Error valuing wallet: %v\n", err)
+			return
+		}
+
+		wallet.TotalValue = walletValuation.Value
+		if err := client.WalletStore.Update(ctx, wallet); err != nil {
+			fmt.Printf("Error updating wallet total value: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Holdings of Wallet %d:\n", id)
+		fmt.Printf("%-8s %-10s %-10s %-20s %-15s %-15s\n", "ID", "Position", "Symbol", "Amount", "Price", "Value")
+		fmt.Println("-----------------------------------------------------------------------------------")
+		for _, holding := range walletValuation.Holdings {
+			fmt.Printf("%-8d %-10d %-10s %-20s %-15s %-15s\n", holding.HoldingID, holding.PositionID, holding.Symbol,
+				holding.Amount, money(holding.Price, walletValuation.Quote), money(holding.Value, walletValuation.Quote))
+		}
+		fmt.Printf("  Total Value: %s\n", money(walletValuation.Value, walletValuation.Quote))
+
+	default:
+		fmt.Printf("Unknown wallets subcommand: %s\n", args[0])
+	}
+}
+
+func handleInvestments(client *gaivota.Client, args []string) {
+	ctx := context.Background()
+	
+	if len(args) == 0 {
+		fmt.Println("Missing subcommand for investments")
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		opts, ok := parseListFlags("investments list", args[1:])
+		if !ok {
+			return
+		}
+		investments, next, err := client.InvestmentStore.All(ctx, opts)
+		if err != nil {
+			fmt.Printf("Error listing investments: %v\n", err)
 			return
 		}
 		
@@ -394,6 +920,7 @@ func handleInvestments(client *gaivota.Client, args []string) {
 		for _, investment := range *investments {
 			fmt.Printf("%-5d %-15d %-20s %-10s\n", investment.ID, investment.PortfolioID, investment.Token, investment.TokenSymbol)
 		}
+		printNextCursor(next)
 
 	case "get":
 		if len(args) < 2 {
@@ -419,6 +946,31 @@ func handleInvestments(client *gaivota.Client, args []string) {
 		fmt.Printf("  Symbol: %s\n", investment.TokenSymbol)
 		fmt.Printf("  Created: %s\n", investment.CreatedAt)
 
+	case "returns":
+		if len(args) < 2 {
+			fmt.Println("Usage: investments returns <id> [from] [to]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid investment ID: %s\n", args[1])
+			return
+		}
+
+		from, to, err := parsePeriod(args[2:])
+		if err != nil {
+			fmt.Printf("Invalid period: %v\n", err)
+			return
+		}
+
+		returns, err := performance.New(client, valuation.New(client)).Investment(ctx, id, from, to)
+		if err != nil {
+			fmt.Printf("Error measuring investment returns: %v\n", err)
+			return
+		}
+
+		printReturns(returns)
+
 	default:
 		fmt.Printf("Unknown investments subcommand: %s\n", args[0])
 	}
@@ -434,19 +986,25 @@ func handlePositions(client *gaivota.Client, args []string) {
 
 	switch args[0] {
 	case "list":
-		positions, err := client.PositionStore.All(ctx)
+		opts, ok := parseListFlags("positions list", args[1:])
+		if !ok {
+			return
+		}
+		positions, next, err := client.PositionStore.All(ctx, opts)
 		if err != nil {
 			fmt.Printf("Error listing positions: %v\n", err)
 			return
 		}
 		
 		fmt.Println("Positions:")
-		fmt.Printf("%-5s %-15s %-15s %-15s %-15s\n", "ID", "Investment ID", "Amount", "Avg Price", "Profit")
-		fmt.Println("-----------------------------------------------------------------------")
+		fmt.Printf("%-5s %-15s %-15s %-20s %-20s\n", "ID", "Investment ID", "Amount", "Avg Price", "Profit")
+		fmt.Println("---------------------------------------------------------------------------------")
 		for _, position := range *positions {
-			fmt.Printf("%-5d %-15d %-15.6f $%-14.2f $%-14.2f\n", 
-				position.ID, position.InvestmentID, position.Amount, position.AveragePrice, position.Profit)
+			fmt.Printf("%-5d %-15d %-15s %-20s %-20s\n",
+				position.ID, position.InvestmentID, position.Amount,
+				money(position.AveragePrice, position.QuoteCurrency), money(position.Profit, position.QuoteCurrency))
 		}
+		printNextCursor(next)
 
 	case "get":
 		if len(args) < 2 {
@@ -468,11 +1026,149 @@ func handlePositions(client *gaivota.Client, args []string) {
 		fmt.Printf("Position Details:\n")
 		fmt.Printf("  ID: %d\n", position.ID)
 		fmt.Printf("  Investment ID: %d\n", position.InvestmentID)
-		fmt.Printf("  Amount: %.6f\n", position.Amount)
-		fmt.Printf("  Average Price: $%.2f\n", position.AveragePrice)
-		fmt.Printf("  Profit: $%.2f\n", position.Profit)
+		fmt.Printf("  Amount: %s\n", position.Amount)
+		fmt.Printf("  Quote Currency: %s\n", position.QuoteCurrency)
+		fmt.Printf("  Average Price: %s\n", money(position.AveragePrice, position.QuoteCurrency))
+		fmt.Printf("  Profit: %s\n", money(position.Profit, position.QuoteCurrency))
 		fmt.Printf("  Created: %s\n", position.CreatedAt)
 
+	case "profit":
+		if len(args) < 2 {
+			fmt.Println("Usage: positions profit <id> [price]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid position ID: %s\n", args[1])
+			return
+		}
+
+		var price *decimal.Decimal
+		if len(args) > 2 {
+			parsed, err := decimal.NewFromString(args[2])
+			if err != nil {
+				fmt.Printf("Invalid price: %s\n", args[2])
+				return
+			}
+			price = &parsed
+		}
+
+		position, err := client.PositionStore.Get(ctx, id)
+		if err != nil {
+			fmt.Printf("Error getting position: %v\n", err)
+			return
+		}
+		currency := position.QuoteCurrency
+
+		result, err := accounting.ReplayPosition(ctx, client, id)
+		if err != nil {
+			fmt.Printf("Error replaying position orders: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Position %d Profit:\n", id)
+		fmt.Printf("  Lots: %d\n", len(result.Lots))
+		fmt.Printf("  Amount: %s\n", result.Amount)
+		fmt.Printf("  Average Price: %s\n", money(result.AveragePrice, currency))
+		fmt.Printf("  Cost Basis: %s\n", money(result.CostBasis, currency))
+		fmt.Printf("  Realized Profit: %s\n", money(result.RealizedProfit, currency))
+		fmt.Printf("  Income: %s\n", money(result.Income, currency))
+		if price != nil {
+			fmt.Printf("  Unrealized Profit at %s: %s\n", money(*price, currency), money(result.UnrealizedProfit(*price), currency))
+		}
+
+	case "gains":
+		if len(args) < 2 {
+			fmt.Println("Missing position ID")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid position ID: %s\n", args[1])
+			return
+		}
+
+		position, err := client.PositionStore.Get(ctx, id)
+		if err != nil {
+			fmt.Printf("Error getting position: %v\n", err)
+			return
+		}
+		currency := position.QuoteCurrency
+
+		result, err := accounting.ReplayPosition(ctx, client, id)
+		if err != nil {
+			fmt.Printf("Error replaying position orders: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Realized Gains for Position %d (%s):\n", id, currency)
+		fmt.Printf("%-10s %-10s %-12s %-14s %-14s %-14s %-6s\n",
+			"Lot", "Disposal", "Amount", "Cost Basis", "Proceeds", "Profit", "Term")
+		fmt.Println("-----------------------------------------------------------------------------------")
+		for _, gain := range result.Gains {
+			// Opened by an order or an event
+			lot := fmt.Sprintf("order %d", gain.LotOrderID)
+			if gain.LotEventID != 0 {
+				lot = fmt.Sprintf("event %d", gain.LotEventID)
+			}
+			// Sold by an order, paid as a transfer's fee or lost
+			disposal := fmt.Sprintf("order %d", gain.SellOrderID)
+			if gain.TransferID != 0 {
+				disposal = fmt.Sprintf("fee %d", gain.TransferID)
+			} else if gain.EventID != 0 {
+				disposal = fmt.Sprintf("lost %d", gain.EventID)
+			}
+			fmt.Printf("%-10s %-10s %-12s %-14s %-14s %-14s %-6s\n",
+				lot, disposal, gain.Amount, gain.CostBasis.StringFixed(2),
+				gain.Proceeds.StringFixed(2), gain.Profit.StringFixed(2), gain.HoldingPeriod)
+		}
+
+		totals := result.RealizedByHoldingPeriod()
+		fmt.Printf("  Short Term: %s\n", money(totals[accounting.HoldingPeriodShort], currency))
+		fmt.Printf("  Long Term: %s\n", money(totals[accounting.HoldingPeriodLong], currency))
+		fmt.Printf("  Income: %s\n", money(result.Income, currency))
+
+	case "value":
+		if len(args) < 2 {
+			fmt.Println("Usage: positions value <id> [at] [currency]")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid position ID: %s\n", args[1])
+			return
+		}
+
+		var at time.Time
+		if len(args) > 2 {
+			at, err = parseTime(args[2])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[2])
+				return
+			}
+		}
+
+		var currency string
+		if len(args) > 3 {
+			currency = strings.ToUpper(args[3])
+		}
+
+		positionValuation, err := valuation.New(client).Position(ctx, id, at, currency)
+		if err != nil {
+			fmt.Printf("Error valuing position: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Position %d Value:\n", id)
+		fmt.Printf("  Symbol: %s\n", positionValuation.Symbol)
+		fmt.Printf("  Amount: %s\n", positionValuation.Amount)
+		quote := positionValuation.Quote
+		fmt.Printf("  Price: %s (at %s)\n", money(positionValuation.Price, quote), positionValuation.PricedAt)
+		fmt.Printf("  Value: %s\n", money(positionValuation.Value, quote))
+		fmt.Printf("  Cost Basis: %s\n", money(positionValuation.CostBasis, quote))
+		fmt.Printf("  Unrealized Profit: %s\n", money(positionValuation.UnrealizedProfit, quote))
+		fmt.Printf("  Realized Profit: %s\n", money(positionValuation.RealizedProfit, quote))
+
 	default:
 		fmt.Printf("Unknown positions subcommand: %s\n", args[0])
 	}
@@ -488,21 +1184,27 @@ func handleOrders(client *gaivota.Client, args []string) {
 
 	switch args[0] {
 	case "list":
-		orders, err := client.OrderStore.All(ctx)
+		opts, ok := parseListFlags("orders list", args[1:])
+		if !ok {
+			return
+		}
+		orders, next, err := client.OrderStore.All(ctx, opts)
 		if err != nil {
 			fmt.Printf("Error listing orders: %v\n", err)
 			return
 		}
 		
 		fmt.Println("Orders:")
-		fmt.Printf("%-5s %-12s %-10s %-12s %-12s %-8s %-8s %-15s\n", 
+		fmt.Printf("%-5s %-12s %-10s %-16s %-16s %-8s %-8s %-15s\n", 
 			"ID", "Position ID", "Amount", "Unit Price", "Total", "Op", "Type", "Exchange")
 		fmt.Println("-----------------------------------------------------------------------------------")
 		for _, order := range orders {
-			fmt.Printf("%-5d %-12d %-10.4f $%-11.2f $%-11.2f %-8s %-8s %-15s\n", 
-				order.ID, order.PositionID, order.Amount, order.UnitPrice, order.TotalPrice,
+			fmt.Printf("%-5d %-12d %-10s %-16s %-16s %-8s %-8s %-15s\n",
+				order.ID, order.PositionID, order.Amount,
+				money(order.UnitPrice, order.QuoteCurrency), money(order.TotalPrice, order.QuoteCurrency),
 				order.Operation, order.Type, order.Exchange)
 		}
+		printNextCursor(next)
 
 	case "get":
 		if len(args) < 2 {
@@ -524,16 +1226,803 @@ func handleOrders(client *gaivota.Client, args []string) {
 		fmt.Printf("Order Details:\n")
 		fmt.Printf("  ID: %d\n", order.ID)
 		fmt.Printf("  Position ID: %d\n", order.PositionID)
-		fmt.Printf("  Amount: %.4f\n", order.Amount)
-		fmt.Printf("  Unit Price: $%.2f\n", order.UnitPrice)
-		fmt.Printf("  Total Price: $%.2f\n", order.TotalPrice)
+		fmt.Printf("  Amount: %s\n", order.Amount)
+		fmt.Printf("  Unit Price: %s\n", money(order.UnitPrice, order.QuoteCurrency))
+		fmt.Printf("  Total Price: %s\n", money(order.TotalPrice, order.QuoteCurrency))
+		fmt.Printf("  Fee: %s %s\n", order.Fee, order.FeeCurrency)
 		fmt.Printf("  Operation: %s\n", order.Operation)
 		fmt.Printf("  Type: %s\n", order.Type)
 		fmt.Printf("  Exchange: %s\n", order.Exchange)
 		fmt.Printf("  Executed At: %s\n", order.ExecutedAt)
 		fmt.Printf("  Created: %s\n", order.CreatedAt)
 
+	case "import":
+		importOrders(ctx, client, args[1:])
+
 	default:
 		fmt.Printf("Unknown orders subcommand: %s\n", args[0])
 	}
 }
+
+func handleTransfers(client *gaivota.Client, args []string) {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Println("Missing subcommand for transfers")
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		opts, ok := parseListFlags("transfers list", args[1:])
+		if !ok {
+			return
+		}
+		transfers, next, err := client.TransferStore.All(ctx, opts)
+		if err != nil {
+			fmt.Printf("Error listing transfers: %v\n", err)
+			return
+		}
+
+		printTransfers(*transfers)
+		printNextCursor(next)
+
+	case "list-by-wallet":
+		if len(args) < 2 {
+			fmt.Println("Missing wallet ID")
+			return
+		}
+		walletId, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid wallet ID: %s\n", args[1])
+			return
+		}
+
+		transfers, err := client.TransferStore.GetByWalletID(ctx, walletId)
+		if err != nil {
+			fmt.Printf("Error listing transfers: %v\n", err)
+			return
+		}
+
+		printTransfers(*transfers)
+
+	case "get":
+		if len(args) < 2 {
+			fmt.Println("Missing transfer ID")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid transfer ID: %s\n", args[1])
+			return
+		}
+
+		transfer, err := client.TransferStore.Get(ctx, id)
+		if err != nil {
+			fmt.Printf("Error getting transfer: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Transfer Details:\n")
+		fmt.Printf("  ID: %d\n", transfer.ID)
+		fmt.Printf("  From Wallet ID: %d\n", transfer.FromWalletID)
+		fmt.Printf("  To Wallet ID: %d\n", transfer.ToWalletID)
+		fmt.Printf("  Position ID: %d\n", transfer.PositionID)
+		fmt.Printf("  Amount: %s\n", transfer.Amount)
+		fmt.Printf("  Fee: %s\n", transfer.Fee)
+		fmt.Printf("  Received: %s\n", transfer.Amount.Sub(transfer.Fee))
+		fmt.Printf("  Executed At: %s\n", transfer.ExecutedAt)
+
+	case "create":
+		if len(args) < 5 {
+			fmt.Println("Usage: transfers create <from_wallet> <to_wallet> <position> <amount> [fee] [at]")
+			return
+		}
+
+		var ids [3]int
+		for i, arg := range args[1:4] {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Printf("Invalid ID: %s\n", arg)
+				return
+			}
+			ids[i] = id
+		}
+
+		amount, err := decimal.NewFromString(args[4])
+		if err != nil {
+			fmt.Printf("Invalid amount: %s\n", args[4])
+			return
+		}
+
+		fee := decimal.Zero
+		if len(args) > 5 {
+			fee, err = decimal.NewFromString(args[5])
+			if err != nil {
+				fmt.Printf("Invalid fee: %s\n", args[5])
+				return
+			}
+		}
+
+		var at time.Time
+		if len(args) > 6 {
+			at, err = parseTime(args[6])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[6])
+				return
+			}
+		}
+
+		transfer, err := gaivota.MakeTransfer(ctx, client, &gaivota.Transfer{
+			FromWalletID: ids[0],
+			ToWalletID:   ids[1],
+			PositionID:   ids[2],
+			Amount:       amount,
+			Fee:          fee,
+			ExecutedAt:   at,
+		})
+		if err != nil {
+			fmt.Printf("Error creating transfer: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Created transfer %d: %s from wallet %d, %s received by wallet %d (fee %s)\n",
+			transfer.ID, transfer.Amount, transfer.FromWalletID, transfer.Amount.Sub(transfer.Fee),
+			transfer.ToWalletID, transfer.Fee)
+
+	default:
+		fmt.Printf("Unknown transfers subcommand: %s\n", args[0])
+	}
+}
+
+func printTransfers(transfers []gaivota.Transfer) {
+	fmt.Println("Transfers:")
+	fmt.Printf("%-5s %-8s %-8s %-12s %-14s %-12s %-25s\n",
+		"ID", "From", "To", "Position ID", "Amount", "Fee", "Executed At")
+	fmt.Println("-----------------------------------------------------------------------------------")
+	for _, transfer := range transfers {
+		fmt.Printf("%-5d %-8d %-8d %-12d %-14s %-12s %-25s\n",
+			transfer.ID, transfer.FromWalletID, transfer.ToWalletID, transfer.PositionID,
+			transfer.Amount, transfer.Fee, transfer.ExecutedAt.Format(time.RFC3339))
+	}
+}
+
+func handleEvents(client *gaivota.Client, args []string) {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Println("Missing subcommand for events")
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		opts, ok := parseListFlags("events list", args[1:])
+		if !ok {
+			return
+		}
+		events, next, err := client.EventStore.All(ctx, opts)
+		if err != nil {
+			fmt.Printf("Error listing events: %v\n", err)
+			return
+		}
+
+		printEvents(*events)
+		printNextCursor(next)
+
+	case "list-by-position":
+		if len(args) < 2 {
+			fmt.Println("Missing position ID")
+			return
+		}
+		positionId, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid position ID: %s\n", args[1])
+			return
+		}
+
+		events, err := client.EventStore.GetByPositionID(ctx, positionId)
+		if err != nil {
+			fmt.Printf("Error listing events: %v\n", err)
+			return
+		}
+
+		printEvents(*events)
+
+	case "get":
+		if len(args) < 2 {
+			fmt.Println("Missing event ID")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid event ID: %s\n", args[1])
+			return
+		}
+
+		event, err := client.EventStore.Get(ctx, id)
+		if err != nil {
+			fmt.Printf("Error getting event: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Event Details:\n")
+		fmt.Printf("  ID: %d\n", event.ID)
+		fmt.Printf("  Position ID: %d\n", event.PositionID)
+		fmt.Printf("  Kind: %s\n", event.Kind)
+		fmt.Printf("  Amount: %s\n", event.Amount)
+		fmt.Printf("  Unit Price: %s\n", money(event.UnitPrice, event.QuoteCurrency))
+		fmt.Printf("  Note: %s\n", event.Note)
+		fmt.Printf("  Executed At: %s\n", event.ExecutedAt)
+
+	case "create":
+		if len(args) < 4 {
+			fmt.Println("Usage: events create <position> <kind> <amount> [unit_price] [at] [note]")
+			return
+		}
+
+		positionId, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid position ID: %s\n", args[1])
+			return
+		}
+
+		kind := gaivota.EventKind(strings.ToLower(args[2]))
+		if !kind.Valid() {
+			fmt.Printf("Invalid kind: %s, must be one of %v\n", args[2], gaivota.EventKinds)
+			return
+		}
+
+		amount, err := decimal.NewFromString(args[3])
+		if err != nil {
+			fmt.Printf("Invalid amount: %s\n", args[3])
+			return
+		}
+
+		unitPrice := decimal.Zero
+		if len(args) > 4 {
+			unitPrice, err = decimal.NewFromString(args[4])
+			if err != nil {
+				fmt.Printf("Invalid unit price: %s\n", args[4])
+				return
+			}
+		}
+
+		at := time.Now()
+		if len(args) > 5 {
+			at, err = parseTime(args[5])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[5])
+				return
+			}
+		}
+
+		note := ""
+		if len(args) > 6 {
+			note = strings.Join(args[6:], " ")
+		}
+
+		event, err := client.EventStore.Add(ctx, &gaivota.Event{
+			PositionID: positionId,
+			Kind:       kind,
+			Amount:     amount,
+			UnitPrice:  unitPrice,
+			Note:       note,
+			ExecutedAt: at,
+		})
+		if err != nil {
+			fmt.Printf("Error creating event: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Created %s event %d: %s of position %d at %s\n",
+			event.Kind, event.ID, event.Amount, event.PositionID, money(event.UnitPrice, event.QuoteCurrency))
+
+	case "delete":
+		if len(args) < 2 {
+			fmt.Println("Missing event ID")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid event ID: %s\n", args[1])
+			return
+		}
+
+		if err := client.EventStore.Delete(ctx, id); err != nil {
+			fmt.Printf("Error deleting event: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Deleted event %d\n", id)
+
+	default:
+		fmt.Printf("Unknown events subcommand: %s\n", args[0])
+	}
+}
+
+func printEvents(events []gaivota.Event) {
+	fmt.Println("Events:")
+	fmt.Printf("%-5s %-12s %-12s %-14s %-14s %-25s %s\n",
+		"ID", "Position ID", "Kind", "Amount", "Unit Price", "Executed At", "Note")
+	fmt.Println("-----------------------------------------------------------------------------------")
+	for _, event := range events {
+		fmt.Printf("%-5d %-12d %-12s %-14s %-14s %-25s %s\n",
+			event.ID, event.PositionID, event.Kind, event.Amount,
+			money(event.UnitPrice, event.QuoteCurrency), event.ExecutedAt.Format(time.RFC3339), event.Note)
+	}
+}
+
+func handlePrices(client *gaivota.Client, args []string) {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Println("Missing subcommand for prices")
+		return
+	}
+
+	switch args[0] {
+	case "get":
+		if len(args) < 2 {
+			fmt.Println("Usage: prices get <symbol> [quote] [at]")
+			return
+		}
+
+		quote := gaivota.DefaultQuoteCurrency
+		if len(args) > 2 {
+			quote = strings.ToUpper(args[2])
+		}
+
+		var at time.Time
+		if len(args) > 3 {
+			var err error
+			at, err = parseTime(args[3])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[3])
+				return
+			}
+		}
+
+		price, err := valuation.New(client).Price(ctx, strings.ToUpper(args[1]), quote, at)
+		if err != nil {
+			fmt.Printf("Error getting price: %v\n", err)
+			return
+		}
+
+		fmt.Printf("%s: %s %s (at %s)\n", price.TokenSymbol, price.Value, price.QuoteCurrency, price.At)
+
+	case "add":
+		if len(args) < 4 {
+			fmt.Println("Usage: prices add <symbol> <quote> <price> [at]")
+			return
+		}
+
+		value, err := decimal.NewFromString(args[3])
+		if err != nil {
+			fmt.Printf("Invalid price: %s\n", args[3])
+			return
+		}
+
+		at := time.Now()
+		if len(args) > 4 {
+			at, err = parseTime(args[4])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[4])
+				return
+			}
+		}
+
+		price := &gaivota.Price{
+			TokenSymbol:   strings.ToUpper(args[1]),
+			QuoteCurrency: strings.ToUpper(args[2]),
+			Value:         value,
+			At:            at,
+			Source:        "cli",
+		}
+		if err := price.Validate(); err != nil {
+			fmt.Printf("Invalid price: %v\n", err)
+			return
+		}
+
+		price, err = client.PriceStore.Add(ctx, price)
+		if err != nil {
+			fmt.Printf("Error storing price: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Stored %s: %s %s (at %s)\n", price.TokenSymbol, price.Value, price.QuoteCurrency, price.At)
+
+	case "import":
+		if len(args) < 2 {
+			fmt.Println("Usage: prices import <file.csv>")
+			return
+		}
+
+		f, err := os.Open(args[1])
+		if err != nil {
+			fmt.Printf("Error opening file: %v\n", err)
+			return
+		}
+		defer f.Close()
+
+		prices, err := pricing.ReadCSV(f)
+		if err != nil {
+			fmt.Printf("Error reading prices: %v\n", err)
+			return
+		}
+
+		for i := range prices {
+			if _, err := client.PriceStore.Add(ctx, &prices[i]); err != nil {
+				fmt.Printf("Error storing price: %v\n", err)
+				return
+			}
+		}
+
+		fmt.Printf("Imported %d prices\n", len(prices))
+
+	default:
+		fmt.Printf("Unknown prices subcommand: %s\n", args[0])
+	}
+}
+
+func handleFX(client *gaivota.Client, args []string) {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Println("Missing subcommand for fx")
+		return
+	}
+
+	switch args[0] {
+	case "get":
+		if len(args) < 3 {
+			fmt.Println("Usage: fx get <base> <quote> [at]")
+			return
+		}
+
+		at := time.Now()
+		if len(args) > 3 {
+			var err error
+			at, err = parseTime(args[3])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[3])
+				return
+			}
+		}
+
+		base, quote := strings.ToUpper(args[1]), strings.ToUpper(args[2])
+
+		rate, err := fx.NewConverter(client.FXRateStore).Rate(ctx, base, quote, at)
+		if err != nil {
+			fmt.Printf("Error getting fx rate: %v\n", err)
+			return
+		}
+
+		fmt.Printf("1 %s = %s %s (at %s)\n", base, rate, quote, at)
+
+	case "add":
+		if len(args) < 4 {
+			fmt.Println("Usage: fx add <base> <quote> <rate> [at]")
+			return
+		}
+
+		value, err := decimal.NewFromString(args[3])
+		if err != nil {
+			fmt.Printf("Invalid rate: %s\n", args[3])
+			return
+		}
+
+		at := time.Now()
+		if len(args) > 4 {
+			at, err = parseTime(args[4])
+			if err != nil {
+				fmt.Printf("Invalid time: %s\n", args[4])
+				return
+			}
+		}
+
+		rate := &gaivota.FXRate{
+			BaseCurrency:  strings.ToUpper(args[1]),
+			QuoteCurrency: strings.ToUpper(args[2]),
+			Rate:          value,
+			At:            at,
+			Source:        "cli",
+		}
+		if err := rate.Validate(); err != nil {
+			fmt.Printf("Invalid fx rate: %v\n", err)
+			return
+		}
+
+		rate, err = client.FXRateStore.Add(ctx, rate)
+		if err != nil {
+			fmt.Printf("Error storing fx rate: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Stored 1 %s = %s %s (at %s)\n", rate.BaseCurrency, rate.Rate, rate.QuoteCurrency, rate.At)
+
+	default:
+		fmt.Printf("Unknown fx subcommand: %s\n", args[0])
+	}
+}
+
+func handleKeys(client *gaivota.Client, args []string) {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Println("Missing subcommand for keys")
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) < 2 {
+			fmt.Println("Missing user ID")
+			return
+		}
+		userID, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid user ID: %s\n", args[1])
+			return
+		}
+
+		keys, err := client.APIKeyStore.GetByUserID(ctx, userID)
+		if err != nil {
+			fmt.Printf("Error listing API keys: %v\n", err)
+			return
+		}
+
+		fmt.Printf("API Keys for User %d:\n", userID)
+		fmt.Printf("%-5s %-25s %-25s\n", "ID", "Name", "Created")
+		fmt.Println("-------------------------------------------------------")
+		for _, key := range *keys {
+			fmt.Printf("%-5d %-25s %-25s\n", key.ID, key.Name, key.CreatedAt.Format(time.RFC3339))
+		}
+
+	case "create":
+		if len(args) < 3 {
+			fmt.Println("Usage: keys create <user_id> <name>")
+			return
+		}
+		userID, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid user ID: %s\n", args[1])
+			return
+		}
+
+		key, apiKey, err := auth.NewAPIKey(ctx, client.APIKeyStore, userID, args[2])
+		if err != nil {
+			fmt.Printf("Error creating API key: %v\n", err)
+			return
+		}
+
+		fmt.Printf("API key %d created, store it now as it cannot be shown again:\n", apiKey.ID)
+		fmt.Printf("  %s\n", key)
+
+	case "revoke":
+		if len(args) < 2 {
+			fmt.Println("Missing API key ID")
+			return
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Invalid API key ID: %s\n", args[1])
+			return
+		}
+
+		err = client.APIKeyStore.Delete(ctx, id)
+		if err != nil {
+			fmt.Printf("Error revoking API key: %v\n", err)
+			return
+		}
+
+		fmt.Printf("API key %d revoked\n", id)
+
+	default:
+		fmt.Printf("Unknown keys subcommand: %s\n", args[0])
+	}
+}
+
+func importOrders(ctx context.Context, client *gaivota.Client, args []string) {
+	flags := flag.NewFlagSet("orders import", flag.ContinueOnError)
+	format := flags.String("format", "", "Exchange export format ("+strings.Join(trades.Formats(), ", ")+") or generic")
+	positionId := flags.Int("position", 0, "Position the trades belong to")
+	exchange := flags.String("exchange", "", "Exchange recorded on the orders, the format by default")
+	columns := flags.String("columns", "", "Generic format column mapping, e.g. id=Trade ID,side=Side,amount=Qty,price=Price,time=Date")
+	timeLayout := flags.String("time-layout", time.RFC3339, "Generic format time layout")
+	dryRun := flags.Bool("dry-run", false, "Preview the orders without adding them")
+
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+
+	if *format == "" || *positionId == 0 || flags.NArg() != 1 {
+		fmt.Println("Usage: orders import --format=<exchange> --position=<id> [--exchange=<name>] [--columns=<mapping>] [--time-layout=<layout>] [--dry-run] <file.csv>")
+		return
+	}
+
+	var mapper trades.Mapper
+	var err error
+
+	if *format == "generic" {
+		mapper, err = trades.ParseColumns(*columns, *timeLayout)
+	} else {
+		mapper, err = trades.Lookup(*format)
+	}
+	if err != nil {
+		fmt.Printf("Invalid format: %v\n", err)
+		return
+	}
+
+	if *exchange == "" {
+		*exchange = *format
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error opening file: %v\n", err)
+		return
+	}
+	defer f.Close()
+
+	read, err := trades.Read(f, mapper)
+	if err != nil {
+		fmt.Printf("Error reading trades: %v\n", err)
+		return
+	}
+
+	plan, err := trades.NewPlan(ctx, client, *positionId, *exchange, read)
+	if err != nil {
+		fmt.Printf("Error planning import: %v\n", err)
+		return
+	}
+
+	fmt.Printf("%-20s %-8s %-20s %-20s %-25s\n", "Trade ID", "Op", "Amount", "Unit Price", "Executed At")
+	fmt.Println("-----------------------------------------------------------------------------------------------")
+	for _, order := range plan.Orders {
+		fmt.Printf("%-20s %-8s %-20s %-20s %-25s\n", order.TradeID, order.Operation, order.Amount,
+			money(order.UnitPrice, order.QuoteCurrency), order.ExecutedAt.Format(time.RFC3339))
+	}
+	fmt.Println("")
+
+	for _, invalid := range plan.Invalid {
+		fmt.Printf("Invalid trade %s\n", invalid)
+	}
+	if len(plan.Invalid) > 0 {
+		fmt.Println("")
+	}
+
+	fmt.Printf("%d new orders, %d duplicate trades, %d trades of other tokens, %d invalid trades\n", len(plan.Orders), len(plan.Duplicates), len(plan.Skipped), len(plan.Invalid))
+
+	if len(plan.Invalid) > 0 {
+		fmt.Println("Fix the invalid trades to import the file, nothing was imported")
+		return
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing was imported")
+		return
+	}
+
+	added, err := plan.Commit(ctx, client.OrderStore)
+	if err != nil {
+		fmt.Printf("Error importing orders, nothing was imported: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Imported %d orders into position %d\n", len(added), *positionId)
+}
+
+func printReturns(returns *performance.Returns) {
+	fmt.Printf("Returns from %s to %s:\n", returns.From, returns.To)
+	fmt.Printf("  Start Value: %s\n", money(returns.StartValue, returns.Quote))
+	fmt.Printf("  End Value: %s\n", money(returns.EndValue, returns.Quote))
+	fmt.Printf("  Net Flows: %s\n", money(returns.NetFlows, returns.Quote))
+	fmt.Printf("  Time-Weighted Return: %s%%\n", returns.TWR.Shift(2).StringFixed(2))
+	if returns.XIRR != nil {
+		fmt.Printf("  Money-Weighted Return (XIRR): %s%% per year\n", returns.XIRR.Shift(2).StringFixed(2))
+	} else {
+		fmt.Println("  Money-Weighted Return (XIRR): n/a")
+	}
+}
+
+// Parses optional [from] [to] arguments, zero times when missing
+func parsePeriod(args []string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if len(args) > 0 {
+		if from, err = parseTime(args[0]); err != nil {
+			return from, to, err
+		}
+	}
+
+	if len(args) > 1 {
+		if to, err = parseTime(args[1]); err != nil {
+			return from, to, err
+		}
+	}
+
+	return from, to, nil
+}
+
+// Parses the options of a list subcommand. Returns false, after printing
+// the problem, when the flags are invalid.
+func parseListFlags(command string, args []string) (gaivota.ListOptions, bool) {
+	var opts gaivota.ListOptions
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.IntVar(&opts.Limit, "limit", 0, "Maximum number of items, 0 for all")
+	flags.StringVar(&opts.Cursor, "cursor", "", "Cursor printed with the previous page")
+	flags.StringVar(&opts.Sort, "sort", "", "Field to sort by, as named in the API (e.g. createdAt)")
+	flags.BoolVar(&opts.Descending, "desc", false, "Sort in descending order")
+	from := flags.String("from", "", "Only items from this time (RFC 3339 or date)")
+	to := flags.String("to", "", "Only items up to this time (RFC 3339 or date)")
+	operation := flags.String("operation", "", "Only buy or sell orders")
+	flags.StringVar(&opts.Exchange, "exchange", "", "Only orders on this exchange")
+	kind := flags.String("kind", "", "Only events of this kind (e.g. reward)")
+	flags.StringVar(&opts.Symbol, "symbol", "", "Only items of this token")
+	flags.StringVar(&opts.Entity, "entity", "", "Only audit entries of this entity (e.g. orders)")
+	flags.IntVar(&opts.EntityID, "id", 0, "Only audit entries of the entity with this ID")
+
+	if err := flags.Parse(args); err != nil {
+		return opts, false
+	}
+
+	var err error
+	if *from != "" {
+		if opts.From, err = parseTime(*from); err != nil {
+			fmt.Printf("Invalid from time: %s\n", *from)
+			return opts, false
+		}
+	}
+
+	if *to != "" {
+		if opts.To, err = parseTime(*to); err != nil {
+			fmt.Printf("Invalid to time: %s\n", *to)
+			return opts, false
+		}
+	}
+
+	opts.Operation = gaivota.OrderOperation(strings.ToLower(*operation))
+	opts.Kind = gaivota.EventKind(strings.ToLower(*kind))
+
+	return opts, true
+}
+
+// Tells how to get the next page of a list, if there is one
+func printNextCursor(next string) {
+	if next != "" {
+		fmt.Printf("\nMore items available, use --cursor=%s for the next page\n", next)
+	}
+}
+
+// Formats an amount of money with its currency code, e.g. 1234.50 EUR
+func money(value decimal.Decimal, currency string) string {
+	return fmt.Sprintf("%s %s", value.StringFixed(2), currency)
+}
+
+// Maps user IDs to their reporting currency, which wallet values are kept in
+func userCurrencies(ctx context.Context, client *gaivota.Client) map[int]string {
+	currencies := map[int]string{}
+
+	users, _, err := client.UserStore.All(ctx, gaivota.ListOptions{})
+	if err != nil {
+		return currencies
+	}
+
+	for _, user := range *users {
+		currencies[user.ID] = user.ReportingCurrency
+	}
+
+	return currencies
+}
+
+// Parses RFC 3339 times or plain dates (e.g. 2021-05-28)
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
@@ -2,56 +2,146 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
-	"path"
 	"time"
 
 	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/audit"
+	"github.com/leoschet/gaivota/auth"
+	"github.com/leoschet/gaivota/inmem"
 	"github.com/leoschet/gaivota/internal/config"
 	"github.com/leoschet/gaivota/log"
+	"github.com/leoschet/gaivota/metrics"
 	"github.com/leoschet/gaivota/mux"
 	"github.com/leoschet/gaivota/postgres"
+	"github.com/leoschet/gaivota/pricing"
+	"github.com/leoschet/gaivota/snapshots"
+	"github.com/leoschet/gaivota/valuation"
 )
 
 func main() {
-	logger := log.New("Gaivota-api - ")
+	var logger gaivota.Logger = log.New(os.Stdout, gaivota.LogLevelInfo, log.FormatText).With("app", "gaivota-api")
 
-	rootPath, err := os.Getwd()
+	settings, _, err := config.Load(config.ServerName, os.Args[1:], "")
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		logger.Log(gaivota.LogLevelFatal, "Error getting root path: %v", err)
+		logger.Log(gaivota.LogLevelFatal, "Error while reading configuration: %v", err)
+	}
+
+	configured, err := log.Parse(os.Stdout, settings.LogLevel, settings.LogFormat)
+	if err != nil {
+		logger.Log(gaivota.LogLevelFatal, "Error while setting up logging: %v", err)
+	}
+	logger = configured.With("app", "gaivota-api")
+
+	if settings.Profile != "" {
+		logger.Log(gaivota.LogLevelInfo, "Using the %s profile", settings.Profile)
+	}
+
+	var client *gaivota.Client
+	var dependencies []mux.Dependency
+
+	dependency := func(checker gaivota.HealthChecker, critical bool) mux.Dependency {
+		return mux.Dependency{
+			Checker:  checker,
+			Timeout:  time.Duration(settings.HealthTimeouts[checker.Name()]),
+			Critical: critical,
+		}
+	}
+
+	var registry *metrics.Registry
+	if settings.Metrics {
+		registry = metrics.NewRegistry()
+	}
+
+	switch settings.Store {
+	case "", "postgres":
+		db, err := postgres.Connect(context.Background(), settings.DatabaseConnString, postgres.Options{
+			Logger:   logger,
+			MaxConns: int32(settings.DatabaseMaxConns),
+			MinConns: int32(settings.DatabaseMinConns),
+		})
+		if err != nil {
+			logger.Log(gaivota.LogLevelFatal, "Error while connecting to Postgres: %v", err)
+		}
+		defer db.Close()
+
+		migrator, err := postgres.NewMigrator(db)
+		if err != nil {
+			logger.Log(gaivota.LogLevelFatal, "Error while loading migrations: %v", err)
+		}
+
+		if settings.AutoMigrate {
+			migrate(migrator, logger)
+		}
+
+		client = db.NewPostgresClient()
+		dependencies = append(dependencies, dependency(db, true), dependency(migrator, true))
+
+		if registry != nil {
+			db.RegisterMetrics(registry)
+		}
+	case "memory":
+		logger.Log(gaivota.LogLevelInfo, "Using the in-memory store, data is lost on exit")
+
+		db := inmem.New()
+
+		client = db.NewClient()
+		dependencies = append(dependencies, dependency(db, true))
+	default:
+		logger.Log(gaivota.LogLevelFatal, "Unknown store %q, expected postgres or memory", settings.Store)
+	}
+
+	if registry != nil {
+		client = metrics.Instrument(client, registry)
 	}
 
-	settings, err := config.ReadFile(path.Join(rootPath, "/config.json"))
+	client.PriceSource, err = pricing.New(client.PriceStore, settings.PriceSource, settings.PriceSourceLocation, settings.PriceSourceKey)
 	if err != nil {
-		logger.Log(gaivota.LogLevelFatal, "Error while reading config file: %v", err)
+		logger.Log(gaivota.LogLevelFatal, "Error while setting up price source: %v", err)
 	}
 
-	if settings.Port == 0 {
-		panic("Missing mandatory environment variable PORT")
+	// Stored prices are still served without the source, so it is not critical
+	if settings.PriceSource != "" {
+		dependencies = append(dependencies, dependency(pricing.HealthCheck(client.PriceSource), false))
 	}
 
-	db, err := postgres.Connect(context.Background(), settings.DatabaseConnString)
+	// Changes made outside of requests, like snapshots, are made by the server
+	client = audit.Record(client, gaivota.Actor{Type: gaivota.ActorTypeSystem})
+
+	tokens, err := auth.NewTokens(settings.AuthSecret, time.Duration(settings.TokenTTL))
 	if err != nil {
-		logger.Log(gaivota.LogLevelFatal, "Error while connecting to Postgres: %v", err)
+		logger.Log(gaivota.LogLevelFatal, "Error while setting up authentication: %v", err)
 	}
-	defer db.Close()
 
-	pgClient := db.NewPostgresClient()
+	jobContext, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if settings.SnapshotJob {
+		snapshotter := snapshots.New(client, valuation.New(client), logger)
+		go snapshotter.Run(jobContext)
+	}
 
 	app := mux.New("/")
-	app.InitRouter(pgClient, []gaivota.HealthChecker{db}, logger)
+	app.SignUps = settings.SignUps
+	app.Metrics = registry
+	app.InitRouter(client, tokens, dependencies, logger)
 
 	addr := fmt.Sprintf("0.0.0.0:%v", settings.Port)
 	// https://golang.org/pkg/net/http/#Server
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      app.Router,
-		IdleTimeout:  120 * time.Second,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		Handler:      app.Handler,
+		IdleTimeout:  time.Duration(settings.IdleTimeout),
+		ReadTimeout:  time.Duration(settings.ReadTimeout),
+		WriteTimeout: time.Duration(settings.WriteTimeout),
 	}
 
 	go func() {
@@ -63,7 +153,7 @@ func main() {
 	}()
 
 	// https://golang.org/pkg/os/signal/#Notify
-	sigChan := make(chan os.Signal)
+	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
 	signal.Notify(sigChan, os.Kill)
 
@@ -72,6 +162,19 @@ func main() {
 	logger.Log(gaivota.LogLevelInfo, "Received terminate %s signal, gracefully shutting down.", sig)
 
 	// https://pkg.go.dev/context
-	timeoutContext, _ := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutContext, cancel := context.WithTimeout(context.Background(), time.Duration(settings.ShutdownTimeout))
+	defer cancel()
 	server.Shutdown(timeoutContext)
 }
+
+// Applies pending migrations, exiting when one fails so the server never
+// runs against a schema it does not expect
+func migrate(migrator *postgres.Migrator, logger gaivota.Logger) {
+	ran, err := migrator.Up(context.Background())
+	for _, migration := range ran {
+		logger.Log(gaivota.LogLevelInfo, "Applied migration %v (%s)", migration.Version, migration.Name)
+	}
+	if err != nil {
+		logger.Log(gaivota.LogLevelFatal, "Error while migrating the database: %v", err)
+	}
+}
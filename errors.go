@@ -0,0 +1,112 @@
+package gaivota
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrConflict is returned when an entity would duplicate another one, e.g. a
+// second portfolio with the same name for a user
+var ErrConflict = errors.New("conflict")
+
+// ErrInvalid is returned for entities with missing or malformed fields, or
+// referencing entities that do not exist
+var ErrInvalid = errors.New("invalid")
+
+// ErrForbidden is returned when the caller may not do what they ask, whether
+// or not the entities exist, e.g. create a portfolio for another user
+var ErrForbidden = errors.New("forbidden")
+
+// FieldError tells what is wrong with a field, named as in JSON
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error details an ErrNotFound, ErrConflict, ErrInvalid, ErrForbidden or
+// ErrFXRateNotFound error, which errors.Is matches with its Kind
+type Error struct {
+	Kind error
+	// What went wrong, worded for users
+	Message string
+	Fields  []FieldError
+	// What caused the error, e.g. a database error
+	Err error
+}
+
+// Errorf returns an Error of the kind, with the message formatted printf style
+func Errorf(kind error, format string, v ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, v...)}
+}
+
+func (e *Error) Error() string {
+	var parts []string
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+
+	if len(e.Fields) > 0 {
+		fields := make([]string, len(e.Fields))
+		for i, field := range e.Fields {
+			fields[i] = field.Field + " " + field.Message
+		}
+		parts = append(parts, strings.Join(fields, ", "))
+	}
+
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+
+	if len(parts) == 0 {
+		return e.Kind.Error()
+	}
+
+	return strings.Join(parts, ": ")
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors collects what is wrong with an entity's fields, to report them
+// all at once
+type FieldErrors []FieldError
+
+// Add records that the field is wrong, with the message formatted printf style
+func (errs *FieldErrors) Add(field string, format string, v ...interface{}) {
+	*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, v...)})
+}
+
+// Err returns an ErrInvalid Error with the fields, or nil when there are none
+func (errs FieldErrors) Err(message string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &Error{Kind: ErrInvalid, Message: message, Fields: errs}
+}
+
+// InvalidListOption returns an ErrInvalid Error about a list option (e.g.
+// "sort"), which is also ErrInvalidListOptions
+func InvalidListOption(option string, format string, v ...interface{}) error {
+	return &Error{
+		Kind:   ErrInvalid,
+		Fields: []FieldError{{Field: option, Message: fmt.Sprintf(format, v...)}},
+		Err:    ErrInvalidListOptions,
+	}
+}
+
+// FieldErrorsOf returns the fields of the Error in err's chain, if any
+func FieldErrorsOf(err error) FieldErrors {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return append(FieldErrors(nil), typed.Fields...)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package fees sums the trading fees paid on a portfolio's orders, per
+// exchange and period.
+package fees
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/fx"
+	"github.com/leoschet/gaivota/snapshots"
+	"github.com/shopspring/decimal"
+)
+
+// Total is what the orders of an exchange paid in a currency during a period
+type Total struct {
+	Exchange string `json:"exchange"`
+	// Start of the period, the summary's From when it is not split
+	Period   time.Time       `json:"period"`
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+	// Amount in the summary's Quote currency, valued when each order executed
+	Value  decimal.Decimal `json:"value"`
+	Orders int             `json:"orders"`
+}
+
+// Summary of the fees paid between From and To, in the Quote currency
+type Summary struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Quote    string    `json:"quote"`
+	Interval string    `json:"interval,omitempty"`
+	// Value of all fees
+	Value decimal.Decimal `json:"value"`
+	// Per period, exchange and currency, in that order
+	Totals []Total `json:"totals"`
+}
+
+type Summarizer struct {
+	client *gaivota.Client
+}
+
+func New(client *gaivota.Client) *Summarizer {
+	return &Summarizer{client: client}
+}
+
+// Portfolio sums the fees of the portfolio's orders executed between `from`
+// and `to`, in its reporting currency. Zero `from` means since the first
+// order, zero `to` now. The period is split by day, week or month when an
+// interval is given.
+func (summarizer *Summarizer) Portfolio(ctx context.Context, portfolioId int, from time.Time, to time.Time, interval string) (*Summary, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if to.Before(from) {
+		return nil, errors.New("period must end after it starts")
+	}
+
+	if interval != "" {
+		if _, err := snapshots.PeriodStart(from, interval); err != nil {
+			return nil, err
+		}
+	}
+
+	portfolio, err := summarizer.client.PortfolioStore.Get(ctx, portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	investments, err := summarizer.client.InvestmentStore.GetByPortfolioID(ctx, portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		From:     from,
+		To:       to,
+		Quote:    portfolio.ReportingCurrency,
+		Interval: interval,
+		Totals:   []Total{},
+	}
+
+	converter := fx.NewConverter(fx.WithPrices(summarizer.client.FXRateStore, summarizer.client.PriceStore))
+
+	type key struct {
+		exchange string
+		period   int64
+		currency string
+	}
+	totals := map[key]*Total{}
+
+	for _, investment := range *investments {
+		positions, err := summarizer.client.PositionStore.GetByInvestmentID(ctx, investment.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, position := range *positions {
+			orders, err := summarizer.client.OrderStore.GetByPositionID(ctx, position.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			var paid []gaivota.Order
+			for _, order := range orders {
+				if order.Fee.IsPositive() && !order.ExecutedAt.Before(from) && !order.ExecutedAt.After(to) {
+					paid = append(paid, order)
+				}
+			}
+
+			valued, err := accounting.ConvertOrders(ctx, converter, paid, investment.TokenSymbol, summary.Quote)
+			if err != nil {
+				return nil, err
+			}
+
+			for i, order := range paid {
+				if from.IsZero() && (summary.From.IsZero() || order.ExecutedAt.Before(summary.From)) {
+					summary.From = order.ExecutedAt
+				}
+
+				// Unsplit totals get their period once From is known
+				var period time.Time
+				if interval != "" {
+					period, _ = snapshots.PeriodStart(order.ExecutedAt, interval)
+				}
+
+				currency := feeCurrency(order)
+				k := key{exchange: order.Exchange, period: period.UnixNano(), currency: currency}
+				total, ok := totals[k]
+				if !ok {
+					total = &Total{Exchange: order.Exchange, Period: period, Currency: currency}
+					totals[k] = total
+				}
+
+				total.Amount = total.Amount.Add(order.Fee)
+				total.Value = total.Value.Add(valued[i].Fee)
+				total.Orders++
+				summary.Value = summary.Value.Add(valued[i].Fee)
+			}
+		}
+	}
+
+	for _, total := range totals {
+		if interval == "" {
+			total.Period = summary.From
+		}
+		summary.Totals = append(summary.Totals, *total)
+	}
+
+	sort.Slice(summary.Totals, func(i, j int) bool {
+		a, b := summary.Totals[i], summary.Totals[j]
+		if !a.Period.Equal(b.Period) {
+			return a.Period.Before(b.Period)
+		}
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		return a.Currency < b.Currency
+	})
+
+	return summary, nil
+}
+
+// Returns the currency the order's fee is paid in, the quote currency by
+// default, upper cased so orders written in any case are summed together
+func feeCurrency(order gaivota.Order) string {
+	if order.FeeCurrency != "" {
+		return strings.ToUpper(order.FeeCurrency)
+	}
+
+	return strings.ToUpper(order.QuoteCurrency)
+}
@@ -0,0 +1,201 @@
+package fees
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/inmem"
+	"github.com/leoschet/gaivota/inmem/inmemtest"
+	"github.com/shopspring/decimal"
+)
+
+// A buy of the test position paying a fee
+type testOrder struct {
+	exchange    string
+	fee         string
+	feeCurrency string
+	executedAt  time.Time
+}
+
+// What a total is expected to be, with decimals as strings
+type testTotal struct {
+	exchange string
+	period   time.Time
+	currency string
+	amount   string
+	value    string
+	orders   int
+}
+
+func at(month time.Month, day int, hour int) time.Time {
+	return time.Date(2021, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+// Creates a USD portfolio with the orders, BNB worth 300 USD
+func newPortfolio(t *testing.T, orders []testOrder) (*gaivota.Client, int) {
+	t.Helper()
+	ctx := context.Background()
+	client := inmem.New().NewClient()
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{ReportingCurrency: "USD"})
+
+	_, err := client.PriceStore.Add(ctx, &gaivota.Price{TokenSymbol: "BNB", QuoteCurrency: "USD", Value: decimal.NewFromInt(300), At: at(1, 1, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range orders {
+		_, err := client.OrderStore.Add(ctx, &gaivota.Order{
+			PositionID:    fixture.Position.ID,
+			Amount:        decimal.NewFromInt(1),
+			UnitPrice:     decimal.NewFromInt(100),
+			TotalPrice:    decimal.NewFromInt(100),
+			QuoteCurrency: "USD",
+			Fee:           decimal.RequireFromString(order.fee),
+			FeeCurrency:   order.feeCurrency,
+			Operation:     gaivota.OrderOperationBuy,
+			Type:          gaivota.OrderTypeMarket,
+			Exchange:      order.exchange,
+			ExecutedAt:    order.executedAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return client, fixture.Portfolio.ID
+}
+
+func assertTotals(t *testing.T, summary *Summary, value string, expected []testTotal) {
+	t.Helper()
+
+	if !summary.Value.Equal(decimal.RequireFromString(value)) {
+		t.Errorf("Fees are worth %v, expected %s", summary.Value, value)
+	}
+
+	if len(summary.Totals) != len(expected) {
+		t.Fatalf("Summary has totals %+v, expected %d", summary.Totals, len(expected))
+	}
+
+	for i, total := range summary.Totals {
+		e := expected[i]
+		if total.Exchange != e.exchange || !total.Period.Equal(e.period) || total.Currency != e.currency || total.Orders != e.orders ||
+			!total.Amount.Equal(decimal.RequireFromString(e.amount)) || !total.Value.Equal(decimal.RequireFromString(e.value)) {
+			t.Errorf("Total %d is %+v, expected %+v", i+1, total, e)
+		}
+	}
+}
+
+var orders = []testOrder{
+	{"kraken", "1", "", at(1, 4, 10)},
+	{"kraken", "2", "USD", at(1, 10, 10)},
+	{"binance", "0.01", "BNB", at(1, 10, 12)},
+	{"binance", "0.5", "", at(1, 12, 9)},
+	{"kraken", "4", "", at(2, 1, 0)},
+	// Free trades are left out
+	{"coinbase", "0", "", at(1, 5, 0)},
+}
+
+func TestPortfolioPerPeriod(t *testing.T) {
+	client, portfolioId := newPortfolio(t, orders)
+
+	tests := []struct {
+		interval string
+		totals   []testTotal
+	}{
+		{
+			interval: "day",
+			totals: []testTotal{
+				{"kraken", at(1, 4, 0), "USD", "1", "1", 1},
+				{"binance", at(1, 10, 0), "BNB", "0.01", "3", 1},
+				{"kraken", at(1, 10, 0), "USD", "2", "2", 1},
+				{"binance", at(1, 12, 0), "USD", "0.5", "0.5", 1},
+				{"kraken", at(2, 1, 0), "USD", "4", "4", 1},
+			},
+		},
+		{
+			// Weeks start on Monday: January 4th and 11th, 2021, and February 1st
+			interval: "week",
+			totals: []testTotal{
+				{"binance", at(1, 4, 0), "BNB", "0.01", "3", 1},
+				{"kraken", at(1, 4, 0), "USD", "3", "3", 2},
+				{"binance", at(1, 11, 0), "USD", "0.5", "0.5", 1},
+				{"kraken", at(2, 1, 0), "USD", "4", "4", 1},
+			},
+		},
+		{
+			interval: "month",
+			totals: []testTotal{
+				{"binance", at(1, 1, 0), "BNB", "0.01", "3", 1},
+				{"binance", at(1, 1, 0), "USD", "0.5", "0.5", 1},
+				{"kraken", at(1, 1, 0), "USD", "3", "3", 2},
+				{"kraken", at(2, 1, 0), "USD", "4", "4", 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.interval, func(t *testing.T) {
+			summary, err := New(client).Portfolio(context.Background(), portfolioId, time.Time{}, at(3, 1, 0), test.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertTotals(t, summary, "10.5", test.totals)
+		})
+	}
+}
+
+func TestPortfolioUnsplit(t *testing.T) {
+	client, portfolioId := newPortfolio(t, orders)
+	summarizer := New(client)
+
+	// Since the first order, which the totals' period starts with
+	summary, err := summarizer.Portfolio(context.Background(), portfolioId, time.Time{}, at(3, 1, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := at(1, 4, 10)
+	if !summary.From.Equal(first) {
+		t.Errorf("Summary is from %v, expected the first order's %v", summary.From, first)
+	}
+	assertTotals(t, summary, "10.5", []testTotal{
+		{"binance", first, "BNB", "0.01", "3", 1},
+		{"binance", first, "USD", "0.5", "0.5", 1},
+		{"kraken", first, "USD", "7", "7", 3},
+	})
+
+	// Within the period only, which the totals' period starts with
+	from := at(1, 10, 11)
+	summary, err = summarizer.Portfolio(context.Background(), portfolioId, from, at(1, 31, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTotals(t, summary, "3.5", []testTotal{
+		{"binance", from, "BNB", "0.01", "3", 1},
+		{"binance", from, "USD", "0.5", "0.5", 1},
+	})
+}
+
+func TestFeeCurrency(t *testing.T) {
+	tests := []struct {
+		feeCurrency string
+		quote       string
+		expected    string
+	}{
+		{"", "USD", "USD"},
+		{"", "usd", "USD"},
+		{"usd", "USD", "USD"},
+		{"bnb", "usd", "BNB"},
+	}
+
+	for _, test := range tests {
+		order := gaivota.Order{FeeCurrency: test.feeCurrency, QuoteCurrency: test.quote}
+		if currency := feeCurrency(order); currency != test.expected {
+			t.Errorf("Fee in %q quoted in %q is in %s, expected %s", test.feeCurrency, test.quote, currency, test.expected)
+		}
+	}
+}
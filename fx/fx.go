@@ -0,0 +1,141 @@
+// Package fx converts amounts between currencies using historical exchange rates.
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/shopspring/decimal"
+)
+
+// Decimal places kept when inverting or crossing rates
+const divisionPrecision = 18
+
+// Rates finds the latest rate at or before a time, e.g. a gaivota.FXRateStore
+type Rates interface {
+	GetAt(ctx context.Context, base string, quote string, at time.Time) (*gaivota.FXRate, error)
+}
+
+// Prices finds the latest price of a token at or before a time, e.g. a
+// gaivota.PriceStore
+type Prices interface {
+	GetAt(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error)
+}
+
+// WithPrices returns rates falling back to token prices, so amounts in a
+// token (e.g. fees paid in BNB) convert like amounts in a currency
+func WithPrices(rates Rates, prices Prices) Rates {
+	return &pricedRates{rates: rates, prices: prices}
+}
+
+type pricedRates struct {
+	rates  Rates
+	prices Prices
+}
+
+func (priced *pricedRates) GetAt(ctx context.Context, base string, quote string, at time.Time) (*gaivota.FXRate, error) {
+	rate, err := priced.rates.GetAt(ctx, base, quote, at)
+	if !errors.Is(err, gaivota.ErrFXRateNotFound) {
+		return rate, err
+	}
+
+	price, priceErr := priced.prices.GetAt(ctx, base, quote, at)
+	if errors.Is(priceErr, gaivota.ErrPriceNotFound) {
+		return nil, err
+	}
+	if priceErr != nil {
+		return nil, priceErr
+	}
+
+	return &gaivota.FXRate{
+		BaseCurrency:  price.TokenSymbol,
+		QuoteCurrency: price.QuoteCurrency,
+		Rate:          price.Value,
+		At:            price.At,
+		Source:        price.Source,
+	}, nil
+}
+
+type Converter struct {
+	rates Rates
+}
+
+func NewConverter(rates Rates) *Converter {
+	return &Converter{rates: rates}
+}
+
+// Rate returns how much one `from` is worth in `to` at the given time. Stored
+// pairs are used as is or inverted; when neither exists, the rate is crossed
+// through the default quote currency (e.g. EUR -> USD -> USDT).
+func (converter *Converter) Rate(ctx context.Context, from string, to string, at time.Time) (decimal.Decimal, error) {
+	from, to = normalize(from), normalize(to)
+
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	rate, err := converter.pair(ctx, from, to, at)
+	if !errors.Is(err, gaivota.ErrFXRateNotFound) {
+		return rate, err
+	}
+
+	pivot := gaivota.DefaultQuoteCurrency
+	if from == pivot || to == pivot {
+		return decimal.Zero, err
+	}
+
+	fromPivot, err := converter.pair(ctx, from, pivot, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	pivotTo, err := converter.pair(ctx, pivot, to, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return fromPivot.Mul(pivotTo), nil
+}
+
+// Convert returns the amount in `from` converted to `to` at the given time
+func (converter *Converter) Convert(ctx context.Context, amount decimal.Decimal, from string, to string, at time.Time) (decimal.Decimal, error) {
+	rate, err := converter.Rate(ctx, from, to, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return amount.Mul(rate), nil
+}
+
+// Looks the pair up, falling back to the inverted pair
+func (converter *Converter) pair(ctx context.Context, from string, to string, at time.Time) (decimal.Decimal, error) {
+	if converter.rates == nil {
+		return decimal.Zero, fmt.Errorf("Could not convert %s to %s: %w", from, to, gaivota.ErrFXRateNotFound)
+	}
+
+	rate, err := converter.rates.GetAt(ctx, from, to, at)
+	if err == nil {
+		return rate.Rate, nil
+	}
+	if !errors.Is(err, gaivota.ErrFXRateNotFound) {
+		return decimal.Zero, err
+	}
+
+	inverse, err := converter.rates.GetAt(ctx, to, from, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if inverse.Rate.IsZero() {
+		return decimal.Zero, fmt.Errorf("Could not invert zero rate from %s to %s: %w", to, from, gaivota.ErrFXRateNotFound)
+	}
+
+	return decimal.NewFromInt(1).DivRound(inverse.Rate, divisionPrecision), nil
+}
+
+func normalize(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
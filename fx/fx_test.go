@@ -0,0 +1,186 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	jan1 = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	jan2 = jan1.AddDate(0, 0, 1)
+	jan3 = jan1.AddDate(0, 0, 2)
+)
+
+var errUnavailable = errors.New("store unavailable")
+
+// Rates and prices stored per pair, like the stores: the latest value at or
+// before the time is found
+type testRates map[string][]gaivota.FXRate
+
+func (rates testRates) add(base string, quote string, value string, at time.Time) {
+	key := base + "/" + quote
+	rates[key] = append(rates[key], gaivota.FXRate{BaseCurrency: base, QuoteCurrency: quote, Rate: decimal.RequireFromString(value), At: at})
+}
+
+func (rates testRates) GetAt(ctx context.Context, base string, quote string, at time.Time) (*gaivota.FXRate, error) {
+	if base == "ERR" || quote == "ERR" {
+		return nil, errUnavailable
+	}
+
+	var found *gaivota.FXRate
+	for _, rate := range rates[base+"/"+quote] {
+		if !rate.At.After(at) && (found == nil || rate.At.After(found.At)) {
+			rate := rate
+			found = &rate
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("Could not get fx rate from %s to %s: %w", base, quote, gaivota.ErrFXRateNotFound)
+	}
+
+	return found, nil
+}
+
+type testPrices testRates
+
+func (prices testPrices) GetAt(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	rate, err := testRates(prices).GetAt(ctx, symbol, quote, at)
+	if errors.Is(err, gaivota.ErrFXRateNotFound) {
+		return nil, fmt.Errorf("Could not get price of %s in %s: %w", symbol, quote, gaivota.ErrPriceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &gaivota.Price{TokenSymbol: rate.BaseCurrency, QuoteCurrency: rate.QuoteCurrency, Value: rate.Rate, At: rate.At}, nil
+}
+
+func newTestRates() testRates {
+	rates := testRates{}
+	rates.add("EUR", "USD", "1.2", jan1)
+	rates.add("EUR", "USD", "1.25", jan2)
+	rates.add("USDT", "USD", "0.8", jan1)
+	rates.add("GBP", "USD", "1.5", jan1)
+	rates.add("GBP", "EUR", "1.1", jan1)
+	rates.add("JPY", "USD", "0", jan1)
+
+	return rates
+}
+
+func TestRate(t *testing.T) {
+	converter := NewConverter(newTestRates())
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		at       time.Time
+		expected string
+	}{
+		{"same currency", "usd", "USD", jan1, "1"},
+		{"direct", "EUR", "USD", jan1, "1.2"},
+		{"direct, latest before the time", "EUR", "USD", jan3, "1.25"},
+		{"lower case", " eur", "usd ", jan1, "1.2"},
+		{"inverted", "USD", "EUR", jan2, "0.8"},
+		{"crossed through USD", "EUR", "USDT", jan1, "1.5"},
+		{"crossed through USD and inverted", "USDT", "EUR", jan2, "0.64"},
+		{"direct before crossed", "GBP", "EUR", jan1, "1.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, err := converter.Rate(context.Background(), test.from, test.to, test.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !rate.Equal(decimal.RequireFromString(test.expected)) {
+				t.Errorf("Rate from %s to %s is %v, expected %s", test.from, test.to, rate, test.expected)
+			}
+		})
+	}
+
+	amount, err := converter.Convert(context.Background(), decimal.NewFromInt(100), "EUR", "USDT", jan1)
+	if err != nil || !amount.Equal(decimal.NewFromInt(150)) {
+		t.Errorf("100 EUR is %v USDT (%v), expected 150", amount, err)
+	}
+}
+
+func TestRateNotFound(t *testing.T) {
+	tests := []struct {
+		name      string
+		converter *Converter
+		from      string
+		to        string
+		at        time.Time
+	}{
+		{"unknown currency", NewConverter(newTestRates()), "EUR", "CHF", jan1},
+		{"unknown pair with USD", NewConverter(newTestRates()), "CHF", "USD", jan1},
+		{"before the first rate", NewConverter(newTestRates()), "EUR", "USD", jan1.Add(-time.Second)},
+		{"crossed with a leg missing", NewConverter(newTestRates()), "EUR", "CHF", jan2},
+		{"inverted zero rate", NewConverter(newTestRates()), "USD", "JPY", jan1},
+		{"no rates", NewConverter(nil), "EUR", "USD", jan1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.converter.Rate(context.Background(), test.from, test.to, test.at); !errors.Is(err, gaivota.ErrFXRateNotFound) {
+				t.Errorf("Rate from %s to %s answered %v, expected ErrFXRateNotFound", test.from, test.to, err)
+			}
+		})
+	}
+
+	// Other errors are not mistaken for missing rates
+	if _, err := NewConverter(newTestRates()).Rate(context.Background(), "ERR", "USD", jan1); !errors.Is(err, errUnavailable) {
+		t.Errorf("Rate of a failing store answered %v, expected its error", err)
+	}
+}
+
+func TestWithPrices(t *testing.T) {
+	prices := testPrices{}
+	testRates(prices).add("BNB", "USD", "250", jan1)
+	testRates(prices).add("BNB", "EUR", "190", jan3)
+	testRates(prices).add("EUR", "USD", "1000", jan1)
+
+	converter := NewConverter(WithPrices(newTestRates(), prices))
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		at       time.Time
+		expected string
+	}{
+		{"token price", "BNB", "USD", jan1, "250"},
+		{"token price, crossed through USD", "BNB", "EUR", jan2, "200"},
+		{"token price before crossing", "BNB", "EUR", jan3, "190"},
+		{"inverted token price", "USD", "BNB", jan1, "0.004"},
+		{"rates before prices", "EUR", "USD", jan1, "1.2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, err := converter.Rate(context.Background(), test.from, test.to, test.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !rate.Equal(decimal.RequireFromString(test.expected)) {
+				t.Errorf("Rate from %s to %s is %v, expected %s", test.from, test.to, rate, test.expected)
+			}
+		})
+	}
+
+	// Tokens without a price are missing rates, not missing prices
+	_, err := converter.Rate(context.Background(), "DOT", "USD", jan1)
+	if !errors.Is(err, gaivota.ErrFXRateNotFound) || errors.Is(err, gaivota.ErrPriceNotFound) {
+		t.Errorf("Rate of a token without price answered %v, expected ErrFXRateNotFound", err)
+	}
+}
@@ -3,18 +3,81 @@ package gaivota
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 type LogLevel string
 
 const (
+	LogLevelDebug LogLevel = "debug"
 	LogLevelInfo  LogLevel = "info"
+	LogLevelWarn  LogLevel = "warn"
+	LogLevelError LogLevel = "error"
+	// Logs the message and exits the process
 	LogLevelFatal LogLevel = "fatal"
 )
 
 type Logger interface {
+	// Log writes the message, formatted printf style when v is given
 	Log(level LogLevel, format string, v ...interface{})
+	// With returns a Logger adding the key-value pairs (e.g. "userId", 1)
+	// to every message
+	With(keyvals ...interface{}) Logger
+}
+
+// ErrNotFound is returned when an entity does not exist, or belongs to
+// another user than the one the request is made for
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidListOptions is returned for unknown sort fields, negative limits
+// and cursors from another list, wrapped in an ErrInvalid Error (see
+// InvalidListOption)
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// ListOptions narrows, sorts and pages list operations. The zero value lists
+// everything, sorted by ID. Filters that do not apply to a store (e.g.
+// Exchange for wallets) are ignored.
+type ListOptions struct {
+	// Maximum number of items, 0 for no limit
+	Limit int
+	// Cursor returned with the previous page, empty for the first page
+	Cursor string
+	// Field to sort by, as named in JSON (e.g. "executedAt"), "id" by default
+	Sort       string
+	Descending bool
+
+	// Date range on when orders, transfers and events were executed, lots acquired
+	// and anything else created. Zero times leave the range open.
+	From time.Time
+	To   time.Time
+	// Orders only
+	Operation OrderOperation
+	Exchange  string
+	// Events only
+	Kind EventKind
+	// Investments, positions, holdings, orders, transfers, events and lots of
+	// this token
+	Symbol string
+	// Only items owned by this user, 0 for everyone's
+	UserID int
+	// Audit entries only: of this entity (e.g. "orders") and, when not 0, of
+	// the one with this ID
+	Entity   string
+	EntityID int
+}
+
+// Transactor runs functions in a transaction
+type Transactor interface {
+	// WithinTx calls fn with a client whose stores share a transaction,
+	// committed when fn returns nil and rolled back otherwise. fn may be
+	// called again when the transaction cannot be serialized, so it must not
+	// have side effects outside the stores.
+	WithinTx(ctx context.Context, fn func(*Client) error) error
 }
 
 type Client struct {
@@ -24,46 +87,117 @@ type Client struct {
 	InvestmentStore InvestmentStore
 	PositionStore   PositionStore
 	HoldingStore    HoldingStore
+	TransferStore   TransferStore
 	OrderStore      OrderStore
+	EventStore      EventStore
+	LotStore        LotStore
+	PriceStore      PriceStore
+	PriceSource     PriceSource
+	FXRateStore     FXRateStore
+	SnapshotStore   SnapshotStore
+	APIKeyStore     APIKeyStore
+	AuditStore      AuditStore
+	Transactor      Transactor
+}
+
+// WithinTx runs fn as a unit of work: the stores of the client it is given
+// share a transaction (see Transactor). The price source is carried over.
+// Without a Transactor, fn is called with the client itself.
+func (client *Client) WithinTx(ctx context.Context, fn func(*Client) error) error {
+	if client.Transactor == nil {
+		return fn(client)
+	}
+
+	return client.Transactor.WithinTx(ctx, func(tx *Client) error {
+		tx.PriceSource = client.PriceSource
+
+		return fn(tx)
+	})
 }
 
 type User struct {
-	ID        int           `json:"id"`
-	Email     string        `json:"email"`
-	FirstName string        `json:"firstName"`
-	LastName  string        `json:"lastName"`
-	CreatedAt time.Time     `json:"-"`
-	UpdatedAt time.Time     `json:"-"`
-	DeletedAt sql.NullTime  `json:"-"`
+	ID                int          `json:"id"`
+	Email             string       `json:"email"`
+	FirstName         string       `json:"firstName"`
+	LastName          string       `json:"lastName"`
+	ReportingCurrency string       `json:"reportingCurrency"`
+	PasswordHash      string       `json:"-"`
+	CreatedAt         time.Time    `json:"-"`
+	UpdatedAt         time.Time    `json:"-"`
+	DeletedAt         sql.NullTime `json:"-"`
 }
 
 type UserStore interface {
 	// Add creates a new User in the UsersStore and returns User with ID
 	Add(context.Context, *User) (*User, error)
-	// Returns all users in the store
-	All(context.Context) (*[]User, error)
+	// Returns a page of the users in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]User, string, error)
 	// Delete the User from the store
 	Delete(ctx context.Context, id int) error
 	// Gets User if `ID` exists
 	Get(ctx context.Context, id int) (*User, error)
-	// Update the User in the store.
+	// Gets User by email, which is unique
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	// Replaces the User's password hash
+	SetPasswordHash(ctx context.Context, id int, hash string) error
+	// Update the User in the store. The password hash is left untouched.
 	Update(context.Context, *User) error
 }
 
+// APIKey is a long-lived credential for a User. Only the key's hash is
+// stored, the key itself is shown once when created.
+type APIKey struct {
+	ID        int          `json:"id"`
+	UserID    int          `json:"user"`
+	Name      string       `json:"name"`
+	KeyHash   string       `json:"-"`
+	CreatedAt time.Time    `json:"createdAt"`
+	DeletedAt sql.NullTime `json:"-"`
+}
+
+type APIKeyStore interface {
+	// Add creates a new APIKey in the APIKeyStore and returns APIKey with ID
+	Add(context.Context, *APIKey) (*APIKey, error)
+	// Delete (revoke) the APIKey
+	Delete(ctx context.Context, id int) error
+	// Gets APIKey if `ID` exists
+	Get(ctx context.Context, id int) (*APIKey, error)
+	// Gets the APIKey with the given hash, if not revoked
+	GetByHash(ctx context.Context, hash string) (*APIKey, error)
+	// Gets all APIKeys for user
+	GetByUserID(ctx context.Context, userId int) (*[]APIKey, error)
+}
+
+// Cost basis method enum
+type CostBasisMethod string
+
+const (
+	CostBasisFIFO    CostBasisMethod = "fifo"
+	CostBasisLIFO    CostBasisMethod = "lifo"
+	CostBasisHIFO    CostBasisMethod = "hifo"
+	CostBasisAverage CostBasisMethod = "average"
+)
+
+// Portfolio totals are reported in ReportingCurrency, which defaults to the
+// user's reporting currency.
 type Portfolio struct {
-	ID        int           `json:"id"`
-	UserID    int           `json:"user"`
-	Name      string        `json:"name"`
-	CreatedAt time.Time     `json:"-"`
-	UpdatedAt time.Time     `json:"-"`
-	DeletedAt sql.NullTime  `json:"-"`
+	ID                int             `json:"id"`
+	UserID            int             `json:"user"`
+	Name              string          `json:"name"`
+	CostBasisMethod   CostBasisMethod `json:"costBasisMethod"`
+	ReportingCurrency string          `json:"reportingCurrency"`
+	CreatedAt         time.Time       `json:"-"`
+	UpdatedAt         time.Time       `json:"-"`
+	DeletedAt         sql.NullTime    `json:"-"`
 }
 
 type PortfolioStore interface {
 	// Add creates a new Portfolio in the PortfoliosStore and returns Portfolio with ID
 	Add(context.Context, *Portfolio) (*Portfolio, error)
-	// Returns all Portfolios in the store
-	All(context.Context) (*[]Portfolio, error)
+	// Returns a page of the Portfolios in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Portfolio, string, error)
 	// Delete the Portfolio from the store
 	Delete(ctx context.Context, id int) error
 	// Gets Portfolio if `ID` exists
@@ -74,23 +208,25 @@ type PortfolioStore interface {
 	Update(context.Context, *Portfolio) error
 }
 
+// TotalValue is in the reporting currency of the wallet's user.
 type Wallet struct {
-	ID         int           `json:"id"`
-	UserID     int           `json:"user"`
-	Name       string        `json:"name"`
-	TotalValue float32       `json:"totalValue"`
-	Address    string        `json:"address"`
-	Location   string        `json:"location"`
-	CreatedAt  time.Time     `json:"-"`
-	UpdatedAt  time.Time     `json:"-"`
-	DeletedAt  sql.NullTime  `json:"-"`
+	ID         int             `json:"id"`
+	UserID     int             `json:"user"`
+	Name       string          `json:"name"`
+	TotalValue decimal.Decimal `json:"totalValue"`
+	Address    string          `json:"address"`
+	Location   string          `json:"location"`
+	CreatedAt  time.Time       `json:"-"`
+	UpdatedAt  time.Time       `json:"-"`
+	DeletedAt  sql.NullTime    `json:"-"`
 }
 
 type WalletStore interface {
 	// Add creates a new Wallet in the WalletsStore and returns Wallet with ID
 	Add(context.Context, *Wallet) (*Wallet, error)
-	// Returns all Wallets in the store
-	All(context.Context) (*[]Wallet, error)
+	// Returns a page of the Wallets in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Wallet, string, error)
 	// Delete the Wallet from the store
 	Delete(ctx context.Context, id int) error
 	// Gets Wallet if `ID` exists
@@ -102,20 +238,21 @@ type WalletStore interface {
 }
 
 type Investment struct {
-	ID          int           `json:"id"`
-	PortfolioID int           `json:"portfolio"`
-	Token       string        `json:"token"`
-	TokenSymbol string        `json:"symbol"`
-	CreatedAt   time.Time     `json:"-"`
-	UpdatedAt   time.Time     `json:"-"`
-	DeletedAt   sql.NullTime  `json:"-"`
+	ID          int          `json:"id"`
+	PortfolioID int          `json:"portfolio"`
+	Token       string       `json:"token"`
+	TokenSymbol string       `json:"symbol"`
+	CreatedAt   time.Time    `json:"-"`
+	UpdatedAt   time.Time    `json:"-"`
+	DeletedAt   sql.NullTime `json:"-"`
 }
 
 type InvestmentStore interface {
 	// Add creates a new Investment in the InvestmentsStore and returns Investment with ID
 	Add(context.Context, *Investment) (*Investment, error)
-	// Returns all Investments in the store
-	All(context.Context) (*[]Investment, error)
+	// Returns a page of the Investments in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Investment, string, error)
 	// Delete the Investment from the store
 	Delete(ctx context.Context, id int) error
 	// Gets Investment if `ID` exists
@@ -128,47 +265,84 @@ type InvestmentStore interface {
 	Update(context.Context, *Investment) error
 }
 
+// A Position is accounted in its QuoteCurrency, which defaults to the
+// portfolio's reporting currency.
 type Position struct {
-	ID           int           `json:"id"`
-	InvestmentID int           `json:"investment"`
-	Amount       float64       `json:"amount"`
-	AveragePrice float64       `json:"averagePrice"`
-	Profit       float64       `json:"profit,omitempty"`
-	CreatedAt    time.Time     `json:"-"`
-	UpdatedAt    time.Time     `json:"-"`
-	DeletedAt    sql.NullTime  `json:"-"`
+	ID            int             `json:"id"`
+	InvestmentID  int             `json:"investment"`
+	QuoteCurrency string          `json:"quoteCurrency"`
+	Amount        decimal.Decimal `json:"amount"`
+	AveragePrice  decimal.Decimal `json:"averagePrice"`
+	Profit        decimal.Decimal `json:"profit,omitempty"`
+	CreatedAt     time.Time       `json:"-"`
+	UpdatedAt     time.Time       `json:"-"`
+	DeletedAt     sql.NullTime    `json:"-"`
 }
 
+// Amount, AveragePrice and Profit are derived from the position's orders and
+// events, converted to the position's QuoteCurrency, so stores ignore them on
+// Add and Update.
 type PositionStore interface {
 	// Add creates a new Position in the PositionsStore and returns Position with ID
 	Add(context.Context, *Position) (*Position, error)
-	// Returns all Positions in the store
-	All(context.Context) (*[]Position, error)
+	// Returns a page of the Positions in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Position, string, error)
 	// Delete the Position from the store
 	Delete(ctx context.Context, id int) error
 	// Gets Position if `ID` exists
 	Get(ctx context.Context, id int) (*Position, error)
+	// Gets all Positions for investment
+	GetByInvestmentID(ctx context.Context, investmentId int) (*[]Position, error)
 	// Update the Position in the store.
 	Update(context.Context, *Position) error
 }
 
+// A Lot is opened by each buy Order, or incoming Event, and consumed by sells
+// and outgoing events according to the portfolio's CostBasisMethod. Lots are
+// derived from orders and events and are read-only. Either OrderID or
+// EventID is set.
+type Lot struct {
+	ID              int             `json:"id"`
+	PositionID      int             `json:"position"`
+	OrderID         int             `json:"order,omitempty"`
+	EventID         int             `json:"event,omitempty"`
+	Amount          decimal.Decimal `json:"amount"`
+	RemainingAmount decimal.Decimal `json:"remainingAmount"`
+	UnitPrice       decimal.Decimal `json:"unitPrice"`
+	AcquiredAt      time.Time       `json:"acquiredAt"`
+	CreatedAt       time.Time       `json:"-"`
+	UpdatedAt       time.Time       `json:"-"`
+}
+
+type LotStore interface {
+	// Returns a page of the Lots in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Lot, string, error)
+	// Gets Lot if `ID` exists
+	Get(ctx context.Context, id int) (*Lot, error)
+	// Gets all Lots for position, in acquisition order
+	GetByPositionID(ctx context.Context, positionId int) (*[]Lot, error)
+}
+
 type Holding struct {
-	ID         int           `json:"id"`
-	WalletID   int           `json:"wallet"`
-	Wallet     Wallet        `json:"-"`
-	PositionID int           `json:"position"`
-	Position   Position      `json:"-"`
-	Amount     float64       `json:"amount"`
-	CreatedAt  time.Time     `json:"-"`
-	UpdatedAt  time.Time     `json:"-"`
-	DeletedAt  sql.NullTime  `json:"-"`
+	ID         int             `json:"id"`
+	WalletID   int             `json:"wallet"`
+	Wallet     Wallet          `json:"-"`
+	PositionID int             `json:"position"`
+	Position   Position        `json:"-"`
+	Amount     decimal.Decimal `json:"amount"`
+	CreatedAt  time.Time       `json:"-"`
+	UpdatedAt  time.Time       `json:"-"`
+	DeletedAt  sql.NullTime    `json:"-"`
 }
 
 type HoldingStore interface {
 	// Add creates a new Holding in the HoldingsStore and returns Holding with ID
 	Add(context.Context, *Holding) (*Holding, error)
-	// Returns all Holdings in the store
-	All(context.Context) (*[]Holding, error)
+	// Returns a page of the Holdings in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Holding, string, error)
 	// Delete the Holding from the store
 	Delete(ctx context.Context, id int) error
 	// Gets Holding if `ID` exists
@@ -183,6 +357,134 @@ type HoldingStore interface {
 	Update(context.Context, *Holding) error
 }
 
+// ErrInsufficientHolding is returned when taking more out of a wallet than
+// it holds
+var ErrInsufficientHolding = errors.New("insufficient holding")
+
+// AdjustHolding adds delta, negative to take out, to the amount of the
+// position held in the wallet. The Holding is created on the first deposit.
+func AdjustHolding(ctx context.Context, store HoldingStore, walletId int, positionId int, delta decimal.Decimal) (*Holding, error) {
+	holdings, err := store.GetByWalletID(ctx, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, holding := range *holdings {
+		if holding.PositionID != positionId {
+			continue
+		}
+
+		holding.Amount = holding.Amount.Add(delta)
+		if holding.Amount.IsNegative() {
+			return nil, fmt.Errorf("Could not take %v of position %v out of wallet %v: %w", delta.Neg(), positionId, walletId, ErrInsufficientHolding)
+		}
+
+		if err := store.Update(ctx, &holding); err != nil {
+			return nil, err
+		}
+
+		return &holding, nil
+	}
+
+	if delta.IsNegative() {
+		return nil, fmt.Errorf("Could not take %v of position %v out of wallet %v: %w", delta.Neg(), positionId, walletId, ErrInsufficientHolding)
+	}
+
+	return store.Add(ctx, &Holding{WalletID: walletId, PositionID: positionId, Amount: delta})
+}
+
+// ErrInvalidTransfer is returned for transfers between the same wallet or
+// wallets of different users, and for amounts or fees out of range, wrapped in
+// an ErrInvalid Error
+var ErrInvalidTransfer = errors.New("invalid transfer")
+
+// A Transfer moves an amount of a position from one of a user's wallets to
+// another. The network fee is paid out of the amount, so the destination
+// receives Amount minus Fee, and the fee leaves the position. Transfers
+// cannot be changed: another transfer moves the coins back.
+type Transfer struct {
+	ID           int             `json:"id"`
+	FromWalletID int             `json:"fromWallet"`
+	ToWalletID   int             `json:"toWallet"`
+	PositionID   int             `json:"position"`
+	Amount       decimal.Decimal `json:"amount"`
+	Fee          decimal.Decimal `json:"fee"`
+	ExecutedAt   time.Time       `json:"executedAt"`
+	CreatedAt    time.Time       `json:"-"`
+}
+
+// Adding a Transfer recomputes the Position it belongs to, as its fee
+// reduces the Position's amount. Holdings are adjusted by MakeTransfer.
+type TransferStore interface {
+	// Add creates a new Transfer in the TransferStore and returns Transfer with ID
+	Add(context.Context, *Transfer) (*Transfer, error)
+	// Returns a page of the Transfers in the store matching the options, and
+	// the cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Transfer, string, error)
+	// Gets Transfer if `ID` exists
+	Get(ctx context.Context, id int) (*Transfer, error)
+	// Gets all Transfers of position, in execution order
+	GetByPositionID(ctx context.Context, positionId int) (*[]Transfer, error)
+	// Gets all Transfers from or to wallet, in execution order
+	GetByWalletID(ctx context.Context, walletId int) (*[]Transfer, error)
+}
+
+// MakeTransfer stores the transfer and moves its amount between the wallets'
+// holdings, all in a single transaction. Both wallets must belong to the
+// same user, and the origin must hold the amount. Transfers without execution
+// time are executed now.
+func MakeTransfer(ctx context.Context, client *Client, transfer *Transfer) (*Transfer, error) {
+	if err := transfer.Validate(); err != nil {
+		return nil, err
+	}
+
+	var newTransfer *Transfer
+	err := client.WithinTx(ctx, func(tx *Client) (err error) {
+		from, err := tx.WalletStore.Get(ctx, transfer.FromWalletID)
+		if err != nil {
+			return err
+		}
+
+		to, err := tx.WalletStore.Get(ctx, transfer.ToWalletID)
+		if err != nil {
+			return err
+		}
+
+		if from.UserID != to.UserID {
+			return &Error{
+				Kind:   ErrInvalid,
+				Fields: []FieldError{{Field: "toWallet", Message: "must belong to the same user as fromWallet"}},
+				Err:    ErrInvalidTransfer,
+			}
+		}
+
+		_, err = AdjustHolding(ctx, tx.HoldingStore, transfer.FromWalletID, transfer.PositionID, transfer.Amount.Neg())
+		if err != nil {
+			return err
+		}
+
+		_, err = AdjustHolding(ctx, tx.HoldingStore, transfer.ToWalletID, transfer.PositionID, transfer.Amount.Sub(transfer.Fee))
+		if err != nil {
+			return err
+		}
+
+		executed := *transfer
+		if executed.ExecutedAt.IsZero() {
+			executed.ExecutedAt = time.Now()
+		}
+
+		newTransfer, err = tx.TransferStore.Add(ctx, &executed)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransfer, nil
+}
+
 // Operations enum
 type OrderOperation string
 
@@ -199,34 +501,298 @@ const (
 	OrderTypeMarket OrderType = "market"
 )
 
+// UnitPrice and TotalPrice are in QuoteCurrency, which defaults to the
+// position's quote currency. TradeID is the exchange's ID for the trade,
+// empty for orders entered by hand. ExecutedAt defaults to when the order is
+// added, and is kept when an update leaves it out.
+//
+// Fee is what the exchange charged, in FeeCurrency: the quote currency by
+// default, or any other currency or token (e.g. BNB), valued at its rate or
+// price at execution time. Fees add to the cost of buys and take from the
+// proceeds of sells.
 type Order struct {
-	ID         int            `json:"id"`
-	PositionID int            `json:"position"`
-	Amount     float32        `json:"amount"`
-	UnitPrice  float32        `json:"unitPrice"`
-	TotalPrice float32        `json:"totalPrice"`
-	Operation  OrderOperation `json:"operation"`
-	Type       OrderType      `json:"type"`
-	Exchange   string         `json:"exchange"`
-	ExecutedAt string         `json:"executedAt"`
-	CreatedAt  time.Time      `json:"-"`
-	UpdatedAt  time.Time      `json:"-"`
-	DeletedAt  sql.NullTime   `json:"-"`
+	ID            int             `json:"id"`
+	PositionID    int             `json:"position"`
+	Amount        decimal.Decimal `json:"amount"`
+	UnitPrice     decimal.Decimal `json:"unitPrice"`
+	TotalPrice    decimal.Decimal `json:"totalPrice"`
+	QuoteCurrency string          `json:"quoteCurrency"`
+	Fee           decimal.Decimal `json:"fee"`
+	FeeCurrency   string          `json:"feeCurrency"`
+	Operation     OrderOperation  `json:"operation"`
+	Type          OrderType       `json:"type"`
+	Exchange      string          `json:"exchange"`
+	TradeID       string          `json:"tradeId"`
+	ExecutedAt    time.Time       `json:"executedAt"`
+	CreatedAt     time.Time       `json:"-"`
+	UpdatedAt     time.Time       `json:"-"`
+	DeletedAt     sql.NullTime    `json:"-"`
 }
 
+// Adding, updating or deleting an Order recomputes the Position it belongs to.
 type OrderStore interface {
 	// Add creates a new Order in the OrdersStore and returns Order with ID
 	Add(context.Context, *Order) (*Order, error)
-	// Returns all Orders in the store
-	All(context.Context) ([]Order, error)
+	// AddMany creates all Orders in a single transaction, none if one fails,
+	// and returns them with IDs
+	AddMany(context.Context, []Order) ([]Order, error)
+	// Returns a page of the Orders in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) ([]Order, string, error)
 	// Delete the Order from the store
 	Delete(ctx context.Context, id int) error
 	// Gets Order if `ID` exists
 	Get(ctx context.Context, id int) (*Order, error)
+	// Gets all Orders for position
+	GetByPositionID(ctx context.Context, positionId int) ([]Order, error)
 	// Update the Order in the store.
 	Update(context.Context, *Order) error
 }
 
+// Kinds of Events
+type EventKind string
+
+const (
+	EventKindDeposit    EventKind = "deposit"
+	EventKindWithdrawal EventKind = "withdrawal"
+	EventKindReward     EventKind = "reward"
+	EventKindAirdrop    EventKind = "airdrop"
+	EventKindFork       EventKind = "fork"
+	EventKindGift       EventKind = "gift"
+	EventKindLost       EventKind = "lost"
+)
+
+// EventKinds lists every kind of Event
+var EventKinds = []EventKind{
+	EventKindDeposit, EventKindWithdrawal, EventKindReward, EventKindAirdrop,
+	EventKindFork, EventKindGift, EventKindLost,
+}
+
+// Valid tells whether kind is one of EventKinds
+func (kind EventKind) Valid() bool {
+	for _, known := range EventKinds {
+		if kind == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Incoming tells whether events of the kind add coins to the position, rather
+// than take them out
+func (kind EventKind) Incoming() bool {
+	return kind != EventKindWithdrawal && kind != EventKindLost
+}
+
+// Income tells whether coins received by events of the kind are income, at
+// their value when received
+func (kind EventKind) Income() bool {
+	return kind == EventKindReward || kind == EventKindAirdrop
+}
+
+// An Event changes a position's amount without a trade. UnitPrice is the
+// value of a coin at execution time in QuoteCurrency, which defaults to the
+// position's quote currency. Incoming coins open a lot:
+//
+//   - deposit: coins bought elsewhere, at their cost (UnitPrice)
+//   - gift: coins received as a gift, at the giver's cost (UnitPrice)
+//   - reward: staking and other rewards, at their value, which is income
+//   - airdrop: like rewards, income at their value
+//   - fork: coins of a chain split, at zero cost
+//
+// Outgoing coins consume lots like sells do:
+//
+//   - withdrawal: coins leaving the portfolio, with their cost, so no gain or
+//     loss is realized
+//   - lost: lost or stolen coins, whose cost is realized as a loss
+//
+// ExecutedAt defaults to when the event is added, and is kept when an update
+// leaves it out.
+type Event struct {
+	ID            int             `json:"id"`
+	PositionID    int             `json:"position"`
+	Kind          EventKind       `json:"kind"`
+	Amount        decimal.Decimal `json:"amount"`
+	UnitPrice     decimal.Decimal `json:"unitPrice"`
+	QuoteCurrency string          `json:"quoteCurrency"`
+	Note          string          `json:"note"`
+	ExecutedAt    time.Time       `json:"executedAt"`
+	CreatedAt     time.Time       `json:"-"`
+	UpdatedAt     time.Time       `json:"-"`
+	DeletedAt     sql.NullTime    `json:"-"`
+}
+
+// Adding, updating or deleting an Event recomputes the Position it belongs to.
+type EventStore interface {
+	// Add creates a new Event in the EventStore and returns Event with ID
+	Add(context.Context, *Event) (*Event, error)
+	// Returns a page of the Events in the store matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]Event, string, error)
+	// Delete the Event from the store
+	Delete(ctx context.Context, id int) error
+	// Gets Event if `ID` exists
+	Get(ctx context.Context, id int) (*Event, error)
+	// Gets all Events of position, in execution order
+	GetByPositionID(ctx context.Context, positionId int) (*[]Event, error)
+	// Update the Event in the store.
+	Update(context.Context, *Event) error
+}
+
+// Currency prices are quoted in when none is given
+const DefaultQuoteCurrency = "USD"
+
+// ErrPriceNotFound is returned when no quote is known for a token
+var ErrPriceNotFound = errors.New("price not found")
+
+type Price struct {
+	ID            int             `json:"id,omitempty"`
+	TokenSymbol   string          `json:"symbol"`
+	QuoteCurrency string          `json:"quote"`
+	Value         decimal.Decimal `json:"price"`
+	At            time.Time       `json:"at"`
+	Source        string          `json:"source,omitempty"`
+	CreatedAt     time.Time       `json:"-"`
+}
+
+// PriceSource quotes a token in a quote currency (e.g. BTC in USD)
+type PriceSource interface {
+	// Gets the current Price of the token
+	Current(ctx context.Context, symbol string, quote string) (*Price, error)
+	// Gets the Price of the token at the given time
+	At(ctx context.Context, symbol string, quote string, at time.Time) (*Price, error)
+}
+
+type PriceStore interface {
+	// Add stores a Price, replacing any Price for the same token, quote and
+	// time, and syncs the positions that convert with it
+	Add(context.Context, *Price) (*Price, error)
+	// Gets the latest Price at or before `at`
+	GetAt(ctx context.Context, symbol string, quote string, at time.Time) (*Price, error)
+	// Gets all Prices between `from` and `to`, oldest first
+	GetRange(ctx context.Context, symbol string, quote string, from time.Time, to time.Time) (*[]Price, error)
+}
+
+// ErrFXRateNotFound is returned when no rate is known between two currencies
+var ErrFXRateNotFound = errors.New("fx rate not found")
+
+// An FXRate converts BaseCurrency to QuoteCurrency: 1 base = Rate quote
+type FXRate struct {
+	ID            int             `json:"id,omitempty"`
+	BaseCurrency  string          `json:"base"`
+	QuoteCurrency string          `json:"quote"`
+	Rate          decimal.Decimal `json:"rate"`
+	At            time.Time       `json:"at"`
+	Source        string          `json:"source,omitempty"`
+	CreatedAt     time.Time       `json:"-"`
+}
+
+type FXRateStore interface {
+	// Add stores an FXRate, replacing any FXRate for the same pair and time,
+	// and syncs the positions that convert with it
+	Add(context.Context, *FXRate) (*FXRate, error)
+	// Gets the latest FXRate at or before `at`
+	GetAt(ctx context.Context, base string, quote string, at time.Time) (*FXRate, error)
+	// Gets all FXRates between `from` and `to`, oldest first
+	GetRange(ctx context.Context, base string, quote string, from time.Time, to time.Time) (*[]FXRate, error)
+}
+
+// A PortfolioSnapshot is a portfolio's valuation at the end of a day, in its
+// reporting currency.
+type PortfolioSnapshot struct {
+	ID               int                  `json:"id,omitempty"`
+	PortfolioID      int                  `json:"portfolio"`
+	Date             time.Time            `json:"date"`
+	QuoteCurrency    string               `json:"quote"`
+	Value            decimal.Decimal      `json:"value"`
+	CostBasis        decimal.Decimal      `json:"costBasis"`
+	RealizedProfit   decimal.Decimal      `json:"realizedProfit"`
+	UnrealizedProfit decimal.Decimal      `json:"unrealizedProfit"`
+	Investments      []InvestmentSnapshot `json:"investments"`
+	Wallets          []WalletSnapshot     `json:"wallets"`
+	CreatedAt        time.Time            `json:"-"`
+	UpdatedAt        time.Time            `json:"-"`
+}
+
+type InvestmentSnapshot struct {
+	InvestmentID int             `json:"investment"`
+	Symbol       string          `json:"symbol"`
+	Amount       decimal.Decimal `json:"amount"`
+	Value        decimal.Decimal `json:"value"`
+	CostBasis    decimal.Decimal `json:"costBasis"`
+}
+
+type WalletSnapshot struct {
+	WalletID int             `json:"wallet"`
+	Value    decimal.Decimal `json:"value"`
+}
+
+type SnapshotStore interface {
+	// Add stores a PortfolioSnapshot, replacing any snapshot of the portfolio on the same date
+	Add(context.Context, *PortfolioSnapshot) (*PortfolioSnapshot, error)
+	// Gets all PortfolioSnapshots of the portfolio between `from` and `to`, oldest first
+	GetRange(ctx context.Context, portfolioId int, from time.Time, to time.Time) (*[]PortfolioSnapshot, error)
+}
+
+type AuditAction string
+
+const (
+	AuditActionInsert AuditAction = "insert"
+	AuditActionUpdate AuditAction = "update"
+	AuditActionDelete AuditAction = "delete"
+)
+
+type ActorType string
+
+const (
+	// A user, with a session token
+	ActorTypeUser ActorType = "user"
+	// A user's API key
+	ActorTypeAPIKey ActorType = "api_key"
+	ActorTypeCLI    ActorType = "cli"
+	// The API server itself, e.g. background jobs
+	ActorTypeSystem ActorType = "system"
+)
+
+// An Actor makes changes. ID is the user or API key ID, 0 for the CLI and
+// the system.
+type Actor struct {
+	Type ActorType `json:"type"`
+	ID   int       `json:"id,omitempty"`
+}
+
+// An AuditEntry records a change to an entity, with the entity as JSON
+// before and after it. Before is null for inserts and After for deletes.
+// Entries are never updated nor deleted.
+type AuditEntry struct {
+	ID     int    `json:"id"`
+	Entity string `json:"entity"`
+	// ID of the changed entity
+	EntityID int         `json:"entityId"`
+	Action   AuditAction `json:"action"`
+	Actor    Actor       `json:"actor"`
+	// User owning the entity, 0 for shared ones like prices
+	UserID    int             `json:"user,omitempty"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type AuditStore interface {
+	// Add appends an AuditEntry and returns it with ID
+	Add(context.Context, *AuditEntry) (*AuditEntry, error)
+	// Returns a page of the AuditEntries matching the options, and the
+	// cursor of the next page (empty on the last one)
+	All(context.Context, ListOptions) (*[]AuditEntry, string, error)
+}
+
+// HealthChecker is a dependency of the API server, checked before it is
+// ready to serve requests
 type HealthChecker interface {
-	Ping() (msg string, err error)
+	// Name of the dependency in health reports, e.g. "postgres"
+	Name() string
+	// Ping returns an error when the dependency cannot be used, giving up
+	// when the context is done
+	Ping(ctx context.Context) error
 }
@@ -3,6 +3,10 @@ module github.com/leoschet/gaivota
 go 1.16
 
 require (
+	github.com/jackc/pgconn v1.8.1
+	github.com/jackc/pgtype v1.7.0
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/leoschet/mux v0.1.0
+	github.com/shopspring/decimal v1.4.0
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 )
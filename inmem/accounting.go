@@ -0,0 +1,197 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/fx"
+)
+
+// Replays the position's orders and events, converted to its currency, and
+// transfers with its portfolio's cost basis method, then stores the derived amount,
+// average price, profit and lots. Mirrors postgres' syncPosition.
+func syncPosition(ctx context.Context, t *tables, positionId int) error {
+	position, ok := t.positions[positionId]
+	if err := found(ok, position.DeletedAt); err != nil {
+		return fmt.Errorf("Could not update position %v: %w", positionId, err)
+	}
+
+	investment := t.investments[position.InvestmentID]
+	method := t.portfolios[investment.PortfolioID].CostBasisMethod
+
+	var orders []gaivota.Order
+	for _, order := range t.orders {
+		if order.PositionID == positionId && !order.DeletedAt.Valid {
+			orders = append(orders, order)
+		}
+	}
+
+	converter := fx.NewConverter(fx.WithPrices(tableRates{t}, tablePrices{t}))
+
+	orders, err := accounting.ConvertOrders(ctx, converter, orders, investment.TokenSymbol, position.QuoteCurrency)
+	if err != nil {
+		return err
+	}
+
+	var transfers []gaivota.Transfer
+	for _, transfer := range t.transfers {
+		if transfer.PositionID == positionId {
+			transfers = append(transfers, transfer)
+		}
+	}
+
+	var events []gaivota.Event
+	for _, event := range t.events {
+		if event.PositionID == positionId && !event.DeletedAt.Valid {
+			events = append(events, event)
+		}
+	}
+
+	events, err = accounting.ConvertEvents(ctx, converter, events, position.QuoteCurrency)
+	if err != nil {
+		return err
+	}
+
+	result, err := accounting.Replay(orders, transfers, events, method)
+	if err != nil {
+		return fmt.Errorf("Could not replay orders for position %v: %w", positionId, err)
+	}
+
+	position.Amount = result.Amount
+	position.AveragePrice = result.AveragePrice
+	position.Profit = result.RealizedProfit
+	position.UpdatedAt = now()
+	t.positions[positionId] = position
+
+	// Lots are derived data, so they are rebuilt instead of diffed
+	for id, lot := range t.lots {
+		if lot.PositionID == positionId {
+			delete(t.lots, id)
+		}
+	}
+
+	for _, lot := range result.Lots {
+		lot.ID = t.nextID("lots")
+		lot.PositionID = positionId
+		lot.CreatedAt = now()
+		lot.UpdatedAt = lot.CreatedAt
+		t.lots[lot.ID] = lot
+	}
+
+	return nil
+}
+
+// Syncs every position of the portfolio, e.g. after its cost basis method changed
+func syncPortfolio(ctx context.Context, t *tables, portfolioId int) error {
+	var positionIds []int
+	for id, position := range t.positions {
+		if t.investments[position.InvestmentID].PortfolioID == portfolioId && !position.DeletedAt.Valid {
+			positionIds = append(positionIds, id)
+		}
+	}
+	sort.Ints(positionIds)
+
+	for _, id := range positionIds {
+		if err := syncPosition(ctx, t, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Syncs the positions converting orders or events executed at or after `at`
+// from or to one of the currencies. Mirrors postgres' syncConverted.
+func syncConverted(ctx context.Context, t *tables, base string, quote string, at time.Time) error {
+	converts := func(from string, to string, executedAt time.Time) bool {
+		return !strings.EqualFold(from, to) && !executedAt.Before(at) &&
+			(strings.EqualFold(from, base) || strings.EqualFold(from, quote) ||
+				strings.EqualFold(to, base) || strings.EqualFold(to, quote))
+	}
+
+	affected := map[int]bool{}
+	for _, order := range t.orders {
+		position := t.positions[order.PositionID]
+		if order.DeletedAt.Valid || position.DeletedAt.Valid {
+			continue
+		}
+
+		fee := !order.Fee.IsZero() && !strings.EqualFold(order.FeeCurrency, order.QuoteCurrency) &&
+			!strings.EqualFold(order.FeeCurrency, t.positionSymbol(order.PositionID))
+
+		if converts(order.QuoteCurrency, position.QuoteCurrency, order.ExecutedAt) ||
+			(fee && converts(order.FeeCurrency, position.QuoteCurrency, order.ExecutedAt)) {
+			affected[order.PositionID] = true
+		}
+	}
+
+	for _, event := range t.events {
+		position := t.positions[event.PositionID]
+		if event.DeletedAt.Valid || position.DeletedAt.Valid || event.UnitPrice.IsZero() {
+			continue
+		}
+
+		if converts(event.QuoteCurrency, position.QuoteCurrency, event.ExecutedAt) {
+			affected[event.PositionID] = true
+		}
+	}
+
+	var positionIds []int
+	for id := range affected {
+		positionIds = append(positionIds, id)
+	}
+	sort.Ints(positionIds)
+
+	for _, id := range positionIds {
+		if err := syncPosition(ctx, t, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Looks rates up in the tables, for writes that already hold them
+type tableRates struct {
+	t *tables
+}
+
+func (rates tableRates) GetAt(ctx context.Context, base string, quote string, at time.Time) (*gaivota.FXRate, error) {
+	return fxRateAt(rates.t, base, quote, at)
+}
+
+// Looks prices up in the tables, like tableRates
+type tablePrices struct {
+	t *tables
+}
+
+func (prices tablePrices) GetAt(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	return priceAt(prices.t, symbol, quote, at)
+}
+
+// Owner of the portfolio, 0 when unknown
+func (t *tables) portfolioOwner(portfolioId int) int {
+	return t.portfolios[portfolioId].UserID
+}
+
+func (t *tables) investmentOwner(investmentId int) int {
+	return t.portfolioOwner(t.investments[investmentId].PortfolioID)
+}
+
+func (t *tables) positionOwner(positionId int) int {
+	return t.investmentOwner(t.positions[positionId].InvestmentID)
+}
+
+func (t *tables) walletOwner(walletId int) int {
+	return t.wallets[walletId].UserID
+}
+
+// Symbol of the token the position is in, upper cased
+func (t *tables) positionSymbol(positionId int) string {
+	return strings.ToUpper(t.investments[t.positions[positionId].InvestmentID].TokenSymbol)
+}
@@ -0,0 +1,128 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewAPIKeyStore(db *Database) *APIKeyStore {
+	return &APIKeyStore{
+		Database: db,
+	}
+}
+
+type APIKeyStore struct {
+	Database *Database
+}
+
+func (store *APIKeyStore) Add(ctx context.Context, key *gaivota.APIKey) (*gaivota.APIKey, error) {
+	var newKey gaivota.APIKey
+
+	err := store.Database.write(func(t *tables) error {
+		_, ok := t.users[key.UserID]
+		if err := foreignKey(ok, "user", key.UserID); err != nil {
+			return err
+		}
+
+		// Hashes are unique among revoked keys too
+		for _, other := range t.apiKeys {
+			if other.KeyHash == key.KeyHash {
+				err := fmt.Errorf("%w: api key hash already exists", ErrUniqueViolation)
+				return violation(gaivota.ErrConflict, "", "", err)
+			}
+		}
+
+		newKey = gaivota.APIKey{
+			ID:        t.nextID("api_keys"),
+			UserID:    key.UserID,
+			Name:      key.Name,
+			KeyHash:   key.KeyHash,
+			CreatedAt: now(),
+		}
+		t.apiKeys[newKey.ID] = newKey
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert api key %s for user %v: %w", key.Name, key.UserID, err)
+	}
+
+	return &newKey, nil
+}
+
+func (store *APIKeyStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		key, ok := t.apiKeys[id]
+		if err := found(ok, key.DeletedAt); err != nil {
+			return err
+		}
+
+		key.DeletedAt = deleted()
+		t.apiKeys[id] = key
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete api key %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *APIKeyStore) Get(ctx context.Context, id int) (*gaivota.APIKey, error) {
+	var key gaivota.APIKey
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		key, ok = t.apiKeys[id]
+		return found(ok, key.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get api key %v: %w", id, err)
+	}
+
+	return &key, nil
+}
+
+func (store *APIKeyStore) GetByHash(ctx context.Context, hash string) (*gaivota.APIKey, error) {
+	var key *gaivota.APIKey
+
+	store.Database.read(func(t *tables) error {
+		for _, other := range t.apiKeys {
+			if other.KeyHash == hash && !other.DeletedAt.Valid {
+				other := other
+				key = &other
+			}
+		}
+		return nil
+	})
+
+	if key == nil {
+		return nil, fmt.Errorf("Could not get api key by hash: %w", gaivota.ErrNotFound)
+	}
+
+	return key, nil
+}
+
+func (store *APIKeyStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.APIKey, error) {
+	var keys []gaivota.APIKey
+
+	store.Database.read(func(t *tables) error {
+		for _, key := range t.apiKeys {
+			if key.UserID == userId && !key.DeletedAt.Valid {
+				keys = append(keys, key)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(keys, func(i int, j int) bool { return keys[i].ID < keys[j].ID })
+
+	return &keys, nil
+}
@@ -0,0 +1,95 @@
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewAuditStore(db *Database) *AuditStore {
+	return &AuditStore{
+		Database: db,
+	}
+}
+
+// AuditStore only appends entries, there is no way to change them
+type AuditStore struct {
+	Database *Database
+}
+
+func (store *AuditStore) Add(ctx context.Context, entry *gaivota.AuditEntry) (*gaivota.AuditEntry, error) {
+	var newEntry gaivota.AuditEntry
+
+	err := store.Database.write(func(t *tables) error {
+		if entry.UserID != 0 {
+			_, ok := t.users[entry.UserID]
+			if err := foreignKey(ok, "user", entry.UserID); err != nil {
+				return err
+			}
+		}
+
+		switch entry.Action {
+		case gaivota.AuditActionInsert, gaivota.AuditActionUpdate, gaivota.AuditActionDelete:
+		default:
+			return check("action", "unknown audit action %q", entry.Action)
+		}
+
+		newEntry = *entry
+		newEntry.ID = t.nextID("audit_log")
+		newEntry.Before = jsonOrNull(entry.Before)
+		newEntry.After = jsonOrNull(entry.After)
+		newEntry.CreatedAt = now()
+		t.auditLog[newEntry.ID] = newEntry
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert audit entry for %s %v: %w", entry.Entity, entry.EntityID, err)
+	}
+
+	return &newEntry, nil
+}
+
+// Empty JSON is read back as null, like a null jsonb column
+func jsonOrNull(value json.RawMessage) json.RawMessage {
+	if len(value) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return append(json.RawMessage(nil), value...)
+}
+
+func (store *AuditStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.AuditEntry, string, error) {
+	var entries []gaivota.AuditEntry
+
+	store.Database.read(func(t *tables) error {
+		for _, entry := range t.auditLog {
+			if between(opts, entry.CreatedAt) && ownedBy(opts, entry.UserID) &&
+				(opts.Entity == "" || opts.Entity == entry.Entity) &&
+				(opts.EntityID == 0 || opts.EntityID == entry.EntityID) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+
+	page, next, err := paginate(opts, len(entries),
+		func(i int) int { return entries[i].ID },
+		func(i int) sortKey { return entries[i].CreatedAt },
+		nil,
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.AuditEntry, 0, len(page))
+	for _, i := range page {
+		result = append(result, entries[i])
+	}
+
+	return &result, next, nil
+}
@@ -0,0 +1,208 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewEventStore(db *Database) *EventStore {
+	return &EventStore{
+		Database: db,
+	}
+}
+
+type EventStore struct {
+	Database *Database
+}
+
+// The position must exist, the kind be one of the enum, the amount positive
+// and the unit price not negative
+func (t *tables) checkEvent(event *gaivota.Event) error {
+	_, ok := t.positions[event.PositionID]
+	if err := foreignKey(ok, "position", event.PositionID); err != nil {
+		return err
+	}
+
+	if !event.Kind.Valid() {
+		return check("kind", "unknown event kind %q", event.Kind)
+	}
+
+	if !event.Amount.IsPositive() || event.UnitPrice.IsNegative() {
+		return check("", "event of %v at %v", event.Amount, event.UnitPrice)
+	}
+
+	return nil
+}
+
+// Returns the events matching the filter, in execution order
+func (store *EventStore) filter(match func(t *tables, event gaivota.Event) bool) *[]gaivota.Event {
+	var events []gaivota.Event
+
+	store.Database.read(func(t *tables) error {
+		for _, event := range t.events {
+			if !event.DeletedAt.Valid && match(t, event) {
+				events = append(events, event)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(events, func(i int, j int) bool {
+		if events[i].ExecutedAt.Equal(events[j].ExecutedAt) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].ExecutedAt.Before(events[j].ExecutedAt)
+	})
+
+	return &events
+}
+
+func (store *EventStore) Add(ctx context.Context, event *gaivota.Event) (*gaivota.Event, error) {
+	var newEvent gaivota.Event
+
+	err := store.Database.write(func(t *tables) error {
+		newEvent = *event
+		newEvent.QuoteCurrency = currency(event.QuoteCurrency, t.positions[event.PositionID].QuoteCurrency)
+		newEvent.CreatedAt = now()
+		newEvent.UpdatedAt = newEvent.CreatedAt
+		newEvent.ExecutedAt = orTime(event.ExecutedAt, newEvent.CreatedAt)
+		newEvent.DeletedAt.Valid = false
+
+		if err := t.checkEvent(&newEvent); err != nil {
+			return err
+		}
+
+		newEvent.ID = t.nextID("events")
+		t.events[newEvent.ID] = newEvent
+
+		return syncPosition(ctx, t, newEvent.PositionID)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert event for position %v: %w",
+			event.PositionID, err,
+		)
+	}
+
+	return &newEvent, nil
+}
+
+func (store *EventStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Event, string, error) {
+	events := *store.filter(func(t *tables, event gaivota.Event) bool {
+		return between(opts, event.ExecutedAt) &&
+			symbol(opts, t.positionSymbol(event.PositionID)) &&
+			ownedBy(opts, t.positionOwner(event.PositionID)) &&
+			(opts.Kind == "" || opts.Kind == event.Kind)
+	})
+
+	page, next, err := paginate(opts, len(events),
+		func(i int) int { return events[i].ID },
+		func(i int) sortKey { return events[i].CreatedAt },
+		map[string]sortColumn{
+			"executedAt": func(i int) sortKey { return events[i].ExecutedAt },
+			"amount":     func(i int) sortKey { return events[i].Amount },
+			"unitPrice":  func(i int) sortKey { return events[i].UnitPrice },
+			"kind":       func(i int) sortKey { return string(events[i].Kind) },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Event, 0, len(page))
+	for _, i := range page {
+		result = append(result, events[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *EventStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		event, ok := t.events[id]
+		if err := found(ok, event.DeletedAt); err != nil {
+			return err
+		}
+
+		event.DeletedAt = deleted()
+		t.events[id] = event
+
+		return syncPosition(ctx, t, event.PositionID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete event %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *EventStore) Get(ctx context.Context, id int) (*gaivota.Event, error) {
+	var event gaivota.Event
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		event, ok = t.events[id]
+		return found(ok, event.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get event %v: %w", id, err)
+	}
+
+	return &event, nil
+}
+
+func (store *EventStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Event, error) {
+	return store.filter(func(t *tables, event gaivota.Event) bool {
+		return event.PositionID == positionId
+	}), nil
+}
+
+func (store *EventStore) Update(ctx context.Context, event *gaivota.Event) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.events[event.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		previousPositionId := stored.PositionID
+
+		stored.PositionID = event.PositionID
+		stored.Kind = event.Kind
+		stored.Amount = event.Amount
+		stored.UnitPrice = event.UnitPrice
+		stored.QuoteCurrency = currency(event.QuoteCurrency, stored.QuoteCurrency)
+		stored.Note = event.Note
+		stored.ExecutedAt = orTime(event.ExecutedAt, stored.ExecutedAt)
+		stored.UpdatedAt = now()
+
+		if err := t.checkEvent(&stored); err != nil {
+			return err
+		}
+
+		t.events[event.ID] = stored
+		event.QuoteCurrency = stored.QuoteCurrency
+		event.ExecutedAt = stored.ExecutedAt
+
+		// Moving an event to another position changes both of them
+		if previousPositionId != event.PositionID {
+			if err := syncPosition(ctx, t, previousPositionId); err != nil {
+				return err
+			}
+		}
+
+		return syncPosition(ctx, t, event.PositionID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update event %v: %w", event.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewFXRateStore(db *Database) *FXRateStore {
+	return &FXRateStore{
+		Database: db,
+	}
+}
+
+type FXRateStore struct {
+	Database *Database
+}
+
+func (store *FXRateStore) Add(ctx context.Context, rate *gaivota.FXRate) (*gaivota.FXRate, error) {
+	var newRate gaivota.FXRate
+
+	err := store.Database.write(func(t *tables) error {
+		newRate = *rate
+
+		// Replaces the rate of the same pair and time
+		for id, other := range t.fxRates {
+			if other.BaseCurrency == rate.BaseCurrency && other.QuoteCurrency == rate.QuoteCurrency && other.At.Equal(rate.At) {
+				newRate.ID = id
+				newRate.CreatedAt = other.CreatedAt
+				break
+			}
+		}
+
+		if newRate.ID == 0 {
+			newRate.ID = t.nextID("fx_rates")
+			newRate.CreatedAt = now()
+		}
+		t.fxRates[newRate.ID] = newRate
+
+		return syncConverted(ctx, t, newRate.BaseCurrency, newRate.QuoteCurrency, newRate.At)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert fx rate from %s to %s: %w", rate.BaseCurrency, rate.QuoteCurrency, err)
+	}
+
+	return &newRate, nil
+}
+
+func (store *FXRateStore) GetAt(ctx context.Context, base string, quote string, at time.Time) (rate *gaivota.FXRate, err error) {
+	store.Database.read(func(t *tables) error {
+		rate, err = fxRateAt(t, base, quote, at)
+		return err
+	})
+
+	return rate, err
+}
+
+// Latest rate of the pair at or before `at`, also used while syncing positions
+func fxRateAt(t *tables, base string, quote string, at time.Time) (*gaivota.FXRate, error) {
+	var rate *gaivota.FXRate
+
+	for _, other := range t.fxRates {
+		if other.BaseCurrency == base && other.QuoteCurrency == quote && !other.At.After(at) &&
+			(rate == nil || other.At.After(rate.At)) {
+			other := other
+			rate = &other
+		}
+	}
+
+	if rate == nil {
+		return nil, fmt.Errorf("Could not get fx rate from %s to %s at %s: %w", base, quote, at, gaivota.ErrFXRateNotFound)
+	}
+
+	return rate, nil
+}
+
+func (store *FXRateStore) GetRange(ctx context.Context, base string, quote string, from time.Time, to time.Time) (*[]gaivota.FXRate, error) {
+	var rates []gaivota.FXRate
+
+	store.Database.read(func(t *tables) error {
+		for _, rate := range t.fxRates {
+			if rate.BaseCurrency == base && rate.QuoteCurrency == quote && !rate.At.Before(from) && !rate.At.After(to) {
+				rates = append(rates, rate)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(rates, func(i int, j int) bool { return rates[i].At.Before(rates[j].At) })
+
+	return &rates, nil
+}
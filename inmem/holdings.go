@@ -0,0 +1,190 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewHoldingStore(db *Database) *HoldingStore {
+	return &HoldingStore{
+		Database: db,
+	}
+}
+
+type HoldingStore struct {
+	Database *Database
+}
+
+// The wallet and the position must exist
+func (t *tables) checkHolding(holding *gaivota.Holding) error {
+	_, ok := t.wallets[holding.WalletID]
+	if err := foreignKey(ok, "wallet", holding.WalletID); err != nil {
+		return err
+	}
+
+	_, ok = t.positions[holding.PositionID]
+
+	return foreignKey(ok, "position", holding.PositionID)
+}
+
+// Returns the holdings matching the filter, sorted by ID
+func (store *HoldingStore) filter(match func(t *tables, holding gaivota.Holding) bool) *[]gaivota.Holding {
+	var holdings []gaivota.Holding
+
+	store.Database.read(func(t *tables) error {
+		for _, holding := range t.holdings {
+			if !holding.DeletedAt.Valid && match(t, holding) {
+				holdings = append(holdings, holding)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(holdings, func(i int, j int) bool { return holdings[i].ID < holdings[j].ID })
+
+	return &holdings
+}
+
+func (store *HoldingStore) Add(ctx context.Context, holding *gaivota.Holding) (*gaivota.Holding, error) {
+	var newHolding gaivota.Holding
+
+	err := store.Database.write(func(t *tables) error {
+		newHolding = gaivota.Holding{
+			WalletID:   holding.WalletID,
+			PositionID: holding.PositionID,
+			Amount:     holding.Amount,
+			CreatedAt:  now(),
+		}
+		newHolding.UpdatedAt = newHolding.CreatedAt
+
+		if err := t.checkHolding(&newHolding); err != nil {
+			return err
+		}
+
+		newHolding.ID = t.nextID("holdings")
+		t.holdings[newHolding.ID] = newHolding
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert holding for wallet %v and position %v: %w",
+			holding.WalletID, holding.PositionID, err,
+		)
+	}
+
+	return &newHolding, nil
+}
+
+func (store *HoldingStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Holding, string, error) {
+	holdings := *store.filter(func(t *tables, holding gaivota.Holding) bool {
+		return between(opts, holding.CreatedAt) &&
+			symbol(opts, t.positionSymbol(holding.PositionID)) &&
+			ownedBy(opts, t.walletOwner(holding.WalletID))
+	})
+
+	page, next, err := paginate(opts, len(holdings),
+		func(i int) int { return holdings[i].ID },
+		func(i int) sortKey { return holdings[i].CreatedAt },
+		map[string]sortColumn{
+			"amount": func(i int) sortKey { return holdings[i].Amount },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Holding, 0, len(page))
+	for _, i := range page {
+		result = append(result, holdings[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *HoldingStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		holding, ok := t.holdings[id]
+		if err := found(ok, holding.DeletedAt); err != nil {
+			return err
+		}
+
+		holding.DeletedAt = deleted()
+		t.holdings[id] = holding
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete holding %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *HoldingStore) Get(ctx context.Context, id int) (*gaivota.Holding, error) {
+	var holding gaivota.Holding
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		holding, ok = t.holdings[id]
+		return found(ok, holding.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get holding %v: %w", id, err)
+	}
+
+	return &holding, nil
+}
+
+func (store *HoldingStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Holding, error) {
+	return store.filter(func(t *tables, holding gaivota.Holding) bool {
+		return t.walletOwner(holding.WalletID) == userId
+	}), nil
+}
+
+func (store *HoldingStore) GetByWalletID(ctx context.Context, walletId int) (*[]gaivota.Holding, error) {
+	return store.filter(func(t *tables, holding gaivota.Holding) bool {
+		return holding.WalletID == walletId
+	}), nil
+}
+
+func (store *HoldingStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Holding, error) {
+	return store.filter(func(t *tables, holding gaivota.Holding) bool {
+		return holding.PositionID == positionId
+	}), nil
+}
+
+func (store *HoldingStore) Update(ctx context.Context, holding *gaivota.Holding) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.holdings[holding.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		stored.WalletID = holding.WalletID
+		stored.PositionID = holding.PositionID
+		stored.Amount = holding.Amount
+		stored.UpdatedAt = now()
+
+		if err := t.checkHolding(&stored); err != nil {
+			return err
+		}
+
+		t.holdings[holding.ID] = stored
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update holding %v: %w", holding.ID, err)
+	}
+
+	return nil
+}
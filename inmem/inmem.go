@@ -0,0 +1,338 @@
+// Package inmem implements the gaivota stores in memory, with the semantics
+// of the postgres package: unique constraints, foreign keys, soft deletes and
+// positions kept in sync with their orders. It backs tests and demos, so data
+// is lost when the process exits.
+package inmem
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Constraint violations are returned wrapped in a gaivota.Error, like the
+// postgres stores do: unique violations as gaivota.ErrConflict, the others
+// as gaivota.ErrInvalid, about the JSON field when known.
+
+// ErrUniqueViolation is returned when a row would duplicate a unique column,
+// e.g. a second user with the same email
+var ErrUniqueViolation = errors.New("unique constraint violation")
+
+// ErrForeignKeyViolation is returned when a row references a row that was
+// never stored. Soft deleted rows can still be referenced, like in Postgres.
+var ErrForeignKeyViolation = errors.New("foreign key violation")
+
+// ErrCheckViolation is returned for values the column does not accept, e.g.
+// an unknown order operation
+var ErrCheckViolation = errors.New("check constraint violation")
+
+// ErrTxConflict is returned by WithinTx when other writes kept committing
+// while the transaction ran
+var ErrTxConflict = errors.New("transaction conflict")
+
+// The rows of every table, by ID
+type tables struct {
+	users       map[int]gaivota.User
+	portfolios  map[int]gaivota.Portfolio
+	wallets     map[int]gaivota.Wallet
+	investments map[int]gaivota.Investment
+	positions   map[int]gaivota.Position
+	holdings    map[int]gaivota.Holding
+	transfers   map[int]gaivota.Transfer
+	orders      map[int]gaivota.Order
+	events      map[int]gaivota.Event
+	lots        map[int]gaivota.Lot
+	prices      map[int]gaivota.Price
+	fxRates     map[int]gaivota.FXRate
+	snapshots   map[int]gaivota.PortfolioSnapshot
+	apiKeys     map[int]gaivota.APIKey
+	auditLog    map[int]gaivota.AuditEntry
+
+	// Last ID handed out per table, like serial columns
+	sequences map[string]int
+}
+
+func newTables() *tables {
+	return &tables{
+		users:       map[int]gaivota.User{},
+		portfolios:  map[int]gaivota.Portfolio{},
+		wallets:     map[int]gaivota.Wallet{},
+		investments: map[int]gaivota.Investment{},
+		positions:   map[int]gaivota.Position{},
+		holdings:    map[int]gaivota.Holding{},
+		transfers:   map[int]gaivota.Transfer{},
+		orders:      map[int]gaivota.Order{},
+		events:      map[int]gaivota.Event{},
+		lots:        map[int]gaivota.Lot{},
+		prices:      map[int]gaivota.Price{},
+		fxRates:     map[int]gaivota.FXRate{},
+		snapshots:   map[int]gaivota.PortfolioSnapshot{},
+		apiKeys:     map[int]gaivota.APIKey{},
+		auditLog:    map[int]gaivota.AuditEntry{},
+		sequences:   map[string]int{},
+	}
+}
+
+// Copies the maps. Rows are values, so changing a copy's rows leaves the
+// original untouched.
+func (t *tables) clone() *tables {
+	c := newTables()
+
+	for id, row := range t.users {
+		c.users[id] = row
+	}
+	for id, row := range t.portfolios {
+		c.portfolios[id] = row
+	}
+	for id, row := range t.wallets {
+		c.wallets[id] = row
+	}
+	for id, row := range t.investments {
+		c.investments[id] = row
+	}
+	for id, row := range t.positions {
+		c.positions[id] = row
+	}
+	for id, row := range t.holdings {
+		c.holdings[id] = row
+	}
+	for id, row := range t.transfers {
+		c.transfers[id] = row
+	}
+	for id, row := range t.orders {
+		c.orders[id] = row
+	}
+	for id, row := range t.events {
+		c.events[id] = row
+	}
+	for id, row := range t.lots {
+		c.lots[id] = row
+	}
+	for id, row := range t.prices {
+		c.prices[id] = row
+	}
+	for id, row := range t.fxRates {
+		c.fxRates[id] = row
+	}
+	for id, row := range t.snapshots {
+		c.snapshots[id] = row
+	}
+	for id, row := range t.apiKeys {
+		c.apiKeys[id] = row
+	}
+	for id, row := range t.auditLog {
+		c.auditLog[id] = row
+	}
+	for table, id := range t.sequences {
+		c.sequences[table] = id
+	}
+
+	return c
+}
+
+// Returns the next ID of the table
+func (t *tables) nextID(table string) int {
+	t.sequences[table]++
+
+	return t.sequences[table]
+}
+
+// Returns ErrForeignKeyViolation when the referenced row was never stored,
+// about the field named after the table (e.g. "user")
+func foreignKey(exists bool, table string, id int) error {
+	if !exists {
+		err := fmt.Errorf("%w: %s %v does not exist", ErrForeignKeyViolation, table, id)
+		return violation(gaivota.ErrInvalid, table, "does not exist", err)
+	}
+
+	return nil
+}
+
+// Returns ErrUniqueViolation when another row already has the field's value
+func unique(taken bool, table string, field string) error {
+	if taken {
+		err := fmt.Errorf("%w: %s %s already exists", ErrUniqueViolation, table, field)
+		return violation(gaivota.ErrConflict, field, "is already taken", err)
+	}
+
+	return nil
+}
+
+// Returns ErrCheckViolation, formatted printf style, about the field when
+// not empty
+func check(field string, format string, v ...interface{}) error {
+	err := fmt.Errorf("%w: "+format, append([]interface{}{ErrCheckViolation}, v...)...)
+	return violation(gaivota.ErrInvalid, field, "is out of range", err)
+}
+
+// Wraps a constraint violation in a gaivota.Error of the kind
+func violation(kind error, field string, message string, err error) error {
+	if field == "" {
+		return &gaivota.Error{Kind: kind, Err: err}
+	}
+
+	return &gaivota.Error{
+		Kind:   kind,
+		Fields: []gaivota.FieldError{{Field: field, Message: message}},
+		Err:    err,
+	}
+}
+
+// Returns gaivota.ErrNotFound when the row does not exist or was deleted
+func found(exists bool, deletedAt sql.NullTime) error {
+	if !exists || deletedAt.Valid {
+		return gaivota.ErrNotFound
+	}
+
+	return nil
+}
+
+// Upper cases the first non-empty currency, like the coalesce over the
+// currencies in the postgres queries
+func currency(currencies ...string) string {
+	for _, c := range currencies {
+		if c != "" {
+			return strings.ToUpper(c)
+		}
+	}
+
+	return gaivota.DefaultQuoteCurrency
+}
+
+// Database holds the tables. Each write works on a copy that replaces the
+// tables once the write succeeds, so a failed write leaves nothing behind.
+// Copying is linear in the data, fine for tests and demos.
+type Database struct {
+	mu      sync.RWMutex
+	tables  *tables
+	version int
+
+	// Database the transaction commits to, set for the databases WithinTx
+	// hands out
+	parent *Database
+}
+
+func New() *Database {
+	return &Database{tables: newTables()}
+}
+
+// Runs fn on a snapshot of the tables
+func (db *Database) read(fn func(t *tables) error) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return fn(db.tables)
+}
+
+// Runs fn on a copy of the tables, kept when fn succeeds
+func (db *Database) write(fn func(t *tables) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t := db.tables.clone()
+	if err := fn(t); err != nil {
+		return err
+	}
+
+	db.tables = t
+	db.version++
+
+	return nil
+}
+
+// How many times WithinTx runs a transaction that conflicts with other writes
+const maxTxAttempts = 5
+
+// WithinTx runs fn with a client whose stores work on a copy of the tables.
+// The copy replaces the tables when fn succeeds, unless another write
+// committed meanwhile: then fn runs again on a fresh copy, like a serializable
+// transaction in Postgres. Nested calls join the outer transaction.
+func (db *Database) WithinTx(ctx context.Context, fn func(*gaivota.Client) error) error {
+	if db.parent != nil {
+		return fn(db.NewClient())
+	}
+
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		db.mu.RLock()
+		tx := &Database{tables: db.tables.clone(), parent: db}
+		version := db.version
+		db.mu.RUnlock()
+
+		if err := fn(tx.NewClient()); err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		db.mu.Lock()
+		committed := db.version == version
+		if committed {
+			db.tables = tx.tables
+			db.version++
+		}
+		db.mu.Unlock()
+
+		if committed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Could not commit transaction after %v attempts: %w", maxTxAttempts, ErrTxConflict)
+}
+
+func (db *Database) NewClient() *gaivota.Client {
+	return &gaivota.Client{
+		UserStore:       NewUserStore(db),
+		PortfolioStore:  NewPortfolioStore(db),
+		WalletStore:     NewWalletStore(db),
+		InvestmentStore: NewInvestmentStore(db),
+		PositionStore:   NewPositionStore(db),
+		HoldingStore:    NewHoldingStore(db),
+		TransferStore:   NewTransferStore(db),
+		OrderStore:      NewOrderStore(db),
+		EventStore:      NewEventStore(db),
+		LotStore:        NewLotStore(db),
+		PriceStore:      NewPriceStore(db),
+		FXRateStore:     NewFXRateStore(db),
+		SnapshotStore:   NewSnapshotStore(db),
+		APIKeyStore:     NewAPIKeyStore(db),
+		AuditStore:      NewAuditStore(db),
+		Transactor:      db,
+	}
+}
+
+func (db *Database) Name() string {
+	return "memory"
+}
+
+// Ping always succeeds, the data is in memory
+func (db *Database) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Returns t, or fallback when t is zero, as columns with a default do
+func orTime(t time.Time, fallback time.Time) time.Time {
+	if t.IsZero() {
+		return fallback
+	}
+
+	return t
+}
+
+// The time rows are stamped with, rounded to microseconds like timestamptz
+func now() time.Time {
+	return time.Now().Round(time.Microsecond)
+}
+
+// Marks a row as soft deleted
+func deleted() sql.NullTime {
+	return sql.NullTime{Time: now(), Valid: true}
+}
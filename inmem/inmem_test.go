@@ -0,0 +1,261 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/inmem/inmemtest"
+	"github.com/shopspring/decimal"
+)
+
+func newOrder(positionId int, exchange string, tradeId string) *gaivota.Order {
+	return &gaivota.Order{
+		PositionID:    positionId,
+		Amount:        decimal.NewFromInt(1),
+		UnitPrice:     decimal.NewFromInt(100),
+		TotalPrice:    decimal.NewFromInt(100),
+		QuoteCurrency: "USD",
+		Operation:     gaivota.OrderOperationBuy,
+		Type:          gaivota.OrderTypeMarket,
+		Exchange:      exchange,
+		TradeID:       tradeId,
+		ExecutedAt:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestUniqueConstraints(t *testing.T) {
+	ctx := context.Background()
+	client := New().NewClient()
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{})
+
+	if _, err := client.OrderStore.Add(ctx, newOrder(fixture.Position.ID, "kraken", "T1")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		add   func() error
+	}{
+		{"user email", "email", func() error {
+			_, err := client.UserStore.Add(ctx, &gaivota.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Byron"})
+			return err
+		}},
+		{"portfolio name of the user", "name", func() error {
+			_, err := client.PortfolioStore.Add(ctx, &gaivota.Portfolio{UserID: fixture.User.ID, Name: "Main"})
+			return err
+		}},
+		{"investment token of the portfolio", "token", func() error {
+			_, err := client.InvestmentStore.Add(ctx, &gaivota.Investment{PortfolioID: fixture.Portfolio.ID, Token: "bitcoin", TokenSymbol: "BTC"})
+			return err
+		}},
+		{"trade ID of the position and exchange", "tradeId", func() error {
+			_, err := client.OrderStore.Add(ctx, newOrder(fixture.Position.ID, "kraken", "T1"))
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.add()
+			if !errors.Is(err, ErrUniqueViolation) || !errors.Is(err, gaivota.ErrConflict) {
+				t.Fatalf("Adding a duplicate answered %v, expected a conflicting unique violation", err)
+			}
+
+			fields := gaivota.FieldErrorsOf(err)
+			if len(fields) != 1 || fields[0].Field != test.field {
+				t.Errorf("Violation is about %+v, expected %s", fields, test.field)
+			}
+		})
+	}
+
+	// The same trade ID on another exchange is another trade
+	if _, err := client.OrderStore.Add(ctx, newOrder(fixture.Position.ID, "coinbase", "T1")); err != nil {
+		t.Errorf("Adding the trade ID of another exchange answered %v", err)
+	}
+}
+
+func TestUniqueConstraintsAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	client := New().NewClient()
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{})
+
+	// Trade IDs are only unique among orders not deleted, so a deleted trade
+	// can be imported again
+	order, err := client.OrderStore.Add(ctx, newOrder(fixture.Position.ID, "kraken", "T1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.OrderStore.Delete(ctx, order.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.OrderStore.Add(ctx, newOrder(fixture.Position.ID, "kraken", "T1")); err != nil {
+		t.Errorf("Adding the trade ID of a deleted order answered %v", err)
+	}
+
+	// Emails stay taken by deleted users
+	if err := client.UserStore.Delete(ctx, fixture.User.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.UserStore.Add(ctx, &gaivota.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Byron"})
+	if !errors.Is(err, ErrUniqueViolation) {
+		t.Errorf("Adding the email of a deleted user answered %v, expected ErrUniqueViolation", err)
+	}
+}
+
+func TestDeletedRowsAreNotFound(t *testing.T) {
+	ctx := context.Background()
+	client := New().NewClient()
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{})
+
+	order, err := client.OrderStore.Add(ctx, newOrder(fixture.Position.ID, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Children first, as the API would delete them
+	tests := []struct {
+		name   string
+		delete func() error
+		get    func() error
+		update func() error
+	}{
+		{
+			name:   "order",
+			delete: func() error { return client.OrderStore.Delete(ctx, order.ID) },
+			get: func() error {
+				_, err := client.OrderStore.Get(ctx, order.ID)
+				return err
+			},
+			update: func() error { return client.OrderStore.Update(ctx, order) },
+		},
+		{
+			name:   "position",
+			delete: func() error { return client.PositionStore.Delete(ctx, fixture.Position.ID) },
+			get: func() error {
+				_, err := client.PositionStore.Get(ctx, fixture.Position.ID)
+				return err
+			},
+			update: func() error { return client.PositionStore.Update(ctx, fixture.Position) },
+		},
+		{
+			name:   "investment",
+			delete: func() error { return client.InvestmentStore.Delete(ctx, fixture.Investment.ID) },
+			get: func() error {
+				_, err := client.InvestmentStore.Get(ctx, fixture.Investment.ID)
+				return err
+			},
+			update: func() error { return client.InvestmentStore.Update(ctx, fixture.Investment) },
+		},
+		{
+			name:   "portfolio",
+			delete: func() error { return client.PortfolioStore.Delete(ctx, fixture.Portfolio.ID) },
+			get: func() error {
+				_, err := client.PortfolioStore.Get(ctx, fixture.Portfolio.ID)
+				return err
+			},
+			update: func() error { return client.PortfolioStore.Update(ctx, fixture.Portfolio) },
+		},
+		{
+			name:   "user",
+			delete: func() error { return client.UserStore.Delete(ctx, fixture.User.ID) },
+			get: func() error {
+				_, err := client.UserStore.Get(ctx, fixture.User.ID)
+				return err
+			},
+			update: func() error { return client.UserStore.Update(ctx, fixture.User) },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.delete(); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, call := range map[string]func() error{"Get": test.get, "Update": test.update, "Delete": test.delete} {
+				if err := call(); !errors.Is(err, gaivota.ErrNotFound) {
+					t.Errorf("%s after delete answered %v, expected ErrNotFound", name, err)
+				}
+			}
+		})
+	}
+
+	if _, err := client.UserStore.GetByEmail(ctx, "ada@example.com"); !errors.Is(err, gaivota.ErrNotFound) {
+		t.Errorf("Getting a deleted user by email answered %v, expected ErrNotFound", err)
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	client := New().NewClient()
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{})
+
+	const missing = 999
+
+	tests := []struct {
+		name  string
+		field string
+		add   func() error
+	}{
+		{"portfolio of a missing user", "user", func() error {
+			_, err := client.PortfolioStore.Add(ctx, &gaivota.Portfolio{UserID: missing, Name: "Main"})
+			return err
+		}},
+		{"wallet of a missing user", "user", func() error {
+			_, err := client.WalletStore.Add(ctx, &gaivota.Wallet{UserID: missing, Name: "Ledger"})
+			return err
+		}},
+		{"investment of a missing portfolio", "portfolio", func() error {
+			_, err := client.InvestmentStore.Add(ctx, &gaivota.Investment{PortfolioID: missing, Token: "ethereum", TokenSymbol: "ETH"})
+			return err
+		}},
+		{"position of a missing investment", "investment", func() error {
+			_, err := client.PositionStore.Add(ctx, &gaivota.Position{InvestmentID: missing, QuoteCurrency: "EUR"})
+			return err
+		}},
+		{"order of a missing position", "position", func() error {
+			_, err := client.OrderStore.Add(ctx, newOrder(missing, "", ""))
+			return err
+		}},
+		{"holding of a missing wallet", "wallet", func() error {
+			_, err := client.HoldingStore.Add(ctx, &gaivota.Holding{WalletID: missing, PositionID: fixture.Position.ID, Amount: decimal.NewFromInt(1)})
+			return err
+		}},
+		{"moving an order to a missing position", "position", func() error {
+			order, err := client.OrderStore.Add(ctx, newOrder(fixture.Position.ID, "", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			order.PositionID = missing
+			return client.OrderStore.Update(ctx, order)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.add()
+			if !errors.Is(err, ErrForeignKeyViolation) || !errors.Is(err, gaivota.ErrInvalid) {
+				t.Fatalf("Answered %v, expected an invalid foreign key violation", err)
+			}
+
+			fields := gaivota.FieldErrorsOf(err)
+			if len(fields) != 1 || fields[0].Field != test.field {
+				t.Errorf("Violation is about %+v, expected %s", fields, test.field)
+			}
+		})
+	}
+
+	// Failed writes leave nothing behind: the moved order stays where it was
+	orders, err := client.OrderStore.GetByPositionID(ctx, fixture.Position.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].PositionID != fixture.Position.ID {
+		t.Errorf("Position has orders %+v, expected the one that failed to move", orders)
+	}
+}
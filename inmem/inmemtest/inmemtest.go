@@ -0,0 +1,53 @@
+// Package inmemtest seeds memory stores with the data most tests start from:
+// a user owning a portfolio with a bitcoin investment and a USD position.
+package inmemtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Fixture is what Seed stored
+type Fixture struct {
+	User       *gaivota.User
+	Portfolio  *gaivota.Portfolio
+	Investment *gaivota.Investment
+	Position   *gaivota.Position
+}
+
+// Seed adds a user with the email owning the portfolio, named "Main" unless
+// set, with a bitcoin (BTC) investment and a position in it quoted in USD.
+// It stops the test when a store fails.
+func Seed(t testing.TB, client *gaivota.Client, email string, portfolio gaivota.Portfolio) Fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := client.UserStore.Add(ctx, &gaivota.User{Email: email, FirstName: "Ada", LastName: "Lovelace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	portfolio.UserID = user.ID
+	if portfolio.Name == "" {
+		portfolio.Name = "Main"
+	}
+
+	newPortfolio, err := client.PortfolioStore.Add(ctx, &portfolio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	investment, err := client.InvestmentStore.Add(ctx, &gaivota.Investment{PortfolioID: newPortfolio.ID, Token: "bitcoin", TokenSymbol: "BTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	position, err := client.PositionStore.Add(ctx, &gaivota.Position{InvestmentID: investment.ID, QuoteCurrency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Fixture{User: user, Portfolio: newPortfolio, Investment: investment, Position: position}
+}
@@ -0,0 +1,186 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewInvestmentStore(db *Database) *InvestmentStore {
+	return &InvestmentStore{
+		Database: db,
+	}
+}
+
+type InvestmentStore struct {
+	Database *Database
+}
+
+// The portfolio must exist, and tokens are unique per portfolio
+func (t *tables) checkInvestment(investment *gaivota.Investment) error {
+	_, ok := t.portfolios[investment.PortfolioID]
+	if err := foreignKey(ok, "portfolio", investment.PortfolioID); err != nil {
+		return err
+	}
+
+	for _, other := range t.investments {
+		if other.ID != investment.ID && other.PortfolioID == investment.PortfolioID && other.Token == investment.Token {
+			return unique(true, "investment", "token")
+		}
+	}
+
+	return nil
+}
+
+// Returns the investments matching the filter, sorted by ID
+func (store *InvestmentStore) filter(match func(t *tables, investment gaivota.Investment) bool) *[]gaivota.Investment {
+	var investments []gaivota.Investment
+
+	store.Database.read(func(t *tables) error {
+		for _, investment := range t.investments {
+			if !investment.DeletedAt.Valid && match(t, investment) {
+				investments = append(investments, investment)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(investments, func(i int, j int) bool { return investments[i].ID < investments[j].ID })
+
+	return &investments
+}
+
+func (store *InvestmentStore) Add(ctx context.Context, investment *gaivota.Investment) (*gaivota.Investment, error) {
+	var newInvestment gaivota.Investment
+
+	err := store.Database.write(func(t *tables) error {
+		newInvestment = gaivota.Investment{
+			PortfolioID: investment.PortfolioID,
+			Token:       investment.Token,
+			TokenSymbol: investment.TokenSymbol,
+			CreatedAt:   now(),
+		}
+		newInvestment.UpdatedAt = newInvestment.CreatedAt
+
+		if err := t.checkInvestment(&newInvestment); err != nil {
+			return err
+		}
+
+		newInvestment.ID = t.nextID("investments")
+		t.investments[newInvestment.ID] = newInvestment
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert investment of %s in portfolio %v: %w", investment.Token, investment.PortfolioID, err)
+	}
+
+	return &newInvestment, nil
+}
+
+func (store *InvestmentStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Investment, string, error) {
+	investments := *store.filter(func(t *tables, investment gaivota.Investment) bool {
+		return between(opts, investment.CreatedAt) &&
+			symbol(opts, investment.TokenSymbol) &&
+			ownedBy(opts, t.portfolioOwner(investment.PortfolioID))
+	})
+
+	page, next, err := paginate(opts, len(investments),
+		func(i int) int { return investments[i].ID },
+		func(i int) sortKey { return investments[i].CreatedAt },
+		map[string]sortColumn{
+			"token":  func(i int) sortKey { return investments[i].Token },
+			"symbol": func(i int) sortKey { return investments[i].TokenSymbol },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Investment, 0, len(page))
+	for _, i := range page {
+		result = append(result, investments[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *InvestmentStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		investment, ok := t.investments[id]
+		if err := found(ok, investment.DeletedAt); err != nil {
+			return err
+		}
+
+		investment.DeletedAt = deleted()
+		t.investments[id] = investment
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete investment %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *InvestmentStore) Get(ctx context.Context, id int) (*gaivota.Investment, error) {
+	var investment gaivota.Investment
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		investment, ok = t.investments[id]
+		return found(ok, investment.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get investment %v: %w", id, err)
+	}
+
+	return &investment, nil
+}
+
+func (store *InvestmentStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Investment, error) {
+	return store.filter(func(t *tables, investment gaivota.Investment) bool {
+		return t.portfolioOwner(investment.PortfolioID) == userId
+	}), nil
+}
+
+func (store *InvestmentStore) GetByPortfolioID(ctx context.Context, portfolioId int) (*[]gaivota.Investment, error) {
+	return store.filter(func(t *tables, investment gaivota.Investment) bool {
+		return investment.PortfolioID == portfolioId
+	}), nil
+}
+
+func (store *InvestmentStore) Update(ctx context.Context, investment *gaivota.Investment) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.investments[investment.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		stored.PortfolioID = investment.PortfolioID
+		stored.Token = investment.Token
+		stored.TokenSymbol = investment.TokenSymbol
+		stored.UpdatedAt = now()
+
+		if err := t.checkInvestment(&stored); err != nil {
+			return err
+		}
+
+		t.investments[investment.ID] = stored
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update investment %v: %w", investment.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package inmem
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/shopspring/decimal"
+)
+
+// A value list operations sort by: int, string, time.Time or decimal.Decimal
+type sortKey interface{}
+
+// Returns the item's sort key, given its index in the rows being listed
+type sortColumn func(i int) sortKey
+
+func compareKeys(a sortKey, b sortKey) int {
+	switch a := a.(type) {
+	case int:
+		b := b.(int)
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		b := b.(time.Time)
+		if a.Before(b) {
+			return -1
+		}
+		if a.After(b) {
+			return 1
+		}
+		return 0
+	case decimal.Decimal:
+		return a.Cmp(b.(decimal.Decimal))
+	default:
+		panic(fmt.Sprintf("inmem: cannot sort by %T", a))
+	}
+}
+
+func formatKey(key sortKey) string {
+	switch key := key.(type) {
+	case int:
+		return strconv.Itoa(key)
+	case string:
+		return key
+	case time.Time:
+		return key.Format(time.RFC3339Nano)
+	case decimal.Decimal:
+		return key.String()
+	default:
+		panic(fmt.Sprintf("inmem: cannot sort by %T", key))
+	}
+}
+
+// Parses a cursor value as the same type as the example key
+func parseKey(example sortKey, value string) (sortKey, error) {
+	switch example.(type) {
+	case int:
+		return strconv.Atoi(value)
+	case string:
+		return value, nil
+	case time.Time:
+		return time.Parse(time.RFC3339Nano, value)
+	case decimal.Decimal:
+		return decimal.NewFromString(value)
+	default:
+		panic(fmt.Sprintf("inmem: cannot sort by %T", example))
+	}
+}
+
+// Where a page ended: the sort value and ID of its last item
+type cursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         int    `json:"id"`
+}
+
+// Sorts, pages and checks the options of a list of n filtered rows, like
+// the postgres list queries. Every table sorts by id and createdAt, given
+// the IDs and creation times. Returns the indexes of the page's rows and the
+// cursor of the next page.
+func paginate(opts gaivota.ListOptions, n int, id func(i int) int, createdAt sortColumn, columns map[string]sortColumn) ([]int, string, error) {
+	all := map[string]sortColumn{
+		"id":        func(i int) sortKey { return id(i) },
+		"createdAt": createdAt,
+	}
+	for name, column := range columns {
+		all[name] = column
+	}
+
+	sortName := opts.Sort
+	if sortName == "" {
+		sortName = "id"
+	}
+
+	column, ok := all[sortName]
+	if !ok {
+		var names []string
+		for name := range all {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, "", gaivota.InvalidListOption("sort", "cannot be %q, expected one of %s", opts.Sort, strings.Join(names, ", "))
+	}
+
+	if opts.Limit < 0 {
+		return nil, "", gaivota.InvalidListOption("limit", "must not be negative")
+	}
+
+	var after *cursor
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil || c.Sort != sortName || c.Descending != opts.Descending {
+			return nil, "", gaivota.InvalidListOption("cursor", "does not belong to this sort")
+		}
+		after = &c
+	}
+
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	// Sorted by the column, then by ID, in the same direction
+	compare := func(a int, b int) int {
+		if c := compareKeys(column(a), column(b)); c != 0 {
+			return c
+		}
+		return compareKeys(id(a), id(b))
+	}
+
+	sort.Slice(indexes, func(a int, b int) bool {
+		c := compare(indexes[a], indexes[b])
+		if opts.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if after != nil && n > 0 {
+		value, err := parseKey(column(0), after.Value)
+		if err != nil {
+			return nil, "", gaivota.InvalidListOption("cursor", "does not belong to this sort")
+		}
+
+		var rest []int
+		for _, i := range indexes {
+			c := compareKeys(column(i), value)
+			if c == 0 {
+				c = compareKeys(id(i), after.ID)
+			}
+			if (c > 0 && !opts.Descending) || (c < 0 && opts.Descending) {
+				rest = append(rest, i)
+			}
+		}
+		indexes = rest
+	}
+
+	if opts.Limit == 0 || len(indexes) <= opts.Limit {
+		return indexes, "", nil
+	}
+
+	page := indexes[:opts.Limit]
+	last := page[len(page)-1]
+	next := encodeCursor(cursor{
+		Sort:       sortName,
+		Descending: opts.Descending,
+		Value:      formatKey(column(last)),
+		ID:         id(last),
+	})
+
+	return page, next, nil
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(data, &c)
+
+	return c, err
+}
+
+// Tells whether the time is within the options' date range
+func between(opts gaivota.ListOptions, t time.Time) bool {
+	if !opts.From.IsZero() && t.Before(opts.From) {
+		return false
+	}
+
+	if !opts.To.IsZero() && t.After(opts.To) {
+		return false
+	}
+
+	return true
+}
+
+// Tells whether the token symbol matches the options' symbol, if any
+func symbol(opts gaivota.ListOptions, tokenSymbol string) bool {
+	return opts.Symbol == "" || strings.EqualFold(opts.Symbol, tokenSymbol)
+}
+
+// Tells whether the owner matches the options' user, if any
+func ownedBy(opts gaivota.ListOptions, userId int) bool {
+	return opts.UserID == 0 || opts.UserID == userId
+}
@@ -0,0 +1,96 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewLotStore(db *Database) *LotStore {
+	return &LotStore{
+		Database: db,
+	}
+}
+
+// Lots are derived from orders by syncPosition, so they are read only
+type LotStore struct {
+	Database *Database
+}
+
+func (store *LotStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Lot, string, error) {
+	var lots []gaivota.Lot
+
+	store.Database.read(func(t *tables) error {
+		for _, lot := range t.lots {
+			if between(opts, lot.AcquiredAt) && symbol(opts, t.positionSymbol(lot.PositionID)) &&
+				ownedBy(opts, t.positionOwner(lot.PositionID)) {
+				lots = append(lots, lot)
+			}
+		}
+		return nil
+	})
+
+	page, next, err := paginate(opts, len(lots),
+		func(i int) int { return lots[i].ID },
+		func(i int) sortKey { return lots[i].CreatedAt },
+		map[string]sortColumn{
+			"acquiredAt": func(i int) sortKey { return lots[i].AcquiredAt },
+			"amount":     func(i int) sortKey { return lots[i].Amount },
+			"unitPrice":  func(i int) sortKey { return lots[i].UnitPrice },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Lot, 0, len(page))
+	for _, i := range page {
+		result = append(result, lots[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *LotStore) Get(ctx context.Context, id int) (*gaivota.Lot, error) {
+	var lot gaivota.Lot
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		if lot, ok = t.lots[id]; !ok {
+			return gaivota.ErrNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get lot %v: %w", id, err)
+	}
+
+	return &lot, nil
+}
+
+func (store *LotStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Lot, error) {
+	var lots []gaivota.Lot
+
+	store.Database.read(func(t *tables) error {
+		for _, lot := range t.lots {
+			if lot.PositionID == positionId {
+				lots = append(lots, lot)
+			}
+		}
+		return nil
+	})
+
+	// In acquisition order
+	sort.Slice(lots, func(i int, j int) bool {
+		if !lots[i].AcquiredAt.Equal(lots[j].AcquiredAt) {
+			return lots[i].AcquiredAt.Before(lots[j].AcquiredAt)
+		}
+		return lots[i].ID < lots[j].ID
+	})
+
+	return &lots, nil
+}
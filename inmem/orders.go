@@ -0,0 +1,280 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewOrderStore(db *Database) *OrderStore {
+	return &OrderStore{
+		Database: db,
+	}
+}
+
+type OrderStore struct {
+	Database *Database
+}
+
+// The position must exist, operation and type be one of their enums, the fee
+// not negative, and trade IDs unique per position and exchange among orders
+// not deleted
+func (t *tables) checkOrder(order *gaivota.Order) error {
+	_, ok := t.positions[order.PositionID]
+	if err := foreignKey(ok, "position", order.PositionID); err != nil {
+		return err
+	}
+
+	switch order.Operation {
+	case gaivota.OrderOperationBuy, gaivota.OrderOperationSell:
+	default:
+		return check("operation", "unknown order operation %q", order.Operation)
+	}
+
+	switch order.Type {
+	case gaivota.OrderTypeLimit, gaivota.OrderTypeMarket:
+	default:
+		return check("type", "unknown order type %q", order.Type)
+	}
+
+	if order.Fee.IsNegative() {
+		return check("fee", "negative order fee %v", order.Fee)
+	}
+
+	if order.TradeID == "" || order.DeletedAt.Valid {
+		return nil
+	}
+
+	for _, other := range t.orders {
+		if other.ID != order.ID && !other.DeletedAt.Valid && other.PositionID == order.PositionID &&
+			other.Exchange == order.Exchange && other.TradeID == order.TradeID {
+			return unique(true, "order", "tradeId")
+		}
+	}
+
+	return nil
+}
+
+func (t *tables) insertOrder(order *gaivota.Order) (*gaivota.Order, error) {
+	newOrder := *order
+	newOrder.ID = 0
+	newOrder.QuoteCurrency = currency(order.QuoteCurrency, t.positions[order.PositionID].QuoteCurrency)
+	newOrder.FeeCurrency = currency(order.FeeCurrency, newOrder.QuoteCurrency)
+	newOrder.CreatedAt = now()
+	newOrder.UpdatedAt = newOrder.CreatedAt
+	newOrder.ExecutedAt = orTime(order.ExecutedAt, newOrder.CreatedAt)
+	newOrder.DeletedAt.Valid = false
+
+	if err := t.checkOrder(&newOrder); err != nil {
+		return nil, err
+	}
+
+	newOrder.ID = t.nextID("orders")
+	t.orders[newOrder.ID] = newOrder
+
+	return &newOrder, nil
+}
+
+func (store *OrderStore) Add(ctx context.Context, order *gaivota.Order) (*gaivota.Order, error) {
+	var newOrder *gaivota.Order
+
+	err := store.Database.write(func(t *tables) (err error) {
+		newOrder, err = t.insertOrder(order)
+		if err != nil {
+			return err
+		}
+
+		return syncPosition(ctx, t, newOrder.PositionID)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert order for position %v: %w",
+			order.PositionID, err,
+		)
+	}
+
+	return newOrder, nil
+}
+
+func (store *OrderStore) AddMany(ctx context.Context, orders []gaivota.Order) ([]gaivota.Order, error) {
+	var newOrders []gaivota.Order
+
+	err := store.Database.write(func(t *tables) error {
+		// Each position is replayed once, after all of its orders are in
+		var positionIds []int
+		seen := map[int]bool{}
+
+		for i := range orders {
+			newOrder, err := t.insertOrder(&orders[i])
+			if err != nil {
+				return fmt.Errorf("order %v of %v: %w", i+1, len(orders), err)
+			}
+
+			newOrders = append(newOrders, *newOrder)
+
+			if !seen[newOrder.PositionID] {
+				seen[newOrder.PositionID] = true
+				positionIds = append(positionIds, newOrder.PositionID)
+			}
+		}
+
+		for _, positionId := range positionIds {
+			if err := syncPosition(ctx, t, positionId); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert orders: %w", err)
+	}
+
+	return newOrders, nil
+}
+
+func (store *OrderStore) All(ctx context.Context, opts gaivota.ListOptions) ([]gaivota.Order, string, error) {
+	var orders []gaivota.Order
+
+	store.Database.read(func(t *tables) error {
+		for _, order := range t.orders {
+			if !order.DeletedAt.Valid && between(opts, order.ExecutedAt) &&
+				symbol(opts, t.positionSymbol(order.PositionID)) && ownedBy(opts, t.positionOwner(order.PositionID)) &&
+				(opts.Operation == "" || opts.Operation == order.Operation) &&
+				(opts.Exchange == "" || strings.EqualFold(opts.Exchange, order.Exchange)) {
+				orders = append(orders, order)
+			}
+		}
+		return nil
+	})
+
+	page, next, err := paginate(opts, len(orders),
+		func(i int) int { return orders[i].ID },
+		func(i int) sortKey { return orders[i].CreatedAt },
+		map[string]sortColumn{
+			"executedAt": func(i int) sortKey { return orders[i].ExecutedAt },
+			"amount":     func(i int) sortKey { return orders[i].Amount },
+			"unitPrice":  func(i int) sortKey { return orders[i].UnitPrice },
+			"totalPrice": func(i int) sortKey { return orders[i].TotalPrice },
+			"exchange":   func(i int) sortKey { return orders[i].Exchange },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []gaivota.Order
+	for _, i := range page {
+		result = append(result, orders[i])
+	}
+
+	return result, next, nil
+}
+
+func (store *OrderStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		order, ok := t.orders[id]
+		if err := found(ok, order.DeletedAt); err != nil {
+			return err
+		}
+
+		order.DeletedAt = deleted()
+		t.orders[id] = order
+
+		return syncPosition(ctx, t, order.PositionID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete order %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *OrderStore) Get(ctx context.Context, id int) (*gaivota.Order, error) {
+	var order gaivota.Order
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		order, ok = t.orders[id]
+		return found(ok, order.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get order %v: %w", id, err)
+	}
+
+	return &order, nil
+}
+
+func (store *OrderStore) GetByPositionID(ctx context.Context, positionId int) ([]gaivota.Order, error) {
+	var orders []gaivota.Order
+
+	store.Database.read(func(t *tables) error {
+		for _, order := range t.orders {
+			if order.PositionID == positionId && !order.DeletedAt.Valid {
+				orders = append(orders, order)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(orders, func(i int, j int) bool { return orders[i].ID < orders[j].ID })
+
+	return orders, nil
+}
+
+func (store *OrderStore) Update(ctx context.Context, order *gaivota.Order) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.orders[order.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		previousPositionId := stored.PositionID
+
+		stored.PositionID = order.PositionID
+		stored.Amount = order.Amount
+		stored.UnitPrice = order.UnitPrice
+		stored.TotalPrice = order.TotalPrice
+		stored.FeeCurrency = currency(order.FeeCurrency, order.QuoteCurrency, stored.FeeCurrency)
+		stored.QuoteCurrency = currency(order.QuoteCurrency, stored.QuoteCurrency)
+		stored.Fee = order.Fee
+		stored.Operation = order.Operation
+		stored.Type = order.Type
+		stored.Exchange = order.Exchange
+		stored.TradeID = order.TradeID
+		stored.ExecutedAt = orTime(order.ExecutedAt, stored.ExecutedAt)
+		stored.UpdatedAt = now()
+
+		if err := t.checkOrder(&stored); err != nil {
+			return err
+		}
+
+		t.orders[order.ID] = stored
+		order.QuoteCurrency = stored.QuoteCurrency
+		order.FeeCurrency = stored.FeeCurrency
+		order.ExecutedAt = stored.ExecutedAt
+
+		// Moving an order to another position changes both of them
+		if previousPositionId != order.PositionID {
+			if err := syncPosition(ctx, t, previousPositionId); err != nil {
+				return err
+			}
+		}
+
+		return syncPosition(ctx, t, order.PositionID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update order %v: %w", order.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewPortfolioStore(db *Database) *PortfolioStore {
+	return &PortfolioStore{
+		Database: db,
+	}
+}
+
+type PortfolioStore struct {
+	Database *Database
+}
+
+// Names are unique per user, and the method one of the enum's
+func (t *tables) checkPortfolio(portfolio *gaivota.Portfolio) error {
+	switch portfolio.CostBasisMethod {
+	case gaivota.CostBasisFIFO, gaivota.CostBasisLIFO, gaivota.CostBasisHIFO, gaivota.CostBasisAverage:
+	default:
+		return check("costBasisMethod", "unknown cost basis method %q", portfolio.CostBasisMethod)
+	}
+
+	for _, other := range t.portfolios {
+		if other.ID != portfolio.ID && other.UserID == portfolio.UserID && other.Name == portfolio.Name {
+			return unique(true, "portfolio", "name")
+		}
+	}
+
+	return nil
+}
+
+func (store *PortfolioStore) Add(ctx context.Context, portfolio *gaivota.Portfolio) (*gaivota.Portfolio, error) {
+	var newPortfolio gaivota.Portfolio
+
+	err := store.Database.write(func(t *tables) error {
+		user, ok := t.users[portfolio.UserID]
+		if err := foreignKey(ok, "user", portfolio.UserID); err != nil {
+			return err
+		}
+
+		method := portfolio.CostBasisMethod
+		if method == "" {
+			method = gaivota.CostBasisAverage
+		}
+
+		// Portfolios report in their user's currency unless told otherwise
+		newPortfolio = gaivota.Portfolio{
+			UserID:            portfolio.UserID,
+			Name:              portfolio.Name,
+			CostBasisMethod:   method,
+			ReportingCurrency: currency(portfolio.ReportingCurrency, user.ReportingCurrency),
+			CreatedAt:         now(),
+		}
+		newPortfolio.UpdatedAt = newPortfolio.CreatedAt
+
+		if err := t.checkPortfolio(&newPortfolio); err != nil {
+			return err
+		}
+
+		newPortfolio.ID = t.nextID("portfolios")
+		t.portfolios[newPortfolio.ID] = newPortfolio
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert portfolio %s for user %v: %w", portfolio.Name, portfolio.UserID, err)
+	}
+
+	return &newPortfolio, nil
+}
+
+func (store *PortfolioStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Portfolio, string, error) {
+	var portfolios []gaivota.Portfolio
+
+	store.Database.read(func(t *tables) error {
+		for _, portfolio := range t.portfolios {
+			if !portfolio.DeletedAt.Valid && between(opts, portfolio.CreatedAt) && ownedBy(opts, portfolio.UserID) {
+				portfolios = append(portfolios, portfolio)
+			}
+		}
+		return nil
+	})
+
+	page, next, err := paginate(opts, len(portfolios),
+		func(i int) int { return portfolios[i].ID },
+		func(i int) sortKey { return portfolios[i].CreatedAt },
+		map[string]sortColumn{
+			"name": func(i int) sortKey { return portfolios[i].Name },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Portfolio, 0, len(page))
+	for _, i := range page {
+		result = append(result, portfolios[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *PortfolioStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		portfolio, ok := t.portfolios[id]
+		if err := found(ok, portfolio.DeletedAt); err != nil {
+			return err
+		}
+
+		portfolio.DeletedAt = deleted()
+		t.portfolios[id] = portfolio
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete portfolio %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *PortfolioStore) Get(ctx context.Context, id int) (*gaivota.Portfolio, error) {
+	var portfolio gaivota.Portfolio
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		portfolio, ok = t.portfolios[id]
+		return found(ok, portfolio.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get portfolio %v: %w", id, err)
+	}
+
+	return &portfolio, nil
+}
+
+func (store *PortfolioStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Portfolio, error) {
+	var portfolios []gaivota.Portfolio
+
+	store.Database.read(func(t *tables) error {
+		for _, portfolio := range t.portfolios {
+			if portfolio.UserID == userId && !portfolio.DeletedAt.Valid {
+				portfolios = append(portfolios, portfolio)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(portfolios, func(i int, j int) bool { return portfolios[i].ID < portfolios[j].ID })
+
+	return &portfolios, nil
+}
+
+func (store *PortfolioStore) Update(ctx context.Context, portfolio *gaivota.Portfolio) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.portfolios[portfolio.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		previousMethod := stored.CostBasisMethod
+
+		if portfolio.CostBasisMethod == "" {
+			portfolio.CostBasisMethod = previousMethod
+		}
+		portfolio.ReportingCurrency = currency(portfolio.ReportingCurrency, stored.ReportingCurrency)
+
+		stored.Name = portfolio.Name
+		stored.CostBasisMethod = portfolio.CostBasisMethod
+		stored.ReportingCurrency = portfolio.ReportingCurrency
+		stored.UpdatedAt = now()
+
+		if err := t.checkPortfolio(&stored); err != nil {
+			return err
+		}
+
+		t.portfolios[portfolio.ID] = stored
+
+		// Lots are consumed differently, so every position must be replayed
+		if stored.CostBasisMethod != previousMethod {
+			return syncPortfolio(ctx, t, portfolio.ID)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update portfolio %v: %w", portfolio.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+	"github.com/shopspring/decimal"
+)
+
+func NewPositionStore(db *Database) *PositionStore {
+	return &PositionStore{
+		Database: db,
+	}
+}
+
+type PositionStore struct {
+	Database *Database
+}
+
+func (store *PositionStore) Add(ctx context.Context, position *gaivota.Position) (*gaivota.Position, error) {
+	var newPosition gaivota.Position
+
+	err := store.Database.write(func(t *tables) error {
+		investment, ok := t.investments[position.InvestmentID]
+		if err := foreignKey(ok, "investment", position.InvestmentID); err != nil {
+			return err
+		}
+
+		// Amount, average price and profit start empty and are kept in sync by
+		// the OrderStore. Positions are accounted in their portfolio's currency
+		// by default.
+		newPosition = gaivota.Position{
+			InvestmentID:  position.InvestmentID,
+			QuoteCurrency: currency(position.QuoteCurrency, t.portfolios[investment.PortfolioID].ReportingCurrency),
+			Amount:        decimal.Zero,
+			AveragePrice:  decimal.Zero,
+			Profit:        decimal.Zero,
+			CreatedAt:     now(),
+		}
+		newPosition.UpdatedAt = newPosition.CreatedAt
+
+		newPosition.ID = t.nextID("positions")
+		t.positions[newPosition.ID] = newPosition
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert position for investment %v: %w", position.InvestmentID, err)
+	}
+
+	return &newPosition, nil
+}
+
+func (store *PositionStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Position, string, error) {
+	var positions []gaivota.Position
+
+	store.Database.read(func(t *tables) error {
+		for _, position := range t.positions {
+			if !position.DeletedAt.Valid && between(opts, position.CreatedAt) &&
+				symbol(opts, t.positionSymbol(position.ID)) && ownedBy(opts, t.positionOwner(position.ID)) {
+				positions = append(positions, position)
+			}
+		}
+		return nil
+	})
+
+	page, next, err := paginate(opts, len(positions),
+		func(i int) int { return positions[i].ID },
+		func(i int) sortKey { return positions[i].CreatedAt },
+		map[string]sortColumn{
+			"amount": func(i int) sortKey { return positions[i].Amount },
+			"profit": func(i int) sortKey { return positions[i].Profit },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Position, 0, len(page))
+	for _, i := range page {
+		result = append(result, positions[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *PositionStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		position, ok := t.positions[id]
+		if err := found(ok, position.DeletedAt); err != nil {
+			return err
+		}
+
+		position.DeletedAt = deleted()
+		t.positions[id] = position
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete position %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *PositionStore) Get(ctx context.Context, id int) (*gaivota.Position, error) {
+	var position gaivota.Position
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		position, ok = t.positions[id]
+		return found(ok, position.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get position %v: %w", id, err)
+	}
+
+	return &position, nil
+}
+
+func (store *PositionStore) GetByInvestmentID(ctx context.Context, investmentId int) (*[]gaivota.Position, error) {
+	var positions []gaivota.Position
+
+	store.Database.read(func(t *tables) error {
+		for _, position := range t.positions {
+			if position.InvestmentID == investmentId && !position.DeletedAt.Valid {
+				positions = append(positions, position)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(positions, func(i int, j int) bool { return positions[i].ID < positions[j].ID })
+
+	return &positions, nil
+}
+
+func (store *PositionStore) Update(ctx context.Context, position *gaivota.Position) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.positions[position.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		_, ok = t.investments[position.InvestmentID]
+		if err := foreignKey(ok, "investment", position.InvestmentID); err != nil {
+			return err
+		}
+
+		stored.InvestmentID = position.InvestmentID
+		stored.QuoteCurrency = currency(position.QuoteCurrency, stored.QuoteCurrency)
+		stored.UpdatedAt = now()
+		t.positions[position.ID] = stored
+
+		position.QuoteCurrency = stored.QuoteCurrency
+
+		// Another portfolio or currency changes how orders are replayed
+		return syncPosition(ctx, t, position.ID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update position %v: %w", position.ID, err)
+	}
+
+	return nil
+}
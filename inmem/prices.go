@@ -0,0 +1,97 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewPriceStore(db *Database) *PriceStore {
+	return &PriceStore{
+		Database: db,
+	}
+}
+
+type PriceStore struct {
+	Database *Database
+}
+
+func (store *PriceStore) Add(ctx context.Context, price *gaivota.Price) (*gaivota.Price, error) {
+	var newPrice gaivota.Price
+
+	err := store.Database.write(func(t *tables) error {
+		newPrice = *price
+
+		// Replaces the price of the same token, quote and time
+		for id, other := range t.prices {
+			if other.TokenSymbol == price.TokenSymbol && other.QuoteCurrency == price.QuoteCurrency && other.At.Equal(price.At) {
+				newPrice.ID = id
+				newPrice.CreatedAt = other.CreatedAt
+				break
+			}
+		}
+
+		if newPrice.ID == 0 {
+			newPrice.ID = t.nextID("prices")
+			newPrice.CreatedAt = now()
+		}
+		t.prices[newPrice.ID] = newPrice
+
+		return syncConverted(ctx, t, newPrice.TokenSymbol, newPrice.QuoteCurrency, newPrice.At)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert price of %s in %s: %w", price.TokenSymbol, price.QuoteCurrency, err)
+	}
+
+	return &newPrice, nil
+}
+
+func (store *PriceStore) GetAt(ctx context.Context, symbol string, quote string, at time.Time) (price *gaivota.Price, err error) {
+	store.Database.read(func(t *tables) error {
+		price, err = priceAt(t, symbol, quote, at)
+		return err
+	})
+
+	return price, err
+}
+
+// Latest price of the token at or before `at`, also used while syncing
+// positions
+func priceAt(t *tables, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	var price *gaivota.Price
+
+	for _, other := range t.prices {
+		if other.TokenSymbol == symbol && other.QuoteCurrency == quote && !other.At.After(at) &&
+			(price == nil || other.At.After(price.At)) {
+			other := other
+			price = &other
+		}
+	}
+
+	if price == nil {
+		return nil, fmt.Errorf("Could not get price of %s in %s at %s: %w", symbol, quote, at, gaivota.ErrPriceNotFound)
+	}
+
+	return price, nil
+}
+
+func (store *PriceStore) GetRange(ctx context.Context, symbol string, quote string, from time.Time, to time.Time) (*[]gaivota.Price, error) {
+	var prices []gaivota.Price
+
+	store.Database.read(func(t *tables) error {
+		for _, price := range t.prices {
+			if price.TokenSymbol == symbol && price.QuoteCurrency == quote && !price.At.Before(from) && !price.At.After(to) {
+				prices = append(prices, price)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(prices, func(i int, j int) bool { return prices[i].At.Before(prices[j].At) })
+
+	return &prices, nil
+}
@@ -0,0 +1,95 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewSnapshotStore(db *Database) *SnapshotStore {
+	return &SnapshotStore{
+		Database: db,
+	}
+}
+
+type SnapshotStore struct {
+	Database *Database
+}
+
+// Truncates to the date, like a date column
+func date(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func (store *SnapshotStore) Add(ctx context.Context, snapshot *gaivota.PortfolioSnapshot) (*gaivota.PortfolioSnapshot, error) {
+	var newSnapshot gaivota.PortfolioSnapshot
+
+	err := store.Database.write(func(t *tables) error {
+		_, ok := t.portfolios[snapshot.PortfolioID]
+		if err := foreignKey(ok, "portfolio", snapshot.PortfolioID); err != nil {
+			return err
+		}
+
+		newSnapshot = *snapshot
+		newSnapshot.Date = date(snapshot.Date)
+		newSnapshot.UpdatedAt = now()
+
+		// Nil slices would be stored as JSON nulls instead of empty arrays
+		if newSnapshot.Investments == nil {
+			newSnapshot.Investments = []gaivota.InvestmentSnapshot{}
+		}
+
+		if newSnapshot.Wallets == nil {
+			newSnapshot.Wallets = []gaivota.WalletSnapshot{}
+		}
+
+		// Replaces the snapshot of the same portfolio and date
+		for id, other := range t.snapshots {
+			if other.PortfolioID == snapshot.PortfolioID && other.Date.Equal(newSnapshot.Date) {
+				newSnapshot.ID = id
+				newSnapshot.CreatedAt = other.CreatedAt
+				t.snapshots[id] = newSnapshot
+				return nil
+			}
+		}
+
+		newSnapshot.ID = t.nextID("portfolio_snapshots")
+		newSnapshot.CreatedAt = newSnapshot.UpdatedAt
+		t.snapshots[newSnapshot.ID] = newSnapshot
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert snapshot of portfolio %v on %s: %w",
+			snapshot.PortfolioID, snapshot.Date.Format("2006-01-02"), err,
+		)
+	}
+
+	return &newSnapshot, nil
+}
+
+func (store *SnapshotStore) GetRange(ctx context.Context, portfolioId int, from time.Time, to time.Time) (*[]gaivota.PortfolioSnapshot, error) {
+	var snapshots []gaivota.PortfolioSnapshot
+
+	from, to = date(from), date(to)
+
+	store.Database.read(func(t *tables) error {
+		for _, snapshot := range t.snapshots {
+			if snapshot.PortfolioID == portfolioId && !snapshot.Date.Before(from) && !snapshot.Date.After(to) {
+				snapshots = append(snapshots, snapshot)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(snapshots, func(i int, j int) bool { return snapshots[i].Date.Before(snapshots[j].Date) })
+
+	return &snapshots, nil
+}
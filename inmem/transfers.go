@@ -0,0 +1,161 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewTransferStore(db *Database) *TransferStore {
+	return &TransferStore{
+		Database: db,
+	}
+}
+
+type TransferStore struct {
+	Database *Database
+}
+
+// The wallets and the position must exist, the wallets differ, the amount be
+// positive and the fee less than it
+func (t *tables) checkTransfer(transfer *gaivota.Transfer) error {
+	wallets := []struct {
+		field string
+		id    int
+	}{{"fromWallet", transfer.FromWalletID}, {"toWallet", transfer.ToWalletID}}
+
+	for _, wallet := range wallets {
+		if _, ok := t.wallets[wallet.id]; !ok {
+			err := fmt.Errorf("%w: wallet %v does not exist", ErrForeignKeyViolation, wallet.id)
+			return violation(gaivota.ErrInvalid, wallet.field, "does not exist", err)
+		}
+	}
+
+	_, ok := t.positions[transfer.PositionID]
+	if err := foreignKey(ok, "position", transfer.PositionID); err != nil {
+		return err
+	}
+
+	if transfer.FromWalletID == transfer.ToWalletID {
+		return check("toWallet", "transfer from and to wallet %v", transfer.FromWalletID)
+	}
+
+	if !transfer.Amount.IsPositive() || transfer.Fee.IsNegative() || !transfer.Fee.LessThan(transfer.Amount) {
+		return check("", "transfer of %v with a fee of %v", transfer.Amount, transfer.Fee)
+	}
+
+	return nil
+}
+
+// Returns the transfers matching the filter, in execution order
+func (store *TransferStore) filter(match func(t *tables, transfer gaivota.Transfer) bool) *[]gaivota.Transfer {
+	var transfers []gaivota.Transfer
+
+	store.Database.read(func(t *tables) error {
+		for _, transfer := range t.transfers {
+			if match(t, transfer) {
+				transfers = append(transfers, transfer)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(transfers, func(i int, j int) bool {
+		if transfers[i].ExecutedAt.Equal(transfers[j].ExecutedAt) {
+			return transfers[i].ID < transfers[j].ID
+		}
+		return transfers[i].ExecutedAt.Before(transfers[j].ExecutedAt)
+	})
+
+	return &transfers
+}
+
+func (store *TransferStore) Add(ctx context.Context, transfer *gaivota.Transfer) (*gaivota.Transfer, error) {
+	var newTransfer gaivota.Transfer
+
+	err := store.Database.write(func(t *tables) error {
+		newTransfer = *transfer
+		newTransfer.CreatedAt = now()
+
+		if err := t.checkTransfer(&newTransfer); err != nil {
+			return err
+		}
+
+		newTransfer.ID = t.nextID("transfers")
+		t.transfers[newTransfer.ID] = newTransfer
+
+		// The fee leaves the position
+		return syncPosition(ctx, t, newTransfer.PositionID)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert transfer from wallet %v to wallet %v: %w",
+			transfer.FromWalletID, transfer.ToWalletID, err,
+		)
+	}
+
+	return &newTransfer, nil
+}
+
+func (store *TransferStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Transfer, string, error) {
+	transfers := *store.filter(func(t *tables, transfer gaivota.Transfer) bool {
+		return between(opts, transfer.ExecutedAt) &&
+			symbol(opts, t.positionSymbol(transfer.PositionID)) &&
+			ownedBy(opts, t.walletOwner(transfer.FromWalletID))
+	})
+
+	page, next, err := paginate(opts, len(transfers),
+		func(i int) int { return transfers[i].ID },
+		func(i int) sortKey { return transfers[i].CreatedAt },
+		map[string]sortColumn{
+			"executedAt": func(i int) sortKey { return transfers[i].ExecutedAt },
+			"amount":     func(i int) sortKey { return transfers[i].Amount },
+			"fee":        func(i int) sortKey { return transfers[i].Fee },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Transfer, 0, len(page))
+	for _, i := range page {
+		result = append(result, transfers[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *TransferStore) Get(ctx context.Context, id int) (*gaivota.Transfer, error) {
+	var transfer gaivota.Transfer
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		transfer, ok = t.transfers[id]
+		if !ok {
+			return gaivota.ErrNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get transfer %v: %w", id, err)
+	}
+
+	return &transfer, nil
+}
+
+func (store *TransferStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Transfer, error) {
+	return store.filter(func(t *tables, transfer gaivota.Transfer) bool {
+		return transfer.PositionID == positionId
+	}), nil
+}
+
+func (store *TransferStore) GetByWalletID(ctx context.Context, walletId int) (*[]gaivota.Transfer, error) {
+	return store.filter(func(t *tables, transfer gaivota.Transfer) bool {
+		return transfer.FromWalletID == walletId || transfer.ToWalletID == walletId
+	}), nil
+}
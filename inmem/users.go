@@ -0,0 +1,201 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewUserStore(db *Database) *UserStore {
+	return &UserStore{
+		Database: db,
+	}
+}
+
+type UserStore struct {
+	Database *Database
+}
+
+// Emails are unique among all users, deleted ones included
+func (t *tables) checkUser(user *gaivota.User) error {
+	for _, other := range t.users {
+		if other.ID != user.ID && other.Email == user.Email {
+			return unique(true, "user", "email")
+		}
+	}
+
+	return nil
+}
+
+func (store *UserStore) Add(ctx context.Context, user *gaivota.User) (*gaivota.User, error) {
+	var newUser gaivota.User
+
+	err := store.Database.write(func(t *tables) error {
+		newUser = gaivota.User{
+			Email:             user.Email,
+			FirstName:         user.FirstName,
+			LastName:          user.LastName,
+			ReportingCurrency: currency(user.ReportingCurrency),
+			PasswordHash:      user.PasswordHash,
+			CreatedAt:         now(),
+		}
+		newUser.UpdatedAt = newUser.CreatedAt
+
+		if err := t.checkUser(&newUser); err != nil {
+			return err
+		}
+
+		newUser.ID = t.nextID("users")
+		t.users[newUser.ID] = newUser
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert user %s: %w", user.Email, err)
+	}
+
+	return &newUser, nil
+}
+
+func (store *UserStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.User, string, error) {
+	var users []gaivota.User
+
+	store.Database.read(func(t *tables) error {
+		for _, user := range t.users {
+			if !user.DeletedAt.Valid && between(opts, user.CreatedAt) && ownedBy(opts, user.ID) {
+				users = append(users, user)
+			}
+		}
+		return nil
+	})
+
+	page, next, err := paginate(opts, len(users),
+		func(i int) int { return users[i].ID },
+		func(i int) sortKey { return users[i].CreatedAt },
+		map[string]sortColumn{
+			"email":    func(i int) sortKey { return users[i].Email },
+			"lastName": func(i int) sortKey { return users[i].LastName },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.User, 0, len(page))
+	for _, i := range page {
+		result = append(result, users[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *UserStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		user, ok := t.users[id]
+		if err := found(ok, user.DeletedAt); err != nil {
+			return err
+		}
+
+		user.DeletedAt = deleted()
+		t.users[id] = user
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete user %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *UserStore) Get(ctx context.Context, id int) (*gaivota.User, error) {
+	var user gaivota.User
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		user, ok = t.users[id]
+		return found(ok, user.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get user %v: %w", id, err)
+	}
+
+	return &user, nil
+}
+
+func (store *UserStore) GetByEmail(ctx context.Context, email string) (*gaivota.User, error) {
+	var user gaivota.User
+
+	err := store.Database.read(func(t *tables) error {
+		for _, other := range t.users {
+			if strings.EqualFold(other.Email, email) && !other.DeletedAt.Valid {
+				user = other
+				return nil
+			}
+		}
+		return gaivota.ErrNotFound
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get user %s: %w", email, err)
+	}
+
+	return &user, nil
+}
+
+func (store *UserStore) SetPasswordHash(ctx context.Context, id int, hash string) error {
+	err := store.Database.write(func(t *tables) error {
+		user, ok := t.users[id]
+		if err := found(ok, user.DeletedAt); err != nil {
+			return err
+		}
+
+		user.PasswordHash = hash
+		user.UpdatedAt = now()
+		t.users[id] = user
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not set password for user %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *UserStore) Update(ctx context.Context, user *gaivota.User) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.users[user.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		stored.Email = user.Email
+		stored.FirstName = user.FirstName
+		stored.LastName = user.LastName
+		stored.ReportingCurrency = currency(user.ReportingCurrency, stored.ReportingCurrency)
+		stored.UpdatedAt = now()
+
+		if err := t.checkUser(&stored); err != nil {
+			return err
+		}
+
+		t.users[user.ID] = stored
+		user.ReportingCurrency = stored.ReportingCurrency
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update user %v: %w", user.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/leoschet/gaivota"
+)
+
+func NewWalletStore(db *Database) *WalletStore {
+	return &WalletStore{
+		Database: db,
+	}
+}
+
+type WalletStore struct {
+	Database *Database
+}
+
+// Names are unique per user
+func (t *tables) checkWallet(wallet *gaivota.Wallet) error {
+	for _, other := range t.wallets {
+		if other.ID != wallet.ID && other.UserID == wallet.UserID && other.Name == wallet.Name {
+			return unique(true, "wallet", "name")
+		}
+	}
+
+	return nil
+}
+
+func (store *WalletStore) Add(ctx context.Context, wallet *gaivota.Wallet) (*gaivota.Wallet, error) {
+	var newWallet gaivota.Wallet
+
+	err := store.Database.write(func(t *tables) error {
+		_, ok := t.users[wallet.UserID]
+		if err := foreignKey(ok, "user", wallet.UserID); err != nil {
+			return err
+		}
+
+		newWallet = gaivota.Wallet{
+			UserID:     wallet.UserID,
+			Name:       wallet.Name,
+			TotalValue: wallet.TotalValue,
+			Address:    wallet.Address,
+			Location:   wallet.Location,
+			CreatedAt:  now(),
+		}
+		newWallet.UpdatedAt = newWallet.CreatedAt
+
+		if err := t.checkWallet(&newWallet); err != nil {
+			return err
+		}
+
+		newWallet.ID = t.nextID("wallets")
+		t.wallets[newWallet.ID] = newWallet
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert wallet %s for user %v: %w", wallet.Name, wallet.UserID, err)
+	}
+
+	return &newWallet, nil
+}
+
+func (store *WalletStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Wallet, string, error) {
+	var wallets []gaivota.Wallet
+
+	store.Database.read(func(t *tables) error {
+		for _, wallet := range t.wallets {
+			if !wallet.DeletedAt.Valid && between(opts, wallet.CreatedAt) && ownedBy(opts, wallet.UserID) {
+				wallets = append(wallets, wallet)
+			}
+		}
+		return nil
+	})
+
+	page, next, err := paginate(opts, len(wallets),
+		func(i int) int { return wallets[i].ID },
+		func(i int) sortKey { return wallets[i].CreatedAt },
+		map[string]sortColumn{
+			"name":       func(i int) sortKey { return wallets[i].Name },
+			"totalValue": func(i int) sortKey { return wallets[i].TotalValue },
+		},
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]gaivota.Wallet, 0, len(page))
+	for _, i := range page {
+		result = append(result, wallets[i])
+	}
+
+	return &result, next, nil
+}
+
+func (store *WalletStore) Delete(ctx context.Context, id int) error {
+	err := store.Database.write(func(t *tables) error {
+		wallet, ok := t.wallets[id]
+		if err := found(ok, wallet.DeletedAt); err != nil {
+			return err
+		}
+
+		wallet.DeletedAt = deleted()
+		t.wallets[id] = wallet
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete wallet %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *WalletStore) Get(ctx context.Context, id int) (*gaivota.Wallet, error) {
+	var wallet gaivota.Wallet
+
+	err := store.Database.read(func(t *tables) error {
+		var ok bool
+		wallet, ok = t.wallets[id]
+		return found(ok, wallet.DeletedAt)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get wallet %v: %w", id, err)
+	}
+
+	return &wallet, nil
+}
+
+func (store *WalletStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Wallet, error) {
+	var wallets []gaivota.Wallet
+
+	store.Database.read(func(t *tables) error {
+		for _, wallet := range t.wallets {
+			if wallet.UserID == userId && !wallet.DeletedAt.Valid {
+				wallets = append(wallets, wallet)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(wallets, func(i int, j int) bool { return wallets[i].ID < wallets[j].ID })
+
+	return &wallets, nil
+}
+
+func (store *WalletStore) Update(ctx context.Context, wallet *gaivota.Wallet) error {
+	err := store.Database.write(func(t *tables) error {
+		stored, ok := t.wallets[wallet.ID]
+		if err := found(ok, stored.DeletedAt); err != nil {
+			return err
+		}
+
+		stored.Name = wallet.Name
+		stored.TotalValue = wallet.TotalValue
+		stored.Address = wallet.Address
+		stored.Location = wallet.Location
+		stored.UpdatedAt = now()
+
+		if err := t.checkWallet(&stored); err != nil {
+			return err
+		}
+
+		t.wallets[wallet.ID] = stored
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update wallet %v: %w", wallet.ID, err)
+	}
+
+	return nil
+}
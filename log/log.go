@@ -1,32 +1,285 @@
+// Package log writes leveled, structured log lines: a message and key-value
+// fields, as text for people or as JSON for log collectors. Lines are written
+// synchronously, one at a time, so they are never lost or reordered.
 package log
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/leoschet/gaivota"
 )
 
-func New(prefix string) *Logger {
+// Format of the log lines
+type Format string
+
+const (
+	// `2021-06-01T10:00:00.000Z INFO  message key=value`
+	FormatText Format = "text"
+	// One JSON object per line, with time, level and msg keys
+	FormatJSON Format = "json"
+)
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Severity of each level, lines below the logger's level are dropped
+var severities = map[gaivota.LogLevel]int{
+	gaivota.LogLevelDebug: 0,
+	gaivota.LogLevelInfo:  1,
+	gaivota.LogLevelWarn:  2,
+	gaivota.LogLevelError: 3,
+	gaivota.LogLevelFatal: 4,
+}
+
+// ParseLevel reads a level from the configuration, info when empty
+func ParseLevel(value string) (gaivota.LogLevel, error) {
+	if value == "" {
+		return gaivota.LogLevelInfo, nil
+	}
+
+	level := gaivota.LogLevel(strings.ToLower(value))
+	if _, ok := severities[level]; !ok {
+		return "", fmt.Errorf("unknown log level %q, expected debug, info, warn, error or fatal", value)
+	}
+
+	return level, nil
+}
+
+// ParseFormat reads a format from the configuration, text when empty
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(value)) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+
+	return "", fmt.Errorf("unknown log format %q, expected text or json", value)
+}
+
+// New returns a Logger writing lines at level and above to w. Unknown levels
+// are treated as info and unknown formats as text.
+func New(w io.Writer, level gaivota.LogLevel, format Format) *Logger {
+	severity, ok := severities[level]
+	if !ok {
+		severity = severities[gaivota.LogLevelInfo]
+	}
+
+	if format != FormatJSON {
+		format = FormatText
+	}
+
 	return &Logger{
-		logger: log.New(os.Stdout, prefix, log.LstdFlags),
+		output:   &output{writer: w, exit: os.Exit},
+		severity: severity,
+		format:   format,
 	}
 }
 
+// Parse returns a Logger writing to w, with the level and format read from
+// the configuration (see ParseLevel and ParseFormat)
+func Parse(w io.Writer, level, format string) (*Logger, error) {
+	parsedLevel, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedFormat, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(w, parsedLevel, parsedFormat), nil
+}
+
+// Logger implements gaivota.Logger. Loggers returned by With share the
+// writer, so their lines do not interleave.
 type Logger struct {
-	logger *log.Logger
+	output   *output
+	severity int
+	format   Format
+	fields   []interface{}
+}
+
+type output struct {
+	mu     sync.Mutex
+	writer io.Writer
+	// Called after a fatal line is written
+	exit func(code int)
+}
+
+// Enabled reports whether lines at level are written
+func (l *Logger) Enabled(level gaivota.LogLevel) bool {
+	severity, ok := severities[level]
+	return !ok || severity >= l.severity
 }
 
+// Log writes the message, formatted with v when given, and the logger's
+// fields. Fatal lines are always written and exit the process.
 func (l *Logger) Log(level gaivota.LogLevel, format string, v ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
 	msg := format
 	if len(v) > 0 {
-		msg = fmt.Sprintf(format, v)
+		msg = fmt.Sprintf(format, v...)
 	}
+	msg = strings.TrimRight(msg, "\n")
+
+	var line []byte
+	if l.format == FormatJSON {
+		line = l.json(time.Now(), level, msg)
+	} else {
+		line = l.text(time.Now(), level, msg)
+	}
+
+	l.output.mu.Lock()
+	defer l.output.mu.Unlock()
+
+	l.output.writer.Write(line)
 
 	if level == gaivota.LogLevelFatal {
-		l.logger.Fatalln(msg)
+		l.output.exit(1)
+	}
+}
+
+// With returns a Logger adding the key-value pairs to every line
+func (l *Logger) With(keyvals ...interface{}) gaivota.Logger {
+	if len(keyvals) == 0 {
+		return l
+	}
+
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals)+1)
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	if len(keyvals)%2 != 0 {
+		fields = append(fields, "MISSING")
+	}
+
+	return &Logger{
+		output:   l.output,
+		severity: l.severity,
+		format:   l.format,
+		fields:   fields,
+	}
+}
+
+func (l *Logger) text(t time.Time, level gaivota.LogLevel, msg string) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s %-5s %s", t.Format(timeFormat), strings.ToUpper(string(level)), msg)
+
+	for i := 0; i < len(l.fields); i += 2 {
+		buf.WriteByte(' ')
+		buf.WriteString(fmt.Sprint(l.fields[i]))
+		buf.WriteByte('=')
+
+		formatted := fmt.Sprint(value(l.fields[i+1]))
+		if formatted == "" || strings.ContainsAny(formatted, " =\"\n\t") {
+			formatted = strconv.Quote(formatted)
+		}
+		buf.WriteString(formatted)
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func (l *Logger) json(t time.Time, level gaivota.LogLevel, msg string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, t.Format(timeFormat))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, string(level))
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, msg)
+
+	for i := 0; i < len(l.fields); i += 2 {
+		buf.WriteByte(',')
+		writeJSON(&buf, fmt.Sprint(l.fields[i]))
+		buf.WriteByte(':')
+		writeJSON(&buf, value(l.fields[i+1]))
+	}
+
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// Writes v as JSON, or as a string when it cannot be marshaled
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	buf.Write(encoded)
+}
+
+// Returns errors, times and values with a String method as strings, which
+// JSON would otherwise encode as objects
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	return v
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a context carrying the key-value pairs along with the
+// ones already in ctx, so lines logged for a request (see Fields) say which
+// request they belong to
+func WithFields(ctx context.Context, keyvals ...interface{}) context.Context {
+	current := Fields(ctx)
+
+	fields := make([]interface{}, 0, len(current)+len(keyvals))
+	fields = append(fields, current...)
+	fields = append(fields, keyvals...)
+
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Fields returns the key-value pairs added to ctx with WithFields
+func Fields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	return fields
+}
+
+// FromContext returns logger adding the fields in ctx to every line
+func FromContext(ctx context.Context, logger gaivota.Logger) gaivota.Logger {
+	return logger.With(Fields(ctx)...)
+}
+
+// Sorted returns the map's entries as key-value pairs, sorted by key, for
+// libraries logging fields as maps
+func Sorted(fields map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	keyvals := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		keyvals = append(keyvals, key, fields[key])
 	}
 
-	go l.logger.Println(msg)
+	return keyvals
 }
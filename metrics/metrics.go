@@ -0,0 +1,300 @@
+// Package metrics counts and times what the API server does, and writes the
+// measurements in Prometheus' text format for GET /metrics.
+// See https://prometheus.io/docs/instrumenting/exposition_formats/
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the histograms timing
+// requests and queries
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics written to Prometheus, in registration order.
+// Registering two metrics with the same name panics.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+type metric interface {
+	write(ctx context.Context, w *bufio.Writer)
+}
+
+// Metadata shared by every metric
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d desc) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+func (r *Registry) register(d desc, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[d.name] {
+		panic(fmt.Sprintf("metric %s registered twice", d.name))
+	}
+
+	r.names[d.name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in Prometheus' text format
+func (r *Registry) Write(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mu.Unlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(ctx, buffered)
+	}
+
+	return buffered.Flush()
+}
+
+// Counter is a value that only goes up, per combination of label values
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+// Counter registers a counter with the given label names
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	counter := &Counter{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		series: make(map[string]*counterSeries),
+	}
+	r.register(counter.desc, counter)
+
+	return counter
+}
+
+// Inc adds one to the series with the label values, given in the order of
+// the label names
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add adds v, which must not be negative, to the series with the label values
+func (c *Counter) Add(v float64, labels ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := seriesKey(labels)
+	series, ok := c.series[key]
+	if !ok {
+		series = &counterSeries{labels: labels}
+		c.series[key] = series
+	}
+
+	series.value += v
+}
+
+func (c *Counter) write(ctx context.Context, w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w)
+	for _, key := range sortedKeys(c.series) {
+		series := c.series[key]
+		sample(w, c.name, c.labels, series.labels, nil, series.value)
+	}
+}
+
+// Histogram counts observations, like durations, in buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	// Observations per bucket, not cumulative
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram registers a histogram with the buckets' upper bounds, sorted
+// ascending, and the given label names
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(histogram.desc, histogram)
+
+	return histogram
+}
+
+// Observe counts v in the series with the label values
+func (h *Histogram) Observe(v float64, labels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := seriesKey(labels)
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			series.counts[i]++
+			break
+		}
+	}
+	series.count++
+	series.sum += v
+}
+
+func (h *Histogram) write(ctx context.Context, w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w)
+	for _, key := range sortedKeys(h.series) {
+		series := h.series[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			sample(w, h.name+"_bucket", h.labels, series.labels, []string{"le", formatFloat(bound)}, float64(cumulative))
+		}
+		sample(w, h.name+"_bucket", h.labels, series.labels, []string{"le", "+Inf"}, float64(series.count))
+		sample(w, h.name+"_sum", h.labels, series.labels, nil, series.sum)
+		sample(w, h.name+"_count", h.labels, series.labels, nil, float64(series.count))
+	}
+}
+
+// Reads a value when metrics are written, e.g. from a connection pool or
+// the database. Values that cannot be read are left out.
+type valueFunc struct {
+	desc
+	value func(ctx context.Context) (float64, error)
+}
+
+// GaugeFunc registers a value that goes up and down, read by fn
+func (r *Registry) GaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) {
+	f := &valueFunc{desc: desc{name: name, help: help, kind: "gauge"}, value: fn}
+	r.register(f.desc, f)
+}
+
+// CounterFunc registers a value that only goes up, counted elsewhere and
+// read by fn
+func (r *Registry) CounterFunc(name, help string, fn func(ctx context.Context) (float64, error)) {
+	f := &valueFunc{desc: desc{name: name, help: help, kind: "counter"}, value: fn}
+	r.register(f.desc, f)
+}
+
+func (f *valueFunc) write(ctx context.Context, w *bufio.Writer) {
+	value, err := f.value(ctx)
+	if err != nil {
+		return
+	}
+
+	f.header(w)
+	sample(w, f.name, nil, nil, nil, value)
+}
+
+// Writes a line like `name{label="value",le="0.5"} 42`
+func sample(w *bufio.Writer, name string, labelNames []string, labelValues []string, extra []string, value float64) {
+	w.WriteString(name)
+
+	pairs := make([]string, 0, len(labelNames)+1)
+	for i, label := range labelNames {
+		var labelValue string
+		if i < len(labelValues) {
+			labelValue = labelValues[i]
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", label, escapeLabel(labelValue)))
+	}
+	if extra != nil {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra[0], extra[1]))
+	}
+
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+// Label values joined by a byte that cannot appear in UTF-8 text
+func seriesKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+func sortedKeys(series interface{}) []string {
+	var keys []string
+	switch series := series.(type) {
+	case map[string]*counterSeries:
+		for key := range series {
+			keys = append(keys, key)
+		}
+	case map[string]*histogramSeries:
+		for key := range series {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
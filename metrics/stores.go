@@ -0,0 +1,646 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Instrument wraps the client's stores so that every call is timed, in
+// gaivota_store_duration_seconds, and failed calls are counted, in
+// gaivota_store_errors_total. Entities that are not found are not errors.
+// Both are labeled with the store, named after its table, and the method.
+//
+// It also counts the orders recorded, in gaivota_orders_recorded_total, and
+// reports how many users and portfolios there are, counted with the client's
+// stores when metrics are written.
+func Instrument(client *gaivota.Client, registry *Registry) *gaivota.Client {
+	instruments := &instruments{
+		duration:       registry.Histogram("gaivota_store_duration_seconds", "Duration of store calls.", DefaultBuckets, "store", "method"),
+		errors:         registry.Counter("gaivota_store_errors_total", "Store calls that failed, besides entities not found.", "store", "method"),
+		ordersRecorded: registry.Counter("gaivota_orders_recorded_total", "Orders recorded, added or imported."),
+	}
+
+	registry.GaugeFunc("gaivota_users", "Users signed up.", func(ctx context.Context) (float64, error) {
+		users, _, err := client.UserStore.All(ctx, gaivota.ListOptions{})
+		if err != nil {
+			return 0, err
+		}
+		return float64(len(*users)), nil
+	})
+
+	registry.GaugeFunc("gaivota_portfolios", "Portfolios of every user.", func(ctx context.Context) (float64, error) {
+		portfolios, _, err := client.PortfolioStore.All(ctx, gaivota.ListOptions{})
+		if err != nil {
+			return 0, err
+		}
+		return float64(len(*portfolios)), nil
+	})
+
+	return instruments.instrument(client)
+}
+
+type instruments struct {
+	duration       *Histogram
+	errors         *Counter
+	ordersRecorded *Counter
+}
+
+func (in *instruments) instrument(client *gaivota.Client) *gaivota.Client {
+	measurer := func(store string) measurer {
+		return measurer{store: store, instruments: in}
+	}
+
+	instrumented := *client
+	instrumented.UserStore = &userStore{UserStore: client.UserStore, measurer: measurer("users")}
+	instrumented.APIKeyStore = &apiKeyStore{APIKeyStore: client.APIKeyStore, measurer: measurer("api_keys")}
+	instrumented.PortfolioStore = &portfolioStore{PortfolioStore: client.PortfolioStore, measurer: measurer("portfolios")}
+	instrumented.WalletStore = &walletStore{WalletStore: client.WalletStore, measurer: measurer("wallets")}
+	instrumented.InvestmentStore = &investmentStore{InvestmentStore: client.InvestmentStore, measurer: measurer("investments")}
+	instrumented.PositionStore = &positionStore{PositionStore: client.PositionStore, measurer: measurer("positions")}
+	instrumented.LotStore = &lotStore{LotStore: client.LotStore, measurer: measurer("lots")}
+	instrumented.HoldingStore = &holdingStore{HoldingStore: client.HoldingStore, measurer: measurer("holdings")}
+	instrumented.TransferStore = &transferStore{TransferStore: client.TransferStore, measurer: measurer("transfers")}
+	instrumented.OrderStore = &orderStore{OrderStore: client.OrderStore, measurer: measurer("orders")}
+	instrumented.EventStore = &eventStore{EventStore: client.EventStore, measurer: measurer("events")}
+	instrumented.PriceStore = &priceStore{PriceStore: client.PriceStore, measurer: measurer("prices")}
+	instrumented.FXRateStore = &fxRateStore{FXRateStore: client.FXRateStore, measurer: measurer("fx_rates")}
+	instrumented.SnapshotStore = &snapshotStore{SnapshotStore: client.SnapshotStore, measurer: measurer("portfolio_snapshots")}
+	instrumented.AuditStore = &auditStore{AuditStore: client.AuditStore, measurer: measurer("audit_log")}
+	if client.Transactor != nil {
+		instrumented.Transactor = &transactor{transactor: client.Transactor, instruments: in}
+	}
+
+	return &instrumented
+}
+
+// Instruments the clients handed out by the transactor too
+type transactor struct {
+	transactor  gaivota.Transactor
+	instruments *instruments
+}
+
+func (instrumented *transactor) WithinTx(ctx context.Context, fn func(*gaivota.Client) error) error {
+	return instrumented.transactor.WithinTx(ctx, func(tx *gaivota.Client) error {
+		return fn(instrumented.instruments.instrument(tx))
+	})
+}
+
+// Measures the calls to a store
+type measurer struct {
+	store string
+	*instruments
+}
+
+// Records a call that started at start and returned *err, deferred by the
+// store methods
+func (m measurer) measure(method string, start time.Time, err *error) {
+	m.duration.Observe(time.Since(start).Seconds(), m.store, method)
+
+	if *err != nil && !errors.Is(*err, gaivota.ErrNotFound) {
+		m.errors.Inc(m.store, method)
+	}
+}
+
+type userStore struct {
+	gaivota.UserStore
+	measurer
+}
+
+func (store *userStore) Add(ctx context.Context, user *gaivota.User) (result *gaivota.User, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.UserStore.Add(ctx, user)
+}
+
+func (store *userStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.User, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.UserStore.All(ctx, opts)
+}
+
+func (store *userStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.UserStore.Delete(ctx, id)
+}
+
+func (store *userStore) Get(ctx context.Context, id int) (result *gaivota.User, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.UserStore.Get(ctx, id)
+}
+
+func (store *userStore) GetByEmail(ctx context.Context, email string) (result *gaivota.User, err error) {
+	defer store.measure("GetByEmail", time.Now(), &err)
+
+	return store.UserStore.GetByEmail(ctx, email)
+}
+
+func (store *userStore) SetPasswordHash(ctx context.Context, id int, hash string) (err error) {
+	defer store.measure("SetPasswordHash", time.Now(), &err)
+
+	return store.UserStore.SetPasswordHash(ctx, id, hash)
+}
+
+func (store *userStore) Update(ctx context.Context, user *gaivota.User) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.UserStore.Update(ctx, user)
+}
+
+type apiKeyStore struct {
+	gaivota.APIKeyStore
+	measurer
+}
+
+func (store *apiKeyStore) Add(ctx context.Context, key *gaivota.APIKey) (result *gaivota.APIKey, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.APIKeyStore.Add(ctx, key)
+}
+
+func (store *apiKeyStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.APIKeyStore.Delete(ctx, id)
+}
+
+func (store *apiKeyStore) Get(ctx context.Context, id int) (result *gaivota.APIKey, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.APIKeyStore.Get(ctx, id)
+}
+
+func (store *apiKeyStore) GetByHash(ctx context.Context, hash string) (result *gaivota.APIKey, err error) {
+	defer store.measure("GetByHash", time.Now(), &err)
+
+	return store.APIKeyStore.GetByHash(ctx, hash)
+}
+
+func (store *apiKeyStore) GetByUserID(ctx context.Context, userId int) (result *[]gaivota.APIKey, err error) {
+	defer store.measure("GetByUserID", time.Now(), &err)
+
+	return store.APIKeyStore.GetByUserID(ctx, userId)
+}
+
+type portfolioStore struct {
+	gaivota.PortfolioStore
+	measurer
+}
+
+func (store *portfolioStore) Add(ctx context.Context, portfolio *gaivota.Portfolio) (result *gaivota.Portfolio, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.PortfolioStore.Add(ctx, portfolio)
+}
+
+func (store *portfolioStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Portfolio, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.PortfolioStore.All(ctx, opts)
+}
+
+func (store *portfolioStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.PortfolioStore.Delete(ctx, id)
+}
+
+func (store *portfolioStore) Get(ctx context.Context, id int) (result *gaivota.Portfolio, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.PortfolioStore.Get(ctx, id)
+}
+
+func (store *portfolioStore) GetByUserID(ctx context.Context, userId int) (result *[]gaivota.Portfolio, err error) {
+	defer store.measure("GetByUserID", time.Now(), &err)
+
+	return store.PortfolioStore.GetByUserID(ctx, userId)
+}
+
+func (store *portfolioStore) Update(ctx context.Context, portfolio *gaivota.Portfolio) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.PortfolioStore.Update(ctx, portfolio)
+}
+
+type walletStore struct {
+	gaivota.WalletStore
+	measurer
+}
+
+func (store *walletStore) Add(ctx context.Context, wallet *gaivota.Wallet) (result *gaivota.Wallet, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.WalletStore.Add(ctx, wallet)
+}
+
+func (store *walletStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Wallet, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.WalletStore.All(ctx, opts)
+}
+
+func (store *walletStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.WalletStore.Delete(ctx, id)
+}
+
+func (store *walletStore) Get(ctx context.Context, id int) (result *gaivota.Wallet, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.WalletStore.Get(ctx, id)
+}
+
+func (store *walletStore) GetByUserID(ctx context.Context, userId int) (result *[]gaivota.Wallet, err error) {
+	defer store.measure("GetByUserID", time.Now(), &err)
+
+	return store.WalletStore.GetByUserID(ctx, userId)
+}
+
+func (store *walletStore) Update(ctx context.Context, wallet *gaivota.Wallet) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.WalletStore.Update(ctx, wallet)
+}
+
+type investmentStore struct {
+	gaivota.InvestmentStore
+	measurer
+}
+
+func (store *investmentStore) Add(ctx context.Context, investment *gaivota.Investment) (result *gaivota.Investment, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.InvestmentStore.Add(ctx, investment)
+}
+
+func (store *investmentStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Investment, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.InvestmentStore.All(ctx, opts)
+}
+
+func (store *investmentStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.InvestmentStore.Delete(ctx, id)
+}
+
+func (store *investmentStore) Get(ctx context.Context, id int) (result *gaivota.Investment, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.InvestmentStore.Get(ctx, id)
+}
+
+func (store *investmentStore) GetByUserID(ctx context.Context, userId int) (result *[]gaivota.Investment, err error) {
+	defer store.measure("GetByUserID", time.Now(), &err)
+
+	return store.InvestmentStore.GetByUserID(ctx, userId)
+}
+
+func (store *investmentStore) GetByPortfolioID(ctx context.Context, portfolioId int) (result *[]gaivota.Investment, err error) {
+	defer store.measure("GetByPortfolioID", time.Now(), &err)
+
+	return store.InvestmentStore.GetByPortfolioID(ctx, portfolioId)
+}
+
+func (store *investmentStore) Update(ctx context.Context, investment *gaivota.Investment) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.InvestmentStore.Update(ctx, investment)
+}
+
+type positionStore struct {
+	gaivota.PositionStore
+	measurer
+}
+
+func (store *positionStore) Add(ctx context.Context, position *gaivota.Position) (result *gaivota.Position, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.PositionStore.Add(ctx, position)
+}
+
+func (store *positionStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Position, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.PositionStore.All(ctx, opts)
+}
+
+func (store *positionStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.PositionStore.Delete(ctx, id)
+}
+
+func (store *positionStore) Get(ctx context.Context, id int) (result *gaivota.Position, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.PositionStore.Get(ctx, id)
+}
+
+func (store *positionStore) GetByInvestmentID(ctx context.Context, investmentId int) (result *[]gaivota.Position, err error) {
+	defer store.measure("GetByInvestmentID", time.Now(), &err)
+
+	return store.PositionStore.GetByInvestmentID(ctx, investmentId)
+}
+
+func (store *positionStore) Update(ctx context.Context, position *gaivota.Position) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.PositionStore.Update(ctx, position)
+}
+
+type lotStore struct {
+	gaivota.LotStore
+	measurer
+}
+
+func (store *lotStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Lot, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.LotStore.All(ctx, opts)
+}
+
+func (store *lotStore) Get(ctx context.Context, id int) (result *gaivota.Lot, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.LotStore.Get(ctx, id)
+}
+
+func (store *lotStore) GetByPositionID(ctx context.Context, positionId int) (result *[]gaivota.Lot, err error) {
+	defer store.measure("GetByPositionID", time.Now(), &err)
+
+	return store.LotStore.GetByPositionID(ctx, positionId)
+}
+
+type holdingStore struct {
+	gaivota.HoldingStore
+	measurer
+}
+
+func (store *holdingStore) Add(ctx context.Context, holding *gaivota.Holding) (result *gaivota.Holding, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.HoldingStore.Add(ctx, holding)
+}
+
+func (store *holdingStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Holding, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.HoldingStore.All(ctx, opts)
+}
+
+func (store *holdingStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.HoldingStore.Delete(ctx, id)
+}
+
+func (store *holdingStore) Get(ctx context.Context, id int) (result *gaivota.Holding, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.HoldingStore.Get(ctx, id)
+}
+
+func (store *holdingStore) GetByUserID(ctx context.Context, userId int) (result *[]gaivota.Holding, err error) {
+	defer store.measure("GetByUserID", time.Now(), &err)
+
+	return store.HoldingStore.GetByUserID(ctx, userId)
+}
+
+func (store *holdingStore) GetByWalletID(ctx context.Context, walletId int) (result *[]gaivota.Holding, err error) {
+	defer store.measure("GetByWalletID", time.Now(), &err)
+
+	return store.HoldingStore.GetByWalletID(ctx, walletId)
+}
+
+func (store *holdingStore) GetByPositionID(ctx context.Context, positionId int) (result *[]gaivota.Holding, err error) {
+	defer store.measure("GetByPositionID", time.Now(), &err)
+
+	return store.HoldingStore.GetByPositionID(ctx, positionId)
+}
+
+func (store *holdingStore) Update(ctx context.Context, holding *gaivota.Holding) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.HoldingStore.Update(ctx, holding)
+}
+
+type transferStore struct {
+	gaivota.TransferStore
+	measurer
+}
+
+func (store *transferStore) Add(ctx context.Context, transfer *gaivota.Transfer) (result *gaivota.Transfer, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.TransferStore.Add(ctx, transfer)
+}
+
+func (store *transferStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Transfer, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.TransferStore.All(ctx, opts)
+}
+
+func (store *transferStore) Get(ctx context.Context, id int) (result *gaivota.Transfer, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.TransferStore.Get(ctx, id)
+}
+
+func (store *transferStore) GetByPositionID(ctx context.Context, positionId int) (result *[]gaivota.Transfer, err error) {
+	defer store.measure("GetByPositionID", time.Now(), &err)
+
+	return store.TransferStore.GetByPositionID(ctx, positionId)
+}
+
+func (store *transferStore) GetByWalletID(ctx context.Context, walletId int) (result *[]gaivota.Transfer, err error) {
+	defer store.measure("GetByWalletID", time.Now(), &err)
+
+	return store.TransferStore.GetByWalletID(ctx, walletId)
+}
+
+type orderStore struct {
+	gaivota.OrderStore
+	measurer
+}
+
+func (store *orderStore) Add(ctx context.Context, order *gaivota.Order) (result *gaivota.Order, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	result, err = store.OrderStore.Add(ctx, order)
+	if err == nil {
+		store.ordersRecorded.Inc()
+	}
+
+	return result, err
+}
+
+func (store *orderStore) AddMany(ctx context.Context, orders []gaivota.Order) (result []gaivota.Order, err error) {
+	defer store.measure("AddMany", time.Now(), &err)
+
+	result, err = store.OrderStore.AddMany(ctx, orders)
+	if err == nil {
+		store.ordersRecorded.Add(float64(len(result)))
+	}
+
+	return result, err
+}
+
+func (store *orderStore) All(ctx context.Context, opts gaivota.ListOptions) (result []gaivota.Order, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.OrderStore.All(ctx, opts)
+}
+
+func (store *orderStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.OrderStore.Delete(ctx, id)
+}
+
+func (store *orderStore) Get(ctx context.Context, id int) (result *gaivota.Order, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.OrderStore.Get(ctx, id)
+}
+
+func (store *orderStore) GetByPositionID(ctx context.Context, positionId int) (result []gaivota.Order, err error) {
+	defer store.measure("GetByPositionID", time.Now(), &err)
+
+	return store.OrderStore.GetByPositionID(ctx, positionId)
+}
+
+func (store *orderStore) Update(ctx context.Context, order *gaivota.Order) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.OrderStore.Update(ctx, order)
+}
+
+type eventStore struct {
+	gaivota.EventStore
+	measurer
+}
+
+func (store *eventStore) Add(ctx context.Context, event *gaivota.Event) (result *gaivota.Event, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.EventStore.Add(ctx, event)
+}
+
+func (store *eventStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.Event, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.EventStore.All(ctx, opts)
+}
+
+func (store *eventStore) Delete(ctx context.Context, id int) (err error) {
+	defer store.measure("Delete", time.Now(), &err)
+
+	return store.EventStore.Delete(ctx, id)
+}
+
+func (store *eventStore) Get(ctx context.Context, id int) (result *gaivota.Event, err error) {
+	defer store.measure("Get", time.Now(), &err)
+
+	return store.EventStore.Get(ctx, id)
+}
+
+func (store *eventStore) GetByPositionID(ctx context.Context, positionId int) (result *[]gaivota.Event, err error) {
+	defer store.measure("GetByPositionID", time.Now(), &err)
+
+	return store.EventStore.GetByPositionID(ctx, positionId)
+}
+
+func (store *eventStore) Update(ctx context.Context, event *gaivota.Event) (err error) {
+	defer store.measure("Update", time.Now(), &err)
+
+	return store.EventStore.Update(ctx, event)
+}
+
+type priceStore struct {
+	gaivota.PriceStore
+	measurer
+}
+
+func (store *priceStore) Add(ctx context.Context, price *gaivota.Price) (result *gaivota.Price, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.PriceStore.Add(ctx, price)
+}
+
+func (store *priceStore) GetAt(ctx context.Context, symbol string, quote string, at time.Time) (result *gaivota.Price, err error) {
+	defer store.measure("GetAt", time.Now(), &err)
+
+	return store.PriceStore.GetAt(ctx, symbol, quote, at)
+}
+
+func (store *priceStore) GetRange(ctx context.Context, symbol string, quote string, from time.Time, to time.Time) (result *[]gaivota.Price, err error) {
+	defer store.measure("GetRange", time.Now(), &err)
+
+	return store.PriceStore.GetRange(ctx, symbol, quote, from, to)
+}
+
+type fxRateStore struct {
+	gaivota.FXRateStore
+	measurer
+}
+
+func (store *fxRateStore) Add(ctx context.Context, rate *gaivota.FXRate) (result *gaivota.FXRate, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.FXRateStore.Add(ctx, rate)
+}
+
+func (store *fxRateStore) GetAt(ctx context.Context, base string, quote string, at time.Time) (result *gaivota.FXRate, err error) {
+	defer store.measure("GetAt", time.Now(), &err)
+
+	return store.FXRateStore.GetAt(ctx, base, quote, at)
+}
+
+func (store *fxRateStore) GetRange(ctx context.Context, base string, quote string, from time.Time, to time.Time) (result *[]gaivota.FXRate, err error) {
+	defer store.measure("GetRange", time.Now(), &err)
+
+	return store.FXRateStore.GetRange(ctx, base, quote, from, to)
+}
+
+type snapshotStore struct {
+	gaivota.SnapshotStore
+	measurer
+}
+
+func (store *snapshotStore) Add(ctx context.Context, snapshot *gaivota.PortfolioSnapshot) (result *gaivota.PortfolioSnapshot, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.SnapshotStore.Add(ctx, snapshot)
+}
+
+func (store *snapshotStore) GetRange(ctx context.Context, portfolioId int, from time.Time, to time.Time) (result *[]gaivota.PortfolioSnapshot, err error) {
+	defer store.measure("GetRange", time.Now(), &err)
+
+	return store.SnapshotStore.GetRange(ctx, portfolioId, from, to)
+}
+
+type auditStore struct {
+	gaivota.AuditStore
+	measurer
+}
+
+func (store *auditStore) Add(ctx context.Context, entry *gaivota.AuditEntry) (result *gaivota.AuditEntry, err error) {
+	defer store.measure("Add", time.Now(), &err)
+
+	return store.AuditStore.Add(ctx, entry)
+}
+
+func (store *auditStore) All(ctx context.Context, opts gaivota.ListOptions) (result *[]gaivota.AuditEntry, next string, err error) {
+	defer store.measure("All", time.Now(), &err)
+
+	return store.AuditStore.All(ctx, opts)
+}
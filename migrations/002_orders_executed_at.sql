@@ -0,0 +1,12 @@
+-- Orders are replayed in execution order, so it must always be known
+update orders set executed_at = created_at where executed_at is null;
+
+alter table orders
+  alter column executed_at set default now(),
+  alter column executed_at set not null;
+
+---- create above / drop below ----
+
+alter table orders
+  alter column executed_at drop not null,
+  alter column executed_at drop default;
@@ -0,0 +1,32 @@
+-- Add cost basis method to portfolios
+create type cost_basis_methods as enum ('fifo', 'lifo', 'hifo', 'average');
+
+alter table portfolios add column cost_basis_method cost_basis_methods not null default 'average';
+
+-- Create lots table, derived from buy orders
+create table lots(
+  id serial primary key,
+  position_id int references positions(id) not null,
+  order_id int references orders(id) not null,
+  amount double precision not null,
+  remaining_amount double precision not null,
+  unit_price double precision not null,
+  acquired_at timestamptz not null,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now(),
+  unique (order_id)
+);
+
+create index lots_position_id_idx on lots(position_id);
+
+create trigger update_lots_updated_at before update on lots for each row execute procedure update_updated_at_column();
+
+---- create above / drop below ----
+
+-- Drop lots table
+drop trigger update_lots_updated_at on lots;
+drop table lots;
+
+-- Drop cost basis method
+alter table portfolios drop column cost_basis_method;
+drop type cost_basis_methods;
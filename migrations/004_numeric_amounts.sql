@@ -0,0 +1,48 @@
+-- Store amounts and prices as exact decimals
+alter table wallets
+  alter column total_value type numeric using total_value::numeric,
+  alter column total_value set default 0;
+
+alter table positions
+  alter column amount type numeric using amount::numeric,
+  alter column average_price type numeric using average_price::numeric,
+  alter column profit type numeric using profit::numeric,
+  alter column profit set default 0;
+
+alter table holdings
+  alter column amount type numeric using amount::numeric;
+
+alter table orders
+  alter column amount type numeric using amount::numeric,
+  alter column unit_price type numeric using unit_price::numeric,
+  alter column total_price type numeric using total_price::numeric;
+
+alter table lots
+  alter column amount type numeric using amount::numeric,
+  alter column remaining_amount type numeric using remaining_amount::numeric,
+  alter column unit_price type numeric using unit_price::numeric;
+
+---- create above / drop below ----
+
+alter table lots
+  alter column amount type double precision,
+  alter column remaining_amount type double precision,
+  alter column unit_price type double precision;
+
+alter table orders
+  alter column amount type double precision,
+  alter column unit_price type double precision,
+  alter column total_price type double precision;
+
+alter table holdings
+  alter column amount type double precision;
+
+alter table positions
+  alter column amount type double precision,
+  alter column average_price type double precision,
+  alter column profit type double precision,
+  alter column profit set default 0.0;
+
+alter table wallets
+  alter column total_value type double precision,
+  alter column total_value set default 0.0;
@@ -0,0 +1,16 @@
+-- Create prices table, holding historical quotes of tokens
+create table prices(
+  id serial primary key,
+  token_symbol varchar(10) not null,
+  quote_currency varchar(10) not null,
+  price numeric not null,
+  priced_at timestamptz not null,
+  source varchar(50) not null default '',
+  created_at timestamptz not null default now(),
+  unique (token_symbol, quote_currency, priced_at)
+);
+
+---- create above / drop below ----
+
+-- Drop prices table
+drop table prices;
@@ -0,0 +1,32 @@
+-- Add reporting currencies to users and portfolios
+alter table users add column reporting_currency varchar(10) not null default 'USD';
+
+alter table portfolios add column reporting_currency varchar(10) not null default 'USD';
+
+-- Add quote currencies to positions and orders
+alter table positions add column quote_currency varchar(10) not null default 'USD';
+
+alter table orders add column quote_currency varchar(10) not null default 'USD';
+
+-- Create fx_rates table, holding historical exchange rates between currencies
+create table fx_rates(
+  id serial primary key,
+  base_currency varchar(10) not null,
+  quote_currency varchar(10) not null,
+  rate numeric not null,
+  rated_at timestamptz not null,
+  source varchar(50) not null default '',
+  created_at timestamptz not null default now(),
+  unique (base_currency, quote_currency, rated_at)
+);
+
+---- create above / drop below ----
+
+-- Drop fx_rates table
+drop table fx_rates;
+
+-- Drop currencies
+alter table orders drop column quote_currency;
+alter table positions drop column quote_currency;
+alter table portfolios drop column reporting_currency;
+alter table users drop column reporting_currency;
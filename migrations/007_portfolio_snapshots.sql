@@ -0,0 +1,24 @@
+-- Create portfolio_snapshots table, holding daily portfolio valuations
+create table portfolio_snapshots(
+  id serial primary key,
+  portfolio_id int references portfolios(id) not null,
+  taken_on date not null,
+  quote_currency varchar(10) not null,
+  value numeric not null,
+  cost_basis numeric not null,
+  realized_profit numeric not null,
+  unrealized_profit numeric not null,
+  investments jsonb not null default '[]',
+  wallets jsonb not null default '[]',
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now(),
+  unique (portfolio_id, taken_on)
+);
+
+create trigger update_portfolio_snapshots_updated_at before update on portfolio_snapshots for each row execute procedure update_updated_at_column();
+
+---- create above / drop below ----
+
+-- Drop portfolio_snapshots table
+drop trigger update_portfolio_snapshots_updated_at on portfolio_snapshots;
+drop table portfolio_snapshots;
@@ -0,0 +1,13 @@
+-- Add the exchange's trade ID to orders, so imported trades are not
+-- recorded twice
+alter table orders add column trade_id varchar(100) not null default '';
+
+create unique index orders_position_exchange_trade_id_key
+  on orders (position_id, exchange, trade_id)
+  where trade_id <> '' and deleted_at is null;
+
+---- create above / drop below ----
+
+-- Drop trade IDs
+drop index orders_position_exchange_trade_id_key;
+alter table orders drop column trade_id;
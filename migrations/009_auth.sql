@@ -0,0 +1,21 @@
+-- Add password hashes to users, empty until a password is set
+alter table users add column password_hash varchar(255) not null default '';
+
+-- Create api_keys table, holding long-lived credentials. Only the SHA-256
+-- hash of each key is stored.
+create table api_keys(
+  id serial primary key,
+  user_id int references users(id) not null,
+  name varchar(50) not null,
+  key_hash char(64) unique not null,
+  created_at timestamptz not null default now(),
+  deleted_at timestamptz
+);
+
+---- create above / drop below ----
+
+-- Drop api_keys table
+drop table api_keys;
+
+-- Drop password hashes
+alter table users drop column password_hash;
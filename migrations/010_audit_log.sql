@@ -0,0 +1,34 @@
+-- Create audit_log table, recording every change to the other tables with
+-- who made it and the row as JSON before and after
+create table audit_log(
+  id serial primary key,
+  entity varchar(50) not null,
+  entity_id int not null,
+  action varchar(10) not null check (action in ('insert', 'update', 'delete')),
+  actor_type varchar(10) not null check (actor_type in ('user', 'api_key', 'cli', 'system')),
+  actor_id int,
+  user_id int references users(id),
+  before jsonb,
+  after jsonb,
+  created_at timestamptz not null default now()
+);
+
+create index audit_log_entity_idx on audit_log(entity, entity_id);
+create index audit_log_user_id_idx on audit_log(user_id);
+
+-- Entries are append-only
+create or replace function prevent_audit_log_change()
+returns trigger as $$
+begin
+  raise exception 'audit_log is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_log_append_only before update or delete on audit_log for each row execute procedure prevent_audit_log_change();
+
+---- create above / drop below ----
+
+-- Drop audit_log table
+drop table audit_log;
+
+drop function prevent_audit_log_change();
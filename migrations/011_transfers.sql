@@ -0,0 +1,23 @@
+-- Create transfers table, moving a position's coins between wallets. The
+-- network fee is paid out of the amount.
+create table transfers(
+  id serial primary key,
+  from_wallet_id int references wallets(id) not null,
+  to_wallet_id int references wallets(id) not null,
+  position_id int references positions(id) not null,
+  amount numeric not null check (amount > 0),
+  fee numeric not null default 0 check (fee >= 0),
+  executed_at timestamptz not null default now(),
+  created_at timestamptz not null default now(),
+  check (from_wallet_id <> to_wallet_id),
+  check (fee < amount)
+);
+
+create index transfers_position_id_idx on transfers(position_id);
+create index transfers_from_wallet_id_idx on transfers(from_wallet_id);
+create index transfers_to_wallet_id_idx on transfers(to_wallet_id);
+
+---- create above / drop below ----
+
+-- Drop transfers table
+drop table transfers;
@@ -0,0 +1,13 @@
+-- Add the fee charged by the exchange to orders, in the quote currency or
+-- any other currency or token
+alter table orders add column fee numeric not null default 0 check (fee >= 0);
+
+alter table orders add column fee_currency varchar(10);
+update orders set fee_currency = quote_currency;
+alter table orders alter column fee_currency set not null;
+
+---- create above / drop below ----
+
+-- Drop fees
+alter table orders drop column fee_currency;
+alter table orders drop column fee;
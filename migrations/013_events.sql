@@ -0,0 +1,39 @@
+-- Create events table, changing positions without trades: deposits,
+-- withdrawals, rewards, airdrops, forks, gifts and lost coins
+create type event_kinds as enum ('deposit', 'withdrawal', 'reward', 'airdrop', 'fork', 'gift', 'lost');
+
+create table events(
+  id serial primary key,
+  position_id int references positions(id) not null,
+  kind event_kinds not null,
+  amount numeric not null check (amount > 0),
+  unit_price numeric not null default 0 check (unit_price >= 0),
+  quote_currency varchar(10) not null,
+  note text not null default '',
+  executed_at timestamptz not null default now(),
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now(),
+  deleted_at timestamptz
+);
+
+create index events_position_id_idx on events(position_id);
+
+create trigger update_events_updated_at before update on events for each row execute procedure update_updated_at_column();
+
+-- Lots are opened by buy orders or incoming events
+alter table lots alter column order_id drop not null;
+alter table lots add column event_id int references events(id) unique;
+alter table lots add constraint lots_order_or_event check ((order_id is null) <> (event_id is null));
+
+---- create above / drop below ----
+
+-- Drop lots opened by events
+alter table lots drop constraint lots_order_or_event;
+delete from lots where event_id is not null;
+alter table lots drop column event_id;
+alter table lots alter column order_id set not null;
+
+-- Drop events table
+drop trigger update_events_updated_at on events;
+drop table events;
+drop type event_kinds;
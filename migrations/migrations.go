@@ -0,0 +1,97 @@
+// Package migrations embeds the database schema migrations, so the binaries
+// carry them and no external tool is needed to migrate.
+//
+// Each migration is a `<version>_<name>.sql` file, versions starting at 1
+// without gaps. The SQL applying it comes first, then a
+// `---- create above / drop below ----` line and the SQL reverting it.
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Separates the SQL applying a migration from the SQL reverting it
+const separator = "---- create above / drop below ----"
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// SHA-256 of the file, to detect migrations edited after being applied
+	Checksum string
+}
+
+// All returns the embedded migrations, ordered by version.
+func All() ([]Migration, error) {
+	return Load(files)
+}
+
+// Load reads the migrations at the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+
+	for _, name := range names {
+		migration, err := parse(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load migration %s: %w", name, err)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i int, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("Could not load migrations: expected version %v, found %v (%s)", i+1, migration.Version, migration.Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+func parse(fsys fs.FS, name string) (*Migration, error) {
+	prefix := strings.SplitN(strings.TrimSuffix(path.Base(name), ".sql"), "_", 2)
+
+	version, err := strconv.Atoi(prefix[0])
+	if err != nil || version < 1 || len(prefix) < 2 {
+		return nil, fmt.Errorf("name must be <version>_<name>.sql")
+	}
+
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+
+	parts := strings.SplitN(string(content), separator, 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("missing %q line", separator)
+	}
+
+	return &Migration{
+		Version:  version,
+		Name:     prefix[1],
+		Up:       strings.TrimSpace(parts[0]),
+		Down:     strings.TrimSpace(parts[1]),
+		Checksum: hex.EncodeToString(sum[:]),
+	}, nil
+}
@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// Checksums of the released migrations. Applied migrations must not change,
+// so only new migrations are added here, never edited.
+var released = []string{
+	"797a918282df1c44e677dd6cfa3ec3525be7e520f7ecd746a1460b78bd4ae34b",
+	"f40e00f4992d06fe276d53aff76310bad8111bc390c60a07366ea7ea8c9bcec2",
+	"f1757d71401b1d7b92535ab5fcdde029c711efe2971f6aea1d136eafe7aca73f",
+	"29adef9ce81fa91da150f6154c8451283bf4f3b692d19e2c3043cadace03c4a7",
+	"ca065490fef66bebfe93c04804802782062324a218a7313b762e145b8232fef9",
+	"7f5db86ae98645e1c2405466e5e72c464eadc21cb0b265ef474a6e3b98e04697",
+	"2cf743ca4b3889b5c0db6ae903ed3166bd75e1d9babcbe3cd5fde4a489ddeb5f",
+	"4c2a6540461a8cb4b0955dad3450892f5fe6b46ae92c9022e36c12a094cd00c5",
+	"dc10147b07beae55511c23525b31f8e2aa7039e7097be6fd324d2e42ef7ad973",
+	"4c66019a04cbce9061d26edb65f5bbe97037ae1287cae0161f02cc97c5593d54",
+	"df058734ca24f8c89eaecc2d51a253973f7518b00db8b6391d1a2020b130c9db",
+	"eded017e9484554f1d39ecaa085bd144d3a03ef81aba2159ebd5c89b7ab65108",
+	"95108314e8a2d29f8edf57e2735e589d0e0a7ac5cd6f909611c474afd8067cbe",
+}
+
+func TestAll(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) < len(released) {
+		t.Fatalf("Loaded %d migrations, expected at least the %d released", len(all), len(released))
+	}
+
+	for i, migration := range all {
+		if migration.Version != i+1 {
+			t.Errorf("Migration %d has version %d, expected versions without gaps", i+1, migration.Version)
+		}
+		if migration.Name == "" || migration.Up == "" || migration.Down == "" {
+			t.Errorf("Migration %d is %+v, expected a name and SQL both ways", migration.Version, migration)
+		}
+
+		if i < len(released) && migration.Checksum != released[i] {
+			t.Errorf("Migration %d (%s) was edited after its release, add a new migration instead", migration.Version, migration.Name)
+		}
+	}
+
+	for _, migration := range all[len(released):] {
+		t.Errorf("Migration %d (%s) has no released checksum, add %s", migration.Version, migration.Name, migration.Checksum)
+	}
+
+	// Loading again gives the same checksums
+	again, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range again {
+		if again[i].Checksum != all[i].Checksum {
+			t.Errorf("Checksum of migration %d changed between loads", i+1)
+		}
+	}
+}
+
+func file(up string, down string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(up + "\n\n" + separator + "\n\n" + down + "\n")}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_email.sql": file("alter table users add email text;", "alter table users drop email;"),
+		"001_init.sql":      file("create table users(id serial);", "drop table users;"),
+		"README.md":         &fstest.MapFile{Data: []byte("Not a migration")},
+	}
+
+	loaded, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded) != 2 || loaded[0].Name != "init" || loaded[1].Name != "add_email" {
+		t.Fatalf("Loaded %+v, expected init then add_email", loaded)
+	}
+
+	if loaded[1].Up != "alter table users add email text;" || loaded[1].Down != "alter table users drop email;" {
+		t.Errorf("Migration 2 is up %q and down %q, expected the SQL around the separator", loaded[1].Up, loaded[1].Down)
+	}
+
+	sum := sha256.Sum256(fsys["001_init.sql"].Data)
+	if loaded[0].Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Checksum is %s, expected the SHA-256 of the file", loaded[0].Checksum)
+	}
+
+	// Any edit changes the checksum, even outside the SQL
+	fsys["001_init.sql"] = &fstest.MapFile{Data: append([]byte("-- Users\n"), fsys["001_init.sql"].Data...)}
+	edited, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited[0].Checksum == loaded[0].Checksum || edited[1].Checksum != loaded[1].Checksum {
+		t.Error("Editing migration 1 did not change only its checksum")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		expected string
+	}{
+		{
+			name: "gap",
+			fsys: fstest.MapFS{
+				"001_init.sql":  file("select 1;", "select 1;"),
+				"003_later.sql": file("select 3;", "select 3;"),
+			},
+			expected: "expected version 2, found 3",
+		},
+		{
+			name:     "not starting at 1",
+			fsys:     fstest.MapFS{"002_init.sql": file("select 2;", "select 2;")},
+			expected: "expected version 1, found 2",
+		},
+		{
+			name:     "name without version",
+			fsys:     fstest.MapFS{"init.sql": file("select 1;", "select 1;")},
+			expected: "name must be <version>_<name>.sql",
+		},
+		{
+			name:     "missing separator",
+			fsys:     fstest.MapFS{"001_init.sql": &fstest.MapFile{Data: []byte("select 1;")}},
+			expected: "missing",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(test.fsys)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Loading answered %v, expected %s", err, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package mux
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/audit"
+)
+
+func InitAuditRouter(mux *Mux, store gaivota.AuditStore, logger gaivota.Logger) {
+	auditHandler := &AuditHandler{
+		logger:     logger,
+		AuditStore: store,
+	}
+
+	router := mux.subrouter("/audit")
+
+	router.Get("/", http.HandlerFunc(auditHandler.All))
+}
+
+type AuditHandler struct {
+	logger     gaivota.Logger
+	AuditStore gaivota.AuditStore
+}
+
+// All lists audit entries, optionally of an `entity` (e.g. orders) and an
+// entity `id`, with the usual list options
+func (handler *AuditHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Audit Entries")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	query := req.URL.Query()
+	opts.Entity = query.Get("entity")
+
+	if opts.Entity != "" && !isAuditEntity(opts.Entity) {
+		httpError(rw, fmt.Sprintf("entity must be one of %s", strings.Join(audit.Entities, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	if value := query.Get("id"); value != "" {
+		if opts.EntityID, err = strconv.Atoi(value); err != nil {
+			httpError(rw, "id must be an integer", http.StatusBadRequest)
+			return
+		}
+
+		if opts.Entity == "" {
+			httpError(rw, "id requires an entity", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, next, err := handler.AuditStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Audit Entries: %v", err)
+		writeError(rw, "Error while getting Audit Entries", err)
+		return
+	}
+
+	writePage(rw, req, entries, next)
+}
+
+func isAuditEntity(entity string) bool {
+	for _, known := range audit.Entities {
+		if entity == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,281 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/audit"
+	"github.com/leoschet/gaivota/auth"
+	"github.com/leoschet/gaivota/log"
+)
+
+// Routes anyone can call. Metrics are scraped by Prometheus, which should be
+// the only one reaching them. The API's documentation is read before having
+// an account.
+var publicRoutes = []struct {
+	method string
+	path   string
+}{
+	{method: http.MethodGet, path: "/ping"},
+	{method: http.MethodGet, path: "/healthz"},
+	{method: http.MethodGet, path: "/readyz"},
+	{method: http.MethodGet, path: "/metrics"},
+	{method: http.MethodGet, path: "/openapi.json"},
+	{method: http.MethodGet, path: "/docs"},
+	{method: http.MethodGet, path: "/docs/redoc.standalone.js"},
+	{method: http.MethodPost, path: "/auth/login"},
+	{method: http.MethodPost, path: "/users"},
+}
+
+func isPublic(req *http.Request) bool {
+	return publicRoute(req.Method, path.Clean("/"+req.URL.Path))
+}
+
+func publicRoute(method string, reqPath string) bool {
+	for _, route := range publicRoutes {
+		if method == route.method && reqPath == route.path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authenticate requires a session token or an API key as a bearer token on
+// every non-public route, and makes the request on behalf of its user so
+// scoped stores (see auth.Scope) only see that user's data. Changes are
+// recorded as made by the user or the API key (see audit.Record), and lines
+// logged for the request carry the user's ID.
+func Authenticate(next http.Handler, authenticator *auth.Authenticator, logger gaivota.Logger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if isPublic(req) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		credential := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if credential == "" || credential == req.Header.Get("Authorization") {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			httpError(rw, "Missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		userId, actor, err := authenticator.Authenticate(req.Context(), credential)
+
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidCredentials) {
+				requestLogger(req.Context(), logger).Log(gaivota.LogLevelError, "Error while authenticating request: %v", err)
+			}
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			httpError(rw, "Invalid or expired credentials", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := audit.WithActor(auth.WithUser(req.Context(), userId), actor)
+		ctx = log.WithFields(ctx, "userId", userId)
+
+		next.ServeHTTP(rw, req.WithContext(ctx))
+	})
+}
+
+func InitAuthRouter(mux *Mux, client *gaivota.Client, tokens *auth.Tokens, logger gaivota.Logger) {
+	authHandler := &AuthHandler{
+		logger:      logger,
+		tokens:      tokens,
+		UserStore:   client.UserStore,
+		APIKeyStore: client.APIKeyStore,
+	}
+
+	router := mux.subrouter("/auth")
+
+	router.Post("/login", http.HandlerFunc(authHandler.Login))
+	router.Get("/me", http.HandlerFunc(authHandler.Me))
+	router.Put("/password", http.HandlerFunc(authHandler.Password))
+	router.Get("/keys", http.HandlerFunc(authHandler.Keys))
+	router.Post("/keys", http.HandlerFunc(authHandler.AddKey))
+	router.Delete("/keys/:keyId", http.HandlerFunc(authHandler.DeleteKey))
+}
+
+type AuthHandler struct {
+	logger      gaivota.Logger
+	tokens      *auth.Tokens
+	UserStore   gaivota.UserStore
+	APIKeyStore gaivota.APIKeyStore
+}
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Login exchanges an email and password for a session token
+func (handler *AuthHandler) Login(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Login")
+
+	var body credentials
+	err := decodeJSON(req, &body)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /auth/login request body: %v", err)
+		writeError(rw, "Error while decoding credentials", err)
+		return
+	}
+
+	user, err := auth.Login(req.Context(), handler.UserStore, body.Email, body.Password)
+
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		httpError(rw, "Invalid email or password", http.StatusUnauthorized)
+		return
+	}
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while logging in %s: %v", body.Email, err)
+		writeError(rw, "Error while logging in", err)
+		return
+	}
+
+	token, expiresAt, err := handler.tokens.Issue(user.ID)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while issuing token for User %v: %v", user.ID, err)
+		writeError(rw, "Error while logging in", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &session{Token: token, ExpiresAt: expiresAt})
+}
+
+// Me returns the authenticated user
+func (handler *AuthHandler) Me(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Me")
+
+	userId, _ := auth.UserID(req.Context())
+	user, err := handler.UserStore.Get(req.Context(), userId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting User %v: %v", userId, err)
+		writeError(rw, "Error while getting User", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, user)
+}
+
+// Password replaces the authenticated user's password
+func (handler *AuthHandler) Password(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Password")
+
+	var body credentials
+	err := decodeJSON(req, &body)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /auth/password request body: %v", err)
+		writeError(rw, "Error while decoding password", err)
+		return
+	}
+
+	hash, err := auth.HashPassword(body.Password)
+
+	if err != nil {
+		writeError(rw, "Invalid password", err)
+		return
+	}
+
+	userId, _ := auth.UserID(req.Context())
+	err = handler.UserStore.SetPasswordHash(req.Context(), userId, hash)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while setting password for User %v: %v", userId, err)
+		writeError(rw, "Error while setting password", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// Keys lists the authenticated user's API keys, without the keys themselves
+func (handler *AuthHandler) Keys(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET API Keys")
+
+	userId, _ := auth.UserID(req.Context())
+	keys, err := handler.APIKeyStore.GetByUserID(req.Context(), userId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting API Keys for User %v: %v", userId, err)
+		writeError(rw, "Error while getting API Keys", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, keys)
+}
+
+type keyName struct {
+	Name string `json:"name"`
+}
+
+type newAPIKey struct {
+	gaivota.APIKey
+	// Only returned when the key is created
+	Key string `json:"key"`
+}
+
+// AddKey creates an API key for the authenticated user
+func (handler *AuthHandler) AddKey(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST API Key")
+
+	var body keyName
+	err := decodeJSON(req, &body)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /auth/keys request body: %v", err)
+		writeError(rw, "Error while decoding API Key data", err)
+		return
+	}
+
+	if err := (&gaivota.APIKey{Name: body.Name}).Validate(); err != nil {
+		writeError(rw, "Invalid API Key", err)
+		return
+	}
+
+	userId, _ := auth.UserID(req.Context())
+	key, apiKey, err := auth.NewAPIKey(req.Context(), handler.APIKeyStore, userId, body.Name)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding API Key: %v", err)
+		writeError(rw, "Error while adding API Key", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, &newAPIKey{APIKey: *apiKey, Key: key})
+}
+
+// DeleteKey revokes one of the authenticated user's API keys
+func (handler *AuthHandler) DeleteKey(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE API Key")
+
+	keyId, err := intParam(req, "keyId")
+
+	if err != nil {
+		httpError(rw, "API Key ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.APIKeyStore.Delete(req.Context(), keyId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting API Key %v: %v", keyId, err)
+		writeError(rw, "Error while deleting API Key", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
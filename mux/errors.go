@@ -0,0 +1,92 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/mux"
+)
+
+// Codes of the error envelope, by status. Other statuses are "internal".
+var errorCodes = map[int]string{
+	http.StatusBadRequest:         "invalid",
+	http.StatusUnauthorized:       "unauthorized",
+	http.StatusForbidden:          "forbidden",
+	http.StatusNotFound:           "not_found",
+	http.StatusMethodNotAllowed:   "method_not_allowed",
+	http.StatusConflict:           "conflict",
+	http.StatusServiceUnavailable: "unavailable",
+}
+
+// Every error is answered with the same JSON envelope:
+//
+//	{"error": {"code": "invalid", "message": "Invalid portfolio", "fields": [{"field": "name", "message": "is required"}]}}
+//
+// The code tells what kind of error it is (see errorCodes), and the fields
+// what is wrong with the request, when known.
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message"`
+	Fields  []gaivota.FieldError `json:"fields,omitempty"`
+}
+
+// Answers with the error envelope, as http.Error does with plain text
+func httpError(rw http.ResponseWriter, message string, status int, fields ...gaivota.FieldError) {
+	code, ok := errorCodes[status]
+	if !ok {
+		code = "internal"
+	}
+
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	writeJSON(rw, status, errorEnvelope{errorBody{Code: code, Message: message, Fields: fields}})
+}
+
+// Answers requests to paths without routes, and with methods their route
+// does not handle
+var routeErrors = mux.ErrorHandlers{
+	NotFound: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		httpError(rw, "Not found", http.StatusNotFound)
+	}),
+	MethodNotAllowed: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		httpError(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}),
+}
+
+// Answers with the status for err (see errorStatus), and the fields of the
+// gaivota.Error in its chain, or its message when it has no fields
+func writeError(rw http.ResponseWriter, message string, err error) {
+	var typed *gaivota.Error
+	if errors.As(err, &typed) && len(typed.Fields) == 0 && typed.Message != "" {
+		message += ": " + typed.Message
+	}
+
+	httpError(rw, message, errorStatus(err), gaivota.FieldErrorsOf(err)...)
+}
+
+// Returns the status code for an error of the stores: 404 for entities that
+// do not exist or belong to another user, 400 for invalid entities and list
+// options, 409 for conflicts with what is stored, like duplicate names,
+// selling more than is held or orders in a currency without a stored rate to
+// the position's, 403 for what the caller may not do, and 500 otherwise
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, gaivota.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, gaivota.ErrInvalid), errors.Is(err, gaivota.ErrInvalidListOptions):
+		return http.StatusBadRequest
+	case errors.Is(err, gaivota.ErrConflict), errors.Is(err, gaivota.ErrInsufficientHolding), errors.Is(err, accounting.ErrOversold):
+		return http.StatusConflict
+	case errors.Is(err, gaivota.ErrFXRateNotFound), errors.Is(err, gaivota.ErrPriceNotFound):
+		return http.StatusConflict
+	case errors.Is(err, gaivota.ErrForbidden):
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
+}
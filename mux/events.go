@@ -0,0 +1,206 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/leoschet/gaivota"
+)
+
+func InitEventRouter(mux *Mux, client *gaivota.Client, logger gaivota.Logger) {
+	eventHandler := &EventHandler{
+		logger:     logger,
+		client:     client,
+		EventStore: client.EventStore,
+	}
+
+	router := mux.subrouter("/events")
+
+	router.Get("/", http.HandlerFunc(eventHandler.All))
+	router.Post("/", http.HandlerFunc(eventHandler.Add))
+	router.Get("/:eventId", http.HandlerFunc(eventHandler.Get))
+	router.Put("/:eventId", http.HandlerFunc(eventHandler.Update))
+	router.Delete("/:eventId", http.HandlerFunc(eventHandler.Delete))
+
+	mux.subrouter("/positions").Get("/:positionId/events", http.HandlerFunc(eventHandler.GetByPositionID))
+}
+
+type EventHandler struct {
+	logger     gaivota.Logger
+	client     *gaivota.Client
+	EventStore gaivota.EventStore
+}
+
+func (handler *EventHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Events")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	events, next, err := handler.EventStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Events: %v", err)
+		writeError(rw, "Error while getting Events", err)
+		return
+	}
+
+	writePage(rw, req, events, next)
+}
+
+func (handler *EventHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Event")
+
+	eventId, err := intParam(req, "eventId")
+
+	if err != nil {
+		httpError(rw, "Event ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	event, err := handler.EventStore.Get(req.Context(), eventId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Event %v: %v", eventId, err)
+		writeError(rw, "Error while getting Event", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, event)
+}
+
+func (handler *EventHandler) GetByPositionID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Events")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	events, err := handler.EventStore.GetByPositionID(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Events for Position %v: %v", positionId, err)
+		writeError(rw, "Error while getting Events", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, events)
+}
+
+func (handler *EventHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Event")
+
+	var event gaivota.Event
+	err := decodeJSON(req, &event)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /events request body: %v", err)
+		writeError(rw, "Error while decoding event data", err)
+		return
+	}
+
+	if err := event.Validate(); err != nil {
+		writeError(rw, "Invalid event", err)
+		return
+	}
+
+	walletId := 0
+	if req.URL.Query().Get("walletId") != "" {
+		walletId, err = strconv.Atoi(req.URL.Query().Get("walletId"))
+		if err != nil {
+			httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// With a wallet, the event, its position and the wallet's holding are
+	// updated together or not at all
+	var newEvent *gaivota.Event
+	err = handler.client.WithinTx(req.Context(), func(tx *gaivota.Client) (err error) {
+		newEvent, err = tx.EventStore.Add(req.Context(), &event)
+		if err != nil || walletId == 0 {
+			return err
+		}
+
+		delta := newEvent.Amount
+		if !newEvent.Kind.Incoming() {
+			delta = delta.Neg()
+		}
+
+		_, err = gaivota.AdjustHolding(req.Context(), tx.HoldingStore, walletId, newEvent.PositionID, delta)
+
+		return err
+	})
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Event: %v", err)
+		writeError(rw, "Error while adding Event", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newEvent)
+}
+
+func (handler *EventHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Event")
+
+	eventId, err := intParam(req, "eventId")
+
+	if err != nil {
+		httpError(rw, "Event ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var event gaivota.Event
+	err = decodeJSON(req, &event)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /events request body: %v", err)
+		writeError(rw, "Error while decoding event data", err)
+		return
+	}
+
+	if err := event.Validate(); err != nil {
+		writeError(rw, "Invalid event", err)
+		return
+	}
+
+	event.ID = eventId
+	err = handler.EventStore.Update(req.Context(), &event)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Event %v: %v", eventId, err)
+		writeError(rw, "Error while updating Event", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &event)
+}
+
+func (handler *EventHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE Event")
+
+	eventId, err := intParam(req, "eventId")
+
+	if err != nil {
+		httpError(rw, "Event ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.EventStore.Delete(req.Context(), eventId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting Event %v: %v", eventId, err)
+		writeError(rw, "Error while deleting Event", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
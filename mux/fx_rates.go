@@ -0,0 +1,117 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/fx"
+	"github.com/leoschet/mux"
+)
+
+// Rates are shared by every user and drive their valuations, so they are only
+// stored with the CLI
+func InitFXRateRouter(mux *Mux, store gaivota.FXRateStore, logger gaivota.Logger) {
+	fxRateHandler := &FXRateHandler{
+		logger:      logger,
+		FXRateStore: store,
+	}
+
+	router := mux.subrouter("/fx")
+
+	router.Get("/:base/:quote", http.HandlerFunc(fxRateHandler.Get))
+	router.Get("/:base/:quote/history", http.HandlerFunc(fxRateHandler.History))
+}
+
+type FXRateHandler struct {
+	logger      gaivota.Logger
+	FXRateStore gaivota.FXRateStore
+}
+
+// Reads the base and quote currency path params
+func fxRateParams(req *http.Request) (string, string) {
+	params := mux.PathParams(req)
+
+	return strings.ToUpper(params["base"]), strings.ToUpper(params["quote"])
+}
+
+// Get returns the rate now, or at the `at` query param. Pairs that are not
+// stored are inverted or crossed through the default quote currency.
+func (handler *FXRateHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET FX Rate")
+
+	base, quote := fxRateParams(req)
+
+	at, err := timeQuery(req, "at")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	rate, err := fx.NewConverter(handler.FXRateStore).Rate(req.Context(), base, quote, at)
+
+	if errors.Is(err, gaivota.ErrFXRateNotFound) {
+		httpError(rw, "FX Rate not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting FX Rate from %s to %s: %v", base, quote, err)
+		writeError(rw, "Error while getting FX Rate", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, gaivota.FXRate{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Rate:          rate,
+		At:            at,
+	})
+}
+
+// History lists the stored rates between the `from` and `to` query params,
+// defaulting to the last 30 days
+func (handler *FXRateHandler) History(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET FX Rate History")
+
+	base, quote := fxRateParams(req)
+
+	from, err := timeQuery(req, "from")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	to, err := endQuery(req, "to")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+
+	rates, err := handler.FXRateStore.GetRange(req.Context(), base, quote, from, to)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting FX Rates from %s to %s: %v", base, quote, err)
+		writeError(rw, "Error while getting FX Rates", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, rates)
+}
@@ -1,34 +1,72 @@
 package mux
 
 import (
+	"context"
 	"net/http"
-	"reflect"
+	"sync"
+	"time"
 
 	"github.com/leoschet/gaivota"
 )
 
-func InitHealthCheckRouter(mux *Mux, dependencies []gaivota.HealthChecker, logger gaivota.Logger) {
+// DefaultHealthTimeout is how long a dependency check may take when its
+// Dependency does not say
+const DefaultHealthTimeout = 2 * time.Second
+
+// Dependency is checked by GET /readyz
+type Dependency struct {
+	Checker gaivota.HealthChecker
+	// How long the check may take, DefaultHealthTimeout when 0
+	Timeout time.Duration
+	// Whether requests cannot be served without the dependency. Other
+	// dependencies being down only degrade the service.
+	Critical bool
+}
+
+func InitHealthCheckRouter(mux *Mux, dependencies []Dependency, logger gaivota.Logger) {
 	healthcheck := &HealthCheck{
 		logger,
 		dependencies,
 	}
 
-	mux.Router.Get("/ping", healthcheck)
+	mux.Router.Get("/ping", http.HandlerFunc(healthcheck.Ping))
+	mux.Router.Get("/healthz", http.HandlerFunc(healthcheck.Live))
+	mux.Router.Get("/readyz", http.HandlerFunc(healthcheck.Ready))
 }
 
 type HealthCheck struct {
 	logger       gaivota.Logger
-	dependencies []gaivota.HealthChecker
+	dependencies []Dependency
+}
+
+// Health of the service, or of one of its dependencies
+const (
+	HealthOK = "ok"
+	// Only dependencies that are not critical are down
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+type health struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks,omitempty"`
+}
+
+type checkResult struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Critical bool    `json:"critical"`
+	Latency  float64 `json:"latencyMs"`
+	Error    string  `json:"error,omitempty"`
 }
 
-func (hc *HealthCheck) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	hc.logger.Log(gaivota.LogLevelInfo, "Handle ping endpoint")
+// Ping answers `pong` when every critical dependency is up
+func (hc *HealthCheck) Ping(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), hc.logger).Log(gaivota.LogLevelDebug, "Handle ping endpoint")
 
-	for _, dep := range hc.dependencies {
-		msg, err := dep.Ping()
-		if err != nil {
-			hc.logger.Log(gaivota.LogLevelInfo, "Error while pinging %s: %v\n", reflect.TypeOf(dep).Name(), err)
-			http.Error(rw, msg, http.StatusInternalServerError)
+	for _, result := range hc.check(req.Context()) {
+		if result.Status != HealthOK && result.Critical {
+			httpError(rw, "Could not reach "+result.Name, http.StatusInternalServerError)
 			return
 		}
 	}
@@ -36,3 +74,77 @@ func (hc *HealthCheck) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	rw.WriteHeader(http.StatusOK)
 	rw.Write([]byte("pong"))
 }
+
+// Live answers as long as the server runs, without checking dependencies:
+// restarting the server would not bring them back
+func (hc *HealthCheck) Live(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), hc.logger).Log(gaivota.LogLevelDebug, "Handle GET Liveness")
+
+	writeJSON(rw, http.StatusOK, health{Status: HealthOK})
+}
+
+// Ready checks every dependency at once, answering 503 when a critical one
+// is down
+func (hc *HealthCheck) Ready(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), hc.logger).Log(gaivota.LogLevelDebug, "Handle GET Readiness")
+
+	report := health{Status: HealthOK, Checks: hc.check(req.Context())}
+	for _, result := range report.Checks {
+		if result.Status == HealthOK {
+			continue
+		}
+
+		if result.Critical {
+			report.Status = HealthDown
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+	}
+
+	status := http.StatusOK
+	if report.Status == HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(rw, status, report)
+}
+
+// Checks the dependencies concurrently, each within its timeout
+func (hc *HealthCheck) check(ctx context.Context) []checkResult {
+	results := make([]checkResult, len(hc.dependencies))
+
+	var wg sync.WaitGroup
+	for i, dependency := range hc.dependencies {
+		wg.Add(1)
+		go func(i int, dependency Dependency) {
+			defer wg.Done()
+
+			timeout := dependency.Timeout
+			if timeout <= 0 {
+				timeout = DefaultHealthTimeout
+			}
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := dependency.Checker.Ping(checkCtx)
+
+			results[i] = checkResult{
+				Name:     dependency.Checker.Name(),
+				Status:   HealthOK,
+				Critical: dependency.Critical,
+				Latency:  float64(time.Since(start).Microseconds()) / 1000,
+			}
+
+			if err != nil {
+				results[i].Status = HealthDown
+				results[i].Error = err.Error()
+				requestLogger(ctx, hc.logger).Log(gaivota.LogLevelWarn, "Error while pinging %s: %v", results[i].Name, err)
+			}
+		}(i, dependency)
+	}
+	wg.Wait()
+
+	return results
+}
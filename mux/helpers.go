@@ -0,0 +1,196 @@
+package mux
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/mux"
+)
+
+// Reads an integer path param from the request
+func intParam(req *http.Request, name string) (int, error) {
+	value, err := strconv.Atoi(mux.PathParams(req)[name])
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+
+	return value, nil
+}
+
+// Decodes the request body into v, rejecting unknown fields. Errors are
+// gaivota.ErrInvalid, about the field when the JSON is well formed.
+func decodeJSON(req *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var fields gaivota.FieldErrors
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return &gaivota.Error{Kind: gaivota.ErrInvalid, Message: "empty request body", Err: err}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		fields.Add(typeErr.Field, "must be %s", jsonType(typeErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		fields.Add(field, "is not a known field")
+	}
+
+	return &gaivota.Error{Kind: gaivota.ErrInvalid, Message: "malformed request body", Fields: fields, Err: err}
+}
+
+// Names the JSON type Go decodes into t, for decoding errors
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+
+	return "an object"
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
+
+// Reads an optional time query param, either RFC 3339 or a plain date.
+// Returns the zero time when the param is missing, and gaivota.ErrInvalid
+// about the param when it is malformed.
+func timeQuery(req *http.Request, name string) (time.Time, error) {
+	t, _, err := parseTimeQuery(req, name)
+	return t, err
+}
+
+// Reads the optional end of a period, like timeQuery, except that a plain
+// date is the end of that day (UTC), so periods include the day they end on
+func endQuery(req *http.Request, name string) (time.Time, error) {
+	t, date, err := parseTimeQuery(req, name)
+	if date {
+		// Stores keep microseconds
+		t = t.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+
+	return t, err
+}
+
+// Parses the time query param, telling whether it was a plain date
+func parseTimeQuery(req *http.Request, name string) (time.Time, bool, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		var errs gaivota.FieldErrors
+		errs.Add(name, "must be a RFC 3339 time or a date")
+
+		return time.Time{}, false, errs.Err("invalid query")
+	}
+
+	return t, true, nil
+}
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// Reads the list options from the query params: limit, cursor, sort,
+// direction (asc or desc), from, to, operation, exchange, kind and symbol. Sort
+// fields and cursors are checked by the stores. Errors are gaivota.ErrInvalid,
+// about the param.
+func listOptions(req *http.Request) (gaivota.ListOptions, error) {
+	query := req.URL.Query()
+	opts := gaivota.ListOptions{
+		Limit:    defaultListLimit,
+		Cursor:   query.Get("cursor"),
+		Sort:     query.Get("sort"),
+		Exchange: query.Get("exchange"),
+		Symbol:   query.Get("symbol"),
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return opts, gaivota.InvalidListOption("limit", "must be an integer between 1 and %d", maxListLimit)
+		}
+		opts.Limit = limit
+	}
+
+	switch query.Get("direction") {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, gaivota.InvalidListOption("direction", "must be asc or desc")
+	}
+
+	switch operation := gaivota.OrderOperation(query.Get("operation")); operation {
+	case "", gaivota.OrderOperationBuy, gaivota.OrderOperationSell:
+		opts.Operation = operation
+	default:
+		return opts, gaivota.InvalidListOption("operation", "must be %s or %s", gaivota.OrderOperationBuy, gaivota.OrderOperationSell)
+	}
+
+	if kind := gaivota.EventKind(query.Get("kind")); kind != "" {
+		if !kind.Valid() {
+			return opts, gaivota.InvalidListOption("kind", "must be one of %v", gaivota.EventKinds)
+		}
+		opts.Kind = kind
+	}
+
+	var err error
+	if opts.From, err = timeQuery(req, "from"); err != nil {
+		return opts, err
+	}
+
+	if opts.To, err = endQuery(req, "to"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// Writes a page of a list as a JSON array. The next page's URL, when there
+// is one, goes in the Link header.
+func writePage(rw http.ResponseWriter, req *http.Request, items interface{}, next string) {
+	if next != "" {
+		link := *req.URL
+		query := link.Query()
+		query.Set("cursor", next)
+		link.RawQuery = query.Encode()
+
+		rw.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", link.String()))
+	}
+
+	writeJSON(rw, http.StatusOK, items)
+}
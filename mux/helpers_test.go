@@ -0,0 +1,48 @@
+package mux
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/log"
+)
+
+func TestInvalidListOptions(t *testing.T) {
+	handler := &OrderHandler{logger: log.New(ioutil.Discard, gaivota.LogLevelError, log.FormatText)}
+
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"limit=0", "limit"},
+		{"limit=ten", "limit"},
+		{"direction=up", "direction"},
+		{"operation=swap", "operation"},
+		{"kind=theft", "kind"},
+		{"from=yesterday", "from"},
+		{"to=2021-13-01", "to"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			handler.All(rw, httptest.NewRequest(http.MethodGet, "/orders?"+test.query, nil))
+
+			if rw.Code != http.StatusBadRequest {
+				t.Fatalf("Answered %d, expected 400", rw.Code)
+			}
+
+			var envelope errorEnvelope
+			if err := json.NewDecoder(rw.Body).Decode(&envelope); err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Error.Code != "invalid" || len(envelope.Error.Fields) != 1 || envelope.Error.Fields[0].Field != test.field {
+				t.Errorf("Answered %+v, expected an invalid %s field", envelope.Error, test.field)
+			}
+		})
+	}
+}
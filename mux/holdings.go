@@ -0,0 +1,221 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/leoschet/gaivota"
+)
+
+func InitHoldingRouter(mux *Mux, store gaivota.HoldingStore, logger gaivota.Logger) {
+	holdingHandler := &HoldingHandler{
+		logger:       logger,
+		HoldingStore: store,
+	}
+
+	router := mux.subrouter("/holdings")
+
+	router.Get("/", http.HandlerFunc(holdingHandler.All))
+	router.Post("/", http.HandlerFunc(holdingHandler.Add))
+	router.Get("/:holdingId", http.HandlerFunc(holdingHandler.Get))
+	router.Put("/:holdingId", http.HandlerFunc(holdingHandler.Update))
+	router.Delete("/:holdingId", http.HandlerFunc(holdingHandler.Delete))
+
+	mux.subrouter("/users").Get("/:userId/holdings", http.HandlerFunc(holdingHandler.GetByUserID))
+	mux.subrouter("/wallets").Get("/:walletId/holdings", http.HandlerFunc(holdingHandler.GetByWalletID))
+	mux.subrouter("/positions").Get("/:positionId/holdings", http.HandlerFunc(holdingHandler.GetByPositionID))
+}
+
+type HoldingHandler struct {
+	logger       gaivota.Logger
+	HoldingStore gaivota.HoldingStore
+}
+
+func (handler *HoldingHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Holdings")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	holdings, next, err := handler.HoldingStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Holdings: %v", err)
+		writeError(rw, "Error while getting Holdings", err)
+		return
+	}
+
+	writePage(rw, req, holdings, next)
+}
+
+func (handler *HoldingHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Holding")
+
+	holdingId, err := intParam(req, "holdingId")
+
+	if err != nil {
+		httpError(rw, "Holding ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	holding, err := handler.HoldingStore.Get(req.Context(), holdingId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Holding %v: %v", holdingId, err)
+		writeError(rw, "Error while getting Holding", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, holding)
+}
+
+func (handler *HoldingHandler) GetByUserID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET User Holdings")
+
+	userId, err := intParam(req, "userId")
+
+	if err != nil {
+		httpError(rw, "User ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	holdings, err := handler.HoldingStore.GetByUserID(req.Context(), userId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Holdings for User %v: %v", userId, err)
+		writeError(rw, "Error while getting Holdings", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, holdings)
+}
+
+func (handler *HoldingHandler) GetByWalletID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Wallet Holdings")
+
+	walletId, err := intParam(req, "walletId")
+
+	if err != nil {
+		httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	holdings, err := handler.HoldingStore.GetByWalletID(req.Context(), walletId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Holdings for Wallet %v: %v", walletId, err)
+		writeError(rw, "Error while getting Holdings", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, holdings)
+}
+
+func (handler *HoldingHandler) GetByPositionID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Holdings")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	holdings, err := handler.HoldingStore.GetByPositionID(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Holdings for Position %v: %v", positionId, err)
+		writeError(rw, "Error while getting Holdings", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, holdings)
+}
+
+func (handler *HoldingHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Holding")
+
+	var holding gaivota.Holding
+	err := decodeJSON(req, &holding)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /holdings request body: %v", err)
+		writeError(rw, "Error while decoding holding data", err)
+		return
+	}
+
+	if err := holding.Validate(); err != nil {
+		writeError(rw, "Invalid holding", err)
+		return
+	}
+
+	newHolding, err := handler.HoldingStore.Add(req.Context(), &holding)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Holding: %v", err)
+		writeError(rw, "Error while adding Holding", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newHolding)
+}
+
+func (handler *HoldingHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Holding")
+
+	holdingId, err := intParam(req, "holdingId")
+
+	if err != nil {
+		httpError(rw, "Holding ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var holding gaivota.Holding
+	err = decodeJSON(req, &holding)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /holdings request body: %v", err)
+		writeError(rw, "Error while decoding holding data", err)
+		return
+	}
+
+	if err := holding.Validate(); err != nil {
+		writeError(rw, "Invalid holding", err)
+		return
+	}
+
+	holding.ID = holdingId
+	err = handler.HoldingStore.Update(req.Context(), &holding)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Holding %v: %v", holdingId, err)
+		writeError(rw, "Error while updating Holding", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &holding)
+}
+
+func (handler *HoldingHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE Holding")
+
+	holdingId, err := intParam(req, "holdingId")
+
+	if err != nil {
+		httpError(rw, "Holding ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.HoldingStore.Delete(req.Context(), holdingId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting Holding %v: %v", holdingId, err)
+		writeError(rw, "Error while deleting Holding", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
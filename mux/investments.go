@@ -0,0 +1,240 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/performance"
+)
+
+func InitInvestmentRouter(mux *Mux, store gaivota.InvestmentStore, calculator *performance.Calculator, logger gaivota.Logger) {
+	investmentHandler := &InvestmentHandler{
+		logger:          logger,
+		calculator:      calculator,
+		InvestmentStore: store,
+	}
+
+	router := mux.subrouter("/investments")
+
+	router.Get("/", http.HandlerFunc(investmentHandler.All))
+	router.Post("/", http.HandlerFunc(investmentHandler.Add))
+	router.Get("/:investmentId", http.HandlerFunc(investmentHandler.Get))
+	router.Put("/:investmentId", http.HandlerFunc(investmentHandler.Update))
+	router.Delete("/:investmentId", http.HandlerFunc(investmentHandler.Delete))
+	router.Get("/:investmentId/returns", http.HandlerFunc(investmentHandler.Returns))
+
+	mux.subrouter("/users").Get("/:userId/investments", http.HandlerFunc(investmentHandler.GetByUserID))
+	mux.subrouter("/portfolios").Get("/:portfolioId/investments", http.HandlerFunc(investmentHandler.GetByPortfolioID))
+}
+
+type InvestmentHandler struct {
+	logger          gaivota.Logger
+	calculator      *performance.Calculator
+	InvestmentStore gaivota.InvestmentStore
+}
+
+// Returns measures time-weighted and money-weighted returns between the `from`
+// and `to` query params, defaulting to since the first order until now
+func (handler *InvestmentHandler) Returns(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Investment Returns")
+
+	investmentId, err := intParam(req, "investmentId")
+
+	if err != nil {
+		httpError(rw, "Investment ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	from, err := timeQuery(req, "from")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	to, err := endQuery(req, "to")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	returns, err := handler.calculator.Investment(req.Context(), investmentId, from, to)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while measuring Investment %v returns: %v", investmentId, err)
+		writeError(rw, "Error while measuring Investment returns", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, returns)
+}
+
+func (handler *InvestmentHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Investments")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	investments, next, err := handler.InvestmentStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Investments: %v", err)
+		writeError(rw, "Error while getting Investments", err)
+		return
+	}
+
+	writePage(rw, req, investments, next)
+}
+
+func (handler *InvestmentHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Investment")
+
+	investmentId, err := intParam(req, "investmentId")
+
+	if err != nil {
+		httpError(rw, "Investment ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	investment, err := handler.InvestmentStore.Get(req.Context(), investmentId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Investment %v: %v", investmentId, err)
+		writeError(rw, "Error while getting Investment", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, investment)
+}
+
+func (handler *InvestmentHandler) GetByUserID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET User Investments")
+
+	userId, err := intParam(req, "userId")
+
+	if err != nil {
+		httpError(rw, "User ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	investments, err := handler.InvestmentStore.GetByUserID(req.Context(), userId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Investments for User %v: %v", userId, err)
+		writeError(rw, "Error while getting Investments", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, investments)
+}
+
+func (handler *InvestmentHandler) GetByPortfolioID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Portfolio Investments")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	investments, err := handler.InvestmentStore.GetByPortfolioID(req.Context(), portfolioId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Investments for Portfolio %v: %v", portfolioId, err)
+		writeError(rw, "Error while getting Investments", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, investments)
+}
+
+func (handler *InvestmentHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Investment")
+
+	var investment gaivota.Investment
+	err := decodeJSON(req, &investment)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /investments request body: %v", err)
+		writeError(rw, "Error while decoding investment data", err)
+		return
+	}
+
+	if err := investment.Validate(); err != nil {
+		writeError(rw, "Invalid investment", err)
+		return
+	}
+
+	newInvestment, err := handler.InvestmentStore.Add(req.Context(), &investment)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Investment: %v", err)
+		writeError(rw, "Error while adding Investment", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newInvestment)
+}
+
+func (handler *InvestmentHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Investment")
+
+	investmentId, err := intParam(req, "investmentId")
+
+	if err != nil {
+		httpError(rw, "Investment ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var investment gaivota.Investment
+	err = decodeJSON(req, &investment)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /investments request body: %v", err)
+		writeError(rw, "Error while decoding investment data", err)
+		return
+	}
+
+	if err := investment.Validate(); err != nil {
+		writeError(rw, "Invalid investment", err)
+		return
+	}
+
+	investment.ID = investmentId
+	err = handler.InvestmentStore.Update(req.Context(), &investment)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Investment %v: %v", investmentId, err)
+		writeError(rw, "Error while updating Investment", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &investment)
+}
+
+func (handler *InvestmentHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE Investment")
+
+	investmentId, err := intParam(req, "investmentId")
+
+	if err != nil {
+		httpError(rw, "Investment ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.InvestmentStore.Delete(req.Context(), investmentId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting Investment %v: %v", investmentId, err)
+		writeError(rw, "Error while deleting Investment", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
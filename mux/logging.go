@@ -0,0 +1,80 @@
+package mux
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/log"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Request IDs set by callers (e.g. a proxy) are kept when they are this safe
+// to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestLog gives every request an ID, the caller's X-Request-ID header or a
+// random one, sent back in the response's X-Request-ID header. Lines logged
+// while serving the request carry the ID (see requestLogger), and a line is
+// logged once the request is served, with its status and duration.
+func RequestLog(next http.Handler, logger gaivota.Logger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		requestId := req.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(requestId) {
+			requestId = newRequestID()
+		}
+		rw.Header().Set(requestIDHeader, requestId)
+
+		ctx := log.WithFields(req.Context(), "requestId", requestId)
+		recorder := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, req.WithContext(ctx))
+
+		requestLogger(ctx, logger).With(
+			"method", req.Method,
+			"path", req.URL.Path,
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"duration", time.Since(start),
+		).Log(gaivota.LogLevelInfo, "Served %s %s", req.Method, req.URL.Path)
+	})
+}
+
+// Returns the logger adding the request's fields to every line: its ID and,
+// once authenticated, its user
+func requestLogger(ctx context.Context, logger gaivota.Logger) gaivota.Logger {
+	return log.FromContext(ctx, logger)
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+// Records the status and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(b []byte) (int, error) {
+	n, err := recorder.ResponseWriter.Write(b)
+	recorder.bytes += n
+
+	return n, err
+}
@@ -0,0 +1,75 @@
+package mux
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/metrics"
+	"github.com/leoschet/mux"
+)
+
+func InitMetricsRouter(mux *Mux, registry *metrics.Registry, logger gaivota.Logger) {
+	metricsHandler := &MetricsHandler{
+		logger:   logger,
+		registry: registry,
+	}
+
+	mux.Router.Get("/metrics", http.HandlerFunc(metricsHandler.Get))
+}
+
+type MetricsHandler struct {
+	logger   gaivota.Logger
+	registry *metrics.Registry
+}
+
+// Get writes the metrics in Prometheus' text format
+func (handler *MetricsHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Metrics")
+
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	if err := handler.registry.Write(req.Context(), rw); err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while writing Metrics: %v", err)
+	}
+}
+
+// Measure counts and times requests per method, route and status. Routes
+// are the registered paths, e.g. `/positions/:positionId`, so that IDs do not
+// make a series per entity. Requests matching no route count as `unmatched`.
+func Measure(next http.Handler, registry *metrics.Registry, routes []Route) http.Handler {
+	requests := registry.Counter("gaivota_http_requests_total", "HTTP requests served.", "method", "route", "status")
+	duration := registry.Histogram("gaivota_http_request_duration_seconds", "Duration of HTTP requests.", metrics.DefaultBuckets, "method", "route", "status")
+
+	// Paths without params first, so `/auth/keys` wins over `/auth/:any`
+	paths := make([]mux.Path, 0, len(routes))
+	for _, route := range routes {
+		paths = append(paths, mux.NewPath(route.Path))
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return strings.Count(string(paths[i]), ":") < strings.Count(string(paths[j]), ":")
+	})
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, req)
+
+		route := "unmatched"
+		reqPath := mux.NewPath(req.URL.Path)
+		for _, path := range paths {
+			if path.Match(reqPath) {
+				route = string(path)
+				break
+			}
+		}
+
+		status := strconv.Itoa(recorder.status)
+		requests.Inc(req.Method, route, status)
+		duration.Observe(time.Since(start).Seconds(), req.Method, route, status)
+	})
+}
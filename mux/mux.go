@@ -1,21 +1,124 @@
 package mux
 
 import (
+	"net/http"
+	"sort"
+
 	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/auth"
+	"github.com/leoschet/gaivota/metrics"
+	"github.com/leoschet/gaivota/performance"
+	"github.com/leoschet/gaivota/valuation"
 	"github.com/leoschet/mux"
 )
 
 func New(prefix string) *Mux {
+	router := mux.NewRouter(prefix)
+	// Subrouters copy the error handlers when created
+	router.Error = routeErrors
+
 	return &Mux{
-		Router: mux.NewRouter(prefix),
+		Router:     router,
+		Handler:    router,
+		SignUps:    true,
+		subrouters: make(map[string]*mux.Router),
 	}
 }
 
 type Mux struct {
 	Router *mux.Router
+	// Router wrapped in middlewares, to be served
+	Handler http.Handler
+	// Let anyone create an account with POST /users, set before InitRouter
+	SignUps bool
+	// Serves GET /metrics and measures requests when set before InitRouter
+	Metrics    *metrics.Registry
+	subrouters map[string]*mux.Router
 }
 
-func (mux *Mux) InitRouter(client *gaivota.Client, dependencies []gaivota.HealthChecker, logger gaivota.Logger) {
+// InitRouter registers every endpoint. Requests are logged and
+// authenticated, and the stores scoped to the caller's data.
+func (mux *Mux) InitRouter(client *gaivota.Client, tokens *auth.Tokens, dependencies []Dependency, logger gaivota.Logger) {
+	authenticator := auth.NewAuthenticator(tokens, client.UserStore, client.APIKeyStore)
+	client = auth.Scope(client)
+
+	valuer := valuation.New(client)
+	calculator := performance.New(client, valuer)
+
 	InitHealthCheckRouter(mux, dependencies, logger)
-	InitPortfolioRouter(mux, client.PortfolioStore, logger)
+	InitAuthRouter(mux, client, tokens, logger)
+	InitUserRouter(mux, client.UserStore, mux.SignUps, logger)
+	InitPortfolioRouter(mux, client, valuer, calculator, logger)
+	InitWalletRouter(mux, client.WalletStore, valuer, logger)
+	InitInvestmentRouter(mux, client.InvestmentStore, calculator, logger)
+	InitPositionRouter(mux, client, valuer, logger)
+	InitHoldingRouter(mux, client.HoldingStore, logger)
+	InitTransferRouter(mux, client, logger)
+	InitOrderRouter(mux, client, logger)
+	InitEventRouter(mux, client, logger)
+	InitPriceRouter(mux, client.PriceStore, client.PriceSource, logger)
+	InitFXRateRouter(mux, client.FXRateStore, logger)
+	InitAuditRouter(mux, client.AuditStore, logger)
+
+	if mux.Metrics != nil {
+		InitMetricsRouter(mux, mux.Metrics, logger)
+	}
+
+	// Last, so the OpenAPI document covers every route
+	InitDocsRouter(mux, logger)
+
+	mux.Handler = Authenticate(mux.Router, authenticator, logger)
+	if mux.Metrics != nil {
+		mux.Handler = Measure(mux.Handler, mux.Metrics, mux.Routes())
+	}
+	mux.Handler = RequestLog(mux.Handler, logger)
+}
+
+// A registered path and the methods it answers
+type Route struct {
+	Path    string
+	Methods []string
+}
+
+// Routes lists the registered routes, sorted by path
+func (m *Mux) Routes() []Route {
+	var routes []Route
+
+	var collect func(router *mux.Router)
+	collect = func(router *mux.Router) {
+		for _, handler := range router.Routes {
+			switch handler := handler.(type) {
+			case *mux.Router:
+				collect(handler)
+			case *mux.Route:
+				route := Route{Path: string(handler.Path)}
+				for method := range handler.Handlers {
+					route.Methods = append(route.Methods, method)
+				}
+				sort.Strings(route.Methods)
+				routes = append(routes, route)
+			}
+		}
+	}
+	collect(m.Router)
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Path < routes[j].Path
+	})
+
+	return routes
+}
+
+// Returns the subrouter for the given prefix, creating it on first use.
+// Nested routes (e.g. `/users/:userId/portfolios`) must be registered in the
+// subrouter owning the first path segment, so all routers share this lookup.
+func (m *Mux) subrouter(prefix string) *mux.Router {
+	if router, ok := m.subrouters[prefix]; ok {
+		return router
+	}
+
+	router := m.Router.NewSubrouter(prefix)
+	m.subrouters[prefix] = router
+
+	return router
 }
@@ -0,0 +1,423 @@
+package mux
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/shopspring/decimal"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Redoc 2.0.0-rc.59 (MIT license, see redoc.standalone.js.LICENSE.txt), as
+// vendored by github.com/mvrilo/go-redoc v0.1.4, so the page runs no script
+// fetched from elsewhere
+//
+//go:embed redoc.standalone.js
+var redocBundle []byte
+
+// InitDocsRouter serves the OpenAPI document of the registered routes at
+// GET /openapi.json, and a page browsing it at GET /docs, along with the
+// Redoc bundle it loads. It must be called after every other router, so the
+// document covers their routes.
+func InitDocsRouter(mux *Mux, logger gaivota.Logger) {
+	docsHandler := &DocsHandler{logger: logger}
+
+	mux.Router.Get("/openapi.json", http.HandlerFunc(docsHandler.Spec))
+	mux.Router.Get("/docs", http.HandlerFunc(docsHandler.Page))
+	mux.Router.Get("/docs/redoc.standalone.js", http.HandlerFunc(docsHandler.Redoc))
+
+	docsHandler.document = openAPI(mux.Routes())
+}
+
+type DocsHandler struct {
+	logger   gaivota.Logger
+	document *openAPIDocument
+}
+
+func (handler *DocsHandler) Spec(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET OpenAPI")
+
+	writeJSON(rw, http.StatusOK, handler.document)
+}
+
+// Page loads the document in Redoc
+func (handler *DocsHandler) Page(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Docs")
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(docsPage)
+}
+
+func (handler *DocsHandler) Redoc(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Redoc")
+
+	rw.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(redocBundle)
+}
+
+// See https://spec.openapis.org/oas/v3.0.3
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Security   []map[string][]string                   `json:"security"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*schema                `json:"schemas"`
+	SecuritySchemes map[string]map[string]interface{} `json:"securitySchemes"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []parameter                 `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	// Empty for public routes, the document's when nil
+	Security *[]map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                    `json:"required"`
+	Content  map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                   `json:"description"`
+	Headers     map[string]openAPIHeader `json:"headers,omitempty"`
+	Content     map[string]openAPIMedia  `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+type openAPIMedia struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+// Builds the document of the routes from their operations (see operations).
+// Routes without one are left out, which TestOpenAPICoversRoutes prevents.
+func openAPI(routes []Route) *openAPIDocument {
+	schemas := newSchemaSet()
+
+	document := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "Gaivota",
+			Description: "Portfolio management API. Errors are answered with the error envelope, and lists with a page of at most `limit` items, the next page's URL in the Link header.",
+			Version:     "1",
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+		Paths:    make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents{
+			Schemas: schemas.components,
+			SecuritySchemes: map[string]map[string]interface{}{
+				"bearer": {
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "A session token from POST /auth/login, or an API key",
+				},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		for _, method := range route.Methods {
+			op, ok := findOperation(method, route.Path)
+			if !ok {
+				continue
+			}
+
+			openAPIPath := pathTemplate(route.Path)
+			if document.Paths[openAPIPath] == nil {
+				document.Paths[openAPIPath] = make(map[string]*openAPIOperation)
+			}
+			document.Paths[openAPIPath][strings.ToLower(method)] = op.spec(schemas)
+		}
+	}
+
+	return document
+}
+
+// Returns the operation documenting the route's method
+func findOperation(method string, routePath string) (operation, bool) {
+	for _, op := range operations {
+		if op.method == method && op.path == routePath {
+			return op, true
+		}
+	}
+
+	return operation{}, false
+}
+
+// Writes path params as OpenAPI does, e.g. `/portfolios/{portfolioId}`
+func pathTemplate(routePath string) string {
+	segments := strings.Split(routePath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Documents the path params: IDs are integers (e.g. portfolioId), others
+// are currency codes and token symbols
+func pathParams(routePath string) []parameter {
+	var params []parameter
+	for _, segment := range strings.Split(routePath, "/") {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+
+		name := segment[1:]
+		param := parameter{Name: name, In: "path", Required: true, Schema: &schema{Type: "string"}}
+
+		switch {
+		case strings.HasSuffix(name, "Id"):
+			param.Description = "ID of the " + strings.TrimSuffix(name, "Id")
+			param.Schema = &schema{Type: "integer"}
+		case name == "symbol":
+			param.Description = "Token symbol, e.g. BTC"
+		default:
+			param.Description = "Currency code, e.g. USD"
+		}
+
+		params = append(params, param)
+	}
+
+	return params
+}
+
+func (op operation) spec(schemas *schemaSet) *openAPIOperation {
+	spec := &openAPIOperation{
+		OperationID: op.id,
+		Summary:     op.summary,
+		Tags:        []string{strings.Split(strings.TrimPrefix(op.path, "/"), "/")[0]},
+		Parameters:  append(pathParams(op.path), op.query...),
+		Responses: map[string]*openAPIResponse{
+			"default": {
+				Description: "Error",
+				Content:     map[string]openAPIMedia{"application/json": {Schema: schemas.of(reflect.TypeOf(errorEnvelope{}))}},
+			},
+		},
+	}
+
+	if publicRoute(op.method, op.path) {
+		spec.Security = &[]map[string][]string{}
+	}
+
+	if op.body != nil {
+		spec.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  map[string]openAPIMedia{"application/json": {Schema: schemas.of(reflect.TypeOf(op.body))}},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	response := &openAPIResponse{Description: http.StatusText(status)}
+	if op.response != nil {
+		mediaType := op.mediaType
+		if mediaType == "" {
+			mediaType = "application/json"
+		}
+		response.Content = map[string]openAPIMedia{mediaType: {Schema: schemas.of(reflect.TypeOf(op.response))}}
+	}
+
+	if op.page {
+		spec.Parameters = append(spec.Parameters, pageParams...)
+		response.Headers = map[string]openAPIHeader{
+			"Link": {Description: "URL of the next page, as `<url>; rel=\"next\"`, unless this is the last one", Schema: &schema{Type: "string"}},
+		}
+	}
+
+	spec.Responses[strconv.Itoa(status)] = response
+
+	return spec
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	decimalType    = reflect.TypeOf(decimal.Decimal{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Values of the enums, which are strings in JSON
+var enums = map[reflect.Type][]string{
+	reflect.TypeOf(gaivota.CostBasisMethod("")):  {string(gaivota.CostBasisFIFO), string(gaivota.CostBasisLIFO), string(gaivota.CostBasisHIFO), string(gaivota.CostBasisAverage)},
+	reflect.TypeOf(gaivota.OrderOperation("")):   {string(gaivota.OrderOperationBuy), string(gaivota.OrderOperationSell)},
+	reflect.TypeOf(gaivota.OrderType("")):        {string(gaivota.OrderTypeLimit), string(gaivota.OrderTypeMarket)},
+	reflect.TypeOf(gaivota.EventKind("")):        eventKinds(),
+	reflect.TypeOf(gaivota.AuditAction("")):      {string(gaivota.AuditActionInsert), string(gaivota.AuditActionUpdate), string(gaivota.AuditActionDelete)},
+	reflect.TypeOf(gaivota.ActorType("")):        {string(gaivota.ActorTypeUser), string(gaivota.ActorTypeAPIKey), string(gaivota.ActorTypeCLI), string(gaivota.ActorTypeSystem)},
+	reflect.TypeOf(accounting.HoldingPeriod("")): {string(accounting.HoldingPeriodShort), string(accounting.HoldingPeriodLong)},
+}
+
+func eventKinds() []string {
+	kinds := make([]string, len(gaivota.EventKinds))
+	for i, kind := range gaivota.EventKinds {
+		kinds[i] = string(kind)
+	}
+
+	return kinds
+}
+
+// Schemas of Go types as encoding/json writes them. Named structs are
+// components, referenced by their name, qualified by their package's when
+// another package has a struct of the same name.
+type schemaSet struct {
+	components map[string]*schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaSet() *schemaSet {
+	return &schemaSet{
+		components: make(map[string]*schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+func (schemas *schemaSet) of(t reflect.Type) *schema {
+	switch t {
+	case timeType:
+		return &schema{Type: "string", Format: "date-time"}
+	case decimalType:
+		return &schema{Type: "string", Format: "decimal", Description: "Decimal number, as a string to keep its precision"}
+	case rawMessageType:
+		return &schema{Description: "Any JSON value"}
+	}
+
+	if values, ok := enums[t]; ok {
+		return &schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemas.of(t.Elem())
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: schemas.of(t.Elem())}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: schemas.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return schemas.object(t)
+		}
+
+		name, ok := schemas.names[t]
+		if !ok {
+			name = schemas.name(t)
+			// Registered before its fields, for types referencing themselves
+			schemas.components[name] = &schema{}
+			*schemas.components[name] = *schemas.object(t)
+		}
+
+		return &schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &schema{Description: "Any JSON value"}
+}
+
+// Names a struct's component, e.g. newAPIKey is NewAPIKey
+func (schemas *schemaSet) name(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := schemas.components[name]; taken {
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	schemas.names[t] = name
+
+	return name
+}
+
+// The fields of the struct by their JSON name. Fields of embedded structs
+// are the struct's own, as encoding/json writes them.
+func (schemas *schemaSet) object(t reflect.Type) *schema {
+	object := &schema{Type: "object", Properties: make(map[string]*schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embedded, property := range schemas.object(field.Type).Properties {
+				object.Properties[embedded] = property
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		object.Properties[name] = schemas.of(field.Type)
+	}
+
+	return object
+}
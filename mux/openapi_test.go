@@ -0,0 +1,88 @@
+package mux
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/auth"
+	"github.com/leoschet/gaivota/inmem"
+	"github.com/leoschet/gaivota/log"
+	"github.com/leoschet/gaivota/metrics"
+)
+
+// Registers every route, as the server does with metrics enabled
+func newTestMux(t *testing.T) *Mux {
+	tokens, err := auth.NewTokens("abcdefghijklmnopqrstuvwxyz0123456789", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New("/")
+	m.Metrics = metrics.NewRegistry()
+	m.InitRouter(inmem.New().NewClient(), tokens, nil, log.New(ioutil.Discard, gaivota.LogLevelError, log.FormatText))
+
+	return m
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	m := newTestMux(t)
+
+	registered := make(map[string]bool)
+	for _, route := range m.Routes() {
+		for _, method := range route.Methods {
+			registered[method+" "+route.Path] = true
+
+			if _, ok := findOperation(method, route.Path); !ok {
+				t.Errorf("%s %s has no operation in the OpenAPI document", method, route.Path)
+			}
+		}
+	}
+
+	for _, op := range operations {
+		if !registered[op.method+" "+op.path] {
+			t.Errorf("Operation %s documents %s %s, which is not a route", op.id, op.method, op.path)
+		}
+	}
+}
+
+func TestOpenAPIIsServed(t *testing.T) {
+	m := newTestMux(t)
+
+	rw := httptest.NewRecorder()
+	m.Handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json answered %d", rw.Code)
+	}
+
+	var document openAPIDocument
+	if err := json.NewDecoder(rw.Body).Decode(&document); err != nil {
+		t.Fatal(err)
+	}
+
+	operationIds := make(map[string]bool)
+	for path, methods := range document.Paths {
+		for method, op := range methods {
+			if operationIds[op.OperationID] {
+				t.Errorf("%s %s repeats operation ID %s", method, path, op.OperationID)
+			}
+			operationIds[op.OperationID] = true
+		}
+	}
+
+	if len(operationIds) != len(operations) {
+		t.Errorf("Document has %d operations, expected %d", len(operationIds), len(operations))
+	}
+
+	if _, ok := document.Paths["/portfolios/{portfolioId}"]["get"]; !ok {
+		t.Errorf("Document has no GET /portfolios/{portfolioId}")
+	}
+
+	if _, ok := document.Components.Schemas["Portfolio"]; !ok {
+		t.Errorf("Document has no Portfolio schema")
+	}
+}
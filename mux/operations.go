@@ -0,0 +1,168 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/audit"
+	"github.com/leoschet/gaivota/fees"
+	"github.com/leoschet/gaivota/performance"
+	"github.com/leoschet/gaivota/snapshots"
+	"github.com/leoschet/gaivota/valuation"
+)
+
+// An operation documents a route's method in the OpenAPI document (see
+// openAPI). Bodies and responses are values of the types encoded, whose
+// schemas follow their JSON tags.
+type operation struct {
+	method  string
+	path    string
+	id      string
+	summary string
+	// Query params, besides the list options of pages
+	query []parameter
+	// Request body, nil for none
+	body interface{}
+	// Status of successful responses, 200 by default
+	status int
+	// Response body, nil for none
+	response interface{}
+	// Media type of the response, JSON by default
+	mediaType string
+	// Whether the response is a page of a list (see writePage)
+	page bool
+}
+
+func query(name string, description string, s *schema) parameter {
+	return parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+var (
+	stringSchema  = &schema{Type: "string"}
+	integerSchema = &schema{Type: "integer"}
+	// RFC 3339 times or plain dates (see timeQuery)
+	timeSchema = &schema{Type: "string", Description: "RFC 3339 time or date, e.g. 2021-06-01"}
+)
+
+func enumSchema(values ...string) *schema {
+	return &schema{Type: "string", Enum: values}
+}
+
+// List options of every page (see listOptions)
+var pageParams = []parameter{
+	query("limit", "Maximum number of items, 100 by default and at most 1000", integerSchema),
+	query("cursor", "Cursor of the page, from the Link header of the previous one", stringSchema),
+	query("sort", "Field to sort by, as named in JSON, id by default", stringSchema),
+	query("direction", "Sort direction", enumSchema("asc", "desc")),
+	query("from", "Only items executed, acquired or created since", timeSchema),
+	query("to", "Only items executed, acquired or created until, included. A date includes that whole day.", timeSchema),
+}
+
+var (
+	symbolParam   = query("symbol", "Only items of this token symbol", stringSchema)
+	atParam       = query("at", "Time to value at, now by default", timeSchema)
+	fromParam     = query("from", "Start of the period", timeSchema)
+	toParam       = query("to", "End of the period, included, now by default. A date ends the period with that day.", timeSchema)
+	intervalParam = query("interval", "Split the period by", enumSchema(snapshots.IntervalDay, snapshots.IntervalWeek, snapshots.IntervalMonth))
+	walletParam   = query("walletId", "Wallet holding the coins, whose holding is updated too", integerSchema)
+)
+
+// Every route's operations. A route without one fails TestOpenAPICoversRoutes.
+var operations = []operation{
+	{method: http.MethodGet, path: "/ping", id: "ping", summary: "Answers pong when every critical dependency is up", response: "", mediaType: "text/plain"},
+	{method: http.MethodGet, path: "/healthz", id: "live", summary: "Answers as long as the server runs", response: health{}},
+	{method: http.MethodGet, path: "/readyz", id: "ready", summary: "Checks every dependency, answering 503 when a critical one is down", response: health{}},
+	{method: http.MethodGet, path: "/metrics", id: "metrics", summary: "Metrics in Prometheus' text format", response: "", mediaType: "text/plain"},
+	{method: http.MethodGet, path: "/openapi.json", id: "openAPI", summary: "This document", response: map[string]interface{}{}},
+	{method: http.MethodGet, path: "/docs", id: "docs", summary: "Page browsing this document", response: "", mediaType: "text/html"},
+	{method: http.MethodGet, path: "/docs/redoc.standalone.js", id: "redoc", summary: "Redoc, which the docs page runs", response: "", mediaType: "text/javascript"},
+
+	{method: http.MethodPost, path: "/auth/login", id: "login", summary: "Exchanges an email and password for a session token", body: credentials{}, response: session{}},
+	{method: http.MethodGet, path: "/auth/me", id: "getMe", summary: "Gets the caller", response: gaivota.User{}},
+	{method: http.MethodPut, path: "/auth/password", id: "setPassword", summary: "Replaces the caller's password", body: credentials{}, status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/auth/keys", id: "listAPIKeys", summary: "Lists the caller's API keys, without the keys themselves", response: []gaivota.APIKey{}},
+	{method: http.MethodPost, path: "/auth/keys", id: "addAPIKey", summary: "Creates an API key, only returned now", body: keyName{}, status: http.StatusCreated, response: newAPIKey{}},
+	{method: http.MethodDelete, path: "/auth/keys/:keyId", id: "deleteAPIKey", summary: "Revokes an API key", status: http.StatusNoContent},
+
+	{method: http.MethodGet, path: "/users", id: "listUsers", summary: "Lists users", response: []gaivota.User{}, page: true},
+	{method: http.MethodPost, path: "/users", id: "addUser", summary: "Signs up, when sign ups are open", body: newUser{}, status: http.StatusCreated, response: gaivota.User{}},
+	{method: http.MethodGet, path: "/users/:userId", id: "getUser", summary: "Gets a user", response: gaivota.User{}},
+	{method: http.MethodPut, path: "/users/:userId", id: "updateUser", summary: "Updates a user", body: gaivota.User{}, response: gaivota.User{}},
+	{method: http.MethodDelete, path: "/users/:userId", id: "deleteUser", summary: "Deletes a user", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/users/:userId/portfolios", id: "listUserPortfolios", summary: "Lists a user's portfolios", response: []gaivota.Portfolio{}},
+	{method: http.MethodGet, path: "/users/:userId/wallets", id: "listUserWallets", summary: "Lists a user's wallets", response: []gaivota.Wallet{}},
+	{method: http.MethodGet, path: "/users/:userId/investments", id: "listUserInvestments", summary: "Lists a user's investments", response: []gaivota.Investment{}},
+	{method: http.MethodGet, path: "/users/:userId/holdings", id: "listUserHoldings", summary: "Lists a user's holdings", response: []gaivota.Holding{}},
+
+	{method: http.MethodGet, path: "/portfolios", id: "listPortfolios", summary: "Lists portfolios", response: []gaivota.Portfolio{}, page: true},
+	{method: http.MethodPost, path: "/portfolios", id: "addPortfolio", summary: "Creates a portfolio, the caller's when it has no user", body: gaivota.Portfolio{}, status: http.StatusCreated, response: gaivota.Portfolio{}},
+	{method: http.MethodGet, path: "/portfolios/:portfolioId", id: "getPortfolio", summary: "Gets a portfolio", response: gaivota.Portfolio{}},
+	{method: http.MethodPut, path: "/portfolios/:portfolioId", id: "updatePortfolio", summary: "Updates a portfolio", body: gaivota.Portfolio{}, response: gaivota.Portfolio{}},
+	{method: http.MethodDelete, path: "/portfolios/:portfolioId", id: "deletePortfolio", summary: "Deletes a portfolio", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/portfolios/:portfolioId/summary", id: "getPortfolioSummary", summary: "Values a portfolio, broken down by investment and wallet", query: []parameter{atParam}, response: valuation.PortfolioSummary{}},
+	{method: http.MethodGet, path: "/portfolios/:portfolioId/returns", id: "getPortfolioReturns", summary: "Measures a portfolio's returns, since its first order by default", query: []parameter{fromParam, toParam}, response: performance.Returns{}},
+	{method: http.MethodGet, path: "/portfolios/:portfolioId/history", id: "getPortfolioHistory", summary: "Lists a portfolio's daily snapshots, of the last year by default", query: []parameter{fromParam, toParam, intervalParam}, response: []gaivota.PortfolioSnapshot{}},
+	{method: http.MethodGet, path: "/portfolios/:portfolioId/fees", id: "getPortfolioFees", summary: "Sums the trading fees of a portfolio's orders per exchange", query: []parameter{fromParam, toParam, intervalParam}, response: fees.Summary{}},
+	{method: http.MethodGet, path: "/portfolios/:portfolioId/investments", id: "listPortfolioInvestments", summary: "Lists a portfolio's investments", response: []gaivota.Investment{}},
+
+	{method: http.MethodGet, path: "/wallets", id: "listWallets", summary: "Lists wallets", response: []gaivota.Wallet{}, page: true},
+	{method: http.MethodPost, path: "/wallets", id: "addWallet", summary: "Creates a wallet, the caller's when it has no user", body: gaivota.Wallet{}, status: http.StatusCreated, response: gaivota.Wallet{}},
+	{method: http.MethodGet, path: "/wallets/:walletId", id: "getWallet", summary: "Gets a wallet", response: gaivota.Wallet{}},
+	{method: http.MethodPut, path: "/wallets/:walletId", id: "updateWallet", summary: "Updates a wallet", body: gaivota.Wallet{}, response: gaivota.Wallet{}},
+	{method: http.MethodDelete, path: "/wallets/:walletId", id: "deleteWallet", summary: "Deletes a wallet", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/wallets/:walletId/value", id: "getWalletValue", summary: "Prices a wallet's holdings and updates its total value", response: valuation.WalletValuation{}},
+	{method: http.MethodGet, path: "/wallets/:walletId/holdings", id: "listWalletHoldings", summary: "Lists a wallet's holdings", response: []gaivota.Holding{}},
+	{method: http.MethodGet, path: "/wallets/:walletId/transfers", id: "listWalletTransfers", summary: "Lists the transfers from and to a wallet", response: []gaivota.Transfer{}},
+
+	{method: http.MethodGet, path: "/investments", id: "listInvestments", summary: "Lists investments", query: []parameter{symbolParam}, response: []gaivota.Investment{}, page: true},
+	{method: http.MethodPost, path: "/investments", id: "addInvestment", summary: "Creates an investment", body: gaivota.Investment{}, status: http.StatusCreated, response: gaivota.Investment{}},
+	{method: http.MethodGet, path: "/investments/:investmentId", id: "getInvestment", summary: "Gets an investment", response: gaivota.Investment{}},
+	{method: http.MethodPut, path: "/investments/:investmentId", id: "updateInvestment", summary: "Updates an investment", body: gaivota.Investment{}, response: gaivota.Investment{}},
+	{method: http.MethodDelete, path: "/investments/:investmentId", id: "deleteInvestment", summary: "Deletes an investment", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/investments/:investmentId/returns", id: "getInvestmentReturns", summary: "Measures an investment's returns, since its first order by default", query: []parameter{fromParam, toParam}, response: performance.Returns{}},
+	{method: http.MethodGet, path: "/investments/:investmentId/positions", id: "listInvestmentPositions", summary: "Lists an investment's positions", response: []gaivota.Position{}},
+
+	{method: http.MethodGet, path: "/positions", id: "listPositions", summary: "Lists positions", query: []parameter{symbolParam}, response: []gaivota.Position{}, page: true},
+	{method: http.MethodPost, path: "/positions", id: "addPosition", summary: "Creates a position", body: gaivota.Position{}, status: http.StatusCreated, response: gaivota.Position{}},
+	{method: http.MethodGet, path: "/positions/:positionId", id: "getPosition", summary: "Gets a position", response: gaivota.Position{}},
+	{method: http.MethodPut, path: "/positions/:positionId", id: "updatePosition", summary: "Updates a position", body: gaivota.Position{}, response: gaivota.Position{}},
+	{method: http.MethodDelete, path: "/positions/:positionId", id: "deletePosition", summary: "Deletes a position", status: http.StatusNoContent},
+	{method: http.MethodGet, path: "/positions/:positionId/profit", id: "getPositionProfit", summary: "Replays a position's orders and events into its profit", query: []parameter{query("price", "Price to compute the unrealized profit at, the current one by default", stringSchema)}, response: positionProfit{}},
+	{method: http.MethodGet, path: "/positions/:positionId/lots", id: "listPositionLots", summary: "Lists a position's lots", response: []gaivota.Lot{}},
+	{method: http.MethodGet, path: "/positions/:positionId/gains", id: "getPositionGains", summary: "Breaks a position's realized profit down by lot and holding period", response: positionGains{}},
+	{method: http.MethodGet, path: "/positions/:positionId/value", id: "getPositionValue", summary: "Prices a position", query: []parameter{atParam, query("currency", "Currency to value in, the position's by default", stringSchema)}, response: valuation.PositionValuation{}},
+	{method: http.MethodGet, path: "/positions/:positionId/holdings", id: "listPositionHoldings", summary: "Lists a position's holdings", response: []gaivota.Holding{}},
+	{method: http.MethodGet, path: "/positions/:positionId/transfers", id: "listPositionTransfers", summary: "Lists a position's transfers", response: []gaivota.Transfer{}},
+	{method: http.MethodGet, path: "/positions/:positionId/orders", id: "listPositionOrders", summary: "Lists a position's orders", response: []gaivota.Order{}},
+	{method: http.MethodGet, path: "/positions/:positionId/events", id: "listPositionEvents", summary: "Lists a position's events", response: []gaivota.Event{}},
+
+	{method: http.MethodGet, path: "/holdings", id: "listHoldings", summary: "Lists holdings", query: []parameter{symbolParam}, response: []gaivota.Holding{}, page: true},
+	{method: http.MethodPost, path: "/holdings", id: "addHolding", summary: "Creates a holding", body: gaivota.Holding{}, status: http.StatusCreated, response: gaivota.Holding{}},
+	{method: http.MethodGet, path: "/holdings/:holdingId", id: "getHolding", summary: "Gets a holding", response: gaivota.Holding{}},
+	{method: http.MethodPut, path: "/holdings/:holdingId", id: "updateHolding", summary: "Updates a holding", body: gaivota.Holding{}, response: gaivota.Holding{}},
+	{method: http.MethodDelete, path: "/holdings/:holdingId", id: "deleteHolding", summary: "Deletes a holding", status: http.StatusNoContent},
+
+	{method: http.MethodGet, path: "/transfers", id: "listTransfers", summary: "Lists transfers", query: []parameter{symbolParam}, response: []gaivota.Transfer{}, page: true},
+	{method: http.MethodPost, path: "/transfers", id: "makeTransfer", summary: "Moves coins between wallets of a user, the fee leaving the position", body: gaivota.Transfer{}, status: http.StatusCreated, response: gaivota.Transfer{}},
+	{method: http.MethodGet, path: "/transfers/:transferId", id: "getTransfer", summary: "Gets a transfer", response: gaivota.Transfer{}},
+
+	{method: http.MethodGet, path: "/orders", id: "listOrders", summary: "Lists orders", query: []parameter{symbolParam, query("operation", "Only orders of this operation", enumSchema(string(gaivota.OrderOperationBuy), string(gaivota.OrderOperationSell))), query("exchange", "Only orders on this exchange", stringSchema)}, response: []gaivota.Order{}, page: true},
+	{method: http.MethodPost, path: "/orders", id: "addOrder", summary: "Creates an order", query: []parameter{walletParam}, body: gaivota.Order{}, status: http.StatusCreated, response: gaivota.Order{}},
+	{method: http.MethodGet, path: "/orders/:orderId", id: "getOrder", summary: "Gets an order", response: gaivota.Order{}},
+	{method: http.MethodPut, path: "/orders/:orderId", id: "updateOrder", summary: "Updates an order", body: gaivota.Order{}, response: gaivota.Order{}},
+	{method: http.MethodDelete, path: "/orders/:orderId", id: "deleteOrder", summary: "Deletes an order", status: http.StatusNoContent},
+
+	{method: http.MethodGet, path: "/events", id: "listEvents", summary: "Lists events", query: []parameter{symbolParam, query("kind", "Only events of this kind", enumSchema(eventKinds()...))}, response: []gaivota.Event{}, page: true},
+	{method: http.MethodPost, path: "/events", id: "addEvent", summary: "Records a deposit, withdrawal, reward or other event", query: []parameter{walletParam}, body: gaivota.Event{}, status: http.StatusCreated, response: gaivota.Event{}},
+	{method: http.MethodGet, path: "/events/:eventId", id: "getEvent", summary: "Gets an event", response: gaivota.Event{}},
+	{method: http.MethodPut, path: "/events/:eventId", id: "updateEvent", summary: "Updates an event", body: gaivota.Event{}, response: gaivota.Event{}},
+	{method: http.MethodDelete, path: "/events/:eventId", id: "deleteEvent", summary: "Deletes an event", status: http.StatusNoContent},
+
+	{method: http.MethodGet, path: "/prices/:symbol", id: "getPrice", summary: "Quotes a token", query: []parameter{query("quote", "Currency to quote in, USD by default", stringSchema), query("at", "Time of the price, now by default", timeSchema)}, response: gaivota.Price{}},
+	{method: http.MethodGet, path: "/prices/:symbol/history", id: "getPriceHistory", summary: "Lists the stored prices of a token, of the last 30 days by default", query: []parameter{query("quote", "Currency to quote in, USD by default", stringSchema), fromParam, toParam}, response: []gaivota.Price{}},
+
+	{method: http.MethodGet, path: "/fx/:base/:quote", id: "getFXRate", summary: "Gets an exchange rate, inverted or crossed when not stored", query: []parameter{query("at", "Time of the rate, now by default", timeSchema)}, response: gaivota.FXRate{}},
+	{method: http.MethodGet, path: "/fx/:base/:quote/history", id: "getFXRateHistory", summary: "Lists the stored exchange rates, of the last 30 days by default", query: []parameter{fromParam, toParam}, response: []gaivota.FXRate{}},
+
+	{method: http.MethodGet, path: "/audit", id: "listAuditEntries", summary: "Lists the changes to the caller's entities", query: []parameter{query("entity", "Only changes to entities of this kind", enumSchema(audit.Entities...)), query("id", "Only changes to the entity with this ID, requires entity", integerSchema)}, response: []gaivota.AuditEntry{}, page: true},
+}
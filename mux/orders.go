@@ -0,0 +1,212 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/leoschet/gaivota"
+)
+
+func InitOrderRouter(mux *Mux, client *gaivota.Client, logger gaivota.Logger) {
+	orderHandler := &OrderHandler{
+		logger:     logger,
+		client:     client,
+		OrderStore: client.OrderStore,
+	}
+
+	router := mux.subrouter("/orders")
+
+	router.Get("/", http.HandlerFunc(orderHandler.All))
+	router.Post("/", http.HandlerFunc(orderHandler.Add))
+	router.Get("/:orderId", http.HandlerFunc(orderHandler.Get))
+	router.Put("/:orderId", http.HandlerFunc(orderHandler.Update))
+	router.Delete("/:orderId", http.HandlerFunc(orderHandler.Delete))
+
+	mux.subrouter("/positions").Get("/:positionId/orders", http.HandlerFunc(orderHandler.GetByPositionID))
+}
+
+type OrderHandler struct {
+	logger     gaivota.Logger
+	client     *gaivota.Client
+	OrderStore gaivota.OrderStore
+}
+
+func (handler *OrderHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Orders")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	orders, next, err := handler.OrderStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Orders: %v", err)
+		writeError(rw, "Error while getting Orders", err)
+		return
+	}
+
+	writePage(rw, req, orders, next)
+}
+
+func (handler *OrderHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Order")
+
+	orderId, err := intParam(req, "orderId")
+
+	if err != nil {
+		httpError(rw, "Order ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	order, err := handler.OrderStore.Get(req.Context(), orderId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Order %v: %v", orderId, err)
+		writeError(rw, "Error while getting Order", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, order)
+}
+
+func (handler *OrderHandler) GetByPositionID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Orders")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	orders, err := handler.OrderStore.GetByPositionID(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Orders for Position %v: %v", positionId, err)
+		writeError(rw, "Error while getting Orders", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, orders)
+}
+
+func (handler *OrderHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Order")
+
+	var order gaivota.Order
+	err := decodeJSON(req, &order)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /orders request body: %v", err)
+		writeError(rw, "Error while decoding order data", err)
+		return
+	}
+
+	if err := order.Validate(); err != nil {
+		writeError(rw, "Invalid order", err)
+		return
+	}
+
+	walletId := 0
+	if req.URL.Query().Get("walletId") != "" {
+		walletId, err = strconv.Atoi(req.URL.Query().Get("walletId"))
+		if err != nil {
+			httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// With a wallet, the order, its position and the wallet's holding are
+	// updated together or not at all
+	var newOrder *gaivota.Order
+	err = handler.client.WithinTx(req.Context(), func(tx *gaivota.Client) (err error) {
+		newOrder, err = tx.OrderStore.Add(req.Context(), &order)
+		if err != nil || walletId == 0 {
+			return err
+		}
+
+		delta := newOrder.Amount
+		if newOrder.Operation == gaivota.OrderOperationSell {
+			delta = delta.Neg()
+		}
+
+		_, err = gaivota.AdjustHolding(req.Context(), tx.HoldingStore, walletId, newOrder.PositionID, delta)
+
+		return err
+	})
+
+	if errors.Is(err, gaivota.ErrInsufficientHolding) {
+		httpError(rw, "Wallet does not hold enough to sell", http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Order: %v", err)
+		writeError(rw, "Error while adding Order", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newOrder)
+}
+
+func (handler *OrderHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Order")
+
+	orderId, err := intParam(req, "orderId")
+
+	if err != nil {
+		httpError(rw, "Order ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var order gaivota.Order
+	err = decodeJSON(req, &order)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /orders request body: %v", err)
+		writeError(rw, "Error while decoding order data", err)
+		return
+	}
+
+	if err := order.Validate(); err != nil {
+		writeError(rw, "Invalid order", err)
+		return
+	}
+
+	order.ID = orderId
+	err = handler.OrderStore.Update(req.Context(), &order)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Order %v: %v", orderId, err)
+		writeError(rw, "Error while updating Order", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &order)
+}
+
+func (handler *OrderHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE Order")
+
+	orderId, err := intParam(req, "orderId")
+
+	if err != nil {
+		httpError(rw, "Order ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.OrderStore.Delete(req.Context(), orderId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting Order %v: %v", orderId, err)
+		writeError(rw, "Error while deleting Order", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -1,70 +1,364 @@
 package mux
 
 import (
-	"context"
-	"encoding/json"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/leoschet/gaivota"
-	"github.com/leoschet/mux"
+	"github.com/leoschet/gaivota/fees"
+	"github.com/leoschet/gaivota/performance"
+	"github.com/leoschet/gaivota/snapshots"
+	"github.com/leoschet/gaivota/valuation"
 )
 
-func InitPortfolioRouter(mux *Mux, store gaivota.PortfolioStore, logger gaivota.Logger) {
+func InitPortfolioRouter(mux *Mux, client *gaivota.Client, valuer *valuation.Valuer, calculator *performance.Calculator, logger gaivota.Logger) {
 	portfolioHandler := &PortfolioHandler{
 		logger:         logger,
-		PortfolioStore: store,
+		valuer:         valuer,
+		calculator:     calculator,
+		summarizer:     fees.New(client),
+		PortfolioStore: client.PortfolioStore,
+		SnapshotStore:  client.SnapshotStore,
 	}
 
-	router := mux.Router.NewSubrouter("/positions")
+	router := mux.subrouter("/portfolios")
 
+	router.Get("/", http.HandlerFunc(portfolioHandler.All))
 	router.Post("/", http.HandlerFunc(portfolioHandler.Add))
 	router.Get("/:portfolioId", http.HandlerFunc(portfolioHandler.Get))
+	router.Put("/:portfolioId", http.HandlerFunc(portfolioHandler.Update))
+	router.Delete("/:portfolioId", http.HandlerFunc(portfolioHandler.Delete))
+	router.Get("/:portfolioId/summary", http.HandlerFunc(portfolioHandler.Summary))
+	router.Get("/:portfolioId/returns", http.HandlerFunc(portfolioHandler.Returns))
+	router.Get("/:portfolioId/history", http.HandlerFunc(portfolioHandler.History))
+	router.Get("/:portfolioId/fees", http.HandlerFunc(portfolioHandler.Fees))
+
+	mux.subrouter("/users").Get("/:userId/portfolios", http.HandlerFunc(portfolioHandler.GetByUserID))
 }
 
 type PortfolioHandler struct {
 	logger         gaivota.Logger
+	valuer         *valuation.Valuer
+	calculator     *performance.Calculator
+	summarizer     *fees.Summarizer
 	PortfolioStore gaivota.PortfolioStore
+	SnapshotStore  gaivota.SnapshotStore
+}
+
+// History lists the portfolio's daily snapshots between the `from` and `to`
+// query params, defaulting to the last year, sampled at the `interval` query
+// param (day, week or month)
+func (handler *PortfolioHandler) History(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Portfolio History")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	from, err := timeQuery(req, "from")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	to, err := endQuery(req, "to")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if from.IsZero() {
+		from = to.AddDate(-1, 0, 0)
+	}
+
+	history, err := handler.SnapshotStore.GetRange(req.Context(), portfolioId, from, to)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Portfolio %v history: %v", portfolioId, err)
+		writeError(rw, "Error while getting Portfolio history", err)
+		return
+	}
+
+	sampled, err := snapshots.Sample(*history, req.URL.Query().Get("interval"))
+
+	if err != nil {
+		httpError(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, sampled)
+}
+
+// Fees sums the trading fees of the portfolio's orders per exchange between
+// the `from` and `to` query params, defaulting to since the first order until
+// now, split by the `interval` query param (day, week or month) when given
+func (handler *PortfolioHandler) Fees(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Portfolio Fees")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	from, err := timeQuery(req, "from")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	to, err := endQuery(req, "to")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	if !to.IsZero() && to.Before(from) {
+		httpError(rw, "Period must end after it starts", http.StatusBadRequest)
+		return
+	}
+
+	interval := req.URL.Query().Get("interval")
+
+	if _, err := snapshots.PeriodStart(from, interval); interval != "" && err != nil {
+		httpError(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summary, err := handler.summarizer.Portfolio(req.Context(), portfolioId, from, to, interval)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while summing Portfolio %v fees: %v", portfolioId, err)
+		writeError(rw, "Error while summing Portfolio fees", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, summary)
+}
+
+// Returns measures time-weighted and money-weighted returns between the `from`
+// and `to` query params, defaulting to since the first order until now
+func (handler *PortfolioHandler) Returns(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Portfolio Returns")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	from, err := timeQuery(req, "from")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	to, err := endQuery(req, "to")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	returns, err := handler.calculator.Portfolio(req.Context(), portfolioId, from, to)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while measuring Portfolio %v returns: %v", portfolioId, err)
+		writeError(rw, "Error while measuring Portfolio returns", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, returns)
+}
+
+// Summary values the portfolio now, or at the `at` query param, and breaks
+// its value down by investment and wallet
+func (handler *PortfolioHandler) Summary(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Portfolio Summary")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	at, err := timeQuery(req, "at")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	summary, err := handler.valuer.Portfolio(req.Context(), portfolioId, at)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while summarizing Portfolio %v: %v", portfolioId, err)
+		writeError(rw, "Error while summarizing Portfolio", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, summary)
+}
+
+func (handler *PortfolioHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Portfolios")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	portfolios, next, err := handler.PortfolioStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Portfolios: %v", err)
+		writeError(rw, "Error while getting Portfolios", err)
+		return
+	}
+
+	writePage(rw, req, portfolios, next)
 }
 
 func (handler *PortfolioHandler) Get(rw http.ResponseWriter, req *http.Request) {
-	handler.logger.Log(gaivota.LogLevelInfo, "Handle GET Portfolio")
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Portfolio")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := handler.PortfolioStore.Get(req.Context(), portfolioId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Portfolio %v: %v", portfolioId, err)
+		writeError(rw, "Error while getting Portfolio", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, portfolio)
+}
 
-	params := mux.PathParams(req)
-	portfolioId, err := strconv.Atoi(params["portfolioId"])
+func (handler *PortfolioHandler) GetByUserID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET User Portfolios")
+
+	userId, err := intParam(req, "userId")
 
 	if err != nil {
-		http.Error(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		httpError(rw, "User ID must be an integer", http.StatusBadRequest)
 		return
 	}
 
-	portfolio, err := handler.PortfolioStore.Get(context.Background(), portfolioId)
+	portfolios, err := handler.PortfolioStore.GetByUserID(req.Context(), userId)
 
 	if err != nil {
-		http.Error(rw, "Error while getting Portfolio", http.StatusInternalServerError)
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Portfolios for User %v: %v", userId, err)
+		writeError(rw, "Error while getting Portfolios", err)
 		return
 	}
 
-	rw.Header().Set("Content-Type", "application/json")
-	rw.WriteHeader(http.StatusCreated)
-	json.NewEncoder(rw).Encode(portfolio)
+	writeJSON(rw, http.StatusOK, portfolios)
 }
 
 func (handler *PortfolioHandler) Add(rw http.ResponseWriter, req *http.Request) {
-	handler.logger.Log(gaivota.LogLevelInfo, "Handle POST Portfolio")
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Portfolio")
 
 	var portfolio gaivota.Portfolio
-	decoder := json.NewDecoder(req.Body)
-	decoder.DisallowUnknownFields()
+	err := decodeJSON(req, &portfolio)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /portfolios request body: %v", err)
+		writeError(rw, "Error while decoding portfolio data", err)
+		return
+	}
+
+	if err := portfolio.Validate(); err != nil {
+		writeError(rw, "Invalid portfolio", err)
+		return
+	}
+
+	newPortfolio, err := handler.PortfolioStore.Add(req.Context(), &portfolio)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Portfolio: %v", err)
+		writeError(rw, "Error while adding Portfolio", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newPortfolio)
+}
+
+func (handler *PortfolioHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Portfolio")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var portfolio gaivota.Portfolio
+	err = decodeJSON(req, &portfolio)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /portfolios request body: %v", err)
+		writeError(rw, "Error while decoding portfolio data", err)
+		return
+	}
+
+	if err := portfolio.Validate(); err != nil {
+		writeError(rw, "Invalid portfolio", err)
+		return
+	}
+
+	portfolio.ID = portfolioId
+	err = handler.PortfolioStore.Update(req.Context(), &portfolio)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Portfolio %v: %v", portfolioId, err)
+		writeError(rw, "Error while updating Portfolio", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &portfolio)
+}
+
+func (handler *PortfolioHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE Portfolio")
+
+	portfolioId, err := intParam(req, "portfolioId")
+
+	if err != nil {
+		httpError(rw, "Portfolio ID must be an integer", http.StatusBadRequest)
+		return
+	}
 
-	err := decoder.Decode(&portfolio)
+	err = handler.PortfolioStore.Delete(req.Context(), portfolioId)
 
-	// TODO: Improve error handling as in: https://www.alexedwards.net/blog/how-to-properly-parse-a-json-request-body
 	if err != nil {
-		handler.logger.Log(gaivota.LogLevelInfo, "Error while decoding POST /portfolio request body: %v", err)
-		http.Error(rw, "Error while decoding portfolio data", http.StatusBadRequest)
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting Portfolio %v: %v", portfolioId, err)
+		writeError(rw, "Error while deleting Portfolio", err)
 		return
 	}
 
-	handler.PortfolioStore.Add(context.Background(), &portfolio)
+	rw.WriteHeader(http.StatusNoContent)
 }
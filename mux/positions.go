@@ -0,0 +1,371 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/valuation"
+	"github.com/shopspring/decimal"
+)
+
+// Profit and gains replay orders across several stores, so the router gets the whole client
+func InitPositionRouter(mux *Mux, client *gaivota.Client, valuer *valuation.Valuer, logger gaivota.Logger) {
+	positionHandler := &PositionHandler{
+		logger:        logger,
+		client:        client,
+		valuer:        valuer,
+		PositionStore: client.PositionStore,
+		LotStore:      client.LotStore,
+	}
+
+	router := mux.subrouter("/positions")
+
+	router.Get("/", http.HandlerFunc(positionHandler.All))
+	router.Post("/", http.HandlerFunc(positionHandler.Add))
+	router.Get("/:positionId", http.HandlerFunc(positionHandler.Get))
+	router.Put("/:positionId", http.HandlerFunc(positionHandler.Update))
+	router.Delete("/:positionId", http.HandlerFunc(positionHandler.Delete))
+	router.Get("/:positionId/profit", http.HandlerFunc(positionHandler.Profit))
+	router.Get("/:positionId/lots", http.HandlerFunc(positionHandler.Lots))
+	router.Get("/:positionId/gains", http.HandlerFunc(positionHandler.Gains))
+	router.Get("/:positionId/value", http.HandlerFunc(positionHandler.Value))
+
+	mux.subrouter("/investments").Get("/:investmentId/positions", http.HandlerFunc(positionHandler.GetByInvestmentID))
+}
+
+type PositionHandler struct {
+	logger        gaivota.Logger
+	client        *gaivota.Client
+	valuer        *valuation.Valuer
+	PositionStore gaivota.PositionStore
+	LotStore      gaivota.LotStore
+}
+
+type positionProfit struct {
+	Quote            string           `json:"quote"`
+	Amount           decimal.Decimal  `json:"amount"`
+	AveragePrice     decimal.Decimal  `json:"averagePrice"`
+	CostBasis        decimal.Decimal  `json:"costBasis"`
+	RealizedProfit   decimal.Decimal  `json:"realizedProfit"`
+	Income           decimal.Decimal  `json:"income"`
+	Price            *decimal.Decimal `json:"price,omitempty"`
+	UnrealizedProfit *decimal.Decimal `json:"unrealizedProfit,omitempty"`
+}
+
+type positionGains struct {
+	Gains     []accounting.Gain `json:"gains"`
+	ShortTerm decimal.Decimal   `json:"shortTerm"`
+	LongTerm  decimal.Decimal   `json:"longTerm"`
+	Income    decimal.Decimal   `json:"income"`
+}
+
+// Profit replays the position's orders and events. The unrealized profit is computed at
+// the `price` query param, or at the current price when a source is available.
+func (handler *PositionHandler) Profit(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Profit")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var price *decimal.Decimal
+	if rawPrice := req.URL.Query().Get("price"); rawPrice != "" {
+		parsed, err := decimal.NewFromString(rawPrice)
+
+		if err != nil {
+			httpError(rw, "Price must be a number", http.StatusBadRequest)
+			return
+		}
+
+		price = &parsed
+	}
+
+	position, err := handler.PositionStore.Get(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Position %v: %v", positionId, err)
+		writeError(rw, "Error while getting Position", err)
+		return
+	}
+
+	result, err := accounting.ReplayPosition(req.Context(), handler.client, positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while replaying Orders for Position %v: %v", positionId, err)
+		writeError(rw, "Error while computing Position Profit", err)
+		return
+	}
+
+	profit := positionProfit{
+		Quote:          position.QuoteCurrency,
+		Amount:         result.Amount,
+		AveragePrice:   result.AveragePrice,
+		CostBasis:      result.CostBasis,
+		RealizedProfit: result.RealizedProfit,
+		Income:         result.Income,
+	}
+	if price == nil {
+		price = handler.currentPrice(req.Context(), position)
+	}
+
+	if price != nil {
+		unrealized := result.UnrealizedProfit(*price)
+		profit.Price = price
+		profit.UnrealizedProfit = &unrealized
+	}
+
+	writeJSON(rw, http.StatusOK, profit)
+}
+
+// Returns nil when the position's token has no known price in its currency
+func (handler *PositionHandler) currentPrice(ctx context.Context, position *gaivota.Position) *decimal.Decimal {
+	investment, err := handler.client.InvestmentStore.Get(ctx, position.InvestmentID)
+	if err != nil {
+		return nil
+	}
+
+	price, err := handler.valuer.Price(ctx, investment.TokenSymbol, position.QuoteCurrency, time.Time{})
+	if err != nil {
+		requestLogger(ctx, handler.logger).Log(gaivota.LogLevelWarn, "No current price for Position %v: %v", position.ID, err)
+		return nil
+	}
+
+	return &price.Value
+}
+
+// Value prices the position now, or at the `at` query param, in the position's
+// currency or in the `currency` query param
+func (handler *PositionHandler) Value(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Value")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	at, err := timeQuery(req, "at")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	currency := strings.ToUpper(req.URL.Query().Get("currency"))
+
+	positionValuation, err := handler.valuer.Position(req.Context(), positionId, at, currency)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while valuing Position %v: %v", positionId, err)
+		writeError(rw, "Error while valuing Position", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, positionValuation)
+}
+
+func (handler *PositionHandler) Lots(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Lots")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	lots, err := handler.LotStore.GetByPositionID(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Lots for Position %v: %v", positionId, err)
+		writeError(rw, "Error while getting Position Lots", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, lots)
+}
+
+// Gains breaks the realized profit down by lot and holding period
+func (handler *PositionHandler) Gains(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Gains")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	result, err := accounting.ReplayPosition(req.Context(), handler.client, positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while replaying Orders for Position %v: %v", positionId, err)
+		writeError(rw, "Error while computing Position Gains", err)
+		return
+	}
+
+	totals := result.RealizedByHoldingPeriod()
+
+	writeJSON(rw, http.StatusOK, positionGains{
+		Gains:     result.Gains,
+		ShortTerm: totals[accounting.HoldingPeriodShort],
+		LongTerm:  totals[accounting.HoldingPeriodLong],
+		Income:    result.Income,
+	})
+}
+
+func (handler *PositionHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Positions")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	positions, next, err := handler.PositionStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Positions: %v", err)
+		writeError(rw, "Error while getting Positions", err)
+		return
+	}
+
+	writePage(rw, req, positions, next)
+}
+
+func (handler *PositionHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	position, err := handler.PositionStore.Get(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Position %v: %v", positionId, err)
+		writeError(rw, "Error while getting Position", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, position)
+}
+
+func (handler *PositionHandler) GetByInvestmentID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Investment Positions")
+
+	investmentId, err := intParam(req, "investmentId")
+
+	if err != nil {
+		httpError(rw, "Investment ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	positions, err := handler.PositionStore.GetByInvestmentID(req.Context(), investmentId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Positions for Investment %v: %v", investmentId, err)
+		writeError(rw, "Error while getting Positions", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, positions)
+}
+
+func (handler *PositionHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Position")
+
+	var position gaivota.Position
+	err := decodeJSON(req, &position)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /positions request body: %v", err)
+		writeError(rw, "Error while decoding position data", err)
+		return
+	}
+
+	if err := position.Validate(); err != nil {
+		writeError(rw, "Invalid position", err)
+		return
+	}
+
+	newPosition, err := handler.PositionStore.Add(req.Context(), &position)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Position: %v", err)
+		writeError(rw, "Error while adding Position", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newPosition)
+}
+
+func (handler *PositionHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Position")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var position gaivota.Position
+	err = decodeJSON(req, &position)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /positions request body: %v", err)
+		writeError(rw, "Error while decoding position data", err)
+		return
+	}
+
+	if err := position.Validate(); err != nil {
+		writeError(rw, "Invalid position", err)
+		return
+	}
+
+	position.ID = positionId
+	err = handler.PositionStore.Update(req.Context(), &position)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Position %v: %v", positionId, err)
+		writeError(rw, "Error while updating Position", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &position)
+}
+
+func (handler *PositionHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE Position")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.PositionStore.Delete(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting Position %v: %v", positionId, err)
+		writeError(rw, "Error while deleting Position", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,125 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/mux"
+)
+
+// The GET endpoint follows the contract expected by pricing.HTTPSource, so a
+// gaivota instance can serve prices to another one, given an API key. Prices
+// are shared by every user and drive their valuations, so they are only
+// stored with the CLI.
+func InitPriceRouter(mux *Mux, store gaivota.PriceStore, source gaivota.PriceSource, logger gaivota.Logger) {
+	priceHandler := &PriceHandler{
+		logger:      logger,
+		PriceStore:  store,
+		PriceSource: source,
+	}
+
+	router := mux.subrouter("/prices")
+
+	router.Get("/:symbol", http.HandlerFunc(priceHandler.Get))
+	router.Get("/:symbol/history", http.HandlerFunc(priceHandler.History))
+}
+
+type PriceHandler struct {
+	logger      gaivota.Logger
+	PriceStore  gaivota.PriceStore
+	PriceSource gaivota.PriceSource
+}
+
+// Reads the token symbol path param and the quote currency query param
+func priceParams(req *http.Request) (string, string) {
+	symbol := strings.ToUpper(mux.PathParams(req)["symbol"])
+
+	quote := strings.ToUpper(req.URL.Query().Get("quote"))
+	if quote == "" {
+		quote = gaivota.DefaultQuoteCurrency
+	}
+
+	return symbol, quote
+}
+
+// Get quotes the token now, or at the `at` query param
+func (handler *PriceHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Price")
+
+	symbol, quote := priceParams(req)
+
+	at, err := timeQuery(req, "at")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	if handler.PriceSource == nil {
+		httpError(rw, "No price source configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var price *gaivota.Price
+	if at.IsZero() {
+		price, err = handler.PriceSource.Current(req.Context(), symbol, quote)
+	} else {
+		price, err = handler.PriceSource.At(req.Context(), symbol, quote, at)
+	}
+
+	if errors.Is(err, gaivota.ErrPriceNotFound) {
+		httpError(rw, "Price not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting %s Price for %s: %v", quote, symbol, err)
+		writeError(rw, "Error while getting Price", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, price)
+}
+
+// History lists the stored prices between the `from` and `to` query params,
+// defaulting to the last 30 days
+func (handler *PriceHandler) History(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Price History")
+
+	symbol, quote := priceParams(req)
+
+	from, err := timeQuery(req, "from")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	to, err := endQuery(req, "to")
+
+	if err != nil {
+		writeError(rw, "Invalid query", err)
+		return
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+
+	prices, err := handler.PriceStore.GetRange(req.Context(), symbol, quote, from, to)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting %s Prices for %s: %v", quote, symbol, err)
+		writeError(rw, "Error while getting Prices", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, prices)
+}
@@ -0,0 +1,156 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+)
+
+func InitTransferRouter(mux *Mux, client *gaivota.Client, logger gaivota.Logger) {
+	transferHandler := &TransferHandler{
+		logger:        logger,
+		client:        client,
+		TransferStore: client.TransferStore,
+	}
+
+	router := mux.subrouter("/transfers")
+
+	router.Get("/", http.HandlerFunc(transferHandler.All))
+	router.Post("/", http.HandlerFunc(transferHandler.Add))
+	router.Get("/:transferId", http.HandlerFunc(transferHandler.Get))
+
+	mux.subrouter("/wallets").Get("/:walletId/transfers", http.HandlerFunc(transferHandler.GetByWalletID))
+	mux.subrouter("/positions").Get("/:positionId/transfers", http.HandlerFunc(transferHandler.GetByPositionID))
+}
+
+type TransferHandler struct {
+	logger        gaivota.Logger
+	client        *gaivota.Client
+	TransferStore gaivota.TransferStore
+}
+
+func (handler *TransferHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Transfers")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	transfers, next, err := handler.TransferStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Transfers: %v", err)
+		writeError(rw, "Error while getting Transfers", err)
+		return
+	}
+
+	writePage(rw, req, transfers, next)
+}
+
+func (handler *TransferHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Transfer")
+
+	transferId, err := intParam(req, "transferId")
+
+	if err != nil {
+		httpError(rw, "Transfer ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := handler.TransferStore.Get(req.Context(), transferId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Transfer %v: %v", transferId, err)
+		writeError(rw, "Error while getting Transfer", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, transfer)
+}
+
+func (handler *TransferHandler) GetByWalletID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Wallet Transfers")
+
+	walletId, err := intParam(req, "walletId")
+
+	if err != nil {
+		httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := handler.TransferStore.GetByWalletID(req.Context(), walletId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Transfers for Wallet %v: %v", walletId, err)
+		writeError(rw, "Error while getting Transfers", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, transfers)
+}
+
+func (handler *TransferHandler) GetByPositionID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Position Transfers")
+
+	positionId, err := intParam(req, "positionId")
+
+	if err != nil {
+		httpError(rw, "Position ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := handler.TransferStore.GetByPositionID(req.Context(), positionId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Transfers for Position %v: %v", positionId, err)
+		writeError(rw, "Error while getting Transfers", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, transfers)
+}
+
+// Moves the amount between the wallets' holdings and stores the transfer,
+// together or not at all
+func (handler *TransferHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Transfer")
+
+	var transfer gaivota.Transfer
+	err := decodeJSON(req, &transfer)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /transfers request body: %v", err)
+		writeError(rw, "Error while decoding transfer data", err)
+		return
+	}
+
+	newTransfer, err := gaivota.MakeTransfer(req.Context(), handler.client, &transfer)
+
+	if errors.Is(err, gaivota.ErrInvalidTransfer) {
+		writeError(rw, "Invalid transfer", err)
+		return
+	}
+
+	if errors.Is(err, gaivota.ErrInsufficientHolding) {
+		httpError(rw, "Wallet does not hold enough to transfer", http.StatusConflict)
+		return
+	}
+
+	if errors.Is(err, accounting.ErrOversold) {
+		httpError(rw, "Position does not hold enough to pay the fee", http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Transfer: %v", err)
+		writeError(rw, "Error while adding Transfer", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newTransfer)
+}
@@ -0,0 +1,187 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/auth"
+)
+
+func InitUserRouter(mux *Mux, store gaivota.UserStore, signUps bool, logger gaivota.Logger) {
+	userHandler := &UserHandler{
+		logger:    logger,
+		signUps:   signUps,
+		UserStore: store,
+	}
+
+	router := mux.subrouter("/users")
+
+	router.Get("/", http.HandlerFunc(userHandler.All))
+	router.Post("/", http.HandlerFunc(userHandler.Add))
+	router.Get("/:userId", http.HandlerFunc(userHandler.Get))
+	router.Put("/:userId", http.HandlerFunc(userHandler.Update))
+	router.Delete("/:userId", http.HandlerFunc(userHandler.Delete))
+}
+
+// Users sign up with a password, which is only stored hashed
+type newUser struct {
+	gaivota.User
+	Password string `json:"password"`
+}
+
+// Checks the user and its password together, so both are reported at once
+func (user *newUser) Validate() error {
+	errs := gaivota.FieldErrorsOf(user.User.Validate())
+	if len(user.Password) < auth.MinPasswordLength {
+		errs.Add("password", "must have at least %d characters", auth.MinPasswordLength)
+	}
+
+	return errs.Err("invalid user")
+}
+
+type UserHandler struct {
+	logger gaivota.Logger
+	// Whether POST /users is open, otherwise users are created with the CLI
+	signUps   bool
+	UserStore gaivota.UserStore
+}
+
+func (handler *UserHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Users")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	users, next, err := handler.UserStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Users: %v", err)
+		writeError(rw, "Error while getting Users", err)
+		return
+	}
+
+	writePage(rw, req, users, next)
+}
+
+func (handler *UserHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET User")
+
+	userId, err := intParam(req, "userId")
+
+	if err != nil {
+		httpError(rw, "User ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	user, err := handler.UserStore.Get(req.Context(), userId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting User %v: %v", userId, err)
+		writeError(rw, "Error while getting User", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, user)
+}
+
+func (handler *UserHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST User")
+
+	if !handler.signUps {
+		httpError(rw, "Sign ups are disabled", http.StatusForbidden)
+		return
+	}
+
+	var user newUser
+	err := decodeJSON(req, &user)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /users request body: %v", err)
+		writeError(rw, "Error while decoding user data", err)
+		return
+	}
+
+	if err := user.Validate(); err != nil {
+		writeError(rw, "Invalid user", err)
+		return
+	}
+
+	user.PasswordHash, err = auth.HashPassword(user.Password)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while hashing password: %v", err)
+		writeError(rw, "Error while adding User", err)
+		return
+	}
+
+	addedUser, err := handler.UserStore.Add(req.Context(), &user.User)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding User: %v", err)
+		writeError(rw, "Error while adding User", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, addedUser)
+}
+
+func (handler *UserHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT User")
+
+	userId, err := intParam(req, "userId")
+
+	if err != nil {
+		httpError(rw, "User ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var user gaivota.User
+	err = decodeJSON(req, &user)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /users request body: %v", err)
+		writeError(rw, "Error while decoding user data", err)
+		return
+	}
+
+	if err := user.Validate(); err != nil {
+		writeError(rw, "Invalid user", err)
+		return
+	}
+
+	user.ID = userId
+	err = handler.UserStore.Update(req.Context(), &user)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating User %v: %v", userId, err)
+		writeError(rw, "Error while updating User", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &user)
+}
+
+func (handler *UserHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE User")
+
+	userId, err := intParam(req, "userId")
+
+	if err != nil {
+		httpError(rw, "User ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.UserStore.Delete(req.Context(), userId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting User %v: %v", userId, err)
+		writeError(rw, "Error while deleting User", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
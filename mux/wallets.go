@@ -0,0 +1,218 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/valuation"
+)
+
+func InitWalletRouter(mux *Mux, store gaivota.WalletStore, valuer *valuation.Valuer, logger gaivota.Logger) {
+	walletHandler := &WalletHandler{
+		logger:      logger,
+		valuer:      valuer,
+		WalletStore: store,
+	}
+
+	router := mux.subrouter("/wallets")
+
+	router.Get("/", http.HandlerFunc(walletHandler.All))
+	router.Post("/", http.HandlerFunc(walletHandler.Add))
+	router.Get("/:walletId", http.HandlerFunc(walletHandler.Get))
+	router.Put("/:walletId", http.HandlerFunc(walletHandler.Update))
+	router.Delete("/:walletId", http.HandlerFunc(walletHandler.Delete))
+	router.Get("/:walletId/value", http.HandlerFunc(walletHandler.Value))
+
+	mux.subrouter("/users").Get("/:userId/wallets", http.HandlerFunc(walletHandler.GetByUserID))
+}
+
+type WalletHandler struct {
+	logger      gaivota.Logger
+	valuer      *valuation.Valuer
+	WalletStore gaivota.WalletStore
+}
+
+// Value prices the wallet's holdings and stores the result as its total value
+func (handler *WalletHandler) Value(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Wallet Value")
+
+	walletId, err := intParam(req, "walletId")
+
+	if err != nil {
+		httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := handler.WalletStore.Get(req.Context(), walletId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Wallet %v: %v", walletId, err)
+		writeError(rw, "Error while getting Wallet", err)
+		return
+	}
+
+	walletValuation, err := handler.valuer.Wallet(req.Context(), walletId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while valuing Wallet %v: %v", walletId, err)
+		writeError(rw, "Error while valuing Wallet", err)
+		return
+	}
+
+	wallet.TotalValue = walletValuation.Value
+	err = handler.WalletStore.Update(req.Context(), wallet)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Wallet %v total value: %v", walletId, err)
+	}
+
+	writeJSON(rw, http.StatusOK, walletValuation)
+}
+
+func (handler *WalletHandler) All(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Wallets")
+
+	opts, err := listOptions(req)
+
+	if err != nil {
+		writeError(rw, "Invalid list options", err)
+		return
+	}
+
+	wallets, next, err := handler.WalletStore.All(req.Context(), opts)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Wallets: %v", err)
+		writeError(rw, "Error while getting Wallets", err)
+		return
+	}
+
+	writePage(rw, req, wallets, next)
+}
+
+func (handler *WalletHandler) Get(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET Wallet")
+
+	walletId, err := intParam(req, "walletId")
+
+	if err != nil {
+		httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := handler.WalletStore.Get(req.Context(), walletId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Wallet %v: %v", walletId, err)
+		writeError(rw, "Error while getting Wallet", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, wallet)
+}
+
+func (handler *WalletHandler) GetByUserID(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle GET User Wallets")
+
+	userId, err := intParam(req, "userId")
+
+	if err != nil {
+		httpError(rw, "User ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	wallets, err := handler.WalletStore.GetByUserID(req.Context(), userId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while getting Wallets for User %v: %v", userId, err)
+		writeError(rw, "Error while getting Wallets", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, wallets)
+}
+
+func (handler *WalletHandler) Add(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle POST Wallet")
+
+	var wallet gaivota.Wallet
+	err := decodeJSON(req, &wallet)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding POST /wallets request body: %v", err)
+		writeError(rw, "Error while decoding wallet data", err)
+		return
+	}
+
+	if err := wallet.Validate(); err != nil {
+		writeError(rw, "Invalid wallet", err)
+		return
+	}
+
+	newWallet, err := handler.WalletStore.Add(req.Context(), &wallet)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while adding Wallet: %v", err)
+		writeError(rw, "Error while adding Wallet", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusCreated, newWallet)
+}
+
+func (handler *WalletHandler) Update(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle PUT Wallet")
+
+	walletId, err := intParam(req, "walletId")
+
+	if err != nil {
+		httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	var wallet gaivota.Wallet
+	err = decodeJSON(req, &wallet)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelWarn, "Error while decoding PUT /wallets request body: %v", err)
+		writeError(rw, "Error while decoding wallet data", err)
+		return
+	}
+
+	if err := wallet.Validate(); err != nil {
+		writeError(rw, "Invalid wallet", err)
+		return
+	}
+
+	wallet.ID = walletId
+	err = handler.WalletStore.Update(req.Context(), &wallet)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while updating Wallet %v: %v", walletId, err)
+		writeError(rw, "Error while updating Wallet", err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, &wallet)
+}
+
+func (handler *WalletHandler) Delete(rw http.ResponseWriter, req *http.Request) {
+	requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelDebug, "Handle DELETE Wallet")
+
+	walletId, err := intParam(req, "walletId")
+
+	if err != nil {
+		httpError(rw, "Wallet ID must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	err = handler.WalletStore.Delete(req.Context(), walletId)
+
+	if err != nil {
+		requestLogger(req.Context(), handler.logger).Log(gaivota.LogLevelError, "Error while deleting Wallet %v: %v", walletId, err)
+		writeError(rw, "Error while deleting Wallet", err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
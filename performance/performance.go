@@ -0,0 +1,347 @@
+// Package performance measures how portfolios and investments performed over
+// a period, with time-weighted and money-weighted returns.
+package performance
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/fx"
+	"github.com/leoschet/gaivota/valuation"
+	"github.com/shopspring/decimal"
+)
+
+// Decimal places kept when dividing values
+const divisionPrecision = 18
+
+// Returns over a period, in the Quote currency. Orders are the cash flows:
+// buys put money in, sells take it out, and their fees put more in. Deposits
+// and gifts put coins in and withdrawals take them out, which are flows at
+// their price at the time. Rewards, airdrops, forks and lost coins are not
+// flows but part of the return.
+type Returns struct {
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Quote      string          `json:"quote"`
+	StartValue decimal.Decimal `json:"startValue"`
+	EndValue   decimal.Decimal `json:"endValue"`
+	// Money put in minus money taken out during the period
+	NetFlows decimal.Decimal `json:"netFlows"`
+	// Time-weighted return, unaffected by the size and timing of flows
+	TWR decimal.Decimal `json:"twr"`
+	// Annual money-weighted return, nil when it cannot be computed (e.g. no flows)
+	XIRR *decimal.Decimal `json:"xirr,omitempty"`
+}
+
+type Calculator struct {
+	client *gaivota.Client
+	valuer *valuation.Valuer
+}
+
+func New(client *gaivota.Client, valuer *valuation.Valuer) *Calculator {
+	return &Calculator{
+		client: client,
+		valuer: valuer,
+	}
+}
+
+// A position's token and its moves, in execution order
+type series struct {
+	symbol string
+	moves  []move
+}
+
+// A change of the amount held, by an order or an event. Flows put money or
+// coins in, or take them out when negative.
+type move struct {
+	at     time.Time
+	amount decimal.Decimal
+	flow   bool
+	// Money paid by orders, in the quote currency. Other flows are valued at
+	// the token's price.
+	cash *decimal.Decimal
+}
+
+// Portfolio measures the returns of every position in the portfolio, in its
+// reporting currency. Zero `from` means since the first order or event, zero
+// `to` now.
+func (calculator *Calculator) Portfolio(ctx context.Context, portfolioId int, from time.Time, to time.Time) (*Returns, error) {
+	portfolio, err := calculator.client.PortfolioStore.Get(ctx, portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	investments, err := calculator.client.InvestmentStore.GetByPortfolioID(ctx, portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	var allSeries []series
+	for _, investment := range *investments {
+		investmentSeries, err := calculator.series(ctx, &investment, portfolio.ReportingCurrency)
+		if err != nil {
+			return nil, err
+		}
+		allSeries = append(allSeries, investmentSeries...)
+	}
+
+	return calculator.returns(ctx, allSeries, portfolio.ReportingCurrency, from, to)
+}
+
+// Investment measures the returns of the investment's positions, in its
+// portfolio's reporting currency
+func (calculator *Calculator) Investment(ctx context.Context, investmentId int, from time.Time, to time.Time) (*Returns, error) {
+	investment, err := calculator.client.InvestmentStore.Get(ctx, investmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio, err := calculator.client.PortfolioStore.Get(ctx, investment.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	investmentSeries, err := calculator.series(ctx, investment, portfolio.ReportingCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculator.returns(ctx, investmentSeries, portfolio.ReportingCurrency, from, to)
+}
+
+func (calculator *Calculator) series(ctx context.Context, investment *gaivota.Investment, quote string) ([]series, error) {
+	positions, err := calculator.client.PositionStore.GetByInvestmentID(ctx, investment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	converter := fx.NewConverter(fx.WithPrices(calculator.client.FXRateStore, calculator.client.PriceStore))
+
+	var allSeries []series
+	for _, position := range *positions {
+		orders, err := calculator.client.OrderStore.GetByPositionID(ctx, position.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		orders, err = accounting.ConvertOrders(ctx, converter, orders, investment.TokenSymbol, quote)
+		if err != nil {
+			return nil, err
+		}
+
+		events, err := calculator.client.EventStore.GetByPositionID(ctx, position.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		var moves []move
+		for _, order := range orders {
+			// Buying costs the investor money, selling pays it back, and fees
+			// cost money either way
+			amount := order.Amount
+			cash := order.Amount.Mul(order.UnitPrice)
+			if order.Operation == gaivota.OrderOperationSell {
+				amount = amount.Neg()
+				cash = cash.Neg()
+			}
+			cash = cash.Add(order.Fee)
+
+			moves = append(moves, move{at: order.ExecutedAt, amount: amount, flow: true, cash: &cash})
+		}
+
+		for _, event := range *events {
+			amount := event.Amount
+			if !event.Kind.Incoming() {
+				amount = amount.Neg()
+			}
+
+			flow := event.Kind == gaivota.EventKindDeposit || event.Kind == gaivota.EventKindGift ||
+				event.Kind == gaivota.EventKindWithdrawal
+
+			moves = append(moves, move{at: event.ExecutedAt, amount: amount, flow: flow})
+		}
+
+		sort.SliceStable(moves, func(i, j int) bool { return moves[i].at.Before(moves[j].at) })
+		allSeries = append(allSeries, series{symbol: investment.TokenSymbol, moves: moves})
+	}
+
+	return allSeries, nil
+}
+
+// Splits the period at every flow. TWR chains the growth of each sub-period,
+// measured between the value after a flow and the value before the next.
+// XIRR treats the start value as a payment and the end value as a receipt.
+func (calculator *Calculator) returns(ctx context.Context, allSeries []series, quote string, from time.Time, to time.Time) (*Returns, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if from.IsZero() {
+		from = to
+		for _, s := range allSeries {
+			if len(s.moves) > 0 && s.moves[0].at.Before(from) {
+				from = s.moves[0].at
+			}
+		}
+	}
+
+	if to.Before(from) {
+		return nil, errors.New("period must end after it starts")
+	}
+
+	valuer := &periodValuer{valuer: calculator.valuer, quote: quote, prices: map[priceKey]decimal.Decimal{}}
+
+	startValue, err := valuer.value(ctx, allSeries, from, true)
+	if err != nil {
+		return nil, err
+	}
+
+	returns := &Returns{
+		From:       from,
+		To:         to,
+		Quote:      quote,
+		StartValue: startValue,
+	}
+
+	var flows []CashFlow
+	if startValue.IsPositive() {
+		flows = append(flows, CashFlow{At: from, Amount: -startValue.InexactFloat64()})
+	}
+
+	var dates []time.Time
+	seen := map[int64]bool{}
+	for _, s := range allSeries {
+		for _, m := range s.moves {
+			if !m.flow || !m.at.After(from) || m.at.After(to) {
+				continue
+			}
+
+			var flow decimal.Decimal
+			if m.cash != nil {
+				flow = *m.cash
+			} else {
+				price, err := valuer.price(ctx, s.symbol, m.at)
+				if err != nil {
+					return nil, err
+				}
+				flow = m.amount.Mul(price)
+			}
+
+			returns.NetFlows = returns.NetFlows.Add(flow)
+			flows = append(flows, CashFlow{At: m.at, Amount: -flow.InexactFloat64()})
+
+			if !seen[m.at.UnixNano()] {
+				seen[m.at.UnixNano()] = true
+				dates = append(dates, m.at)
+			}
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	growth := decimal.NewFromInt(1)
+	previous := startValue
+
+	for _, date := range dates {
+		before, err := valuer.value(ctx, allSeries, date, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// Nothing was held, so there was nothing to grow
+		if previous.IsPositive() {
+			growth = growth.Mul(before.DivRound(previous, divisionPrecision))
+		}
+
+		previous, err = valuer.value(ctx, allSeries, date, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	returns.EndValue, err = valuer.value(ctx, allSeries, to, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous.IsPositive() {
+		growth = growth.Mul(returns.EndValue.DivRound(previous, divisionPrecision))
+	}
+
+	returns.TWR = growth.Sub(decimal.NewFromInt(1)).Round(8)
+
+	if returns.EndValue.IsPositive() {
+		flows = append(flows, CashFlow{At: to, Amount: returns.EndValue.InexactFloat64()})
+	}
+
+	if rate, err := XIRR(flows); err == nil {
+		xirr := decimal.NewFromFloat(rate).Round(8)
+		returns.XIRR = &xirr
+	}
+
+	return returns, nil
+}
+
+type priceKey struct {
+	symbol string
+	at     int64
+}
+
+// Values series at points in time, pricing each token once per point
+type periodValuer struct {
+	valuer *valuation.Valuer
+	quote  string
+	prices map[priceKey]decimal.Decimal
+}
+
+// Values what was held at `at`, including the moves at that time when
+// `inclusive` is set
+func (valuer *periodValuer) value(ctx context.Context, allSeries []series, at time.Time, inclusive bool) (decimal.Decimal, error) {
+	total := decimal.Zero
+
+	for _, s := range allSeries {
+		amount := decimal.Zero
+		for _, m := range s.moves {
+			if m.at.After(at) || (!inclusive && m.at.Equal(at)) {
+				break
+			}
+
+			amount = amount.Add(m.amount)
+		}
+
+		if amount.IsZero() {
+			continue
+		}
+
+		price, err := valuer.price(ctx, s.symbol, at)
+		if err != nil {
+			return decimal.Zero, err
+		}
+
+		total = total.Add(amount.Mul(price))
+	}
+
+	return total, nil
+}
+
+func (valuer *periodValuer) price(ctx context.Context, symbol string, at time.Time) (decimal.Decimal, error) {
+	key := priceKey{symbol: symbol, at: at.UnixNano()}
+	if price, ok := valuer.prices[key]; ok {
+		return price, nil
+	}
+
+	price, err := valuer.valuer.Price(ctx, symbol, valuer.quote, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	valuer.prices[key] = price.Value
+
+	return price.Value, nil
+}
@@ -0,0 +1,171 @@
+package performance
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/inmem"
+	"github.com/leoschet/gaivota/inmem/inmemtest"
+	"github.com/leoschet/gaivota/pricing"
+	"github.com/leoschet/gaivota/valuation"
+	"github.com/shopspring/decimal"
+)
+
+// Days of the test period, with BTC worth 100, 200 and 150 USD
+var (
+	day0  = date(2021, 1, 1)
+	day10 = day0.AddDate(0, 0, 10)
+	day20 = day0.AddDate(0, 0, 20)
+)
+
+// Creates a USD portfolio holding BTC, priced from the stored prices only,
+// with the orders buying BTC at its price on each day
+func newPortfolio(t *testing.T, buys ...time.Time) (*Calculator, int) {
+	t.Helper()
+	ctx := context.Background()
+	client := inmem.New().NewClient()
+	client.PriceSource = pricing.NewStoreSource(client.PriceStore, nil, 0)
+
+	prices := map[time.Time]int64{day0: 100, day10: 200, day20: 150}
+	for at, value := range prices {
+		_, err := client.PriceStore.Add(ctx, &gaivota.Price{TokenSymbol: "BTC", QuoteCurrency: "USD", Value: decimal.NewFromInt(value), At: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{ReportingCurrency: "USD"})
+
+	for _, at := range buys {
+		price := decimal.NewFromInt(prices[at])
+		_, err := client.OrderStore.Add(ctx, &gaivota.Order{
+			PositionID:    fixture.Position.ID,
+			Amount:        decimal.NewFromInt(1),
+			UnitPrice:     price,
+			TotalPrice:    price,
+			QuoteCurrency: "USD",
+			Operation:     gaivota.OrderOperationBuy,
+			Type:          gaivota.OrderTypeMarket,
+			ExecutedAt:    at,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return New(client, valuation.New(client)), fixture.Portfolio.ID
+}
+
+func TestReturns(t *testing.T) {
+	tests := []struct {
+		name       string
+		buys       []time.Time
+		from       time.Time
+		to         time.Time
+		startValue string
+		endValue   string
+		netFlows   string
+		twr        string
+	}{
+		{
+			// Doubles until the second buy, then loses a quarter: 2 * 0.75
+			name:       "chains sub-periods",
+			buys:       []time.Time{day0, day10},
+			from:       day0,
+			to:         day20,
+			startValue: "100",
+			endValue:   "300",
+			netFlows:   "200",
+			twr:        "0.5",
+		},
+		{
+			name:       "starts with the first order by default",
+			buys:       []time.Time{day0, day10},
+			to:         day20,
+			startValue: "100",
+			endValue:   "300",
+			netFlows:   "200",
+			twr:        "0.5",
+		},
+		{
+			// The buy is part of the start value, not a flow
+			name:       "flow at the start",
+			buys:       []time.Time{day0, day10},
+			from:       day10,
+			to:         day20,
+			startValue: "400",
+			endValue:   "300",
+			netFlows:   "0",
+			twr:        "-0.25",
+		},
+		{
+			// The buy is a flow, adding nothing to the last sub-period
+			name:       "flow at the end",
+			buys:       []time.Time{day0, day10},
+			from:       day0,
+			to:         day10,
+			startValue: "100",
+			endValue:   "400",
+			netFlows:   "200",
+			twr:        "1",
+		},
+		{
+			// Nothing held before the first buy, so only what follows it grows
+			name:       "zero start value",
+			buys:       []time.Time{day10},
+			from:       day0,
+			to:         day20,
+			startValue: "0",
+			endValue:   "150",
+			netFlows:   "200",
+			twr:        "-0.25",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calculator, portfolioId := newPortfolio(t, test.buys...)
+
+			returns, err := calculator.Portfolio(context.Background(), portfolioId, test.from, test.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[string]string{"StartValue": test.startValue, "EndValue": test.endValue, "NetFlows": test.netFlows, "TWR": test.twr}
+			got := map[string]decimal.Decimal{"StartValue": returns.StartValue, "EndValue": returns.EndValue, "NetFlows": returns.NetFlows, "TWR": returns.TWR}
+			for name, value := range expected {
+				if !got[name].Equal(decimal.RequireFromString(value)) {
+					t.Errorf("%s is %v, expected %s", name, got[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestReturnsXIRR(t *testing.T) {
+	// Paying 100 and 200 to get 300 back breaks even
+	calculator, portfolioId := newPortfolio(t, day0, day10)
+
+	returns, err := calculator.Portfolio(context.Background(), portfolioId, day0, day20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returns.XIRR == nil || !returns.XIRR.IsZero() {
+		t.Errorf("XIRR is %v, expected 0", returns.XIRR)
+	}
+
+	// Paying 200 to get 150 back 10 days later, found by bisection
+	calculator, portfolioId = newPortfolio(t, day10)
+
+	returns, err = calculator.Portfolio(context.Background(), portfolioId, day0, day20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returns.XIRR == nil {
+		t.Fatal("XIRR is missing")
+	}
+	assertRate(t, returns.XIRR.InexactFloat64(), math.Pow(0.75, daysPerYear/10)-1)
+}
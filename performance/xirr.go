@@ -0,0 +1,136 @@
+package performance
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrNoConvergence is returned when no rate zeroes the cash flows' net present value
+var ErrNoConvergence = errors.New("xirr did not converge")
+
+const (
+	daysPerYear   = 365.0
+	maxIterations = 100
+	tolerance     = 1e-9
+)
+
+// CashFlow is money paid (negative) or received (positive) by the investor
+type CashFlow struct {
+	At     time.Time
+	Amount float64
+}
+
+// XIRR returns the annual rate at which the cash flows' net present value is
+// zero. Flows need at least one payment and one receipt.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, ErrNoConvergence
+	}
+
+	flows = append([]CashFlow(nil), flows...)
+	sort.SliceStable(flows, func(i, j int) bool {
+		return flows[i].At.Before(flows[j].At)
+	})
+
+	var paid, received bool
+	for _, flow := range flows {
+		paid = paid || flow.Amount < 0
+		received = received || flow.Amount > 0
+	}
+
+	if !paid || !received {
+		return 0, ErrNoConvergence
+	}
+
+	// Newton's method is fast but may diverge, bisection always finishes
+	if rate, ok := newton(flows, 0.1); ok {
+		return rate, nil
+	}
+
+	return bisect(flows)
+}
+
+// Years between the first flow and the given one
+func years(flows []CashFlow, i int) float64 {
+	return flows[i].At.Sub(flows[0].At).Hours() / 24 / daysPerYear
+}
+
+func npv(flows []CashFlow, rate float64) float64 {
+	var total float64
+	for i, flow := range flows {
+		total += flow.Amount / math.Pow(1+rate, years(flows, i))
+	}
+
+	return total
+}
+
+// Derivative of npv with respect to the rate
+func dnpv(flows []CashFlow, rate float64) float64 {
+	var total float64
+	for i, flow := range flows {
+		t := years(flows, i)
+		total -= t * flow.Amount / math.Pow(1+rate, t+1)
+	}
+
+	return total
+}
+
+func newton(flows []CashFlow, guess float64) (float64, bool) {
+	rate := guess
+
+	for i := 0; i < maxIterations; i++ {
+		value := npv(flows, rate)
+		if math.Abs(value) < tolerance {
+			return rate, true
+		}
+
+		derivative := dnpv(flows, rate)
+		if derivative == 0 {
+			return 0, false
+		}
+
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			return 0, false
+		}
+
+		if math.Abs(next-rate) < tolerance {
+			return next, true
+		}
+
+		rate = next
+	}
+
+	return 0, false
+}
+
+func bisect(flows []CashFlow) (float64, error) {
+	low, high := -0.999999, 1.0
+
+	// Widen the upper bound until the npv changes sign
+	for npv(flows, low)*npv(flows, high) > 0 {
+		high *= 2
+		if high > 1e6 {
+			return 0, ErrNoConvergence
+		}
+	}
+
+	for i := 0; i < 4*maxIterations; i++ {
+		mid := (low + high) / 2
+		value := npv(flows, mid)
+
+		if math.Abs(value) < tolerance || (high-low)/2 < tolerance {
+			return mid, nil
+		}
+
+		if npv(flows, low)*value < 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	return 0, ErrNoConvergence
+}
@@ -0,0 +1,115 @@
+package performance
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func assertRate(t *testing.T, got float64, expected float64) {
+	t.Helper()
+	if math.Abs(got-expected) > 1e-6 {
+		t.Errorf("Rate is %v, expected %v", got, expected)
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name     string
+		flows    []CashFlow
+		expected float64
+	}{
+		{
+			name:     "a year at 10%",
+			flows:    []CashFlow{{date(2021, 1, 1), -1000}, {date(2022, 1, 1), 1100}},
+			expected: 0.1,
+		},
+		{
+			name:     "a year losing half",
+			flows:    []CashFlow{{date(2021, 1, 1), -1000}, {date(2022, 1, 1), 500}},
+			expected: -0.5,
+		},
+		{
+			name:     "break even",
+			flows:    []CashFlow{{date(2021, 1, 1), -100}, {date(2021, 1, 11), -200}, {date(2021, 1, 21), 300}},
+			expected: 0,
+		},
+		{
+			// Known answer of spreadsheets' XIRR
+			name: "spreadsheet example",
+			flows: []CashFlow{
+				{date(2008, 1, 1), -10000},
+				{date(2008, 3, 1), 2750},
+				{date(2008, 10, 30), 4250},
+				{date(2009, 2, 15), 3250},
+				{date(2009, 4, 1), 2750},
+			},
+			expected: 0.373362535,
+		},
+		{
+			name:     "unsorted flows",
+			flows:    []CashFlow{{date(2022, 1, 1), 1100}, {date(2021, 1, 1), -1000}},
+			expected: 0.1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, err := XIRR(test.flows)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertRate(t, rate, test.expected)
+		})
+	}
+}
+
+func TestXIRRBisection(t *testing.T) {
+	// Losing a quarter in 10 days is close to losing everything in a year,
+	// where Newton's method steps past -100%
+	flows := []CashFlow{{date(2021, 1, 1), -200}, {date(2021, 1, 11), 150}}
+	expected := math.Pow(0.75, daysPerYear/10) - 1
+
+	if _, ok := newton(flows, 0.1); ok {
+		t.Fatal("Newton's method converged, the test needs flows it does not")
+	}
+
+	rate, err := XIRR(flows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRate(t, rate, expected)
+
+	// Bisection alone finds known answers too
+	rate, err = bisect([]CashFlow{{date(2021, 1, 1), -1000}, {date(2022, 1, 1), 1100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRate(t, rate, 0.1)
+}
+
+func TestXIRRNoConvergence(t *testing.T) {
+	tests := []struct {
+		name  string
+		flows []CashFlow
+	}{
+		{"no flows", nil},
+		{"a single flow", []CashFlow{{date(2021, 1, 1), -100}}},
+		{"only payments", []CashFlow{{date(2021, 1, 1), -100}, {date(2022, 1, 1), -100}}},
+		{"only receipts", []CashFlow{{date(2021, 1, 1), 100}, {date(2022, 1, 1), 100}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := XIRR(test.flows); !errors.Is(err, ErrNoConvergence) {
+				t.Errorf("XIRR answered %v, expected ErrNoConvergence", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/fx"
+)
+
+// Replays the position's orders and events, converted to its currency, and
+// transfers with its portfolio's cost basis method, then stores the derived amount,
+// average price, profit and lots.
+func syncPosition(ctx context.Context, q querier, positionId int) error {
+	methodQuery := `select p.cost_basis_method, pos.quote_currency, coalesce(i.token_symbol, '')
+						from positions as pos
+						join investments as i on i.id = pos.investment_id
+						join portfolios as p on p.id = i.portfolio_id
+						where pos.id = $1`
+
+	var method gaivota.CostBasisMethod
+	var currency string
+	var symbol string
+
+	err := q.QueryRow(ctx, methodQuery, positionId).Scan(&method, &currency, &symbol)
+	if err != nil {
+		return fmt.Errorf("Could not get cost basis method for position %v: %w", positionId, err)
+	}
+
+	ordersQuery := `select "id", "position_id", "amount", "unit_price", "total_price", "quote_currency", "fee", "fee_currency", "operation", "type", "exchange", "trade_id", "executed_at", "created_at", "updated_at", "deleted_at"
+						from orders where position_id = $1 and deleted_at is null`
+
+	rows, err := q.Query(ctx, ordersQuery, positionId)
+	if err != nil {
+		return fmt.Errorf("Could not get orders for position %v: %w", positionId, err)
+	}
+
+	orders, err := (&OrderStore{}).scanAll(rows)
+	if err != nil {
+		return err
+	}
+
+	converter := fx.NewConverter(fx.WithPrices(querierRates{q}, querierPrices{q}))
+
+	orders, err = accounting.ConvertOrders(ctx, converter, orders, symbol, currency)
+	if err != nil {
+		return err
+	}
+
+	transfers, err := getTransfers(ctx, q, "position_id = $1", positionId)
+	if err != nil {
+		return err
+	}
+
+	events, err := getEvents(ctx, q, positionId)
+	if err != nil {
+		return err
+	}
+
+	converted, err := accounting.ConvertEvents(ctx, converter, *events, currency)
+	if err != nil {
+		return err
+	}
+
+	result, err := accounting.Replay(orders, *transfers, converted, method)
+	if err != nil {
+		return fmt.Errorf("Could not replay orders for position %v: %w", positionId, err)
+	}
+
+	updateQuery := `update positions
+						set amount = $1,
+								average_price = $2,
+								profit = $3
+						where id = $4 and deleted_at is null`
+
+	cmdTags, err := q.Exec(ctx, updateQuery, result.Amount, result.AveragePrice, result.RealizedProfit, positionId)
+
+	if err = affected(cmdTags, err); err != nil {
+		return fmt.Errorf("Could not update position %v: %w", positionId, err)
+	}
+
+	// Lots are derived data, so they are rebuilt instead of diffed
+	_, err = q.Exec(ctx, `delete from lots where position_id = $1`, positionId)
+	if err != nil {
+		return fmt.Errorf("Could not delete lots for position %v: %w", positionId, err)
+	}
+
+	// Lots are opened by an order or an event, the other ID is 0
+	insertQuery := `insert into lots ("position_id", "order_id", "event_id", "amount", "remaining_amount", "unit_price", "acquired_at")
+						values ($1, nullif($2, 0), nullif($3, 0), $4, $5, $6, $7)`
+
+	for _, lot := range result.Lots {
+		_, err = q.Exec(
+			ctx, insertQuery, positionId, lot.OrderID, lot.EventID, lot.Amount,
+			lot.RemainingAmount, lot.UnitPrice, lot.AcquiredAt,
+		)
+		if err != nil {
+			return fmt.Errorf("Could not insert lot for order %v or event %v: %w", lot.OrderID, lot.EventID, err)
+		}
+	}
+
+	return nil
+}
+
+// Syncs every position of the portfolio, e.g. after its cost basis method changed
+func syncPortfolio(ctx context.Context, q querier, portfolioId int) error {
+	query := `select pos.id
+						from positions as pos
+						join investments as i on i.id = pos.investment_id
+						where i.portfolio_id = $1 and pos.deleted_at is null`
+
+	return syncPositions(ctx, q, fmt.Sprintf("portfolio %v", portfolioId), query, portfolioId)
+}
+
+// Syncs the positions converting orders or events executed at or after `at`
+// from or to one of the currencies, e.g. after a rate between them was
+// stored. A rate or price between two currencies is used by every conversion
+// from or to either of them, directly or crossed through the default quote
+// currency.
+func syncConverted(ctx context.Context, q querier, base string, quote string, at time.Time) error {
+	query := `select pos.id
+						from positions as pos
+						join investments as i on i.id = pos.investment_id
+						where pos.deleted_at is null and (
+							exists (
+								select 1 from orders as o
+								where o.position_id = pos.id and o.deleted_at is null and o.executed_at >= $3 and (
+									(upper(o.quote_currency) <> upper(pos.quote_currency)
+										and (upper(o.quote_currency) in ($1, $2) or upper(pos.quote_currency) in ($1, $2)))
+									or (o.fee <> 0
+										and upper(o.fee_currency) not in (upper(pos.quote_currency), upper(o.quote_currency), upper(coalesce(i.token_symbol, '')))
+										and (upper(o.fee_currency) in ($1, $2) or upper(pos.quote_currency) in ($1, $2)))
+								)
+							)
+							or exists (
+								select 1 from events as e
+								where e.position_id = pos.id and e.deleted_at is null and e.executed_at >= $3
+									and e.unit_price <> 0 and upper(e.quote_currency) <> upper(pos.quote_currency)
+									and (upper(e.quote_currency) in ($1, $2) or upper(pos.quote_currency) in ($1, $2))
+							)
+						)
+						order by pos.id`
+
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+
+	return syncPositions(ctx, q, fmt.Sprintf("rate from %s to %s", base, quote), query, base, quote, at)
+}
+
+// Syncs the positions whose IDs the query selects, which are read before the
+// first sync runs on the same connection
+func syncPositions(ctx context.Context, q querier, of string, query string, args ...interface{}) error {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("Could not get positions for %s: %w", of, err)
+	}
+	defer rows.Close()
+
+	var positionIds []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("Error while scanning positions: %w", err)
+		}
+		positionIds = append(positionIds, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error while scanning positions: %w", err)
+	}
+
+	for _, id := range positionIds {
+		if err := syncPosition(ctx, q, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewAPIKeyStore(db *Database) *APIKeyStore {
+	return &APIKeyStore{
+		Database: db,
+	}
+}
+
+type APIKeyStore struct {
+	Database *Database
+}
+
+func (store *APIKeyStore) scanAll(rows pgx.Rows) (*[]gaivota.APIKey, error) {
+	defer rows.Close()
+
+	var keys []gaivota.APIKey
+
+	for rows.Next() {
+		key, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning api keys: %w", err)
+		}
+
+		keys = append(keys, *key)
+	}
+
+	return &keys, rows.Err()
+}
+
+func (store *APIKeyStore) scanOne(row pgx.Row) (*gaivota.APIKey, error) {
+	var key gaivota.APIKey
+
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.KeyHash, &key.CreatedAt, &key.DeletedAt,
+	)
+
+	return &key, notFound(err)
+}
+
+func (store *APIKeyStore) Add(ctx context.Context, key *gaivota.APIKey) (*gaivota.APIKey, error) {
+	query := `insert into api_keys ("user_id", "name", "key_hash")
+						values ($1, $2, $3)
+						returning "id", "user_id", "name", "key_hash", "created_at", "deleted_at"`
+
+	row := store.Database.conn().QueryRow(ctx, query, key.UserID, key.Name, key.KeyHash)
+
+	newKey, err := store.scanOne(row)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert api key %s for user %v: %w", key.Name, key.UserID, err)
+	}
+
+	return newKey, nil
+}
+
+func (store *APIKeyStore) Delete(ctx context.Context, id int) error {
+	query := `update api_keys
+						set deleted_at = now()
+						where id = $1 and deleted_at is null`
+
+	cmdTags, err := store.Database.conn().Exec(ctx, query, id)
+
+	if err = affected(cmdTags, err); err != nil {
+		return fmt.Errorf("Could not delete api key %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *APIKeyStore) Get(ctx context.Context, id int) (*gaivota.APIKey, error) {
+	query := `select "id", "user_id", "name", "key_hash", "created_at", "deleted_at"
+						from api_keys where id = $1 and deleted_at is null`
+
+	row := store.Database.conn().QueryRow(ctx, query, id)
+
+	key, err := store.scanOne(row)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get api key %v: %w", id, err)
+	}
+
+	return key, nil
+}
+
+func (store *APIKeyStore) GetByHash(ctx context.Context, hash string) (*gaivota.APIKey, error) {
+	query := `select "id", "user_id", "name", "key_hash", "created_at", "deleted_at"
+						from api_keys where key_hash = $1 and deleted_at is null`
+
+	row := store.Database.conn().QueryRow(ctx, query, hash)
+
+	key, err := store.scanOne(row)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get api key by hash: %w", err)
+	}
+
+	return key, nil
+}
+
+func (store *APIKeyStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.APIKey, error) {
+	query := `select "id", "user_id", "name", "key_hash", "created_at", "deleted_at"
+						from api_keys where user_id = $1 and deleted_at is null`
+
+	rows, err := store.Database.conn().Query(ctx, query, userId)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get api keys for user %v: %w", userId, err)
+	}
+
+	return store.scanAll(rows)
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewAuditStore(db *Database) *AuditStore {
+	return &AuditStore{
+		Database: db,
+	}
+}
+
+// AuditStore appends to the audit_log table, which refuses updates and
+// deletes
+type AuditStore struct {
+	Database *Database
+}
+
+func (store *AuditStore) scanAll(rows pgx.Rows) (*[]gaivota.AuditEntry, error) {
+	defer rows.Close()
+
+	var entries []gaivota.AuditEntry
+
+	for rows.Next() {
+		entry, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning audit entries: %w", err)
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	return &entries, rows.Err()
+}
+
+// Missing actor and user IDs are selected as 0, and null JSON columns are
+// scanned as JSON nulls
+func (store *AuditStore) scanOne(row pgx.Row) (*gaivota.AuditEntry, error) {
+	var entry gaivota.AuditEntry
+
+	err := row.Scan(
+		&entry.ID, &entry.Entity, &entry.EntityID, &entry.Action, &entry.Actor.Type, &entry.Actor.ID,
+		&entry.UserID, &entry.Before, &entry.After, &entry.CreatedAt,
+	)
+
+	return &entry, notFound(err)
+}
+
+func (store *AuditStore) Add(ctx context.Context, entry *gaivota.AuditEntry) (*gaivota.AuditEntry, error) {
+	query := `insert into audit_log ("entity", "entity_id", "action", "actor_type", "actor_id", "user_id", "before", "after")
+						values ($1, $2, $3, $4, nullif($5, 0), nullif($6, 0), $7, $8)
+						returning "id", "entity", "entity_id", "action", "actor_type", coalesce("actor_id", 0), coalesce("user_id", 0), "before", "after", "created_at"`
+
+	// Passed as bytes so empty JSON is stored as null
+	row := store.Database.conn().QueryRow(
+		ctx, query, entry.Entity, entry.EntityID, entry.Action, entry.Actor.Type, entry.Actor.ID,
+		entry.UserID, []byte(entry.Before), []byte(entry.After),
+	)
+
+	newEntry, err := store.scanOne(row)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert audit entry for %s %v: %w", entry.Entity, entry.EntityID, err)
+	}
+
+	return newEntry, nil
+}
+
+var auditLogSortColumns = sortColumns(nil)
+
+func (store *AuditStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.AuditEntry, string, error) {
+	list, err := newListQuery(opts, auditLogSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.between("created_at")
+	list.ownedBy("user_id = %s")
+
+	if opts.Entity != "" {
+		list.where("entity = " + list.arg(opts.Entity))
+	}
+
+	if opts.EntityID != 0 {
+		list.where("entity_id = " + list.arg(opts.EntityID))
+	}
+
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "entity", "entity_id", "action", "actor_type", coalesce("actor_id", 0), coalesce("user_id", 0), "before", "after", "created_at"`, "audit_log")
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not get audit entries: %w", err)
+	}
+
+	entries, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*entries = (*entries)[:list.size(len(*entries))]
+
+	return entries, list.next(), nil
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+// Fields, as named in JSON, of the unique constraints users can violate.
+// Other constraints are named after their column (see constraintField).
+var uniqueFields = map[string]string{
+	"users_email_key":                       "email",
+	"portfolios_user_id_name_key":           "name",
+	"wallets_user_id_name_key":              "name",
+	"investments_portfolio_id_token_key":    "token",
+	"orders_position_exchange_trade_id_key": "tradeId",
+}
+
+// Maps constraint violations to gaivota's errors: unique violations are
+// ErrConflict, and missing references, checks and values the columns do not
+// accept ErrInvalid. The database error is kept as the cause.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+func storeError(err error) error {
+	var typed *gaivota.Error
+	var pgErr *pgconn.PgError
+	if err == nil || errors.As(err, &typed) || !errors.As(err, &pgErr) {
+		return err
+	}
+
+	field := constraintField(pgErr)
+
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		field = uniqueFields[pgErr.ConstraintName]
+		return fieldError(gaivota.ErrConflict, "already exists", field, "is already taken", err)
+	case "23503": // foreign_key_violation
+		// Deleting a row other rows still reference
+		if strings.HasPrefix(pgErr.Message, "update or delete") {
+			return &gaivota.Error{Kind: gaivota.ErrConflict, Message: "still referenced by " + pgErr.TableName, Err: err}
+		}
+		return fieldError(gaivota.ErrInvalid, "references a missing entity", field, "does not exist", err)
+	case "23514": // check_violation
+		return fieldError(gaivota.ErrInvalid, "value out of range", field, "is out of range", err)
+	case "23502": // not_null_violation
+		return fieldError(gaivota.ErrInvalid, "missing value", jsonField(pgErr.ColumnName), "is required", err)
+	case "22001", "22P02", "22003": // string_data_right_truncation, invalid_text_representation, numeric_value_out_of_range
+		return &gaivota.Error{Kind: gaivota.ErrInvalid, Message: "invalid value", Err: err}
+	}
+
+	return err
+}
+
+// Returns an Error about the field, or with the message when the field is
+// not known
+func fieldError(kind error, message string, field string, fieldMessage string, err error) error {
+	if field == "" {
+		return &gaivota.Error{Kind: kind, Message: message, Err: err}
+	}
+
+	return &gaivota.Error{
+		Kind:   kind,
+		Fields: []gaivota.FieldError{{Field: field, Message: fieldMessage}},
+		Err:    err,
+	}
+}
+
+// Returns the JSON field of constraints named by Postgres' default, like
+// portfolios_user_id_fkey or transfers_amount_check. Table constraints, like
+// transfers_check, have no field.
+func constraintField(pgErr *pgconn.PgError) string {
+	name := strings.TrimPrefix(pgErr.ConstraintName, pgErr.TableName+"_")
+	for _, suffix := range []string{"_fkey", "_check"} {
+		if strings.HasSuffix(name, suffix) {
+			return jsonField(strings.TrimSuffix(name, suffix))
+		}
+	}
+
+	return ""
+}
+
+// Names a column as in JSON: references without their _id suffix, in camel
+// case (e.g. from_wallet_id is fromWallet)
+func jsonField(column string) string {
+	words := strings.Split(strings.TrimSuffix(column, "_id"), "_")
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+
+	return strings.Join(words, "")
+}
+
+// Runs queries on the pool or a transaction, returning constraint violations
+// as gaivota's errors
+type typedErrors struct {
+	querier querier
+}
+
+func (q typedErrors) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := q.querier.Exec(ctx, sql, arguments...)
+	return tag, storeError(err)
+}
+
+func (q typedErrors) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := q.querier.Query(ctx, sql, args...)
+	return rows, storeError(err)
+}
+
+func (q typedErrors) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return typedRow{q.querier.QueryRow(ctx, sql, args...)}
+}
+
+// Inserts and updates returning columns fail when scanned
+type typedRow struct {
+	row pgx.Row
+}
+
+func (row typedRow) Scan(dest ...interface{}) error {
+	return storeError(row.row.Scan(dest...))
+}
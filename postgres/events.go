@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewEventStore(db *Database) *EventStore {
+	return &EventStore{
+		Database: db,
+	}
+}
+
+type EventStore struct {
+	Database *Database
+}
+
+const eventColumns = `"id", "position_id", "kind", "amount", "unit_price", "quote_currency", "note", "executed_at", "created_at", "updated_at", "deleted_at"`
+
+func (store *EventStore) scanAll(rows pgx.Rows) (*[]gaivota.Event, error) {
+	defer rows.Close()
+
+	var events []gaivota.Event
+
+	for rows.Next() {
+		event, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning events: %w", err)
+		}
+
+		events = append(events, *event)
+	}
+
+	return &events, rows.Err()
+}
+
+func (store *EventStore) scanOne(row pgx.Row) (*gaivota.Event, error) {
+	var event gaivota.Event
+
+	err := row.Scan(
+		&event.ID, &event.PositionID, &event.Kind, &event.Amount, &event.UnitPrice, &event.QuoteCurrency,
+		&event.Note, &event.ExecutedAt, &event.CreatedAt, &event.UpdatedAt, &event.DeletedAt,
+	)
+
+	return &event, notFound(err)
+}
+
+func (store *EventStore) Add(ctx context.Context, event *gaivota.Event) (*gaivota.Event, error) {
+	query := `insert into events ("position_id", "kind", "amount", "unit_price", "quote_currency", "note", "executed_at")
+						values ($1, $2, $3, $4, coalesce(
+							nullif(upper($5), ''),
+							(select quote_currency from positions where id = $1)
+						), $6, coalesce($7::timestamptz, now()))
+						returning ` + eventColumns
+
+	var newEvent *gaivota.Event
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) (err error) {
+		row := tx.QueryRow(
+			ctx, query, event.PositionID, event.Kind, event.Amount, event.UnitPrice,
+			event.QuoteCurrency, event.Note, optionalTime(event.ExecutedAt),
+		)
+
+		newEvent, err = store.scanOne(row)
+		if err != nil {
+			return err
+		}
+
+		return syncPosition(ctx, tx, newEvent.PositionID)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert event for position %v: %w",
+			event.PositionID, err,
+		)
+	}
+
+	return newEvent, nil
+}
+
+var eventsSortColumns = sortColumns(map[string]sortColumn{
+	"executedAt": {expression: "executed_at", cast: "timestamptz"},
+	"amount":     {expression: "amount", cast: "numeric"},
+	"unitPrice":  {expression: "unit_price", cast: "numeric"},
+	"kind":       {expression: "kind::text", cast: "text"},
+})
+
+func (store *EventStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Event, string, error) {
+	list, err := newListQuery(opts, eventsSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.where("deleted_at is null")
+	list.between("executed_at")
+	list.symbol("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id where upper(i.token_symbol) = %s)")
+	list.ownedBy("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id join portfolios as p on p.id = i.portfolio_id where p.user_id = %s)")
+	if list.opts.Kind != "" {
+		list.where("kind = " + list.arg(list.opts.Kind))
+	}
+
+	rows, err := list.query(ctx, store.Database.conn(), eventColumns, "events")
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not get events: %w", err)
+	}
+
+	events, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*events = (*events)[:list.size(len(*events))]
+
+	return events, list.next(), nil
+}
+
+func (store *EventStore) Delete(ctx context.Context, id int) error {
+	query := `update events
+						set deleted_at = now()
+						where id = $1 and deleted_at is null
+						returning "position_id"`
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) error {
+		var positionId int
+
+		err := tx.QueryRow(ctx, query, id).Scan(&positionId)
+		if err != nil {
+			return err
+		}
+
+		return syncPosition(ctx, tx, positionId)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete event %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (store *EventStore) Get(ctx context.Context, id int) (*gaivota.Event, error) {
+	query := `select ` + eventColumns + ` from events where id = $1 and deleted_at is null`
+
+	event, err := store.scanOne(store.Database.conn().QueryRow(ctx, query, id))
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get event %v: %w", id, err)
+	}
+
+	return event, nil
+}
+
+func (store *EventStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Event, error) {
+	return getEvents(ctx, store.Database.conn(), positionId)
+}
+
+// Returns the position's events in execution order
+func getEvents(ctx context.Context, q querier, positionId int) (*[]gaivota.Event, error) {
+	query := `select ` + eventColumns + ` from events
+						where position_id = $1 and deleted_at is null
+						order by executed_at, id`
+
+	rows, err := q.Query(ctx, query, positionId)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get events for position %v: %w", positionId, err)
+	}
+
+	return (&EventStore{}).scanAll(rows)
+}
+
+func (store *EventStore) Update(ctx context.Context, event *gaivota.Event) error {
+	selectQuery := `select "position_id" from events where id = $1 and deleted_at is null`
+
+	updateQuery := `update events
+						set position_id = $1,
+								kind = $2,
+								amount = $3,
+								unit_price = $4,
+								quote_currency = coalesce(nullif(upper($5), ''), quote_currency),
+								note = $6,
+								executed_at = coalesce($7::timestamptz, executed_at)
+						where id = $8 and deleted_at is null
+						returning "quote_currency", "executed_at"`
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) error {
+		var previousPositionId int
+
+		err := tx.QueryRow(ctx, selectQuery, event.ID).Scan(&previousPositionId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(
+			ctx, updateQuery, event.PositionID, event.Kind, event.Amount, event.UnitPrice,
+			event.QuoteCurrency, event.Note, optionalTime(event.ExecutedAt), event.ID,
+		).Scan(&event.QuoteCurrency, &event.ExecutedAt)
+		if err != nil {
+			return err
+		}
+
+		// Moving an event to another position changes both of them
+		if previousPositionId != event.PositionID {
+			err = syncPosition(ctx, tx, previousPositionId)
+			if err != nil {
+				return err
+			}
+		}
+
+		return syncPosition(ctx, tx, event.PositionID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not update event %v: %w", event.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewFXRateStore(db *Database) *FXRateStore {
+	return &FXRateStore{
+		Database: db,
+	}
+}
+
+type FXRateStore struct {
+	Database *Database
+}
+
+func (store *FXRateStore) scanAll(rows pgx.Rows) (*[]gaivota.FXRate, error) {
+	defer rows.Close()
+
+	var rates []gaivota.FXRate
+
+	for rows.Next() {
+		rate, err := scanFXRate(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning fx rates: %w", err)
+		}
+
+		rates = append(rates, *rate)
+	}
+
+	return &rates, rows.Err()
+}
+
+func scanFXRate(row pgx.Row) (*gaivota.FXRate, error) {
+	var rate gaivota.FXRate
+
+	err := row.Scan(
+		&rate.ID, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate,
+		&rate.At, &rate.Source, &rate.CreatedAt,
+	)
+
+	return &rate, err
+}
+
+func (store *FXRateStore) Add(ctx context.Context, rate *gaivota.FXRate) (*gaivota.FXRate, error) {
+	query := `insert into fx_rates ("base_currency", "quote_currency", "rate", "rated_at", "source")
+						values (upper($1), upper($2), $3, $4, $5)
+						on conflict ("base_currency", "quote_currency", "rated_at")
+						do update set rate = excluded.rate, source = excluded.source
+						returning "id", "base_currency", "quote_currency", "rate", "rated_at", "source", "created_at"`
+
+	var newRate *gaivota.FXRate
+
+	// Positions converting with the rate are synced in the same transaction
+	err := store.Database.begin(ctx, func(tx pgx.Tx) (err error) {
+		row := tx.QueryRow(
+			ctx, query, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.At, rate.Source,
+		)
+
+		newRate, err = scanFXRate(row)
+		if err != nil {
+			return err
+		}
+
+		return syncConverted(ctx, tx, newRate.BaseCurrency, newRate.QuoteCurrency, newRate.At)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert fx rate from %s to %s: %w", rate.BaseCurrency, rate.QuoteCurrency, err)
+	}
+
+	return newRate, nil
+}
+
+func (store *FXRateStore) GetAt(ctx context.Context, base string, quote string, at time.Time) (*gaivota.FXRate, error) {
+	return fxRateAt(ctx, store.Database.conn(), base, quote, at)
+}
+
+func (store *FXRateStore) GetRange(ctx context.Context, base string, quote string, from time.Time, to time.Time) (*[]gaivota.FXRate, error) {
+	query := `select "id", "base_currency", "quote_currency", "rate", "rated_at", "source", "created_at"
+						from fx_rates
+						where base_currency = $1 and quote_currency = $2 and rated_at between $3 and $4
+						order by rated_at`
+
+	rows, err := store.Database.conn().Query(ctx, query, base, quote, from, to)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get fx rates from %s to %s: %w", base, quote, err)
+	}
+
+	return store.scanAll(rows)
+}
+
+// Shared by the store and by position syncs, which convert orders inside their transaction
+func fxRateAt(ctx context.Context, q querier, base string, quote string, at time.Time) (*gaivota.FXRate, error) {
+	query := `select "id", "base_currency", "quote_currency", "rate", "rated_at", "source", "created_at"
+						from fx_rates
+						where base_currency = $1 and quote_currency = $2 and rated_at <= $3
+						order by rated_at desc
+						limit 1`
+
+	rate, err := scanFXRate(q.QueryRow(ctx, query, base, quote, at))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = gaivota.ErrFXRateNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get fx rate from %s to %s at %s: %w", base, quote, at, err)
+	}
+
+	return rate, nil
+}
+
+// Looks rates up with the given querier, e.g. a transaction
+type querierRates struct {
+	q querier
+}
+
+func (rates querierRates) GetAt(ctx context.Context, base string, quote string, at time.Time) (*gaivota.FXRate, error) {
+	return fxRateAt(ctx, rates.q, base, quote, at)
+}
+
+// Looks prices up with the given querier, like querierRates
+type querierPrices struct {
+	q querier
+}
+
+func (prices querierPrices) GetAt(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	return priceAt(ctx, prices.q, symbol, quote, at)
+}
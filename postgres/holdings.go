@@ -19,10 +19,11 @@ type HoldingStore struct {
 }
 
 func (store *HoldingStore) getByFK(ctx context.Context, fk_column string, fk int) (*[]gaivota.Holding, error) {
-	query := `select "id", "wallet_id", "position_id", "amount", "created_at", "updated_at", "deleted_at"
-						from holdings where $1 = $2`
+	// fk_column is never user input, so it is safe to format it into the query
+	query := fmt.Sprintf(`select "id", "wallet_id", "position_id", "amount", "created_at", "updated_at", "deleted_at"
+						from holdings where %s = $1 and deleted_at is null`, fk_column)
 
-	rows, err := store.Database.Pool.Query(ctx, query, fk_column, fk)
+	rows, err := store.Database.conn().Query(ctx, query, fk)
 
 	if err != nil {
 		return nil, fmt.Errorf("Could not get holdings where %s is %v: %w", fk_column, fk, err)
@@ -32,6 +33,8 @@ func (store *HoldingStore) getByFK(ctx context.Context, fk_column string, fk int
 }
 
 func (store *HoldingStore) scanAll(rows pgx.Rows) (*[]gaivota.Holding, error) {
+	defer rows.Close()
+
 	var holdings []gaivota.Holding
 
 	for rows.Next() {
@@ -44,7 +47,7 @@ func (store *HoldingStore) scanAll(rows pgx.Rows) (*[]gaivota.Holding, error) {
 		holdings = append(holdings, *holding)
 	}
 
-	return &holdings, nil
+	return &holdings, rows.Err()
 }
 
 func (store *HoldingStore) scanOne(row pgx.Row) (*gaivota.Holding, error) {
@@ -55,7 +58,7 @@ func (store *HoldingStore) scanOne(row pgx.Row) (*gaivota.Holding, error) {
 		&holding.CreatedAt, &holding.UpdatedAt, &holding.DeletedAt,
 	)
 
-	return &holding, err
+	return &holding, notFound(err)
 }
 
 func (store *HoldingStore) Add(ctx context.Context, holding *gaivota.Holding) (*gaivota.Holding, error) {
@@ -63,7 +66,7 @@ func (store *HoldingStore) Add(ctx context.Context, holding *gaivota.Holding) (*
 						values ($1, $2, $3)
 						returning "id", "wallet_id", "position_id", "amount", "created_at", "updated_at", "deleted_at"`
 
-	row := store.Database.Pool.QueryRow(
+	row := store.Database.conn().QueryRow(
 		ctx, query, holding.WalletID, holding.PositionID, holding.Amount,
 	)
 
@@ -79,27 +82,46 @@ func (store *HoldingStore) Add(ctx context.Context, holding *gaivota.Holding) (*
 	return newHolding, nil
 }
 
-func (store *HoldingStore) All(ctx context.Context) (*[]gaivota.Holding, error) {
-	query := `select "id", "wallet_id", "position_id", "amount", "created_at", "updated_at", "deleted_at"
-						from holdings`
+var holdingsSortColumns = sortColumns(map[string]sortColumn{
+	"amount": {expression: "amount", cast: "numeric"},
+})
+
+func (store *HoldingStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Holding, string, error) {
+	list, err := newListQuery(opts, holdingsSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.where("deleted_at is null")
+	list.between("created_at")
+	list.symbol("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id where upper(i.token_symbol) = %s)")
+	list.ownedBy("wallet_id in (select id from wallets where user_id = %s)")
 
-	rows, err := store.Database.Pool.Query(ctx, query)
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "wallet_id", "position_id", "amount", "created_at", "updated_at", "deleted_at"`, "holdings")
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get holdings: %w", err)
+		return nil, "", fmt.Errorf("Could not get holdings: %w", err)
 	}
 
-	return store.scanAll(rows)
+	holdings, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*holdings = (*holdings)[:list.size(len(*holdings))]
+
+	return holdings, list.next(), nil
 }
 
 func (store *HoldingStore) Delete(ctx context.Context, id int) error {
 	query := `update holdings
 						set deleted_at = now()
-						where id = $1`
+						where id = $1 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(ctx, query, id)
+	cmdTags, err := store.Database.conn().Exec(ctx, query, id)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not delete holding %v: %w", id, err)
 	}
 
@@ -108,9 +130,9 @@ func (store *HoldingStore) Delete(ctx context.Context, id int) error {
 
 func (store *HoldingStore) Get(ctx context.Context, id int) (*gaivota.Holding, error) {
 	query := `select "id", "wallet_id", "position_id", "amount", "created_at", "updated_at", "deleted_at"
-						from holdings where id = $1`
+						from holdings where id = $1 and deleted_at is null`
 
-	row := store.Database.Pool.QueryRow(
+	row := store.Database.conn().QueryRow(
 		ctx, query, id,
 	)
 
@@ -124,12 +146,12 @@ func (store *HoldingStore) Get(ctx context.Context, id int) (*gaivota.Holding, e
 }
 
 func (store *HoldingStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Holding, error) {
-	query := `select "h.id", "h.wallet_id", "h.position_id", "h.amount", "h.created_at", "h.updated_at", "h.deleted_at"
+	query := `select h.id, h.wallet_id, h.position_id, h.amount, h.created_at, h.updated_at, h.deleted_at
 						from holdings as h
-						join wallets as w on "w.id" = "h.wallet_id"
-						where w.user_id = $1`
+						join wallets as w on w.id = h.wallet_id
+						where w.user_id = $1 and h.deleted_at is null`
 
-	rows, err := store.Database.Pool.Query(ctx, query, userId)
+	rows, err := store.Database.conn().Query(ctx, query, userId)
 
 	if err != nil {
 		return nil, fmt.Errorf("Could not get holdings for user %v: %w", userId, err)
@@ -151,11 +173,11 @@ func (store *HoldingStore) Update(ctx context.Context, holding *gaivota.Holding)
 						set wallet_id = $1,
 								position_id = $2,
 								amount = $3
-						where id = $4`
+						where id = $4 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(ctx, query, &holding.WalletID, &holding.PositionID, &holding.Amount, &holding.ID)
+	cmdTags, err := store.Database.conn().Exec(ctx, query, &holding.WalletID, &holding.PositionID, &holding.Amount, &holding.ID)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not update holding %v: %w", holding.ID, err)
 	}
 
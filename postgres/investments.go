@@ -19,19 +19,22 @@ type InvestmentStore struct {
 }
 
 func (store *InvestmentStore) getByFK(ctx context.Context, fk_column string, fk int) (*[]gaivota.Investment, error) {
-	query := `select "id", "portfolio_id", "token", "token_symbol", "created_at", "updated_at", "deleted_at"
-						from investments where $1 = $2`
+	// fk_column is never user input, so it is safe to format it into the query
+	query := fmt.Sprintf(`select "id", "portfolio_id", "token", "token_symbol", "created_at", "updated_at", "deleted_at"
+						from investments where %s = $1 and deleted_at is null`, fk_column)
 
-	rows, err := store.Database.Pool.Query(ctx, query, fk_column, fk)
+	rows, err := store.Database.conn().Query(ctx, query, fk)
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get holdings where %s is %v: %w", fk_column, fk, err)
+		return nil, fmt.Errorf("Could not get investments where %s is %v: %w", fk_column, fk, err)
 	}
 
 	return store.scanAll(rows)
 }
 
 func (store *InvestmentStore) scanAll(rows pgx.Rows) (*[]gaivota.Investment, error) {
+	defer rows.Close()
+
 	var investments []gaivota.Investment
 
 	for rows.Next() {
@@ -44,7 +47,7 @@ func (store *InvestmentStore) scanAll(rows pgx.Rows) (*[]gaivota.Investment, err
 		investments = append(investments, *investment)
 	}
 
-	return &investments, nil
+	return &investments, rows.Err()
 }
 
 func (store *InvestmentStore) scanOne(row pgx.Row) (*gaivota.Investment, error) {
@@ -55,7 +58,7 @@ func (store *InvestmentStore) scanOne(row pgx.Row) (*gaivota.Investment, error)
 		&investment.CreatedAt, &investment.UpdatedAt, &investment.DeletedAt,
 	)
 
-	return &investment, err
+	return &investment, notFound(err)
 }
 
 func (store *InvestmentStore) Add(ctx context.Context, investment *gaivota.Investment) (*gaivota.Investment, error) {
@@ -63,7 +66,7 @@ func (store *InvestmentStore) Add(ctx context.Context, investment *gaivota.Inves
 						values ($1, $2, $3)
 						returning "id", "portfolio_id", "token", "token_symbol", "created_at", "updated_at", "deleted_at"`
 
-	row := store.Database.Pool.QueryRow(ctx, query, investment.PortfolioID, investment.Token, investment.TokenSymbol)
+	row := store.Database.conn().QueryRow(ctx, query, investment.PortfolioID, investment.Token, investment.TokenSymbol)
 
 	newInvestment, err := store.scanOne(row)
 
@@ -74,29 +77,49 @@ func (store *InvestmentStore) Add(ctx context.Context, investment *gaivota.Inves
 	return newInvestment, nil
 }
 
-func (store *InvestmentStore) All(ctx context.Context) (*[]gaivota.Investment, error) {
-	query := `select "id", "portfolio_id", "token", "token_symbol", "created_at", "updated_at", "deleted_at"
-						from investments`
+var investmentsSortColumns = sortColumns(map[string]sortColumn{
+	"token":  {expression: "token", cast: "text"},
+	"symbol": {expression: "coalesce(token_symbol, '')", cast: "text"},
+})
+
+func (store *InvestmentStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Investment, string, error) {
+	list, err := newListQuery(opts, investmentsSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.where("deleted_at is null")
+	list.between("created_at")
+	list.symbol("upper(token_symbol) = %s")
+	list.ownedBy("portfolio_id in (select id from portfolios where user_id = %s)")
 
-	rows, err := store.Database.Pool.Query(ctx, query)
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "portfolio_id", "token", "token_symbol", "created_at", "updated_at", "deleted_at"`, "investments")
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get investments: %w", err)
+		return nil, "", fmt.Errorf("Could not get investments: %w", err)
 	}
 
-	return store.scanAll(rows)
+	investments, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*investments = (*investments)[:list.size(len(*investments))]
+
+	return investments, list.next(), nil
 }
 
 func (store *InvestmentStore) Delete(ctx context.Context, id int) error {
 	query := `update investments
-						set deleted_at = now(),
-						where id = $1`
+						set deleted_at = now()
+						where id = $1 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(
+	cmdTags, err := store.Database.conn().Exec(
 		ctx, query, id,
 	)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not delete investment %v: %w", id, err)
 	}
 
@@ -105,9 +128,9 @@ func (store *InvestmentStore) Delete(ctx context.Context, id int) error {
 
 func (store *InvestmentStore) Get(ctx context.Context, id int) (*gaivota.Investment, error) {
 	query := `select "id", "portfolio_id", "token", "token_symbol", "created_at", "updated_at", "deleted_at"
-						from investments where id = $1`
+						from investments where id = $1 and deleted_at is null`
 
-	row := store.Database.Pool.QueryRow(ctx, query, id)
+	row := store.Database.conn().QueryRow(ctx, query, id)
 
 	investment, err := store.scanOne(row)
 
@@ -119,7 +142,18 @@ func (store *InvestmentStore) Get(ctx context.Context, id int) (*gaivota.Investm
 }
 
 func (store *InvestmentStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Investment, error) {
-	return store.getByFK(ctx, "user_id", userId)
+	query := `select i.id, i.portfolio_id, i.token, i.token_symbol, i.created_at, i.updated_at, i.deleted_at
+						from investments as i
+						join portfolios as p on p.id = i.portfolio_id
+						where p.user_id = $1 and i.deleted_at is null`
+
+	rows, err := store.Database.conn().Query(ctx, query, userId)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get investments for user %v: %w", userId, err)
+	}
+
+	return store.scanAll(rows)
 }
 
 func (store *InvestmentStore) GetByPortfolioID(ctx context.Context, portfolioId int) (*[]gaivota.Investment, error) {
@@ -128,14 +162,16 @@ func (store *InvestmentStore) GetByPortfolioID(ctx context.Context, portfolioId
 
 func (store *InvestmentStore) Update(ctx context.Context, investment *gaivota.Investment) error {
 	query := `update investments
-						set portfolio_id = $1
-						where id = $2`
+						set portfolio_id = $1,
+								token = $2,
+								token_symbol = $3
+						where id = $4 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(
-		ctx, query, &investment.PortfolioID, &investment.ID,
+	cmdTags, err := store.Database.conn().Exec(
+		ctx, query, &investment.PortfolioID, &investment.Token, &investment.TokenSymbol, &investment.ID,
 	)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not update investment %v: %w", investment.ID, err)
 	}
 
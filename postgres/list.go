@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+// A column list operations can sort by
+type sortColumn struct {
+	// SQL expression, which must not be null
+	expression string
+	// Postgres type cursor values are cast back to
+	cast string
+}
+
+// Sort columns every table has, by their JSON API name
+func sortColumns(extra map[string]sortColumn) map[string]sortColumn {
+	columns := map[string]sortColumn{
+		"id":        {expression: "id", cast: "int"},
+		"createdAt": {expression: "created_at", cast: "timestamptz"},
+	}
+
+	for name, column := range extra {
+		columns[name] = column
+	}
+
+	return columns
+}
+
+// Where a page ended: the sort value and ID of its last row
+type cursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(data, &c)
+
+	return c, err
+}
+
+// Builds the where, order by and limit clauses of a list query from
+// gaivota.ListOptions. Values are always passed as query arguments.
+type listQuery struct {
+	opts       gaivota.ListOptions
+	sortName   string
+	sort       sortColumn
+	conditions []string
+	args       []interface{}
+
+	// Sort value and ID of every row read, to build the next cursor
+	values []string
+	ids    []int
+}
+
+func newListQuery(opts gaivota.ListOptions, columns map[string]sortColumn) (*listQuery, error) {
+	list := &listQuery{opts: opts, sortName: opts.Sort}
+	if list.sortName == "" {
+		list.sortName = "id"
+	}
+
+	column, ok := columns[list.sortName]
+	if !ok {
+		var names []string
+		for name := range columns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, gaivota.InvalidListOption("sort", "cannot be %q, expected one of %s", opts.Sort, strings.Join(names, ", "))
+	}
+	list.sort = column
+
+	if opts.Limit < 0 {
+		return nil, gaivota.InvalidListOption("limit", "must not be negative")
+	}
+
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil || c.Sort != list.sortName || c.Descending != opts.Descending {
+			return nil, gaivota.InvalidListOption("cursor", "does not belong to this sort")
+		}
+
+		operator := ">"
+		if opts.Descending {
+			operator = "<"
+		}
+
+		list.where(fmt.Sprintf("(%s, id) %s (%s::%s, %s::int)", column.expression, operator, list.arg(c.Value), column.cast, list.arg(c.ID)))
+	}
+
+	return list, nil
+}
+
+// Adds an argument and returns its placeholder
+func (list *listQuery) arg(value interface{}) string {
+	list.args = append(list.args, value)
+
+	return fmt.Sprintf("$%d", len(list.args))
+}
+
+func (list *listQuery) where(condition string) {
+	list.conditions = append(list.conditions, condition)
+}
+
+// Filters on the date range, given the column it applies to
+func (list *listQuery) between(column string) {
+	if !list.opts.From.IsZero() {
+		list.where(fmt.Sprintf("%s >= %s", column, list.arg(list.opts.From)))
+	}
+
+	if !list.opts.To.IsZero() {
+		list.where(fmt.Sprintf("%s <= %s", column, list.arg(list.opts.To)))
+	}
+}
+
+// Filters on the owner, given a condition with a %s placeholder for the
+// user ID
+func (list *listQuery) ownedBy(condition string) {
+	if list.opts.UserID != 0 {
+		list.where(fmt.Sprintf(condition, list.arg(list.opts.UserID)))
+	}
+}
+
+// Filters on the token symbol, given a condition with a %s placeholder for
+// the symbol
+func (list *listQuery) symbol(condition string) {
+	if list.opts.Symbol != "" {
+		list.where(fmt.Sprintf(condition, list.arg(strings.ToUpper(list.opts.Symbol))))
+	}
+}
+
+// Selects the columns from the table with the list clauses. One more row
+// than the limit is read to know whether there is a next page.
+func (list *listQuery) query(ctx context.Context, q querier, columns string, table string) (pgx.Rows, error) {
+	direction := "asc"
+	if list.opts.Descending {
+		direction = "desc"
+	}
+
+	// The sort value and ID are read after the entity's columns
+	query := fmt.Sprintf("select %s, (%s)::text, id from %s", columns, list.sort.expression, table)
+
+	if len(list.conditions) > 0 {
+		query += " where " + strings.Join(list.conditions, " and ")
+	}
+
+	query += fmt.Sprintf(" order by %s %s, id %s", list.sort.expression, direction, direction)
+
+	if list.opts.Limit > 0 {
+		query += " limit " + list.arg(list.opts.Limit+1)
+	}
+
+	rows, err := q.Query(ctx, query, list.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listRows{Rows: rows, list: list}, nil
+}
+
+// Returns how many of the rows read belong to the page
+func (list *listQuery) size(read int) int {
+	if list.opts.Limit > 0 && read > list.opts.Limit {
+		return list.opts.Limit
+	}
+
+	return read
+}
+
+// Returns the cursor of the next page, empty on the last one
+func (list *listQuery) next() string {
+	if list.opts.Limit == 0 || len(list.ids) <= list.opts.Limit {
+		return ""
+	}
+
+	last := list.opts.Limit - 1
+
+	return encodeCursor(cursor{
+		Sort:       list.sortName,
+		Descending: list.opts.Descending,
+		Value:      list.values[last],
+		ID:         list.ids[last],
+	})
+}
+
+// Scans the sort value and ID selected after the entity's columns, so the
+// stores' scanners are used as is
+type listRows struct {
+	pgx.Rows
+	list *listQuery
+}
+
+func (rows *listRows) Scan(dest ...interface{}) error {
+	var value string
+	var id int
+
+	err := rows.Rows.Scan(append(dest, &value, &id)...)
+	if err != nil {
+		return err
+	}
+
+	rows.list.values = append(rows.list.values, value)
+	rows.list.ids = append(rows.list.ids, id)
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/leoschet/gaivota"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []cursor{
+		{Sort: "id", Value: "42", ID: 42},
+		{Sort: "executedAt", Descending: true, Value: "2021-03-01 12:00:00+00", ID: 7},
+		{Sort: "symbol", Value: "", ID: 1},
+		{Sort: "name", Value: "Ünïcode, \"quotes\" and / slashes", ID: 3},
+	}
+
+	for _, expected := range cursors {
+		encoded := encodeCursor(expected)
+
+		if _, err := base64.RawURLEncoding.DecodeString(encoded); err != nil {
+			t.Errorf("Cursor %q is not URL safe: %v", encoded, err)
+		}
+
+		decoded, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("Decoding %q answered %v", encoded, err)
+		}
+		if decoded != expected {
+			t.Errorf("Cursor decoded to %+v, expected %+v", decoded, expected)
+		}
+	}
+}
+
+func TestNewListQueryCursor(t *testing.T) {
+	columns := sortColumns(map[string]sortColumn{
+		"executedAt": {expression: "executed_at", cast: "timestamptz"},
+	})
+	valid := encodeCursor(cursor{Sort: "executedAt", Descending: true, Value: "2021-03-01 12:00:00+00", ID: 7})
+	byExecutedAt := gaivota.ListOptions{Sort: "executedAt", Descending: true}
+
+	list, err := newListQuery(gaivota.ListOptions{Sort: "executedAt", Descending: true, Cursor: valid}, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.conditions) != 1 || list.conditions[0] != "(executed_at, id) < ($1::timestamptz, $2::int)" {
+		t.Errorf("Cursor conditions are %q", list.conditions)
+	}
+	if len(list.args) != 2 || list.args[0] != "2021-03-01 12:00:00+00" || list.args[1] != 7 {
+		t.Errorf("Cursor arguments are %v, expected its value and ID", list.args)
+	}
+
+	tests := []struct {
+		name   string
+		opts   gaivota.ListOptions
+		cursor string
+	}{
+		{name: "not base64", opts: byExecutedAt, cursor: "not a cursor!"},
+		{name: "truncated", opts: byExecutedAt, cursor: valid[:len(valid)-4]},
+		{name: "not JSON", opts: byExecutedAt, cursor: base64.RawURLEncoding.EncodeToString([]byte("executedAt,7"))},
+		{name: "wrong types", opts: byExecutedAt, cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"executedAt","d":true,"v":"x","id":"7"}`))},
+		{name: "other sort", opts: gaivota.ListOptions{Sort: "createdAt", Descending: true}, cursor: valid},
+		{name: "other direction", opts: gaivota.ListOptions{Sort: "executedAt"}, cursor: valid},
+		{name: "sort edited", opts: gaivota.ListOptions{Descending: true}, cursor: encodeCursor(cursor{Sort: "id", Descending: false, Value: "7", ID: 7})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := test.opts
+			opts.Cursor = test.cursor
+
+			_, err := newListQuery(opts, columns)
+			if !errors.Is(err, gaivota.ErrInvalidListOptions) {
+				t.Fatalf("Cursor %q answered %v, expected ErrInvalidListOptions", test.cursor, err)
+			}
+			if fields := gaivota.FieldErrorsOf(err); len(fields) != 1 || fields[0].Field != "cursor" {
+				t.Errorf("Fields are %v, expected the cursor", fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/log"
+)
+
+// Forwards pgx's logs to a gaivota.Logger, with the fields of the query's
+// context (e.g. the request ID). pgx logs every query at info level, which
+// is too chatty for anything but debugging, so those lines are debug ones.
+type pgxLogger struct {
+	logger gaivota.Logger
+}
+
+func (l pgxLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	// Arguments may hold secrets, like password hashes
+	delete(data, "args")
+
+	l.logger.With(log.Fields(ctx)...).With(log.Sorted(data)...).Log(logLevel(level), msg)
+}
+
+func logLevel(level pgx.LogLevel) gaivota.LogLevel {
+	switch level {
+	case pgx.LogLevelError:
+		return gaivota.LogLevelError
+	case pgx.LogLevelWarn:
+		return gaivota.LogLevelWarn
+	}
+
+	return gaivota.LogLevelDebug
+}
+
+// Returns the least severe pgx level worth logging: queries are only logged
+// when the logger writes debug lines
+func pgxLogLevel(logger gaivota.Logger) pgx.LogLevel {
+	if leveled, ok := logger.(interface{ Enabled(gaivota.LogLevel) bool }); ok && !leveled.Enabled(gaivota.LogLevelDebug) {
+		return pgx.LogLevelWarn
+	}
+
+	return pgx.LogLevelInfo
+}
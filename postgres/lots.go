@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewLotStore(db *Database) *LotStore {
+	return &LotStore{
+		Database: db,
+	}
+}
+
+type LotStore struct {
+	Database *Database
+}
+
+// Lots are opened by an order or an event, the other one is 0
+const lotColumns = `"id", "position_id", coalesce("order_id", 0), coalesce("event_id", 0), "amount", "remaining_amount", "unit_price", "acquired_at", "created_at", "updated_at"`
+
+func (store *LotStore) scanAll(rows pgx.Rows) (*[]gaivota.Lot, error) {
+	defer rows.Close()
+
+	var lots []gaivota.Lot
+
+	for rows.Next() {
+		lot, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning lots: %w", err)
+		}
+
+		lots = append(lots, *lot)
+	}
+
+	return &lots, rows.Err()
+}
+
+func (store *LotStore) scanOne(row pgx.Row) (*gaivota.Lot, error) {
+	var lot gaivota.Lot
+
+	err := row.Scan(
+		&lot.ID, &lot.PositionID, &lot.OrderID, &lot.EventID, &lot.Amount, &lot.RemainingAmount,
+		&lot.UnitPrice, &lot.AcquiredAt, &lot.CreatedAt, &lot.UpdatedAt,
+	)
+
+	return &lot, notFound(err)
+}
+
+var lotsSortColumns = sortColumns(map[string]sortColumn{
+	"acquiredAt": {expression: "acquired_at", cast: "timestamptz"},
+	"amount":     {expression: "amount", cast: "numeric"},
+	"unitPrice":  {expression: "unit_price", cast: "numeric"},
+})
+
+func (store *LotStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Lot, string, error) {
+	list, err := newListQuery(opts, lotsSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.between("acquired_at")
+	list.symbol("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id where upper(i.token_symbol) = %s)")
+	list.ownedBy("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id join portfolios as p on p.id = i.portfolio_id where p.user_id = %s)")
+
+	rows, err := list.query(ctx, store.Database.conn(), lotColumns, "lots")
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not get lots: %w", err)
+	}
+
+	lots, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*lots = (*lots)[:list.size(len(*lots))]
+
+	return lots, list.next(), nil
+}
+
+func (store *LotStore) Get(ctx context.Context, id int) (*gaivota.Lot, error) {
+	query := `select ` + lotColumns + ` from lots where id = $1`
+
+	row := store.Database.conn().QueryRow(ctx, query, id)
+
+	lot, err := store.scanOne(row)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get lot %v: %w", id, err)
+	}
+
+	return lot, nil
+}
+
+func (store *LotStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Lot, error) {
+	query := `select ` + lotColumns + ` from lots where position_id = $1 order by acquired_at, id`
+
+	rows, err := store.Database.conn().Query(ctx, query, positionId)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get lots for position %v: %w", positionId, err)
+	}
+
+	return store.scanAll(rows)
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/leoschet/gaivota/metrics"
+)
+
+// RegisterMetrics reports the connection pool's statistics, read when
+// metrics are written
+func (db *Database) RegisterMetrics(registry *metrics.Registry) {
+	gauge := func(name, help string, value func() float64) {
+		registry.GaugeFunc(name, help, func(ctx context.Context) (float64, error) {
+			return value(), nil
+		})
+	}
+	counter := func(name, help string, value func() float64) {
+		registry.CounterFunc(name, help, func(ctx context.Context) (float64, error) {
+			return value(), nil
+		})
+	}
+
+	gauge("gaivota_db_pool_conns", "Connections open in the pool.", func() float64 {
+		return float64(db.Pool.Stat().TotalConns())
+	})
+	gauge("gaivota_db_pool_acquired_conns", "Connections in use.", func() float64 {
+		return float64(db.Pool.Stat().AcquiredConns())
+	})
+	gauge("gaivota_db_pool_idle_conns", "Connections open and not in use.", func() float64 {
+		return float64(db.Pool.Stat().IdleConns())
+	})
+	gauge("gaivota_db_pool_constructing_conns", "Connections being opened.", func() float64 {
+		return float64(db.Pool.Stat().ConstructingConns())
+	})
+	gauge("gaivota_db_pool_max_conns", "Most connections the pool opens.", func() float64 {
+		return float64(db.Pool.Stat().MaxConns())
+	})
+	counter("gaivota_db_pool_acquires_total", "Connections acquired from the pool.", func() float64 {
+		return float64(db.Pool.Stat().AcquireCount())
+	})
+	counter("gaivota_db_pool_empty_acquires_total", "Acquires that waited for a connection because none was idle.", func() float64 {
+		return float64(db.Pool.Stat().EmptyAcquireCount())
+	})
+	counter("gaivota_db_pool_canceled_acquires_total", "Acquires canceled by their context.", func() float64 {
+		return float64(db.Pool.Stat().CanceledAcquireCount())
+	})
+	counter("gaivota_db_pool_acquire_duration_seconds_total", "Time spent acquiring connections.", func() float64 {
+		return db.Pool.Stat().AcquireDuration().Seconds()
+	})
+}
@@ -0,0 +1,367 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/leoschet/gaivota/migrations"
+)
+
+// ErrMigrationEdited is returned when an applied migration no longer matches
+// its file. Migrations are never edited once applied: add a new one instead.
+var ErrMigrationEdited = errors.New("applied migration was edited")
+
+// ErrUnknownMigration is returned for versions there is no migration for,
+// e.g. a database migrated by a newer release
+var ErrUnknownMigration = errors.New("unknown migration")
+
+// Key of the advisory lock held while migrating, so servers auto-migrating on
+// start and the CLI do not migrate at the same time
+const migrationLock = 4829104
+
+const (
+	MigrationApplied = "applied"
+	MigrationPending = "pending"
+	MigrationEdited  = "edited"
+	MigrationUnknown = "unknown"
+)
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	State     string
+	AppliedAt time.Time
+}
+
+// A row of the schema_migrations table
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func NewMigrator(db *Database) (*Migrator, error) {
+	all, err := migrations.All()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		Database:   db,
+		Migrations: all,
+	}, nil
+}
+
+// Migrator applies and reverts the embedded migrations, one transaction per
+// migration, recording the applied ones in schema_migrations.
+type Migrator struct {
+	Database   *Database
+	Migrations []migrations.Migration
+}
+
+// Latest version there is a migration for
+func (m *Migrator) Latest() int {
+	return len(m.Migrations)
+}
+
+// Version returns the version the database is migrated to, 0 when no
+// migration was applied.
+func (m *Migrator) Version(ctx context.Context) (version int, err error) {
+	err = m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		version = len(applied)
+		return nil
+	})
+
+	return version, err
+}
+
+// Name of the migrations in health checks
+func (m *Migrator) Name() string {
+	return "migrations"
+}
+
+// Ping fails while the database is behind the latest migration, so the API
+// server is not ready to run against a schema it does not expect. Unlike
+// Version, it only reads schema_migrations: it neither waits for migrations
+// in progress nor records tern's version.
+func (m *Migrator) Ping(ctx context.Context) error {
+	var version int
+	err := m.Database.Pool.QueryRow(ctx, `select count(*) from schema_migrations`).Scan(&version)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42P01" {
+		// undefined_table: nothing was migrated with the Migrator yet
+		version, err = 0, nil
+	}
+	if err != nil {
+		return fmt.Errorf("Could not read the schema version: %w", err)
+	}
+
+	if version < m.Latest() {
+		return fmt.Errorf("database schema at version %v, expected %v: run the pending migrations", version, m.Latest())
+	}
+
+	return nil
+}
+
+// Status lists every migration, known or only recorded in the database, by
+// version.
+func (m *Migrator) Status(ctx context.Context) (statuses []MigrationStatus, err error) {
+	err = m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.Migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: MigrationPending}
+
+			if row, ok := applied[migration.Version]; ok {
+				status.State = MigrationApplied
+				status.AppliedAt = row.AppliedAt
+
+				if row.Checksum != migration.Checksum {
+					status.State = MigrationEdited
+				}
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		for version := m.Latest() + 1; version <= len(applied); version++ {
+			row := applied[version]
+			statuses = append(statuses, MigrationStatus{
+				Version: row.Version, Name: row.Name, State: MigrationUnknown, AppliedAt: row.AppliedAt,
+			})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]migrations.Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) ([]migrations.Migration, error) {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		return nil, nil
+	}
+
+	return m.To(ctx, version-1)
+}
+
+// To applies or reverts migrations until the database is at `target`, 0
+// reverting all of them. It returns the migrations run, in the order they
+// ran. Nothing runs when an applied migration was edited.
+func (m *Migrator) To(ctx context.Context, target int) (ran []migrations.Migration, err error) {
+	if target < 0 || target > m.Latest() {
+		return nil, fmt.Errorf("Could not migrate to version %v: %w", target, ErrUnknownMigration)
+	}
+
+	err = m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for version := len(applied); version < target; version++ {
+			migration := m.Migrations[version]
+
+			err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(
+					ctx,
+					`insert into schema_migrations ("version", "name", "checksum") values ($1, $2, $3)`,
+					migration.Version, migration.Name, migration.Checksum,
+				)
+				return err
+			})
+
+			if err != nil {
+				return fmt.Errorf("Could not apply migration %v (%s): %w", migration.Version, migration.Name, err)
+			}
+
+			ran = append(ran, migration)
+		}
+
+		for version := len(applied); version > target; version-- {
+			migration := m.Migrations[version-1]
+
+			err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(ctx, `delete from schema_migrations where version = $1`, migration.Version)
+				return err
+			})
+
+			if err != nil {
+				return fmt.Errorf("Could not revert migration %v (%s): %w", migration.Version, migration.Name, err)
+			}
+
+			ran = append(ran, migration)
+		}
+
+		return nil
+	})
+
+	return ran, err
+}
+
+// Checks the applied migrations are known and unchanged
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	for version := 1; version <= len(applied); version++ {
+		row := applied[version]
+
+		if version > m.Latest() {
+			return fmt.Errorf(
+				"Database is at version %v but only %v migrations are known: %w",
+				len(applied), m.Latest(), ErrUnknownMigration,
+			)
+		}
+
+		if row.Checksum != m.Migrations[version-1].Checksum {
+			return fmt.Errorf("Migration %v (%s): %w", version, row.Name, ErrMigrationEdited)
+		}
+	}
+
+	return nil
+}
+
+// Runs fn on a connection holding the migration lock, after creating the
+// schema_migrations table if needed
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.Database.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("Could not acquire connection to migrate: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `select pg_advisory_lock($1)`, migrationLock); err != nil {
+		return fmt.Errorf("Could not lock migrations: %w", err)
+	}
+	// The lock is held by the session, so it must be released before the
+	// connection goes back to the pool
+	defer conn.Exec(context.Background(), `select pg_advisory_unlock($1)`, migrationLock)
+
+	query := `create table if not exists schema_migrations(
+							version int primary key,
+							name varchar not null,
+							checksum char(64) not null,
+							applied_at timestamptz not null default now()
+						)`
+
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("Could not create schema_migrations table: %w", err)
+	}
+
+	if err := m.adoptTern(ctx, conn); err != nil {
+		return fmt.Errorf("Could not adopt tern schema version: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// Databases migrated with tern only record their version, in schema_version.
+// When nothing is recorded in schema_migrations yet, the migrations up to
+// that version are recorded as applied, with the checksums of the embedded
+// files tern ran.
+func (m *Migrator) adoptTern(ctx context.Context, conn *pgxpool.Conn) error {
+	var recorded bool
+	var hasTern bool
+
+	query := `select exists(select 1 from schema_migrations), to_regclass('schema_version') is not null`
+
+	if err := conn.QueryRow(ctx, query).Scan(&recorded, &hasTern); err != nil {
+		return err
+	}
+
+	if recorded || !hasTern {
+		return nil
+	}
+
+	var version int
+	if err := conn.QueryRow(ctx, `select version from schema_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return fmt.Errorf("tern version %v: %w", version, ErrUnknownMigration)
+	}
+
+	return conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		for _, migration := range m.Migrations[:version] {
+			_, err := tx.Exec(
+				ctx,
+				`insert into schema_migrations ("version", "name", "checksum") values ($1, $2, $3)`,
+				migration.Version, migration.Name, migration.Checksum,
+			)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Returns the rows of schema_migrations by version
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `select "version", "name", "checksum", "applied_at" from schema_migrations order by version`)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+
+	for rows.Next() {
+		var row appliedMigration
+
+		if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.AppliedAt); err != nil {
+			return nil, fmt.Errorf("Error while scanning applied migrations: %w", err)
+		}
+
+		applied[row.Version] = row
+	}
+
+	// Versions are applied in order and reverted from the latest, so they are
+	// always 1 to the current one
+	for version := 1; version <= len(applied); version++ {
+		if _, ok := applied[version]; !ok {
+			return nil, fmt.Errorf("Could not get applied migrations: version %v is missing", version)
+		}
+	}
+
+	return applied, rows.Err()
+}
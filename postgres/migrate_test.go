@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/leoschet/gaivota/migrations"
+)
+
+func TestMigratorVerify(t *testing.T) {
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := &Migrator{Migrations: all}
+
+	// Applied as released up to the version
+	applied := func(version int) map[int]appliedMigration {
+		rows := map[int]appliedMigration{}
+		for _, migration := range all[:version] {
+			rows[migration.Version] = appliedMigration{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum}
+		}
+		return rows
+	}
+
+	for _, version := range []int{0, 1, len(all)} {
+		if err := migrator.verify(applied(version)); err != nil {
+			t.Errorf("Verifying %d applied migrations answered %v", version, err)
+		}
+	}
+
+	edited := applied(len(all))
+	row := edited[2]
+	row.Checksum = "0000"
+	edited[2] = row
+	if err := migrator.verify(edited); !errors.Is(err, ErrMigrationEdited) {
+		t.Errorf("Verifying an edited migration answered %v, expected ErrMigrationEdited", err)
+	}
+
+	newer := applied(len(all))
+	newer[len(all)+1] = appliedMigration{Version: len(all) + 1, Name: "from_a_newer_release", Checksum: "1111"}
+	if err := migrator.verify(newer); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("Verifying a migration from a newer release answered %v, expected ErrUnknownMigration", err)
+	}
+}
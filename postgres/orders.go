@@ -19,6 +19,8 @@ type OrderStore struct {
 }
 
 func (store *OrderStore) scanAll(rows pgx.Rows) ([]gaivota.Order, error) {
+	defer rows.Close()
+
 	var orders []gaivota.Order
 
 	for rows.Next() {
@@ -31,32 +33,53 @@ func (store *OrderStore) scanAll(rows pgx.Rows) ([]gaivota.Order, error) {
 		orders = append(orders, *order)
 	}
 
-	return orders, nil
+	return orders, rows.Err()
 }
 
 func (store *OrderStore) scanOne(row pgx.Row) (*gaivota.Order, error) {
 	var order gaivota.Order
 
 	err := row.Scan(
-		&order.ID, &order.PositionID, &order.Amount, &order.UnitPrice, &order.TotalPrice,
-		&order.Operation, &order.Type, &order.Exchange, &order.ExecutedAt,
+		&order.ID, &order.PositionID, &order.Amount, &order.UnitPrice, &order.TotalPrice, &order.QuoteCurrency,
+		&order.Fee, &order.FeeCurrency, &order.Operation, &order.Type, &order.Exchange, &order.TradeID, &order.ExecutedAt,
 		&order.CreatedAt, &order.UpdatedAt, &order.DeletedAt,
 	)
 
-	return &order, err
+	return &order, notFound(err)
+}
+
+const insertOrderQuery = `insert into orders ("position_id", "amount", "unit_price", "total_price", "quote_currency", "fee", "fee_currency", "operation", "type", "exchange", "trade_id", "executed_at")
+						values ($1, $2, $3, $4, coalesce(
+							nullif(upper($5), ''),
+							(select quote_currency from positions where id = $1)
+						), $11, coalesce(
+							nullif(upper($12), ''),
+							nullif(upper($5), ''),
+							(select quote_currency from positions where id = $1)
+						), $6, $7, $8, $9, coalesce($10::timestamptz, now()))
+						returning "id", "position_id", "amount", "unit_price", "total_price", "quote_currency", "fee", "fee_currency", "operation", "type", "exchange", "trade_id", "executed_at", "created_at", "updated_at", "deleted_at"`
+
+func (store *OrderStore) insert(ctx context.Context, tx pgx.Tx, order *gaivota.Order) (*gaivota.Order, error) {
+	row := tx.QueryRow(
+		ctx, insertOrderQuery, order.PositionID, order.Amount, order.UnitPrice, order.TotalPrice,
+		order.QuoteCurrency, order.Operation, order.Type, order.Exchange, order.TradeID, optionalTime(order.ExecutedAt),
+		order.Fee, order.FeeCurrency,
+	)
+
+	return store.scanOne(row)
 }
 
 func (store *OrderStore) Add(ctx context.Context, order *gaivota.Order) (*gaivota.Order, error) {
-	query := `insert into orders ("position_id", "amount", "unit_price", "total_price", "operation", "type", "exchange", "executed_at")
-						values ($1, $2, $3, $4, $5, $6, $7, $8)
-						returning "id", "position_id", "amount", "unit_price", "total_price", "operation", "type", "exchange", "executed_at", "created_at", "updated_at", "deleted_at"`
+	var newOrder *gaivota.Order
 
-	row := store.Database.Pool.QueryRow(
-		ctx, query, order.PositionID, order.Amount, order.UnitPrice, order.TotalPrice,
-		order.Operation, order.Type, order.Exchange, order.ExecutedAt,
-	)
+	err := store.Database.begin(ctx, func(tx pgx.Tx) (err error) {
+		newOrder, err = store.insert(ctx, tx, order)
+		if err != nil {
+			return err
+		}
 
-	newOrder, err := store.scanOne(row)
+		return syncPosition(ctx, tx, newOrder.PositionID)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -68,27 +91,105 @@ func (store *OrderStore) Add(ctx context.Context, order *gaivota.Order) (*gaivot
 	return newOrder, nil
 }
 
-func (store *OrderStore) All(ctx context.Context) ([]gaivota.Order, error) {
-	query := `select "id", "position_id", "amount", "unit_price", "total_price", "operation", "type", "exchange", "executed_at", "created_at", "updated_at", "deleted_at"
-						from orders where deleted_at is null`
+func (store *OrderStore) AddMany(ctx context.Context, orders []gaivota.Order) ([]gaivota.Order, error) {
+	var newOrders []gaivota.Order
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) error {
+		// Each position is replayed once, after all of its orders are in
+		var positionIds []int
+		seen := map[int]bool{}
+
+		for i := range orders {
+			newOrder, err := store.insert(ctx, tx, &orders[i])
+			if err != nil {
+				return fmt.Errorf("order %v of %v: %w", i+1, len(orders), err)
+			}
+
+			newOrders = append(newOrders, *newOrder)
+
+			if !seen[newOrder.PositionID] {
+				seen[newOrder.PositionID] = true
+				positionIds = append(positionIds, newOrder.PositionID)
+			}
+		}
+
+		for _, positionId := range positionIds {
+			if err := syncPosition(ctx, tx, positionId); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert orders: %w", err)
+	}
+
+	return newOrders, nil
+}
+
+var ordersSortColumns = sortColumns(map[string]sortColumn{
+	"executedAt": {expression: "executed_at", cast: "timestamptz"},
+	"amount":     {expression: "amount", cast: "numeric"},
+	"unitPrice":  {expression: "unit_price", cast: "numeric"},
+	"totalPrice": {expression: "total_price", cast: "numeric"},
+	"exchange":   {expression: "exchange", cast: "text"},
+})
 
-	rows, err := store.Database.Pool.Query(ctx, query)
+func (store *OrderStore) All(ctx context.Context, opts gaivota.ListOptions) ([]gaivota.Order, string, error) {
+	list, err := newListQuery(opts, ordersSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.where("deleted_at is null")
+	list.between("executed_at")
+	list.symbol("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id where upper(i.token_symbol) = %s)")
+	list.ownedBy("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id join portfolios as p on p.id = i.portfolio_id where p.user_id = %s)")
+	if list.opts.Operation != "" {
+		list.where("operation = " + list.arg(list.opts.Operation))
+	}
+
+	if list.opts.Exchange != "" {
+		list.where("lower(exchange) = lower(" + list.arg(list.opts.Exchange) + ")")
+	}
+
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "position_id", "amount", "unit_price", "total_price", "quote_currency", "fee", "fee_currency", "operation", "type", "exchange", "trade_id", "executed_at", "created_at", "updated_at", "deleted_at"`, "orders")
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get orders: %w", err)
+		return nil, "", fmt.Errorf("Could not get orders: %w", err)
 	}
 
-	return store.scanAll(rows)
+	orders, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	orders = orders[:list.size(len(orders))]
+
+	return orders, list.next(), nil
 }
 
 func (store *OrderStore) Delete(ctx context.Context, id int) error {
 	query := `update orders
 						set deleted_at = now()
-						where id = $1`
+						where id = $1 and deleted_at is null
+						returning "position_id"`
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) error {
+		var positionId int
 
-	cmdTags, err := store.Database.Pool.Exec(ctx, query, id)
+		err := tx.QueryRow(ctx, query, id).Scan(&positionId)
+		if err != nil {
+			return err
+		}
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+		return syncPosition(ctx, tx, positionId)
+	})
+
+	if err != nil {
 		return fmt.Errorf("Could not delete order %v: %w", id, err)
 	}
 
@@ -96,10 +197,10 @@ func (store *OrderStore) Delete(ctx context.Context, id int) error {
 }
 
 func (store *OrderStore) Get(ctx context.Context, id int) (*gaivota.Order, error) {
-	query := `select "id", "position_id", "amount", "unit_price", "total_price", "operation", "type", "exchange", "executed_at", "created_at", "updated_at", "deleted_at"
+	query := `select "id", "position_id", "amount", "unit_price", "total_price", "quote_currency", "fee", "fee_currency", "operation", "type", "exchange", "trade_id", "executed_at", "created_at", "updated_at", "deleted_at"
 						from orders where id = $1 and deleted_at is null`
 
-	row := store.Database.Pool.QueryRow(ctx, query, id)
+	row := store.Database.conn().QueryRow(ctx, query, id)
 
 	order, err := store.scanOne(row)
 
@@ -110,24 +211,67 @@ func (store *OrderStore) Get(ctx context.Context, id int) (*gaivota.Order, error
 	return order, nil
 }
 
+func (store *OrderStore) GetByPositionID(ctx context.Context, positionId int) ([]gaivota.Order, error) {
+	query := `select "id", "position_id", "amount", "unit_price", "total_price", "quote_currency", "fee", "fee_currency", "operation", "type", "exchange", "trade_id", "executed_at", "created_at", "updated_at", "deleted_at"
+						from orders where position_id = $1 and deleted_at is null`
+
+	rows, err := store.Database.conn().Query(ctx, query, positionId)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get orders for position %v: %w", positionId, err)
+	}
+
+	return store.scanAll(rows)
+}
+
 func (store *OrderStore) Update(ctx context.Context, order *gaivota.Order) error {
-	query := `update orders
+	selectQuery := `select "position_id" from orders where id = $1 and deleted_at is null`
+
+	updateQuery := `update orders
 						set position_id = $1,
 								amount = $2,
 								unit_price = $3,
 								total_price = $4,
-								operation = $5,
-								type = $6,
-								exchange = $7,
-								executed_at = $8
-						where id = $9`
-
-	cmdTags, err := store.Database.Pool.Exec(
-		ctx, query, order.PositionID, order.Amount, order.UnitPrice, order.TotalPrice,
-		order.Operation, order.Type, order.Exchange, order.ExecutedAt, order.ID,
-	)
+								quote_currency = coalesce(nullif(upper($5), ''), quote_currency),
+								operation = $6,
+								type = $7,
+								exchange = $8,
+								trade_id = $9,
+								executed_at = coalesce($10::timestamptz, executed_at),
+								fee = $11,
+								fee_currency = coalesce(nullif(upper($12), ''), nullif(upper($5), ''), fee_currency)
+						where id = $13 and deleted_at is null
+						returning "quote_currency", "fee_currency", "executed_at"`
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) error {
+		var previousPositionId int
+
+		err := tx.QueryRow(ctx, selectQuery, order.ID).Scan(&previousPositionId)
+		if err != nil {
+			return err
+		}
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+		err = tx.QueryRow(
+			ctx, updateQuery, order.PositionID, order.Amount, order.UnitPrice, order.TotalPrice,
+			order.QuoteCurrency, order.Operation, order.Type, order.Exchange, order.TradeID, optionalTime(order.ExecutedAt),
+			order.Fee, order.FeeCurrency, order.ID,
+		).Scan(&order.QuoteCurrency, &order.FeeCurrency, &order.ExecutedAt)
+		if err != nil {
+			return err
+		}
+
+		// Moving an order to another position changes both of them
+		if previousPositionId != order.PositionID {
+			err = syncPosition(ctx, tx, previousPositionId)
+			if err != nil {
+				return err
+			}
+		}
+
+		return syncPosition(ctx, tx, order.PositionID)
+	})
+
+	if err != nil {
 		return fmt.Errorf("Could not update order %v: %w", order.ID, err)
 	}
 
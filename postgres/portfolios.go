@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/leoschet/gaivota"
@@ -19,6 +20,8 @@ type PortfolioStore struct {
 }
 
 func (store *PortfolioStore) scanAll(rows pgx.Rows) (*[]gaivota.Portfolio, error) {
+	defer rows.Close()
+
 	var portfolios []gaivota.Portfolio
 
 	for rows.Next() {
@@ -31,23 +34,36 @@ func (store *PortfolioStore) scanAll(rows pgx.Rows) (*[]gaivota.Portfolio, error
 		portfolios = append(portfolios, *portfolio)
 	}
 
-	return &portfolios, nil
+	return &portfolios, rows.Err()
 }
 
 func (store *PortfolioStore) scanOne(row pgx.Row) (*gaivota.Portfolio, error) {
 	var portfolio gaivota.Portfolio
 
-	err := row.Scan(&portfolio.ID, &portfolio.UserID, &portfolio.Name, &portfolio.CreatedAt, &portfolio.UpdatedAt, &portfolio.DeletedAt)
+	err := row.Scan(
+		&portfolio.ID, &portfolio.UserID, &portfolio.Name, &portfolio.CostBasisMethod, &portfolio.ReportingCurrency,
+		&portfolio.CreatedAt, &portfolio.UpdatedAt, &portfolio.DeletedAt,
+	)
 
-	return &portfolio, err
+	return &portfolio, notFound(err)
 }
 
 func (store *PortfolioStore) Add(ctx context.Context, portfolio *gaivota.Portfolio) (*gaivota.Portfolio, error) {
-	query := `insert into portfolios ("user_id", "name")
-						values ($1, $2)
-						returning "id", "user_id", "name", "created_at", "updated_at", "deleted_at"`
+	method := portfolio.CostBasisMethod
+	if method == "" {
+		method = gaivota.CostBasisAverage
+	}
+
+	// Portfolios report in their user's currency unless told otherwise
+	query := `insert into portfolios ("user_id", "name", "cost_basis_method", "reporting_currency")
+						values ($1, $2, $3, coalesce(
+							nullif(upper($4), ''),
+							(select reporting_currency from users where id = $1),
+							'USD'
+						))
+						returning "id", "user_id", "name", "cost_basis_method", "reporting_currency", "created_at", "updated_at", "deleted_at"`
 
-	row := store.Database.Pool.QueryRow(ctx, query, portfolio.UserID, portfolio.Name)
+	row := store.Database.conn().QueryRow(ctx, query, portfolio.UserID, portfolio.Name, method, portfolio.ReportingCurrency)
 
 	newPortfolio, err := store.scanOne(row)
 
@@ -58,29 +74,47 @@ func (store *PortfolioStore) Add(ctx context.Context, portfolio *gaivota.Portfol
 	return newPortfolio, nil
 }
 
-func (store *PortfolioStore) All(ctx context.Context) (*[]gaivota.Portfolio, error) {
-	query := `select "id", "user_id", "name", "created_at", "updated_at", "deleted_at"
-						from portfolios`
+var portfoliosSortColumns = sortColumns(map[string]sortColumn{
+	"name": {expression: "name", cast: "text"},
+})
+
+func (store *PortfolioStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Portfolio, string, error) {
+	list, err := newListQuery(opts, portfoliosSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.where("deleted_at is null")
+	list.between("created_at")
+	list.ownedBy("user_id = %s")
 
-	rows, err := store.Database.Pool.Query(ctx, query)
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "user_id", "name", "cost_basis_method", "reporting_currency", "created_at", "updated_at", "deleted_at"`, "portfolios")
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get portfolios: %w", err)
+		return nil, "", fmt.Errorf("Could not get portfolios: %w", err)
 	}
 
-	return store.scanAll(rows)
+	portfolios, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*portfolios = (*portfolios)[:list.size(len(*portfolios))]
+
+	return portfolios, list.next(), nil
 }
 
 func (store *PortfolioStore) Delete(ctx context.Context, id int) error {
 	query := `update portfolios
 						set deleted_at = now()
-						where id = $1`
+						where id = $1 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(
+	cmdTags, err := store.Database.conn().Exec(
 		ctx, query, id,
 	)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not delete portfolio %v: %w", id, err)
 	}
 
@@ -88,12 +122,10 @@ func (store *PortfolioStore) Delete(ctx context.Context, id int) error {
 }
 
 func (store *PortfolioStore) Get(ctx context.Context, id int) (*gaivota.Portfolio, error) {
-	query := `select "id", "user_id", "name", "created_at", "updated_at", "deleted_at"
-						from portfolios where id = $1`
+	query := `select "id", "user_id", "name", "cost_basis_method", "reporting_currency", "created_at", "updated_at", "deleted_at"
+						from portfolios where id = $1 and deleted_at is null`
 
-	portfolio := &gaivota.Portfolio{}
-
-	row := store.Database.Pool.QueryRow(ctx, query, id)
+	row := store.Database.conn().QueryRow(ctx, query, id)
 
 	portfolio, err := store.scanOne(row)
 
@@ -105,10 +137,10 @@ func (store *PortfolioStore) Get(ctx context.Context, id int) (*gaivota.Portfoli
 }
 
 func (store *PortfolioStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Portfolio, error) {
-	query := `select "id", "user_id", "name", "created_at", "updated_at", "deleted_at"
-						from portfolios where user_id = $1`
+	query := `select "id", "user_id", "name", "cost_basis_method", "reporting_currency", "created_at", "updated_at", "deleted_at"
+						from portfolios where user_id = $1 and deleted_at is null`
 
-	rows, err := store.Database.Pool.Query(ctx, query, userId)
+	rows, err := store.Database.conn().Query(ctx, query, userId)
 
 	if err != nil {
 		return nil, fmt.Errorf("Could not get portfolios for user %v: %w", userId, err)
@@ -118,15 +150,51 @@ func (store *PortfolioStore) GetByUserID(ctx context.Context, userId int) (*[]ga
 }
 
 func (store *PortfolioStore) Update(ctx context.Context, portfolio *gaivota.Portfolio) error {
-	query := `update portfolios
-						set name = $1
-						where id = $2`
+	selectQuery := `select "cost_basis_method", "reporting_currency" from portfolios
+						where id = $1 and deleted_at is null
+						for update`
 
-	cmdTags, err := store.Database.Pool.Exec(
-		ctx, query, &portfolio.Name, &portfolio.ID,
-	)
+	updateQuery := `update portfolios
+						set name = $1,
+								cost_basis_method = $2,
+								reporting_currency = $3
+						where id = $4`
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) error {
+		var previousMethod gaivota.CostBasisMethod
+		var previousCurrency string
+
+		err := tx.QueryRow(ctx, selectQuery, portfolio.ID).Scan(&previousMethod, &previousCurrency)
+		if err != nil {
+			return err
+		}
+
+		if portfolio.CostBasisMethod == "" {
+			portfolio.CostBasisMethod = previousMethod
+		}
+
+		portfolio.ReportingCurrency = strings.ToUpper(portfolio.ReportingCurrency)
+		if portfolio.ReportingCurrency == "" {
+			portfolio.ReportingCurrency = previousCurrency
+		}
+
+		_, err = tx.Exec(
+			ctx, updateQuery, &portfolio.Name, &portfolio.CostBasisMethod,
+			&portfolio.ReportingCurrency, &portfolio.ID,
+		)
+		if err != nil {
+			return err
+		}
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+		// Lots are consumed differently, so every position must be replayed
+		if portfolio.CostBasisMethod != previousMethod {
+			return syncPortfolio(ctx, tx, portfolio.ID)
+		}
+
+		return nil
+	})
+
+	if err != nil {
 		return fmt.Errorf("Could not update portfolio %v: %w", portfolio.ID, err)
 	}
 
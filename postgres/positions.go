@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/leoschet/gaivota"
 )
 
@@ -17,68 +18,104 @@ type PositionStore struct {
 	Database *Database
 }
 
-func (store *PositionStore) Add(ctx context.Context, position *gaivota.Position) (*gaivota.Position, error) {
-	query := `insert into positions ("investment_id", "amount", "average_price", "profit")
-						values ($1, $2, $3, $4)
-						returning "id", "investment_id", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"`
-
-	var newPosition gaivota.Position
-
-	err := store.Database.Pool.QueryRow(
-		ctx, query, position.InvestmentID, position.Amount,
-		position.AveragePrice, position.Profit,
-	).Scan(
-		&newPosition.ID, &newPosition.InvestmentID, &newPosition.Amount,
-		&newPosition.AveragePrice, &newPosition.Profit,
-		&newPosition.CreatedAt, &newPosition.UpdatedAt, &newPosition.DeletedAt,
+func (store *PositionStore) scanAll(rows pgx.Rows) (*[]gaivota.Position, error) {
+	defer rows.Close()
+
+	var positions []gaivota.Position
+
+	for rows.Next() {
+		position, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning positions: %w", err)
+		}
+
+		positions = append(positions, *position)
+	}
+
+	return &positions, rows.Err()
+}
+
+func (store *PositionStore) scanOne(row pgx.Row) (*gaivota.Position, error) {
+	var position gaivota.Position
+
+	err := row.Scan(
+		&position.ID, &position.InvestmentID, &position.QuoteCurrency, &position.Amount,
+		&position.AveragePrice, &position.Profit,
+		&position.CreatedAt, &position.UpdatedAt, &position.DeletedAt,
 	)
 
+	return &position, notFound(err)
+}
+
+func (store *PositionStore) Add(ctx context.Context, position *gaivota.Position) (*gaivota.Position, error) {
+	// Amount, average price and profit start empty and are kept in sync by the
+	// OrderStore. Positions are accounted in their portfolio's currency by default.
+	query := `insert into positions ("investment_id", "quote_currency", "amount", "average_price", "profit")
+						values ($1, coalesce(
+							nullif(upper($2), ''),
+							(select p.reporting_currency
+								from investments as i
+								join portfolios as p on p.id = i.portfolio_id
+								where i.id = $1),
+							'USD'
+						), 0, 0, 0)
+						returning "id", "investment_id", "quote_currency", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"`
+
+	row := store.Database.conn().QueryRow(ctx, query, position.InvestmentID, position.QuoteCurrency)
+
+	newPosition, err := store.scanOne(row)
+
 	if err != nil {
 		return nil, fmt.Errorf("Could not insert position for investment %v: %w", position.InvestmentID, err)
 	}
 
-	return &newPosition, nil
+	return newPosition, nil
 }
 
-func (store *PositionStore) All(ctx context.Context) (*[]gaivota.Position, error) {
-	query := `select "id", "investment_id", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"
-						from positions`
-
-	var positions []gaivota.Position
-	rows, err := store.Database.Pool.Query(ctx, query)
+var positionsSortColumns = sortColumns(map[string]sortColumn{
+	"amount": {expression: "amount", cast: "numeric"},
+	"profit": {expression: "profit", cast: "numeric"},
+})
 
+func (store *PositionStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Position, string, error) {
+	list, err := newListQuery(opts, positionsSortColumns)
 	if err != nil {
-		return nil, fmt.Errorf("Could not get positions: %w", err)
+		return nil, "", err
 	}
 
-	for rows.Next() {
-		var position gaivota.Position
-		err = rows.Scan(
-			&position.ID, &position.InvestmentID, &position.Amount,
-			&position.AveragePrice, &position.Profit,
-			&position.CreatedAt, &position.UpdatedAt, &position.DeletedAt,
-		)
+	list.where("deleted_at is null")
+	list.between("created_at")
+	list.symbol("investment_id in (select id from investments where upper(token_symbol) = %s)")
+	list.ownedBy("investment_id in (select i.id from investments as i join portfolios as p on p.id = i.portfolio_id where p.user_id = %s)")
 
-		if err != nil {
-			return nil, fmt.Errorf("Error while scanning positions: %w", err)
-		}
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "investment_id", "quote_currency", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"`, "positions")
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not get positions: %w", err)
+	}
 
-		positions = append(positions, position)
+	positions, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
 	}
 
-	return &positions, nil
+	*positions = (*positions)[:list.size(len(*positions))]
+
+	return positions, list.next(), nil
 }
 
 func (store *PositionStore) Delete(ctx context.Context, id int) error {
 	query := `update positions
-						set deleted_at = now(),
-						where id = $1`
+						set deleted_at = now()
+						where id = $1 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(
+	cmdTags, err := store.Database.conn().Exec(
 		ctx, query, id,
 	)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not delete position %v: %w", id, err)
 	}
 
@@ -86,18 +123,12 @@ func (store *PositionStore) Delete(ctx context.Context, id int) error {
 }
 
 func (store *PositionStore) Get(ctx context.Context, id int) (*gaivota.Position, error) {
-	query := `select "id", "investment_id", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"
-						from positions where id = $1`
+	query := `select "id", "investment_id", "quote_currency", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"
+						from positions where id = $1 and deleted_at is null`
 
-	position := &gaivota.Position{}
+	row := store.Database.conn().QueryRow(ctx, query, id)
 
-	err := store.Database.Pool.QueryRow(
-		ctx, query, id,
-	).Scan(
-		&position.ID, &position.InvestmentID, &position.Amount,
-		&position.AveragePrice, &position.Profit,
-		&position.CreatedAt, &position.UpdatedAt, &position.DeletedAt,
-	)
+	position, err := store.scanOne(row)
 
 	if err != nil {
 		return nil, fmt.Errorf("Could not get position %v: %w", id, err)
@@ -106,40 +137,37 @@ func (store *PositionStore) Get(ctx context.Context, id int) (*gaivota.Position,
 	return position, nil
 }
 
-func (store *PositionStore) GetByUserID(ctx context.Context, userId int) (*gaivota.Position, error) {
-	query := `select "id", "investment_id", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"
-						from positions where investment_id = $1`
-
-	position := &gaivota.Position{}
+func (store *PositionStore) GetByInvestmentID(ctx context.Context, investmentId int) (*[]gaivota.Position, error) {
+	query := `select "id", "investment_id", "quote_currency", "amount", "average_price", "profit", "created_at", "updated_at", "deleted_at"
+						from positions where investment_id = $1 and deleted_at is null`
 
-	err := store.Database.Pool.QueryRow(
-		ctx, query, userId,
-	).Scan(
-		&position.ID, &position.InvestmentID, &position.Amount,
-		&position.AveragePrice, &position.Profit,
-		&position.CreatedAt, &position.UpdatedAt, &position.DeletedAt,
-	)
+	rows, err := store.Database.conn().Query(ctx, query, investmentId)
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get positions for user %v: %w", userId, err)
+		return nil, fmt.Errorf("Could not get positions for investment %v: %w", investmentId, err)
 	}
 
-	return position, nil
+	return store.scanAll(rows)
 }
 
 func (store *PositionStore) Update(ctx context.Context, position *gaivota.Position) error {
 	query := `update positions
 						set investment_id = $1,
-								amount = $2
-								average_price = $3
-								profit = $4
-						where id = $5`
+								quote_currency = coalesce(nullif(upper($2), ''), quote_currency)
+						where id = $3 and deleted_at is null
+						returning "quote_currency"`
 
-	cmdTags, err := store.Database.Pool.Exec(
-		ctx, query, &position.InvestmentID, &position.Amount, &position.AveragePrice, &position.Profit,
-	)
+	// Another portfolio or currency changes how orders are replayed
+	err := store.Database.begin(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, &position.InvestmentID, &position.QuoteCurrency, &position.ID).Scan(&position.QuoteCurrency)
+		if err != nil {
+			return err
+		}
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+		return syncPosition(ctx, tx, position.ID)
+	})
+
+	if err != nil {
 		return fmt.Errorf("Could not update position %v: %w", position.ID, err)
 	}
 
@@ -2,10 +2,16 @@ package postgres
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+	numeric "github.com/jackc/pgtype/ext/shopspring-numeric"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/leoschet/gaivota"
 )
@@ -19,26 +25,166 @@ func toSnakeCase(str string) string {
 	return strings.ToLower(snake)
 }
 
-func Connect(ctx context.Context, connString string) (*Database, error) {
+// Options of the connection pool. The zero value keeps pgx's defaults and
+// does not log.
+type Options struct {
+	// Logs database warnings and errors, and queries at debug level
+	Logger gaivota.Logger
+	// Most and least connections kept open, 0 for pgx's defaults
+	MaxConns int32
+	MinConns int32
+}
+
+// Connect opens a connection pool
+func Connect(ctx context.Context, connString string, options Options) (*Database, error) {
 	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: Add a logger
-	// poolConfig.ConnConfig.Logger = logger
+	if options.Logger != nil {
+		poolConfig.ConnConfig.Logger = pgxLogger{options.Logger}
+		poolConfig.ConnConfig.LogLevel = pgxLogLevel(options.Logger)
+	}
+
+	if options.MaxConns > 0 {
+		poolConfig.MaxConns = options.MaxConns
+	}
+
+	if options.MinConns > 0 {
+		poolConfig.MinConns = options.MinConns
+	}
+
+	// Scan numeric columns straight into decimal.Decimal, without going through floats
+	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		conn.ConnInfo().RegisterDataType(pgtype.DataType{
+			Value: &numeric.Numeric{},
+			Name:  "numeric",
+			OID:   pgtype.NumericOID,
+		})
+		return nil
+	}
 
 	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	db := &Database{Pool: pool}
+	db := &Database{Pool: pool, logger: options.Logger}
 	return db, nil
 }
 
 type Database struct {
 	Pool *pgxpool.Pool
+	// Transaction the stores run in, set for the clients WithinTx hands out
+	tx pgx.Tx
+	// Optional, see Connect
+	logger gaivota.Logger
+}
+
+// Returns what the stores run queries on: the transaction, or the pool.
+// Constraint violations are returned as gaivota's errors (see storeError).
+func (db *Database) conn() querier {
+	if db.tx != nil {
+		return typedErrors{db.tx}
+	}
+
+	return typedErrors{db.Pool}
+}
+
+// Runs fn in a transaction. Within WithinTx, it is a savepoint of the
+// shared transaction. Constraint violations are returned as gaivota's errors
+// (see storeError).
+func (db *Database) begin(ctx context.Context, fn func(pgx.Tx) error) error {
+	if db.tx != nil {
+		return storeError(db.tx.BeginFunc(ctx, fn))
+	}
+
+	return storeError(db.Pool.BeginFunc(ctx, fn))
+}
+
+// How many times WithinTx runs a transaction that fails to serialize
+const maxTxAttempts = 5
+
+// WithinTx runs fn with a client whose stores share a serializable
+// transaction. The transaction is rolled back when fn fails, and run again
+// from the start, up to maxTxAttempts times, on serialization failures and
+// deadlocks. Nested calls join the outer transaction.
+func (db *Database) WithinTx(ctx context.Context, fn func(*gaivota.Client) error) error {
+	if db.tx != nil {
+		return fn(db.NewPostgresClient())
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = db.Pool.BeginTxFunc(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+			txDb := &Database{Pool: db.Pool, tx: tx, logger: db.logger}
+
+			return fn(txDb.NewPostgresClient())
+		})
+
+		if !retryable(err) {
+			return err
+		}
+
+		// Back off a little longer each time, so conflicting transactions
+		// are less likely to collide again
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt*attempt) * 10 * time.Millisecond):
+		}
+	}
+
+	return fmt.Errorf("Could not commit transaction after %v attempts: %w", maxTxAttempts, err)
+}
+
+// Reports whether the transaction failed to serialize or deadlocked, and
+// can succeed when run again
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	// https://www.postgresql.org/docs/current/errcodes-appendix.html
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// Methods shared by *pgxpool.Pool and pgx.Tx, so queries can run on either
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Maps rows that do not exist to gaivota.ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return gaivota.ErrNotFound
+	}
+
+	return err
+}
+
+// Returns gaivota.ErrNotFound when a statement without errors changed no
+// rows, e.g. an update of a deleted row
+func affected(tag pgconn.CommandTag, err error) error {
+	if err == nil && tag.RowsAffected() == 0 {
+		return gaivota.ErrNotFound
+	}
+
+	return err
+}
+
+// Binds zero times as null, so columns fall back to their default or stored
+// value, e.g. `coalesce($1, now())`
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
 }
 
 func (db *Database) NewPostgresClient() *gaivota.Client {
@@ -48,7 +194,15 @@ func (db *Database) NewPostgresClient() *gaivota.Client {
 	investmentStore := NewInvestmentStore(db)
 	positionStore := NewPositionStore(db)
 	holdingStore := NewHoldingStore(db)
+	transferStore := NewTransferStore(db)
 	orderStore := NewOrderStore(db)
+	eventStore := NewEventStore(db)
+	lotStore := NewLotStore(db)
+	priceStore := NewPriceStore(db)
+	fxRateStore := NewFXRateStore(db)
+	snapshotStore := NewSnapshotStore(db)
+	apiKeyStore := NewAPIKeyStore(db)
+	auditStore := NewAuditStore(db)
 
 	return &gaivota.Client{
 		UserStore:       userStore,
@@ -57,7 +211,16 @@ func (db *Database) NewPostgresClient() *gaivota.Client {
 		InvestmentStore: investmentStore,
 		PositionStore:   positionStore,
 		HoldingStore:    holdingStore,
+		TransferStore:   transferStore,
 		OrderStore:      orderStore,
+		EventStore:      eventStore,
+		LotStore:        lotStore,
+		PriceStore:      priceStore,
+		FXRateStore:     fxRateStore,
+		SnapshotStore:   snapshotStore,
+		APIKeyStore:     apiKeyStore,
+		AuditStore:      auditStore,
+		Transactor:      db,
 	}
 }
 
@@ -65,17 +228,21 @@ func (db *Database) Close() {
 	db.Pool.Close()
 }
 
-func (db *Database) Ping() (msg string, err error) {
-	err = db.Pool.Ping(context.Background())
+func (db *Database) Name() string {
+	return "postgres"
+}
 
-	if err != nil {
-		return "Could not connect to the Database", err
+func (db *Database) Ping(ctx context.Context) error {
+	if err := db.Pool.Ping(ctx); err != nil {
+		return fmt.Errorf("Could not connect to the Database: %w", err)
 	}
 
-	stat := db.Pool.Stat()
-	log.Printf("Total of PostgreSQL connections in pool: %v\n", stat.TotalConns())
+	if db.logger != nil {
+		stat := db.Pool.Stat()
+		db.logger.Log(gaivota.LogLevelDebug, "Total of PostgreSQL connections in pool: %v", stat.TotalConns())
+	}
 
-	return "", nil
+	return nil
 }
 
 // func (db *Database) CreateTable(model interface{}) {
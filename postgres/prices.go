@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewPriceStore(db *Database) *PriceStore {
+	return &PriceStore{
+		Database: db,
+	}
+}
+
+type PriceStore struct {
+	Database *Database
+}
+
+func (store *PriceStore) scanAll(rows pgx.Rows) (*[]gaivota.Price, error) {
+	defer rows.Close()
+
+	var prices []gaivota.Price
+
+	for rows.Next() {
+		price, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning prices: %w", err)
+		}
+
+		prices = append(prices, *price)
+	}
+
+	return &prices, rows.Err()
+}
+
+func (store *PriceStore) scanOne(row pgx.Row) (*gaivota.Price, error) {
+	var price gaivota.Price
+
+	err := row.Scan(
+		&price.ID, &price.TokenSymbol, &price.QuoteCurrency, &price.Value,
+		&price.At, &price.Source, &price.CreatedAt,
+	)
+
+	return &price, err
+}
+
+func (store *PriceStore) Add(ctx context.Context, price *gaivota.Price) (*gaivota.Price, error) {
+	query := `insert into prices ("token_symbol", "quote_currency", "price", "priced_at", "source")
+						values ($1, $2, $3, $4, $5)
+						on conflict ("token_symbol", "quote_currency", "priced_at")
+						do update set price = excluded.price, source = excluded.source
+						returning "id", "token_symbol", "quote_currency", "price", "priced_at", "source", "created_at"`
+
+	var newPrice *gaivota.Price
+
+	// Positions converting with the price are synced in the same transaction
+	err := store.Database.begin(ctx, func(tx pgx.Tx) (err error) {
+		row := tx.QueryRow(
+			ctx, query, price.TokenSymbol, price.QuoteCurrency, price.Value, price.At, price.Source,
+		)
+
+		newPrice, err = store.scanOne(row)
+		if err != nil {
+			return err
+		}
+
+		return syncConverted(ctx, tx, newPrice.TokenSymbol, newPrice.QuoteCurrency, newPrice.At)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not insert price of %s in %s: %w", price.TokenSymbol, price.QuoteCurrency, err)
+	}
+
+	return newPrice, nil
+}
+
+func (store *PriceStore) GetAt(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	return priceAt(ctx, store.Database.conn(), symbol, quote, at)
+}
+
+// Shared by the store and by position syncs, which value fees inside their transaction
+func priceAt(ctx context.Context, q querier, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	query := `select "id", "token_symbol", "quote_currency", "price", "priced_at", "source", "created_at"
+						from prices
+						where token_symbol = $1 and quote_currency = $2 and priced_at <= $3
+						order by priced_at desc
+						limit 1`
+
+	price, err := (&PriceStore{}).scanOne(q.QueryRow(ctx, query, symbol, quote, at))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = gaivota.ErrPriceNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get price of %s in %s at %s: %w", symbol, quote, at, err)
+	}
+
+	return price, nil
+}
+
+func (store *PriceStore) GetRange(ctx context.Context, symbol string, quote string, from time.Time, to time.Time) (*[]gaivota.Price, error) {
+	query := `select "id", "token_symbol", "quote_currency", "price", "priced_at", "source", "created_at"
+						from prices
+						where token_symbol = $1 and quote_currency = $2 and priced_at between $3 and $4
+						order by priced_at`
+
+	rows, err := store.Database.conn().Query(ctx, query, symbol, quote, from, to)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get prices of %s in %s: %w", symbol, quote, err)
+	}
+
+	return store.scanAll(rows)
+}
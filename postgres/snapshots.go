@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewSnapshotStore(db *Database) *SnapshotStore {
+	return &SnapshotStore{
+		Database: db,
+	}
+}
+
+type SnapshotStore struct {
+	Database *Database
+}
+
+func (store *SnapshotStore) scanAll(rows pgx.Rows) (*[]gaivota.PortfolioSnapshot, error) {
+	defer rows.Close()
+
+	var snapshots []gaivota.PortfolioSnapshot
+
+	for rows.Next() {
+		snapshot, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning portfolio snapshots: %w", err)
+		}
+
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	return &snapshots, rows.Err()
+}
+
+// Investments and wallets are stored as JSON arrays
+func (store *SnapshotStore) scanOne(row pgx.Row) (*gaivota.PortfolioSnapshot, error) {
+	var snapshot gaivota.PortfolioSnapshot
+
+	err := row.Scan(
+		&snapshot.ID, &snapshot.PortfolioID, &snapshot.Date, &snapshot.QuoteCurrency,
+		&snapshot.Value, &snapshot.CostBasis, &snapshot.RealizedProfit, &snapshot.UnrealizedProfit,
+		&snapshot.Investments, &snapshot.Wallets, &snapshot.CreatedAt, &snapshot.UpdatedAt,
+	)
+
+	return &snapshot, err
+}
+
+func (store *SnapshotStore) Add(ctx context.Context, snapshot *gaivota.PortfolioSnapshot) (*gaivota.PortfolioSnapshot, error) {
+	query := `insert into portfolio_snapshots ("portfolio_id", "taken_on", "quote_currency", "value", "cost_basis", "realized_profit", "unrealized_profit", "investments", "wallets")
+						values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+						on conflict ("portfolio_id", "taken_on")
+						do update set quote_currency = excluded.quote_currency,
+								value = excluded.value,
+								cost_basis = excluded.cost_basis,
+								realized_profit = excluded.realized_profit,
+								unrealized_profit = excluded.unrealized_profit,
+								investments = excluded.investments,
+								wallets = excluded.wallets
+						returning "id", "portfolio_id", "taken_on", "quote_currency", "value", "cost_basis", "realized_profit", "unrealized_profit", "investments", "wallets", "created_at", "updated_at"`
+
+	// Nil slices would be stored as JSON nulls instead of empty arrays
+	investments := snapshot.Investments
+	if investments == nil {
+		investments = []gaivota.InvestmentSnapshot{}
+	}
+
+	wallets := snapshot.Wallets
+	if wallets == nil {
+		wallets = []gaivota.WalletSnapshot{}
+	}
+
+	row := store.Database.conn().QueryRow(
+		ctx, query, snapshot.PortfolioID, snapshot.Date, snapshot.QuoteCurrency,
+		snapshot.Value, snapshot.CostBasis, snapshot.RealizedProfit, snapshot.UnrealizedProfit,
+		investments, wallets,
+	)
+
+	newSnapshot, err := store.scanOne(row)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert snapshot of portfolio %v on %s: %w",
+			snapshot.PortfolioID, snapshot.Date.Format("2006-01-02"), err,
+		)
+	}
+
+	return newSnapshot, nil
+}
+
+func (store *SnapshotStore) GetRange(ctx context.Context, portfolioId int, from time.Time, to time.Time) (*[]gaivota.PortfolioSnapshot, error) {
+	query := `select "id", "portfolio_id", "taken_on", "quote_currency", "value", "cost_basis", "realized_profit", "unrealized_profit", "investments", "wallets", "created_at", "updated_at"
+						from portfolio_snapshots
+						where portfolio_id = $1 and taken_on between $2::date and $3::date
+						order by taken_on`
+
+	rows, err := store.Database.conn().Query(ctx, query, portfolioId, from, to)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get snapshots of portfolio %v: %w", portfolioId, err)
+	}
+
+	return store.scanAll(rows)
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/leoschet/gaivota"
+)
+
+func NewTransferStore(db *Database) *TransferStore {
+	return &TransferStore{
+		Database: db,
+	}
+}
+
+type TransferStore struct {
+	Database *Database
+}
+
+const transferColumns = `"id", "from_wallet_id", "to_wallet_id", "position_id", "amount", "fee", "executed_at", "created_at"`
+
+func (store *TransferStore) scanAll(rows pgx.Rows) (*[]gaivota.Transfer, error) {
+	defer rows.Close()
+
+	var transfers []gaivota.Transfer
+
+	for rows.Next() {
+		transfer, err := store.scanOne(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error while scanning transfers: %w", err)
+		}
+
+		transfers = append(transfers, *transfer)
+	}
+
+	return &transfers, rows.Err()
+}
+
+func (store *TransferStore) scanOne(row pgx.Row) (*gaivota.Transfer, error) {
+	var transfer gaivota.Transfer
+
+	err := row.Scan(
+		&transfer.ID, &transfer.FromWalletID, &transfer.ToWalletID, &transfer.PositionID,
+		&transfer.Amount, &transfer.Fee, &transfer.ExecutedAt, &transfer.CreatedAt,
+	)
+
+	return &transfer, notFound(err)
+}
+
+func (store *TransferStore) Add(ctx context.Context, transfer *gaivota.Transfer) (*gaivota.Transfer, error) {
+	query := `insert into transfers ("from_wallet_id", "to_wallet_id", "position_id", "amount", "fee", "executed_at")
+						values ($1, $2, $3, $4, $5, $6)
+						returning ` + transferColumns
+
+	var newTransfer *gaivota.Transfer
+
+	err := store.Database.begin(ctx, func(tx pgx.Tx) (err error) {
+		row := tx.QueryRow(
+			ctx, query, transfer.FromWalletID, transfer.ToWalletID, transfer.PositionID,
+			transfer.Amount, transfer.Fee, transfer.ExecutedAt,
+		)
+
+		newTransfer, err = store.scanOne(row)
+		if err != nil {
+			return err
+		}
+
+		// The fee leaves the position
+		return syncPosition(ctx, tx, newTransfer.PositionID)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not insert transfer from wallet %v to wallet %v: %w",
+			transfer.FromWalletID, transfer.ToWalletID, err,
+		)
+	}
+
+	return newTransfer, nil
+}
+
+var transfersSortColumns = sortColumns(map[string]sortColumn{
+	"executedAt": {expression: "executed_at", cast: "timestamptz"},
+	"amount":     {expression: "amount", cast: "numeric"},
+	"fee":        {expression: "fee", cast: "numeric"},
+})
+
+func (store *TransferStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Transfer, string, error) {
+	list, err := newListQuery(opts, transfersSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.between("executed_at")
+	list.symbol("position_id in (select pos.id from positions as pos join investments as i on i.id = pos.investment_id where upper(i.token_symbol) = %s)")
+	list.ownedBy("from_wallet_id in (select id from wallets where user_id = %s)")
+
+	rows, err := list.query(ctx, store.Database.conn(), transferColumns, "transfers")
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not get transfers: %w", err)
+	}
+
+	transfers, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*transfers = (*transfers)[:list.size(len(*transfers))]
+
+	return transfers, list.next(), nil
+}
+
+func (store *TransferStore) Get(ctx context.Context, id int) (*gaivota.Transfer, error) {
+	query := `select ` + transferColumns + ` from transfers where id = $1`
+
+	transfer, err := store.scanOne(store.Database.conn().QueryRow(ctx, query, id))
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get transfer %v: %w", id, err)
+	}
+
+	return transfer, nil
+}
+
+func (store *TransferStore) GetByPositionID(ctx context.Context, positionId int) (*[]gaivota.Transfer, error) {
+	return getTransfers(ctx, store.Database.conn(), "position_id = $1", positionId)
+}
+
+func (store *TransferStore) GetByWalletID(ctx context.Context, walletId int) (*[]gaivota.Transfer, error) {
+	return getTransfers(ctx, store.Database.conn(), "from_wallet_id = $1 or to_wallet_id = $1", walletId)
+}
+
+// Returns the transfers matching condition in execution order. condition is
+// never user input, so it is safe to format it into the query.
+func getTransfers(ctx context.Context, q querier, condition string, id int) (*[]gaivota.Transfer, error) {
+	query := fmt.Sprintf(`select %s from transfers where %s order by executed_at, id`, transferColumns, condition)
+
+	rows, err := q.Query(ctx, query, id)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get transfers where %s is %v: %w", condition, id, err)
+	}
+
+	return (&TransferStore{}).scanAll(rows)
+}
@@ -19,6 +19,8 @@ type UserStore struct {
 }
 
 func (store *UserStore) scanAll(rows pgx.Rows) (*[]gaivota.User, error) {
+	defer rows.Close()
+
 	var users []gaivota.User
 
 	for rows.Next() {
@@ -31,23 +33,26 @@ func (store *UserStore) scanAll(rows pgx.Rows) (*[]gaivota.User, error) {
 		users = append(users, *user)
 	}
 
-	return &users, nil
+	return &users, rows.Err()
 }
 
 func (store *UserStore) scanOne(row pgx.Row) (*gaivota.User, error) {
 	var user gaivota.User
 
-	err := row.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
+	err := row.Scan(
+		&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.ReportingCurrency, &user.PasswordHash,
+		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
+	)
 
-	return &user, err
+	return &user, notFound(err)
 }
 
 func (store *UserStore) Add(ctx context.Context, user *gaivota.User) (*gaivota.User, error) {
-	query := `insert into users ("email", "first_name", "last_name")
-						values ($1, $2, $3)
-						returning "id", "email", "first_name", "last_name", "created_at", "updated_at", "deleted_at"`
+	query := `insert into users ("email", "first_name", "last_name", "reporting_currency", "password_hash")
+						values ($1, $2, $3, coalesce(nullif(upper($4), ''), 'USD'), $5)
+						returning "id", "email", "first_name", "last_name", "reporting_currency", "password_hash", "created_at", "updated_at", "deleted_at"`
 
-	row := store.Database.Pool.QueryRow(ctx, query, user.Email, user.FirstName, user.LastName)
+	row := store.Database.conn().QueryRow(ctx, query, user.Email, user.FirstName, user.LastName, user.ReportingCurrency, user.PasswordHash)
 
 	newUser, err := store.scanOne(row)
 
@@ -58,29 +63,48 @@ func (store *UserStore) Add(ctx context.Context, user *gaivota.User) (*gaivota.U
 	return newUser, nil
 }
 
-func (store *UserStore) All(ctx context.Context) (*[]gaivota.User, error) {
-	query := `select "id", "email", "first_name", "last_name", "created_at", "updated_at", "deleted_at"
-						from users`
+var usersSortColumns = sortColumns(map[string]sortColumn{
+	"email":    {expression: "email", cast: "text"},
+	"lastName": {expression: "last_name", cast: "text"},
+})
+
+func (store *UserStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.User, string, error) {
+	list, err := newListQuery(opts, usersSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.where("deleted_at is null")
+	list.between("created_at")
+	list.ownedBy("id = %s")
+
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "email", "first_name", "last_name", "reporting_currency", "password_hash", "created_at", "updated_at", "deleted_at"`, "users")
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not get users: %w", err)
+	}
 
-	rows, err := store.Database.Pool.Query(ctx, query)
+	users, err := store.scanAll(rows)
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get users: %w", err)
+		return nil, "", err
 	}
 
-	return store.scanAll(rows)
+	*users = (*users)[:list.size(len(*users))]
+
+	return users, list.next(), nil
 }
 
 func (store *UserStore) Delete(ctx context.Context, id int) error {
 	query := `update users
-						set deleted_at = now(),
-						where id = $1`
+						set deleted_at = now()
+						where id = $1 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(
+	cmdTags, err := store.Database.conn().Exec(
 		ctx, query, id,
 	)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not delete user %v: %w", id, err)
 	}
 
@@ -88,10 +112,10 @@ func (store *UserStore) Delete(ctx context.Context, id int) error {
 }
 
 func (store *UserStore) Get(ctx context.Context, id int) (*gaivota.User, error) {
-	query := `select "id", "email", "first_name", "last_name", "created_at", "updated_at", "deleted_at"
-						from users where id = $1`
+	query := `select "id", "email", "first_name", "last_name", "reporting_currency", "password_hash", "created_at", "updated_at", "deleted_at"
+						from users where id = $1 and deleted_at is null`
 
-	row := store.Database.Pool.QueryRow(ctx, query, id)
+	row := store.Database.conn().QueryRow(ctx, query, id)
 
 	user, err := store.scanOne(row)
 
@@ -102,18 +126,49 @@ func (store *UserStore) Get(ctx context.Context, id int) (*gaivota.User, error)
 	return user, nil
 }
 
+func (store *UserStore) GetByEmail(ctx context.Context, email string) (*gaivota.User, error) {
+	query := `select "id", "email", "first_name", "last_name", "reporting_currency", "password_hash", "created_at", "updated_at", "deleted_at"
+						from users where lower(email) = lower($1) and deleted_at is null`
+
+	row := store.Database.conn().QueryRow(ctx, query, email)
+
+	user, err := store.scanOne(row)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get user %s: %w", email, err)
+	}
+
+	return user, nil
+}
+
+func (store *UserStore) SetPasswordHash(ctx context.Context, id int, hash string) error {
+	query := `update users
+						set password_hash = $1
+						where id = $2 and deleted_at is null`
+
+	cmdTags, err := store.Database.conn().Exec(ctx, query, hash, id)
+
+	if err = affected(cmdTags, err); err != nil {
+		return fmt.Errorf("Could not set password for user %v: %w", id, err)
+	}
+
+	return nil
+}
+
 func (store *UserStore) Update(ctx context.Context, user *gaivota.User) error {
 	query := `update users
 						set email = $1,
 								first_name = $2,
-								last_name = $3
-						where id = $4`
+								last_name = $3,
+								reporting_currency = coalesce(nullif(upper($4), ''), reporting_currency)
+						where id = $5 and deleted_at is null
+						returning "reporting_currency"`
 
-	cmdTags, err := store.Database.Pool.Exec(
-		ctx, query, user.Email, user.FirstName, user.LastName, user.ID,
-	)
+	err := store.Database.conn().QueryRow(
+		ctx, query, user.Email, user.FirstName, user.LastName, user.ReportingCurrency, user.ID,
+	).Scan(&user.ReportingCurrency)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err != nil {
 		return fmt.Errorf("Could not update user %v: %w", user.ID, err)
 	}
 
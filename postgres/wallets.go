@@ -19,6 +19,8 @@ type WalletStore struct {
 }
 
 func (store *WalletStore) scanAll(rows pgx.Rows) (*[]gaivota.Wallet, error) {
+	defer rows.Close()
+
 	var wallets []gaivota.Wallet
 
 	for rows.Next() {
@@ -31,7 +33,7 @@ func (store *WalletStore) scanAll(rows pgx.Rows) (*[]gaivota.Wallet, error) {
 		wallets = append(wallets, *wallet)
 	}
 
-	return &wallets, nil
+	return &wallets, rows.Err()
 }
 
 func (store *WalletStore) scanOne(row pgx.Row) (*gaivota.Wallet, error) {
@@ -43,7 +45,7 @@ func (store *WalletStore) scanOne(row pgx.Row) (*gaivota.Wallet, error) {
 		&wallet.CreatedAt, &wallet.UpdatedAt, &wallet.DeletedAt,
 	)
 
-	return &wallet, err
+	return &wallet, notFound(err)
 }
 
 func (store *WalletStore) Add(ctx context.Context, wallet *gaivota.Wallet) (*gaivota.Wallet, error) {
@@ -51,7 +53,7 @@ func (store *WalletStore) Add(ctx context.Context, wallet *gaivota.Wallet) (*gai
 						values ($1, $2, $3, $4, $5)
 						returning "id", "user_id", "name", "total_value", "address", "location", "created_at", "updated_at", "deleted_at"`
 
-	row := store.Database.Pool.QueryRow(
+	row := store.Database.conn().QueryRow(
 		ctx, query, wallet.UserID, wallet.Name,
 		wallet.TotalValue, wallet.Address, wallet.Location,
 	)
@@ -65,29 +67,48 @@ func (store *WalletStore) Add(ctx context.Context, wallet *gaivota.Wallet) (*gai
 	return newWallet, nil
 }
 
-func (store *WalletStore) All(ctx context.Context) (*[]gaivota.Wallet, error) {
-	query := `select "id", "user_id", "name", "total_value", "address", "location", "created_at", "updated_at", "deleted_at"
-						from wallets`
+var walletsSortColumns = sortColumns(map[string]sortColumn{
+	"name":       {expression: "name", cast: "text"},
+	"totalValue": {expression: "total_value", cast: "numeric"},
+})
+
+func (store *WalletStore) All(ctx context.Context, opts gaivota.ListOptions) (*[]gaivota.Wallet, string, error) {
+	list, err := newListQuery(opts, walletsSortColumns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list.where("deleted_at is null")
+	list.between("created_at")
+	list.ownedBy("user_id = %s")
 
-	rows, err := store.Database.Pool.Query(ctx, query)
+	rows, err := list.query(ctx, store.Database.conn(), `"id", "user_id", "name", "total_value", "address", "location", "created_at", "updated_at", "deleted_at"`, "wallets")
 
 	if err != nil {
-		return nil, fmt.Errorf("Could not get wallets: %w", err)
+		return nil, "", fmt.Errorf("Could not get wallets: %w", err)
 	}
 
-	return store.scanAll(rows)
+	wallets, err := store.scanAll(rows)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	*wallets = (*wallets)[:list.size(len(*wallets))]
+
+	return wallets, list.next(), nil
 }
 
 func (store *WalletStore) Delete(ctx context.Context, id int) error {
 	query := `update wallets
-						set deleted_at = now(),
-						where id = $1`
+						set deleted_at = now()
+						where id = $1 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(
+	cmdTags, err := store.Database.conn().Exec(
 		ctx, query, id,
 	)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not delete wallet %v: %w", id, err)
 	}
 
@@ -96,9 +117,9 @@ func (store *WalletStore) Delete(ctx context.Context, id int) error {
 
 func (store *WalletStore) Get(ctx context.Context, id int) (*gaivota.Wallet, error) {
 	query := `select "id", "user_id", "name", "total_value", "address", "location", "created_at", "updated_at", "deleted_at"
-						from wallets where id = $1`
+						from wallets where id = $1 and deleted_at is null`
 
-	row := store.Database.Pool.QueryRow(
+	row := store.Database.conn().QueryRow(
 		ctx, query, id,
 	)
 
@@ -113,9 +134,9 @@ func (store *WalletStore) Get(ctx context.Context, id int) (*gaivota.Wallet, err
 
 func (store *WalletStore) GetByUserID(ctx context.Context, userId int) (*[]gaivota.Wallet, error) {
 	query := `select "id", "user_id", "name", "total_value", "address", "location", "created_at", "updated_at", "deleted_at"
-						from wallets where user_id = $1`
+						from wallets where user_id = $1 and deleted_at is null`
 
-	rows, err := store.Database.Pool.Query(ctx, query, userId)
+	rows, err := store.Database.conn().Query(ctx, query, userId)
 
 	if err != nil {
 		return nil, fmt.Errorf("Could not get wallets for user %v: %w", userId, err)
@@ -127,14 +148,16 @@ func (store *WalletStore) GetByUserID(ctx context.Context, userId int) (*[]gaivo
 func (store *WalletStore) Update(ctx context.Context, wallet *gaivota.Wallet) error {
 	query := `update wallets
 						set name = $1,
-								total_value = $2
-						where id = $3`
+								total_value = $2,
+								address = $3,
+								location = $4
+						where id = $5 and deleted_at is null`
 
-	cmdTags, err := store.Database.Pool.Exec(
-		ctx, query, &wallet.Name, &wallet.TotalValue, &wallet.ID,
+	cmdTags, err := store.Database.conn().Exec(
+		ctx, query, &wallet.Name, &wallet.TotalValue, &wallet.Address, &wallet.Location, &wallet.ID,
 	)
 
-	if err != nil || cmdTags.RowsAffected() == 0 {
+	if err = affected(cmdTags, err); err != nil {
 		return fmt.Errorf("Could not update wallet %v: %w", wallet.ID, err)
 	}
 
@@ -0,0 +1,85 @@
+package pricing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Cache keeps prices from another source in memory. Current prices expire
+// after the TTL, historical ones never change so they are kept for good.
+type Cache struct {
+	source gaivota.PriceSource
+	ttl    time.Duration
+
+	mu         sync.Mutex
+	current    map[string]cachedPrice
+	historical map[string]gaivota.Price
+}
+
+type cachedPrice struct {
+	price     gaivota.Price
+	expiresAt time.Time
+}
+
+func NewCache(source gaivota.PriceSource, ttl time.Duration) *Cache {
+	return &Cache{
+		source:     source,
+		ttl:        ttl,
+		current:    make(map[string]cachedPrice),
+		historical: make(map[string]gaivota.Price),
+	}
+}
+
+// Ping checks the cached source
+func (cache *Cache) Ping(ctx context.Context) error {
+	return ping(ctx, cache.source)
+}
+
+func (cache *Cache) Current(ctx context.Context, symbol string, quote string) (*gaivota.Price, error) {
+	key := sourceKey(symbol, quote)
+
+	cache.mu.Lock()
+	cached, ok := cache.current[key]
+	cache.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return &cached.price, nil
+	}
+
+	price, err := cache.source.Current(ctx, symbol, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	cache.current[key] = cachedPrice{price: *price, expiresAt: time.Now().Add(cache.ttl)}
+	cache.mu.Unlock()
+
+	return price, nil
+}
+
+func (cache *Cache) At(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	key := sourceKey(symbol, quote) + "@" + at.UTC().Format(time.RFC3339Nano)
+
+	cache.mu.Lock()
+	cached, ok := cache.historical[key]
+	cache.mu.Unlock()
+
+	if ok {
+		return &cached, nil
+	}
+
+	price, err := cache.source.At(ctx, symbol, quote, at)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	cache.historical[key] = *price
+	cache.mu.Unlock()
+
+	return price, nil
+}
@@ -0,0 +1,127 @@
+package pricing
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/shopspring/decimal"
+)
+
+// CSVSource serves prices loaded from a CSV file with the header
+// `symbol,quote,time,price`, where time is RFC 3339 or a plain date.
+type CSVSource struct {
+	// Prices per symbol and quote, oldest first
+	prices map[string][]gaivota.Price
+}
+
+func NewCSVSource(path string) (*CSVSource, error) {
+	f, err := os.Open(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prices, err := ReadCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load prices from %s: %w", path, err)
+	}
+
+	source := &CSVSource{prices: make(map[string][]gaivota.Price)}
+	for _, price := range prices {
+		key := sourceKey(price.TokenSymbol, price.QuoteCurrency)
+		source.prices[key] = append(source.prices[key], price)
+	}
+
+	for _, series := range source.prices {
+		sort.Slice(series, func(i, j int) bool { return series[i].At.Before(series[j].At) })
+	}
+
+	return source, nil
+}
+
+// ReadCSV parses prices in the CSVSource format.
+func ReadCSV(r io.Reader) ([]gaivota.Price, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	for _, name := range []string{"symbol", "quote", "time", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	var prices []gaivota.Price
+	// The header is line 1
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		at, err := parseTime(record[columns["time"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid time: %w", line, err)
+		}
+
+		value, err := decimal.NewFromString(record[columns["price"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price: %w", line, err)
+		}
+
+		prices = append(prices, gaivota.Price{
+			TokenSymbol:   normalize(record[columns["symbol"]]),
+			QuoteCurrency: normalize(record[columns["quote"]]),
+			Value:         value,
+			At:            at,
+			Source:        SourceCSV,
+		})
+	}
+
+	return prices, nil
+}
+
+func (source *CSVSource) Current(ctx context.Context, symbol string, quote string) (*gaivota.Price, error) {
+	series := source.prices[sourceKey(symbol, quote)]
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no %s price for %s: %w", quote, symbol, gaivota.ErrPriceNotFound)
+	}
+
+	price := series[len(series)-1]
+	return &price, nil
+}
+
+func (source *CSVSource) At(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	series := source.prices[sourceKey(symbol, quote)]
+
+	// Index of the first price after `at`, so the one before it is the answer
+	i := sort.Search(len(series), func(i int) bool { return series[i].At.After(at) })
+	if i == 0 {
+		return nil, fmt.Errorf("no %s price for %s at %s: %w", quote, symbol, at, gaivota.ErrPriceNotFound)
+	}
+
+	price := series[i-1]
+	return &price, nil
+}
+
+func sourceKey(symbol string, quote string) string {
+	return normalize(symbol) + "/" + normalize(quote)
+}
@@ -0,0 +1,103 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+// HTTPSource fetches prices from a JSON API. It requests
+// `GET <baseURL>/prices/<symbol>?quote=<quote>[&at=<RFC 3339 time>]` and
+// expects a gaivota.Price encoded as JSON, answering 404 for unknown prices.
+// Gaivota's own `/prices` endpoint follows the same contract, and requires
+// one of its API keys as the key.
+type HTTPSource struct {
+	baseURL string
+	key     string
+	client  *http.Client
+}
+
+// NewHTTPSource creates a source for baseURL, sending key as a bearer token
+// when it is not empty. A nil client defaults to one with a 10 seconds
+// timeout.
+func NewHTTPSource(baseURL string, key string, client *http.Client) *HTTPSource {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &HTTPSource{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		key:     key,
+		client:  client,
+	}
+}
+
+// Ping asks for a current price: the service is reachable when it answers
+// with the price or that there is none
+func (source *HTTPSource) Ping(ctx context.Context) error {
+	_, err := source.Current(ctx, "BTC", "USD")
+	if errors.Is(err, gaivota.ErrPriceNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+func (source *HTTPSource) Current(ctx context.Context, symbol string, quote string) (*gaivota.Price, error) {
+	return source.fetch(ctx, symbol, quote, url.Values{})
+}
+
+func (source *HTTPSource) At(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	return source.fetch(ctx, symbol, quote, url.Values{"at": {at.UTC().Format(time.RFC3339)}})
+}
+
+func (source *HTTPSource) fetch(ctx context.Context, symbol string, quote string, params url.Values) (*gaivota.Price, error) {
+	params.Set("quote", normalize(quote))
+	endpoint := fmt.Sprintf("%s/prices/%s?%s", source.baseURL, url.PathEscape(normalize(symbol)), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.key != "" {
+		req.Header.Set("Authorization", "Bearer "+source.key)
+	}
+
+	res, err := source.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s price for %s: %w", quote, symbol, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("no %s price for %s: %w", quote, symbol, gaivota.ErrPriceNotFound)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch %s price for %s: unexpected status %s", quote, symbol, res.Status)
+	}
+
+	var price gaivota.Price
+	if err := json.NewDecoder(res.Body).Decode(&price); err != nil {
+		return nil, fmt.Errorf("could not decode %s price for %s: %w", quote, symbol, err)
+	}
+
+	price.TokenSymbol = normalize(symbol)
+	price.QuoteCurrency = normalize(quote)
+	price.Source = SourceHTTP
+
+	// Sources may omit the time of current quotes
+	if price.At.IsZero() {
+		price.At = time.Now()
+	}
+
+	return &price, nil
+}
@@ -0,0 +1,96 @@
+// Package pricing implements gaivota.PriceSource on top of files, HTTP
+// services and the PriceStore.
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+// Price source kinds accepted by NewSource
+const (
+	SourceCSV  = "csv"
+	SourceHTTP = "http"
+)
+
+// New builds the price source used by the binaries: prices are served from
+// the store, falling back to the source of the given kind (if any), with
+// current prices cached for a minute.
+func New(store gaivota.PriceStore, kind string, location string, key string) (gaivota.PriceSource, error) {
+	var fallback gaivota.PriceSource
+
+	if kind != "" {
+		var err error
+		fallback, err = NewSource(kind, location, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Daily prices are the coarsest we expect to store
+	storeSource := NewStoreSource(store, fallback, 24*time.Hour)
+
+	return NewCache(storeSource, time.Minute), nil
+}
+
+// NewSource builds a price source of the given kind. Location is a file path
+// for csv sources and a base URL for http ones, which send the key, if any.
+func NewSource(kind string, location string, key string) (gaivota.PriceSource, error) {
+	switch kind {
+	case SourceCSV:
+		return NewCSVSource(location)
+	case SourceHTTP:
+		return NewHTTPSource(location, key, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown price source %q", kind)
+	}
+}
+
+// Sources that can tell whether they are reachable
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthCheck reports whether the source, e.g. the one New builds, can fetch
+// prices. Sources that cannot tell are always healthy.
+func HealthCheck(source gaivota.PriceSource) gaivota.HealthChecker {
+	return &healthCheck{source: source}
+}
+
+type healthCheck struct {
+	source gaivota.PriceSource
+}
+
+func (check *healthCheck) Name() string {
+	return "price_source"
+}
+
+func (check *healthCheck) Ping(ctx context.Context) error {
+	return ping(ctx, check.source)
+}
+
+func ping(ctx context.Context, source gaivota.PriceSource) error {
+	if pinger, ok := source.(pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+// Symbols and currencies are compared case insensitively
+func normalize(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// Parses RFC 3339 timestamps or plain dates (e.g. 2021-05-28)
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,77 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leoschet/gaivota"
+)
+
+// StoreSource serves prices from the PriceStore, falling back to another
+// source for prices it does not have yet and storing them for later.
+type StoreSource struct {
+	store gaivota.PriceStore
+	// Optional, when nil only stored prices are served
+	source gaivota.PriceSource
+	// How old a stored price may be and still be used for a given time
+	maxAge time.Duration
+}
+
+func NewStoreSource(store gaivota.PriceStore, source gaivota.PriceSource, maxAge time.Duration) *StoreSource {
+	return &StoreSource{
+		store:  store,
+		source: source,
+		maxAge: maxAge,
+	}
+}
+
+// Ping checks the fallback source, the store is checked with the database
+func (source *StoreSource) Ping(ctx context.Context) error {
+	if source.source == nil {
+		return nil
+	}
+
+	return ping(ctx, source.source)
+}
+
+// Current prices always come from the fallback source when there is one,
+// the stored series is only updated with them.
+func (source *StoreSource) Current(ctx context.Context, symbol string, quote string) (*gaivota.Price, error) {
+	if source.source == nil {
+		return source.store.GetAt(ctx, normalize(symbol), normalize(quote), time.Now())
+	}
+
+	price, err := source.source.Current(ctx, symbol, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	return source.save(ctx, price)
+}
+
+func (source *StoreSource) At(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	price, err := source.store.GetAt(ctx, normalize(symbol), normalize(quote), at)
+
+	if err == nil && at.Sub(price.At) <= source.maxAge {
+		return price, nil
+	}
+
+	if source.source == nil || (err != nil && !errors.Is(err, gaivota.ErrPriceNotFound)) {
+		return price, err
+	}
+
+	fetched, err := source.source.At(ctx, symbol, quote, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return source.save(ctx, fetched)
+}
+
+func (source *StoreSource) save(ctx context.Context, price *gaivota.Price) (*gaivota.Price, error) {
+	price.TokenSymbol = normalize(price.TokenSymbol)
+	price.QuoteCurrency = normalize(price.QuoteCurrency)
+
+	return source.store.Add(ctx, price)
+}
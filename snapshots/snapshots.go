@@ -0,0 +1,240 @@
+// Package snapshots records daily portfolio valuations, either as they happen
+// or reconstructed from orders and historical prices.
+package snapshots
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/valuation"
+)
+
+// Intervals a snapshot series can be sampled at
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+type Snapshotter struct {
+	client *gaivota.Client
+	valuer *valuation.Valuer
+	logger gaivota.Logger
+}
+
+func New(client *gaivota.Client, valuer *valuation.Valuer, logger gaivota.Logger) *Snapshotter {
+	return &Snapshotter{
+		client: client,
+		valuer: valuer,
+		logger: logger.With("job", "snapshots"),
+	}
+}
+
+// Take values the portfolio at the end of the day, or now if the day is not
+// over yet, and stores the result as the day's snapshot.
+func (snapshotter *Snapshotter) Take(ctx context.Context, portfolioId int, day time.Time) (*gaivota.PortfolioSnapshot, error) {
+	date := truncateDay(day)
+
+	at := date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	if now := time.Now(); at.After(now) {
+		at = now
+	}
+
+	summary, err := snapshotter.valuer.Portfolio(ctx, portfolioId, at)
+	if err != nil {
+		return nil, fmt.Errorf("Could not value portfolio %v on %s: %w", portfolioId, date.Format("2006-01-02"), err)
+	}
+
+	snapshot := &gaivota.PortfolioSnapshot{
+		PortfolioID:      portfolioId,
+		Date:             date,
+		QuoteCurrency:    summary.Quote,
+		Value:            summary.Value,
+		CostBasis:        summary.CostBasis,
+		RealizedProfit:   summary.RealizedProfit,
+		UnrealizedProfit: summary.UnrealizedProfit,
+		Investments:      []gaivota.InvestmentSnapshot{},
+		Wallets:          []gaivota.WalletSnapshot{},
+	}
+
+	for _, investment := range summary.Investments {
+		snapshot.Investments = append(snapshot.Investments, gaivota.InvestmentSnapshot{
+			InvestmentID: investment.InvestmentID,
+			Symbol:       investment.Symbol,
+			Amount:       investment.Amount,
+			Value:        investment.Value,
+			CostBasis:    investment.CostBasis,
+		})
+	}
+
+	for _, wallet := range summary.Wallets {
+		snapshot.Wallets = append(snapshot.Wallets, gaivota.WalletSnapshot{
+			WalletID: wallet.WalletID,
+			Value:    wallet.Value,
+		})
+	}
+
+	return snapshotter.client.SnapshotStore.Add(ctx, snapshot)
+}
+
+// TakeAll snapshots every portfolio for the day. A portfolio that cannot be
+// valued (e.g. a missing price) does not stop the others.
+func (snapshotter *Snapshotter) TakeAll(ctx context.Context, day time.Time) (taken int, err error) {
+	portfolios, _, err := snapshotter.client.PortfolioStore.All(ctx, gaivota.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	var failed int
+	for _, portfolio := range *portfolios {
+		if _, err := snapshotter.Take(ctx, portfolio.ID, day); err != nil {
+			snapshotter.logger.With("portfolioId", portfolio.ID).Log(gaivota.LogLevelError, "Error while taking snapshot: %v", err)
+			failed++
+			continue
+		}
+		taken++
+	}
+
+	if failed > 0 {
+		return taken, fmt.Errorf("Could not snapshot %v of %v portfolios", failed, len(*portfolios))
+	}
+
+	return taken, nil
+}
+
+// Backfill reconstructs a snapshot for every day between `from` and `to`,
+// replaying the orders executed by then at the prices of the day. A zero
+// `from` starts at the portfolio's first order, a zero `to` ends today.
+func (snapshotter *Snapshotter) Backfill(ctx context.Context, portfolioId int, from time.Time, to time.Time) (taken int, err error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if from.IsZero() {
+		from, err = snapshotter.firstOrder(ctx, portfolioId)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for day := truncateDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if _, err := snapshotter.Take(ctx, portfolioId, day); err != nil {
+			return taken, err
+		}
+		taken++
+	}
+
+	return taken, nil
+}
+
+// Run snapshots every portfolio now and then right after each midnight
+// (UTC), until the context is done.
+func (snapshotter *Snapshotter) Run(ctx context.Context) {
+	for {
+		taken, err := snapshotter.TakeAll(ctx, time.Now())
+		if err != nil {
+			snapshotter.logger.Log(gaivota.LogLevelError, "Error while taking daily snapshots: %v", err)
+		}
+		snapshotter.logger.Log(gaivota.LogLevelInfo, "Took %v daily portfolio snapshots", taken)
+
+		// The last snapshot of a day is taken right after it ends
+		next := truncateDay(time.Now()).AddDate(0, 0, 1).Add(time.Minute)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		if _, err := snapshotter.TakeAll(ctx, next.AddDate(0, 0, -1)); err != nil {
+			snapshotter.logger.Log(gaivota.LogLevelError, "Error while closing daily snapshots: %v", err)
+		}
+	}
+}
+
+// Returns when the portfolio's first order was executed, or now without orders
+func (snapshotter *Snapshotter) firstOrder(ctx context.Context, portfolioId int) (time.Time, error) {
+	first := time.Now()
+
+	investments, err := snapshotter.client.InvestmentStore.GetByPortfolioID(ctx, portfolioId)
+	if err != nil {
+		return first, err
+	}
+
+	for _, investment := range *investments {
+		positions, err := snapshotter.client.PositionStore.GetByInvestmentID(ctx, investment.ID)
+		if err != nil {
+			return first, err
+		}
+
+		for _, position := range *positions {
+			orders, err := snapshotter.client.OrderStore.GetByPositionID(ctx, position.ID)
+			if err != nil {
+				return first, err
+			}
+
+			for _, order := range orders {
+				if order.ExecutedAt.Before(first) {
+					first = order.ExecutedAt
+				}
+			}
+		}
+	}
+
+	return first, nil
+}
+
+// Sample keeps the last snapshot of each interval, e.g. the closing value of
+// every week. Snapshots must be sorted by date.
+func Sample(snapshots []gaivota.PortfolioSnapshot, interval string) ([]gaivota.PortfolioSnapshot, error) {
+	if interval == "" || interval == IntervalDay {
+		return snapshots, nil
+	}
+
+	if _, err := PeriodStart(time.Time{}, interval); err != nil {
+		return nil, err
+	}
+
+	sampled := []gaivota.PortfolioSnapshot{}
+	for i, snapshot := range snapshots {
+		last := i == len(snapshots)-1
+		if last {
+			sampled = append(sampled, snapshot)
+			continue
+		}
+
+		next, _ := PeriodStart(snapshots[i+1].Date, interval)
+		current, _ := PeriodStart(snapshot.Date, interval)
+		if !next.Equal(current) {
+			sampled = append(sampled, snapshot)
+		}
+	}
+
+	return sampled, nil
+}
+
+// PeriodStart returns the start of the UTC day, week (starting on Monday) or
+// month `t` is in.
+func PeriodStart(t time.Time, interval string) (time.Time, error) {
+	date := truncateDay(t)
+
+	switch interval {
+	case IntervalDay:
+		return date, nil
+	case IntervalWeek:
+		return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7)), nil
+	case IntervalMonth:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("Unknown interval %q, expected %s, %s or %s", interval, IntervalDay, IntervalWeek, IntervalMonth)
+	}
+}
+
+// Snapshots are taken per UTC day
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
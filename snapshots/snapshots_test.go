@@ -0,0 +1,200 @@
+package snapshots
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/inmem"
+	"github.com/leoschet/gaivota/inmem/inmemtest"
+	"github.com/leoschet/gaivota/log"
+	"github.com/leoschet/gaivota/pricing"
+	"github.com/leoschet/gaivota/valuation"
+	"github.com/shopspring/decimal"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodStart(t *testing.T) {
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+	berlin := time.FixedZone("CET", 60*60)
+
+	tests := []struct {
+		name     string
+		t        time.Time
+		interval string
+		expected time.Time
+	}{
+		{"day", time.Date(2021, 3, 10, 15, 30, 0, 0, time.UTC), IntervalDay, date(2021, 3, 10)},
+		{"day, in UTC", time.Date(2021, 3, 10, 22, 0, 0, 0, saoPaulo), IntervalDay, date(2021, 3, 11)},
+		{"week, on a Monday", time.Date(2021, 1, 11, 0, 0, 0, 0, time.UTC), IntervalWeek, date(2021, 1, 11)},
+		{"week, on a Sunday", time.Date(2021, 1, 17, 23, 59, 59, 0, time.UTC), IntervalWeek, date(2021, 1, 11)},
+		{"week, Monday in Berlin is still Sunday in UTC", time.Date(2021, 1, 18, 0, 30, 0, 0, berlin), IntervalWeek, date(2021, 1, 11)},
+		{"week, starting on the 1st", date(2021, 3, 3), IntervalWeek, date(2021, 3, 1)},
+		{"week, starting the month before", date(2021, 5, 1), IntervalWeek, date(2021, 4, 26)},
+		{"week, starting the year before", date(2021, 1, 3), IntervalWeek, date(2020, 12, 28)},
+		{"month, first day", date(2021, 2, 1), IntervalMonth, date(2021, 2, 1)},
+		{"month, last moment", time.Date(2021, 2, 28, 23, 59, 59, 0, time.UTC), IntervalMonth, date(2021, 2, 1)},
+		{"month, leap day", date(2020, 2, 29), IntervalMonth, date(2020, 2, 1)},
+		{"month, end of the year", time.Date(2020, 12, 31, 12, 0, 0, 0, time.UTC), IntervalMonth, date(2020, 12, 1)},
+		{"month, start of the year", date(2021, 1, 1), IntervalMonth, date(2021, 1, 1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, err := PeriodStart(test.t, test.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !start.Equal(test.expected) {
+				t.Errorf("Period of %v starts %v, expected %v", test.t, start, test.expected)
+			}
+		})
+	}
+
+	if _, err := PeriodStart(date(2021, 1, 1), "year"); err == nil {
+		t.Error("Yearly periods started, expected an unknown interval")
+	}
+}
+
+// Daily snapshots from `from` to `to`, valued by their day of the month
+func dailySnapshots(from time.Time, to time.Time) []gaivota.PortfolioSnapshot {
+	var snapshots []gaivota.PortfolioSnapshot
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		snapshots = append(snapshots, gaivota.PortfolioSnapshot{Date: day, Value: decimal.NewFromInt(int64(day.Day()))})
+	}
+
+	return snapshots
+}
+
+func TestSample(t *testing.T) {
+	// From Monday, December 21st 2020 to Tuesday, February 2nd 2021
+	snapshots := dailySnapshots(date(2020, 12, 21), date(2021, 2, 2))
+
+	tests := []struct {
+		interval string
+		expected []time.Time
+	}{
+		{IntervalWeek, []time.Time{
+			date(2020, 12, 27), date(2021, 1, 3), date(2021, 1, 10), date(2021, 1, 17),
+			date(2021, 1, 24), date(2021, 1, 31), date(2021, 2, 2),
+		}},
+		{IntervalMonth, []time.Time{date(2020, 12, 31), date(2021, 1, 31), date(2021, 2, 2)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.interval, func(t *testing.T) {
+			sampled, err := Sample(snapshots, test.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(sampled) != len(test.expected) {
+				t.Fatalf("Sampled %d snapshots, expected %d", len(sampled), len(test.expected))
+			}
+			for i, snapshot := range sampled {
+				if !snapshot.Date.Equal(test.expected[i]) {
+					t.Errorf("Snapshot %d is of %v, expected %v", i+1, snapshot.Date, test.expected[i])
+				}
+			}
+		})
+	}
+
+	// Days keep every snapshot
+	for _, interval := range []string{"", IntervalDay} {
+		if sampled, err := Sample(snapshots, interval); err != nil || len(sampled) != len(snapshots) {
+			t.Errorf("Sampling by %q kept %d of %d snapshots (%v)", interval, len(sampled), len(snapshots), err)
+		}
+	}
+	// Gaps do not matter, only which period snapshots are in
+	sparse := []gaivota.PortfolioSnapshot{{Date: date(2021, 1, 29)}, {Date: date(2021, 3, 2)}}
+	if sampled, err := Sample(sparse, IntervalMonth); err != nil || len(sampled) != 2 {
+		t.Errorf("Sampling months with gaps kept %+v (%v), expected both", sampled, err)
+	}
+
+	if _, err := Sample(snapshots, "year"); err == nil {
+		t.Error("Sampling by year succeeded, expected an unknown interval")
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	ctx := context.Background()
+	client := inmem.New().NewClient()
+	client.PriceSource = pricing.NewStoreSource(client.PriceStore, nil, 0)
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{ReportingCurrency: "USD"})
+
+	// BTC is worth 100 on December 30th 2020 and 200 from January 1st
+	for at, value := range map[time.Time]int64{date(2020, 12, 30): 100, date(2021, 1, 1): 200} {
+		_, err := client.PriceStore.Add(ctx, &gaivota.Price{TokenSymbol: "BTC", QuoteCurrency: "USD", Value: decimal.NewFromInt(value), At: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := client.OrderStore.Add(ctx, &gaivota.Order{
+		PositionID:    fixture.Position.ID,
+		Amount:        decimal.NewFromInt(1),
+		UnitPrice:     decimal.NewFromInt(100),
+		TotalPrice:    decimal.NewFromInt(100),
+		QuoteCurrency: "USD",
+		Operation:     gaivota.OrderOperationBuy,
+		Type:          gaivota.OrderTypeMarket,
+		ExecutedAt:    time.Date(2020, 12, 30, 18, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotter := New(client, valuation.New(client), log.New(ioutil.Discard, gaivota.LogLevelError, log.FormatText))
+
+	// Without `from`, every day since the first order's, across the year
+	taken, err := snapshotter.Backfill(ctx, fixture.Portfolio.ID, time.Time{}, time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taken != 4 {
+		t.Errorf("Backfilled %d days, expected December 30th to January 2nd", taken)
+	}
+
+	stored, err := client.SnapshotStore.GetRange(ctx, fixture.Portfolio.ID, date(2020, 1, 1), date(2022, 1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		date  time.Time
+		value int64
+	}{
+		{date(2020, 12, 30), 100},
+		{date(2020, 12, 31), 100},
+		{date(2021, 1, 1), 200},
+		{date(2021, 1, 2), 200},
+	}
+	if len(*stored) != len(expected) {
+		t.Fatalf("Stored %d snapshots, expected %d", len(*stored), len(expected))
+	}
+	for i, snapshot := range *stored {
+		if !snapshot.Date.Equal(expected[i].date) || !snapshot.Value.Equal(decimal.NewFromInt(expected[i].value)) {
+			t.Errorf("Snapshot %d is worth %v on %v, expected %d on %v", i+1, snapshot.Value, snapshot.Date, expected[i].value, expected[i].date)
+		}
+	}
+
+	// Backfilling again replaces the days, a `from` in the day included
+	taken, err = snapshotter.Backfill(ctx, fixture.Portfolio.ID, time.Date(2021, 1, 1, 20, 0, 0, 0, time.UTC), date(2021, 1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taken != 2 {
+		t.Errorf("Backfilled %d days, expected January 1st and 2nd", taken)
+	}
+
+	stored, err = client.SnapshotStore.GetRange(ctx, fixture.Portfolio.ID, date(2020, 1, 1), date(2022, 1, 1))
+	if err != nil || len(*stored) != len(expected) {
+		t.Errorf("Backfilling again left %d snapshots (%v), expected %d", len(*stored), err, len(expected))
+	}
+}
@@ -0,0 +1,112 @@
+package trades
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fields a ColumnMapper reads, "id", "symbol", "quote", "type", "total", "fee"
+// and "fee_currency" are optional
+var columnFields = []string{"id", "symbol", "quote", "side", "type", "amount", "price", "total", "fee", "fee_currency", "time"}
+
+// ColumnMapper maps exports of any layout, given which column holds each
+// field. Times are parsed with the layout, RFC 3339 by default.
+type ColumnMapper struct {
+	Columns    map[string]string
+	TimeLayout string
+}
+
+// ParseColumns reads a column mapping such as
+// "id=Trade ID,side=Side,amount=Qty,price=Price,time=Date"
+func ParseColumns(spec string, timeLayout string) (*ColumnMapper, error) {
+	mapper := &ColumnMapper{Columns: map[string]string{}, TimeLayout: timeLayout}
+	if mapper.TimeLayout == "" {
+		mapper.TimeLayout = time.RFC3339
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid column mapping %q, expected field=column", pair)
+		}
+
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !knownField(field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(columnFields, ", "))
+		}
+
+		mapper.Columns[field] = strings.TrimSpace(parts[1])
+	}
+
+	for _, field := range []string{"side", "amount", "price", "time"} {
+		if _, ok := mapper.Columns[field]; !ok {
+			return nil, fmt.Errorf("missing column mapping for %q", field)
+		}
+	}
+
+	return mapper, nil
+}
+
+func (mapper *ColumnMapper) Map(row map[string]string) (*Trade, error) {
+	trade := &Trade{
+		ID:          mapper.value(row, "id"),
+		Symbol:      mapper.value(row, "symbol"),
+		Quote:       mapper.value(row, "quote"),
+		Type:        parseType(mapper.value(row, "type")),
+		FeeCurrency: mapper.value(row, "fee_currency"),
+	}
+
+	var err error
+
+	if trade.Operation, err = parseOperation(mapper.value(row, "side")); err != nil {
+		return nil, err
+	}
+
+	if trade.Amount, err = parseAmount("amount", mapper.value(row, "amount")); err != nil {
+		return nil, err
+	}
+
+	if trade.UnitPrice, err = parseAmount("price", mapper.value(row, "price")); err != nil {
+		return nil, err
+	}
+
+	if total := mapper.value(row, "total"); total != "" {
+		if trade.TotalPrice, err = parseAmount("total", total); err != nil {
+			return nil, err
+		}
+	}
+
+	if fee := mapper.value(row, "fee"); fee != "" {
+		if trade.Fee, err = parseAmount("fee", fee); err != nil {
+			return nil, err
+		}
+	}
+
+	executedAt := mapper.value(row, "time")
+	if trade.ExecutedAt, err = time.Parse(mapper.TimeLayout, executedAt); err != nil {
+		return nil, fmt.Errorf("invalid time %q, expected layout %s", executedAt, mapper.TimeLayout)
+	}
+
+	return trade, nil
+}
+
+// Returns the row's value for the field, empty when it is not mapped
+func (mapper *ColumnMapper) value(row map[string]string, field string) string {
+	name, ok := mapper.Columns[field]
+	if !ok {
+		return ""
+	}
+
+	return row[name]
+}
+
+func knownField(field string) bool {
+	for _, known := range columnFields {
+		if field == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,188 @@
+package trades
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("coinbase", MapperFunc(mapCoinbase))
+	Register("kraken", MapperFunc(mapKraken))
+}
+
+// Maps Coinbase Pro fills exports:
+// portfolio,trade id,product,side,created at,size,size unit,price,fee,total,price/fee/total unit
+func mapCoinbase(row map[string]string) (*Trade, error) {
+	trade := &Trade{}
+	var err error
+
+	if trade.ID, err = column(row, "trade id"); err != nil {
+		return nil, err
+	}
+
+	product, err := column(row, "product")
+	if err != nil {
+		return nil, err
+	}
+
+	// Products are BASE-QUOTE, e.g. BTC-USD
+	pair := strings.SplitN(product, "-", 2)
+	if len(pair) != 2 {
+		return nil, fmt.Errorf("invalid product %q", product)
+	}
+	trade.Symbol, trade.Quote = pair[0], pair[1]
+
+	side, err := column(row, "side")
+	if err != nil {
+		return nil, err
+	}
+	if trade.Operation, err = parseOperation(side); err != nil {
+		return nil, err
+	}
+
+	executedAt, err := column(row, "created at")
+	if err != nil {
+		return nil, err
+	}
+	if trade.ExecutedAt, err = time.Parse(time.RFC3339, executedAt); err != nil {
+		return nil, fmt.Errorf("invalid created at %q", executedAt)
+	}
+
+	size, err := column(row, "size")
+	if err != nil {
+		return nil, err
+	}
+	if trade.Amount, err = parseAmount("size", size); err != nil {
+		return nil, err
+	}
+
+	price, err := column(row, "price")
+	if err != nil {
+		return nil, err
+	}
+	if trade.UnitPrice, err = parseAmount("price", price); err != nil {
+		return nil, err
+	}
+
+	if fee, ok := row["fee"]; ok && fee != "" {
+		if trade.Fee, err = parseAmount("fee", fee); err != nil {
+			return nil, err
+		}
+		trade.FeeCurrency = row["price/fee/total unit"]
+	}
+
+	return trade, nil
+}
+
+// Maps Kraken trades exports:
+// txid,ordertxid,pair,time,type,ordertype,price,cost,fee,vol,margin,misc,ledgers
+func mapKraken(row map[string]string) (*Trade, error) {
+	trade := &Trade{}
+	var err error
+
+	if trade.ID, err = column(row, "txid"); err != nil {
+		return nil, err
+	}
+
+	pair, err := column(row, "pair")
+	if err != nil {
+		return nil, err
+	}
+	if trade.Symbol, trade.Quote, err = splitKrakenPair(pair); err != nil {
+		return nil, err
+	}
+
+	side, err := column(row, "type")
+	if err != nil {
+		return nil, err
+	}
+	if trade.Operation, err = parseOperation(side); err != nil {
+		return nil, err
+	}
+
+	trade.Type = parseType(row["ordertype"])
+
+	executedAt, err := column(row, "time")
+	if err != nil {
+		return nil, err
+	}
+	if trade.ExecutedAt, err = time.Parse("2006-01-02 15:04:05", executedAt); err != nil {
+		return nil, fmt.Errorf("invalid time %q", executedAt)
+	}
+
+	volume, err := column(row, "vol")
+	if err != nil {
+		return nil, err
+	}
+	if trade.Amount, err = parseAmount("vol", volume); err != nil {
+		return nil, err
+	}
+
+	price, err := column(row, "price")
+	if err != nil {
+		return nil, err
+	}
+	if trade.UnitPrice, err = parseAmount("price", price); err != nil {
+		return nil, err
+	}
+
+	if cost, ok := row["cost"]; ok && cost != "" {
+		if trade.TotalPrice, err = parseAmount("cost", cost); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fees are in the quote currency
+	if fee, ok := row["fee"]; ok && fee != "" {
+		if trade.Fee, err = parseAmount("fee", fee); err != nil {
+			return nil, err
+		}
+	}
+
+	return trade, nil
+}
+
+// Kraken's own asset codes for tokens known by another symbol
+var krakenAssets = map[string]string{
+	"XBT": "BTC",
+	"XDG": "DOGE",
+}
+
+// Quotes Kraken pairs can end with, longest first
+var krakenQuotes = []string{"USDT", "USDC", "ZUSD", "ZEUR", "ZGBP", "ZCAD", "ZJPY", "XXBT", "XETH", "USD", "EUR", "GBP", "CAD", "JPY", "CHF", "XBT", "ETH", "DAI"}
+
+// Splits pairs such as XXBTZUSD, ETHUSDT or DOT/EUR into their base and quote
+func splitKrakenPair(pair string) (string, string, error) {
+	var base, quote string
+
+	if parts := strings.SplitN(pair, "/", 2); len(parts) == 2 {
+		base, quote = parts[0], parts[1]
+	} else {
+		for _, candidate := range krakenQuotes {
+			if strings.HasSuffix(pair, candidate) && len(pair) > len(candidate) {
+				base, quote = strings.TrimSuffix(pair, candidate), candidate
+				break
+			}
+		}
+	}
+
+	if base == "" {
+		return "", "", fmt.Errorf("invalid pair %q", pair)
+	}
+
+	return krakenAsset(base), krakenAsset(quote), nil
+}
+
+// Drops the X (crypto) and Z (fiat) prefixes of legacy four letter codes
+func krakenAsset(code string) string {
+	if len(code) == 4 && (code[0] == 'X' || code[0] == 'Z') {
+		code = code[1:]
+	}
+
+	if symbol, ok := krakenAssets[code]; ok {
+		return symbol
+	}
+
+	return code
+}
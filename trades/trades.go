@@ -0,0 +1,308 @@
+// Package trades imports orders from the trade history exports of exchanges.
+package trades
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/shopspring/decimal"
+)
+
+// ErrUnknownFormat is returned when no mapper is registered for a format
+var ErrUnknownFormat = errors.New("unknown trade format")
+
+// Trade is a row of a trade history export, before it becomes an Order
+type Trade struct {
+	// Exchange's trade ID, empty when the export has none
+	ID        string
+	Symbol    string
+	Quote     string
+	Operation gaivota.OrderOperation
+	Type      gaivota.OrderType
+	Amount    decimal.Decimal
+	UnitPrice decimal.Decimal
+	// Zero when the export has no total, Amount * UnitPrice is used instead
+	TotalPrice decimal.Decimal
+	Fee        decimal.Decimal
+	// Empty when the fee is in the Quote currency
+	FeeCurrency string
+	ExecutedAt  time.Time
+	// Line of the export the trade was read from, 0 when not read by Read
+	Line int
+}
+
+// Mapper turns a CSV row, keyed by header, into a Trade
+type Mapper interface {
+	Map(row map[string]string) (*Trade, error)
+}
+
+// MapperFunc adapts a function to the Mapper interface
+type MapperFunc func(row map[string]string) (*Trade, error)
+
+func (f MapperFunc) Map(row map[string]string) (*Trade, error) {
+	return f(row)
+}
+
+var (
+	mappersMu sync.RWMutex
+	mappers   = map[string]Mapper{}
+)
+
+// Register makes a mapper available under the exchange's name. It panics when
+// the name is taken, like database/sql drivers.
+func Register(format string, mapper Mapper) {
+	mappersMu.Lock()
+	defer mappersMu.Unlock()
+
+	format = strings.ToLower(format)
+	if _, ok := mappers[format]; ok {
+		panic("trades: Register called twice for format " + format)
+	}
+
+	mappers[format] = mapper
+}
+
+// Lookup returns the mapper registered for the format
+func Lookup(format string) (Mapper, error) {
+	mappersMu.RLock()
+	mapper, ok := mappers[strings.ToLower(format)]
+	mappersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownFormat, format, strings.Join(Formats(), ", "))
+	}
+
+	return mapper, nil
+}
+
+// Formats lists the registered formats, sorted
+func Formats() []string {
+	mappersMu.RLock()
+	defer mappersMu.RUnlock()
+
+	var formats []string
+	for format := range mappers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// Read maps every row of a CSV export. Errors name the line they come from.
+func Read(r io.Reader, mapper Mapper) ([]Trade, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header: %w", err)
+	}
+
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	var trades []Trade
+	// The header is line 1
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = strings.TrimSpace(record[i])
+			}
+		}
+
+		trade, err := mapper.Map(row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		trade.Line = line
+		trades = append(trades, *trade)
+	}
+
+	return trades, nil
+}
+
+// Plan is what importing trades into a position would do
+type Plan struct {
+	// Orders to add
+	Orders []gaivota.Order
+	// Trades already recorded for the position, or repeated in the file
+	Duplicates []Trade
+	// Trades of another token than the position's
+	Skipped []Trade
+	// Trades that do not make valid orders
+	Invalid []InvalidTrade
+}
+
+// InvalidTrade is a trade whose order fails gaivota.Order.Validate
+type InvalidTrade struct {
+	Trade
+	Err error
+}
+
+func (invalid InvalidTrade) Error() string {
+	if invalid.Line == 0 {
+		return invalid.Err.Error()
+	}
+
+	return fmt.Sprintf("line %d: %v", invalid.Line, invalid.Err)
+}
+
+// NewPlan turns trades into orders for the position. Trades of other tokens
+// and trades whose ID is already recorded for the position and exchange are
+// left out, and trades that do not make valid orders are reported instead of
+// planned. The exchange is recorded in lower case, as trade IDs are unique per
+// exchange name.
+func NewPlan(ctx context.Context, client *gaivota.Client, positionId int, exchange string, trades []Trade) (*Plan, error) {
+	exchange = strings.ToLower(strings.TrimSpace(exchange))
+
+	position, err := client.PositionStore.Get(ctx, positionId)
+	if err != nil {
+		return nil, err
+	}
+
+	investment, err := client.InvestmentStore.Get(ctx, position.InvestmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := client.OrderStore.GetByPositionID(ctx, positionId)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, order := range existing {
+		// Orders added through the API may spell the exchange in another case
+		if order.TradeID != "" && strings.ToLower(order.Exchange) == exchange {
+			seen[order.TradeID] = true
+		}
+	}
+
+	plan := &Plan{}
+	for _, trade := range trades {
+		if trade.Symbol != "" && !strings.EqualFold(trade.Symbol, investment.TokenSymbol) {
+			plan.Skipped = append(plan.Skipped, trade)
+			continue
+		}
+
+		if trade.ID != "" {
+			if seen[trade.ID] {
+				plan.Duplicates = append(plan.Duplicates, trade)
+				continue
+			}
+			seen[trade.ID] = true
+		}
+
+		total := trade.TotalPrice
+		if total.IsZero() {
+			total = trade.Amount.Mul(trade.UnitPrice)
+		}
+
+		orderType := trade.Type
+		if orderType == "" {
+			orderType = gaivota.OrderTypeMarket
+		}
+
+		order := gaivota.Order{
+			PositionID:    positionId,
+			Amount:        trade.Amount,
+			UnitPrice:     trade.UnitPrice,
+			TotalPrice:    total,
+			QuoteCurrency: strings.ToUpper(trade.Quote),
+			Fee:           trade.Fee,
+			FeeCurrency:   strings.ToUpper(trade.FeeCurrency),
+			Operation:     trade.Operation,
+			Type:          orderType,
+			Exchange:      exchange,
+			TradeID:       trade.ID,
+			ExecutedAt:    trade.ExecutedAt,
+		}
+
+		if err := order.Validate(); err != nil {
+			plan.Invalid = append(plan.Invalid, InvalidTrade{Trade: trade, Err: err})
+			continue
+		}
+
+		plan.Orders = append(plan.Orders, order)
+	}
+
+	return plan, nil
+}
+
+// Commit adds the plan's orders in a single transaction. Plans with invalid
+// trades are not committed, so an export is imported whole once fixed.
+func (plan *Plan) Commit(ctx context.Context, store gaivota.OrderStore) ([]gaivota.Order, error) {
+	if len(plan.Invalid) > 0 {
+		return nil, gaivota.Errorf(gaivota.ErrInvalid, "%d trades do not make valid orders, e.g. %v", len(plan.Invalid), plan.Invalid[0])
+	}
+
+	if len(plan.Orders) == 0 {
+		return nil, nil
+	}
+
+	return store.AddMany(ctx, plan.Orders)
+}
+
+// Parses "buy" and "sell", in any case
+func parseOperation(value string) (gaivota.OrderOperation, error) {
+	switch strings.ToLower(value) {
+	case string(gaivota.OrderOperationBuy):
+		return gaivota.OrderOperationBuy, nil
+	case string(gaivota.OrderOperationSell):
+		return gaivota.OrderOperationSell, nil
+	default:
+		return "", fmt.Errorf("unknown side %q, expected buy or sell", value)
+	}
+}
+
+// Parses "limit" and "market", in any case, empty when unknown
+func parseType(value string) gaivota.OrderType {
+	switch strings.ToLower(value) {
+	case string(gaivota.OrderTypeLimit):
+		return gaivota.OrderTypeLimit
+	case string(gaivota.OrderTypeMarket):
+		return gaivota.OrderTypeMarket
+	default:
+		return ""
+	}
+}
+
+// Parses a positive decimal, thousands separators allowed
+func parseAmount(name string, value string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(strings.ReplaceAll(value, ",", ""))
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	return amount.Abs(), nil
+}
+
+// Returns the row's value for a column that must be there
+func column(row map[string]string, name string) (string, error) {
+	value, ok := row[name]
+	if !ok || value == "" {
+		return "", fmt.Errorf("missing %q column", name)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,322 @@
+package trades
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/inmem"
+	"github.com/leoschet/gaivota/inmem/inmemtest"
+	"github.com/shopspring/decimal"
+)
+
+// What a trade is expected to be read as, with decimals as strings
+type testTrade struct {
+	id          string
+	symbol      string
+	quote       string
+	operation   gaivota.OrderOperation
+	orderType   gaivota.OrderType
+	amount      string
+	unitPrice   string
+	totalPrice  string
+	fee         string
+	feeCurrency string
+	executedAt  time.Time
+	line        int
+}
+
+func assertTrades(t *testing.T, got []Trade, expected []testTrade) {
+	t.Helper()
+
+	if len(got) != len(expected) {
+		t.Fatalf("Read %d trades, expected %d", len(got), len(expected))
+	}
+
+	for i, trade := range got {
+		e := expected[i]
+		if trade.ID != e.id || trade.Symbol != e.symbol || trade.Quote != e.quote || trade.Operation != e.operation ||
+			trade.Type != e.orderType || trade.FeeCurrency != e.feeCurrency || !trade.ExecutedAt.Equal(e.executedAt) || trade.Line != e.line {
+			t.Errorf("Trade %d is %+v, expected %+v", i+1, trade, e)
+		}
+
+		decimals := map[string][2]interface{}{
+			"Amount":     {trade.Amount, e.amount},
+			"UnitPrice":  {trade.UnitPrice, e.unitPrice},
+			"TotalPrice": {trade.TotalPrice, e.totalPrice},
+			"Fee":        {trade.Fee, e.fee},
+		}
+		for name, values := range decimals {
+			value, expected := values[0].(decimal.Decimal), values[1].(string)
+			if expected == "" {
+				expected = "0"
+			}
+			if !value.Equal(decimal.RequireFromString(expected)) {
+				t.Errorf("%s of trade %d is %v, expected %s", name, i+1, value, expected)
+			}
+		}
+	}
+}
+
+func TestReadCoinbase(t *testing.T) {
+	export := `portfolio,trade id,product,side,created at,size,size unit,price,fee,total,price/fee/total unit
+default,101,BTC-USD,BUY,2021-03-01T10:00:00.000Z,0.5,BTC,"50,000.00",12.5,-25012.5,USD
+default,102,ETH-EUR,SELL,2021-03-02T11:30:00.000Z,2,ETH,1500,,3000,EUR
+`
+	mapper, err := Lookup("Coinbase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trades, err := Read(strings.NewReader(export), mapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTrades(t, trades, []testTrade{
+		{
+			id: "101", symbol: "BTC", quote: "USD", operation: gaivota.OrderOperationBuy, amount: "0.5", unitPrice: "50000",
+			fee: "12.5", feeCurrency: "USD", executedAt: time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC), line: 2,
+		},
+		{
+			// Without a fee, the fee currency is left to default to the quote
+			id: "102", symbol: "ETH", quote: "EUR", operation: gaivota.OrderOperationSell, amount: "2", unitPrice: "1500",
+			executedAt: time.Date(2021, 3, 2, 11, 30, 0, 0, time.UTC), line: 3,
+		},
+	})
+}
+
+func TestReadKraken(t *testing.T) {
+	export := `txid,ordertxid,pair,time,type,ordertype,price,cost,fee,vol,margin,misc,ledgers
+TXA-1,OA-1,XXBTZUSD,2021-04-01 08:00:00,buy,limit,58000.0,29000.0,46.4,0.5,0.0,,
+TXA-2,OA-2,XETHXXBT,2021-04-02 09:15:00,sell,market,0.034,0.068,0.0001,2.0,0.0,,
+TXA-3,OA-3,ETHUSDT,2021-04-03 10:30:00,buy,stop market,2000,4000,6.4,2,0.0,,
+`
+	mapper, err := Lookup("kraken")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trades, err := Read(strings.NewReader(export), mapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTrades(t, trades, []testTrade{
+		{
+			id: "TXA-1", symbol: "BTC", quote: "USD", operation: gaivota.OrderOperationBuy, orderType: gaivota.OrderTypeLimit,
+			amount: "0.5", unitPrice: "58000", totalPrice: "29000", fee: "46.4",
+			executedAt: time.Date(2021, 4, 1, 8, 0, 0, 0, time.UTC), line: 2,
+		},
+		{
+			id: "TXA-2", symbol: "ETH", quote: "BTC", operation: gaivota.OrderOperationSell, orderType: gaivota.OrderTypeMarket,
+			amount: "2", unitPrice: "0.034", totalPrice: "0.068", fee: "0.0001",
+			executedAt: time.Date(2021, 4, 2, 9, 15, 0, 0, time.UTC), line: 3,
+		},
+		{
+			// Unknown order types are left to default to market
+			id: "TXA-3", symbol: "ETH", quote: "USDT", operation: gaivota.OrderOperationBuy,
+			amount: "2", unitPrice: "2000", totalPrice: "4000", fee: "6.4",
+			executedAt: time.Date(2021, 4, 3, 10, 30, 0, 0, time.UTC), line: 4,
+		},
+	})
+}
+
+func TestSplitKrakenPair(t *testing.T) {
+	tests := []struct {
+		pair   string
+		base   string
+		quote  string
+		failed bool
+	}{
+		{pair: "XXBTZUSD", base: "BTC", quote: "USD"},
+		{pair: "XETHXXBT", base: "ETH", quote: "BTC"},
+		{pair: "XETHZEUR", base: "ETH", quote: "EUR"},
+		{pair: "ETHUSDT", base: "ETH", quote: "USDT"},
+		{pair: "USDCUSD", base: "USDC", quote: "USD"},
+		{pair: "XDGUSD", base: "DOGE", quote: "USD"},
+		{pair: "DOT/EUR", base: "DOT", quote: "EUR"},
+		{pair: "XBT/USD", base: "BTC", quote: "USD"},
+		{pair: "USD", failed: true},
+		{pair: "DOTKRW", failed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pair, func(t *testing.T) {
+			base, quote, err := splitKrakenPair(test.pair)
+			if test.failed {
+				if err == nil {
+					t.Errorf("Split into %s and %s, expected an error", base, quote)
+				}
+				return
+			}
+
+			if err != nil || base != test.base || quote != test.quote {
+				t.Errorf("Split into %s and %s (%v), expected %s and %s", base, quote, err, test.base, test.quote)
+			}
+		})
+	}
+}
+
+func TestReadColumns(t *testing.T) {
+	export := `Date,Kind,Qty,Price,Fee,Fee Asset,Ref
+01/05/2021,Buy,"1,000",0.25,1,ADA,r1
+02/05/2021,sell,-400,0.5,,,r2
+`
+	mapper, err := ParseColumns("id=Ref,side=Kind,amount=Qty,price=Price,fee=Fee,fee_currency=Fee Asset,time=Date", "02/01/2006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trades, err := Read(strings.NewReader(export), mapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTrades(t, trades, []testTrade{
+		{
+			id: "r1", operation: gaivota.OrderOperationBuy, amount: "1000", unitPrice: "0.25", fee: "1", feeCurrency: "ADA",
+			executedAt: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), line: 2,
+		},
+		{
+			// Signed amounts are read as sizes, the side tells the direction
+			id: "r2", operation: gaivota.OrderOperationSell, amount: "400", unitPrice: "0.5",
+			executedAt: time.Date(2021, 5, 2, 0, 0, 0, 0, time.UTC), line: 3,
+		},
+	})
+}
+
+func TestParseColumnsErrors(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"side=Side,amount=Qty,price=Price", `missing column mapping for "time"`},
+		{"side=Side,amount=Qty,price=Price,time=Date,venue=Exchange", `unknown field "venue"`},
+		{"side,amount=Qty,price=Price,time=Date", `invalid column mapping "side"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			_, err := ParseColumns(test.spec, "")
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Parsing answered %v, expected %s", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestReadErrorsNameTheLine(t *testing.T) {
+	export := `txid,ordertxid,pair,time,type,ordertype,price,cost,fee,vol,margin,misc,ledgers
+TXA-1,OA-1,XXBTZUSD,2021-04-01 08:00:00,buy,limit,58000.0,29000.0,46.4,0.5,0.0,,
+TXA-2,OA-2,XXBTZUSD,2021-04-02 09:15:00,transfer,market,58000.0,29000.0,46.4,0.5,0.0,,
+`
+	mapper, err := Lookup("kraken")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Read(strings.NewReader(export), mapper)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3: unknown side") {
+		t.Errorf("Reading answered %v, expected an unknown side on line 3", err)
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	ctx := context.Background()
+	client := inmem.New().NewClient()
+	fixture := inmemtest.Seed(t, client, "ada@example.com", gaivota.Portfolio{})
+
+	// Recorded before, through the API, with the exchange in another case
+	_, err := client.OrderStore.Add(ctx, &gaivota.Order{
+		PositionID:    fixture.Position.ID,
+		Amount:        decimal.NewFromInt(1),
+		UnitPrice:     decimal.NewFromInt(100),
+		TotalPrice:    decimal.NewFromInt(100),
+		QuoteCurrency: "USD",
+		Operation:     gaivota.OrderOperationBuy,
+		Type:          gaivota.OrderTypeMarket,
+		Exchange:      "Kraken",
+		TradeID:       "T1",
+		ExecutedAt:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trade := func(id string, symbol string, amount int64, line int) Trade {
+		return Trade{
+			ID: id, Symbol: symbol, Quote: "usd", Operation: gaivota.OrderOperationBuy, Amount: decimal.NewFromInt(amount),
+			UnitPrice: decimal.NewFromInt(100), Fee: decimal.NewFromInt(1), ExecutedAt: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), Line: line,
+		}
+	}
+	withFeeCurrency := trade("T5", "BTC", 1, 7)
+	withFeeCurrency.FeeCurrency = "bnb"
+
+	trades := []Trade{
+		trade("T1", "BTC", 1, 2),
+		trade("T2", "btc", 1, 3),
+		trade("T2", "BTC", 1, 4),
+		trade("T3", "ETH", 1, 5),
+		trade("T4", "BTC", 0, 6),
+		withFeeCurrency,
+	}
+
+	plan, err := NewPlan(ctx, client, fixture.Position.ID, " KRAKEN ", trades)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(trades []Trade) []string {
+		var ids []string
+		for _, trade := range trades {
+			ids = append(ids, trade.ID)
+		}
+		return ids
+	}
+
+	if got := strings.Join(ids(plan.Duplicates), ","); got != "T1,T2" {
+		t.Errorf("Duplicates are %s, expected the recorded T1 and the repeated T2", got)
+	}
+	if got := strings.Join(ids(plan.Skipped), ","); got != "T3" {
+		t.Errorf("Skipped %s, expected the ETH trade T3", got)
+	}
+	if len(plan.Invalid) != 1 || plan.Invalid[0].Error() != "line 6: invalid order: amount must be positive" {
+		t.Errorf("Invalid trades are %v, expected line 6's zero amount", plan.Invalid)
+	}
+
+	// Invalid trades keep the whole plan from being committed
+	if _, err := plan.Commit(ctx, client.OrderStore); !errors.Is(err, gaivota.ErrInvalid) || !strings.Contains(err.Error(), "line 6") {
+		t.Errorf("Committing answered %v, expected ErrInvalid naming line 6", err)
+	}
+
+	// Fees in BNB are valued at its price
+	_, err = client.PriceStore.Add(ctx, &gaivota.Price{TokenSymbol: "BNB", QuoteCurrency: "USD", Value: decimal.NewFromInt(300), At: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan.Invalid = nil
+	orders, err := plan.Commit(ctx, client.OrderStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 2 {
+		t.Fatalf("Committed %d orders, expected T2 and T5", len(orders))
+	}
+	for _, order := range orders {
+		if order.Exchange != "kraken" || order.QuoteCurrency != "USD" {
+			t.Errorf("Order %s is on %q in %q, expected kraken in USD", order.TradeID, order.Exchange, order.QuoteCurrency)
+		}
+	}
+	if orders[0].FeeCurrency != "USD" {
+		t.Errorf("Fee of T2 is in %q, expected the quote currency", orders[0].FeeCurrency)
+	}
+	if orders[1].FeeCurrency != "BNB" {
+		t.Errorf("Fee of T5 is in %q, expected BNB", orders[1].FeeCurrency)
+	}
+}
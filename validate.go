@@ -0,0 +1,243 @@
+package gaivota
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Validate methods check the fields of entities created or updated by users,
+// so mistakes are reported per field rather than failing in the stores.
+// Owners left out (e.g. a portfolio's user) default to the caller, and
+// currencies left out to the parent's, so they are optional. What depends on
+// other entities, like unique names, is left to the stores.
+
+// Currency codes and token symbols, like USD or BTC
+var currencyPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,10}$`)
+
+func required(errs *FieldErrors, field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		errs.Add(field, "is required")
+	}
+}
+
+func maxLength(errs *FieldErrors, field string, value string, max int) {
+	if len([]rune(value)) > max {
+		errs.Add(field, "must have at most %d characters", max)
+	}
+}
+
+func reference(errs *FieldErrors, field string, id int) {
+	if id <= 0 {
+		errs.Add(field, "is required")
+	}
+}
+
+// Empty currencies are left to default
+func currency(errs *FieldErrors, field string, value string) {
+	if value != "" && !currencyPattern.MatchString(value) {
+		errs.Add(field, "must be a code of up to 10 letters and digits, like USD")
+	}
+}
+
+func positive(errs *FieldErrors, field string, value decimal.Decimal) {
+	if !value.IsPositive() {
+		errs.Add(field, "must be positive")
+	}
+}
+
+func notNegative(errs *FieldErrors, field string, value decimal.Decimal) {
+	if value.IsNegative() {
+		errs.Add(field, "must not be negative")
+	}
+}
+
+func (user *User) Validate() error {
+	var errs FieldErrors
+
+	required(&errs, "email", user.Email)
+	if user.Email != "" && !strings.Contains(user.Email, "@") {
+		errs.Add("email", "must be an email address")
+	}
+	maxLength(&errs, "email", user.Email, 320)
+	required(&errs, "firstName", user.FirstName)
+	required(&errs, "lastName", user.LastName)
+	currency(&errs, "reportingCurrency", user.ReportingCurrency)
+
+	return errs.Err("invalid user")
+}
+
+func (key *APIKey) Validate() error {
+	var errs FieldErrors
+
+	required(&errs, "name", key.Name)
+	maxLength(&errs, "name", key.Name, 50)
+
+	return errs.Err("invalid api key")
+}
+
+// Valid tells whether method is one of the cost basis methods
+func (method CostBasisMethod) Valid() bool {
+	switch method {
+	case CostBasisFIFO, CostBasisLIFO, CostBasisHIFO, CostBasisAverage:
+		return true
+	}
+
+	return false
+}
+
+func (portfolio *Portfolio) Validate() error {
+	var errs FieldErrors
+
+	required(&errs, "name", portfolio.Name)
+	maxLength(&errs, "name", portfolio.Name, 50)
+	if portfolio.CostBasisMethod != "" && !portfolio.CostBasisMethod.Valid() {
+		errs.Add("costBasisMethod", "must be one of %s, %s, %s or %s", CostBasisFIFO, CostBasisLIFO, CostBasisHIFO, CostBasisAverage)
+	}
+	currency(&errs, "reportingCurrency", portfolio.ReportingCurrency)
+
+	return errs.Err("invalid portfolio")
+}
+
+func (wallet *Wallet) Validate() error {
+	var errs FieldErrors
+
+	required(&errs, "name", wallet.Name)
+	maxLength(&errs, "name", wallet.Name, 50)
+	maxLength(&errs, "location", wallet.Location, 50)
+	notNegative(&errs, "totalValue", wallet.TotalValue)
+
+	return errs.Err("invalid wallet")
+}
+
+func (investment *Investment) Validate() error {
+	var errs FieldErrors
+
+	reference(&errs, "portfolio", investment.PortfolioID)
+	required(&errs, "token", investment.Token)
+	maxLength(&errs, "token", investment.Token, 50)
+	currency(&errs, "symbol", investment.TokenSymbol)
+
+	return errs.Err("invalid investment")
+}
+
+// Amounts and prices of positions are computed from their orders and events,
+// so only what they belong to is checked
+func (position *Position) Validate() error {
+	var errs FieldErrors
+
+	reference(&errs, "investment", position.InvestmentID)
+	currency(&errs, "quoteCurrency", position.QuoteCurrency)
+
+	return errs.Err("invalid position")
+}
+
+func (holding *Holding) Validate() error {
+	var errs FieldErrors
+
+	reference(&errs, "wallet", holding.WalletID)
+	reference(&errs, "position", holding.PositionID)
+	notNegative(&errs, "amount", holding.Amount)
+
+	return errs.Err("invalid holding")
+}
+
+// Transfers failing validation are also ErrInvalidTransfer
+func (transfer *Transfer) Validate() error {
+	var errs FieldErrors
+
+	reference(&errs, "fromWallet", transfer.FromWalletID)
+	reference(&errs, "toWallet", transfer.ToWalletID)
+	if transfer.FromWalletID > 0 && transfer.FromWalletID == transfer.ToWalletID {
+		errs.Add("toWallet", "must not be the same as fromWallet")
+	}
+	reference(&errs, "position", transfer.PositionID)
+	positive(&errs, "amount", transfer.Amount)
+	notNegative(&errs, "fee", transfer.Fee)
+	if transfer.Amount.IsPositive() && !transfer.Fee.LessThan(transfer.Amount) {
+		errs.Add("fee", "must be less than the amount")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &Error{Kind: ErrInvalid, Fields: errs, Err: ErrInvalidTransfer}
+}
+
+// Valid tells whether operation is buy or sell
+func (operation OrderOperation) Valid() bool {
+	return operation == OrderOperationBuy || operation == OrderOperationSell
+}
+
+// Valid tells whether t is limit or market
+func (t OrderType) Valid() bool {
+	return t == OrderTypeLimit || t == OrderTypeMarket
+}
+
+func (order *Order) Validate() error {
+	var errs FieldErrors
+
+	reference(&errs, "position", order.PositionID)
+	positive(&errs, "amount", order.Amount)
+	notNegative(&errs, "unitPrice", order.UnitPrice)
+	notNegative(&errs, "totalPrice", order.TotalPrice)
+	currency(&errs, "quoteCurrency", order.QuoteCurrency)
+	notNegative(&errs, "fee", order.Fee)
+	currency(&errs, "feeCurrency", order.FeeCurrency)
+	if !order.Operation.Valid() {
+		errs.Add("operation", "must be %s or %s", OrderOperationBuy, OrderOperationSell)
+	}
+	if !order.Type.Valid() {
+		errs.Add("type", "must be %s or %s", OrderTypeLimit, OrderTypeMarket)
+	}
+	maxLength(&errs, "exchange", order.Exchange, 50)
+	maxLength(&errs, "tradeId", order.TradeID, 100)
+
+	return errs.Err("invalid order")
+}
+
+func (event *Event) Validate() error {
+	var errs FieldErrors
+
+	reference(&errs, "position", event.PositionID)
+	if !event.Kind.Valid() {
+		errs.Add("kind", "must be one of %v", EventKinds)
+	}
+	positive(&errs, "amount", event.Amount)
+	notNegative(&errs, "unitPrice", event.UnitPrice)
+	currency(&errs, "quoteCurrency", event.QuoteCurrency)
+
+	return errs.Err("invalid event")
+}
+
+func (price *Price) Validate() error {
+	var errs FieldErrors
+
+	required(&errs, "symbol", price.TokenSymbol)
+	currency(&errs, "symbol", price.TokenSymbol)
+	required(&errs, "quote", price.QuoteCurrency)
+	currency(&errs, "quote", price.QuoteCurrency)
+	positive(&errs, "price", price.Value)
+	if price.At.IsZero() {
+		errs.Add("at", "is required")
+	}
+
+	return errs.Err("invalid price")
+}
+
+func (rate *FXRate) Validate() error {
+	var errs FieldErrors
+
+	required(&errs, "base", rate.BaseCurrency)
+	currency(&errs, "base", rate.BaseCurrency)
+	required(&errs, "quote", rate.QuoteCurrency)
+	currency(&errs, "quote", rate.QuoteCurrency)
+	positive(&errs, "rate", rate.Rate)
+	if rate.At.IsZero() {
+		errs.Add("at", "is required")
+	}
+
+	return errs.Err("invalid fx rate")
+}
@@ -0,0 +1,173 @@
+package valuation
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Holdings not assigned to any wallet are allocated to this wallet ID
+const UnassignedWalletID = 0
+
+type InvestmentSummary struct {
+	InvestmentID     int             `json:"investment"`
+	Token            string          `json:"token"`
+	Symbol           string          `json:"symbol"`
+	Amount           decimal.Decimal `json:"amount"`
+	Value            decimal.Decimal `json:"value"`
+	CostBasis        decimal.Decimal `json:"costBasis"`
+	RealizedProfit   decimal.Decimal `json:"realizedProfit"`
+	UnrealizedProfit decimal.Decimal `json:"unrealizedProfit"`
+	Income           decimal.Decimal `json:"income"`
+	Allocation       decimal.Decimal `json:"allocation"`
+}
+
+type WalletSummary struct {
+	WalletID   int             `json:"wallet"`
+	Name       string          `json:"name"`
+	Value      decimal.Decimal `json:"value"`
+	Allocation decimal.Decimal `json:"allocation"`
+}
+
+// PortfolioSummary values a portfolio in its reporting currency. Allocations
+// are percentages of the portfolio's total value.
+type PortfolioSummary struct {
+	PortfolioID      int                 `json:"portfolio"`
+	Name             string              `json:"name"`
+	Quote            string              `json:"quote"`
+	Value            decimal.Decimal     `json:"value"`
+	CostBasis        decimal.Decimal     `json:"costBasis"`
+	RealizedProfit   decimal.Decimal     `json:"realizedProfit"`
+	UnrealizedProfit decimal.Decimal     `json:"unrealizedProfit"`
+	Income           decimal.Decimal     `json:"income"`
+	Investments      []InvestmentSummary `json:"investments"`
+	Wallets          []WalletSummary     `json:"wallets"`
+	At               time.Time           `json:"at"`
+}
+
+// Portfolio walks the portfolio's investments, positions and holdings and
+// values them now, or at `at` when it is not zero.
+func (valuer *Valuer) Portfolio(ctx context.Context, portfolioId int, at time.Time) (*PortfolioSummary, error) {
+	portfolio, err := valuer.client.PortfolioStore.Get(ctx, portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	investments, err := valuer.client.InvestmentStore.GetByPortfolioID(ctx, portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &PortfolioSummary{
+		PortfolioID: portfolio.ID,
+		Name:        portfolio.Name,
+		Quote:       portfolio.ReportingCurrency,
+		Investments: []InvestmentSummary{},
+		Wallets:     []WalletSummary{},
+		At:          at,
+	}
+	if summary.At.IsZero() {
+		summary.At = time.Now()
+	}
+
+	// Wallets are listed in the order their first holding is found
+	wallets := map[int]*WalletSummary{}
+	var walletIds []int
+
+	addToWallet := func(walletId int, name string, value decimal.Decimal) {
+		wallet, ok := wallets[walletId]
+		if !ok {
+			wallet = &WalletSummary{WalletID: walletId, Name: name}
+			wallets[walletId] = wallet
+			walletIds = append(walletIds, walletId)
+		}
+		wallet.Value = wallet.Value.Add(value)
+	}
+
+	for _, investment := range *investments {
+		investmentSummary := InvestmentSummary{
+			InvestmentID: investment.ID,
+			Token:        investment.Token,
+			Symbol:       investment.TokenSymbol,
+		}
+
+		positions, err := valuer.client.PositionStore.GetByInvestmentID(ctx, investment.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, position := range *positions {
+			positionValuation, err := valuer.Position(ctx, position.ID, at, summary.Quote)
+			if err != nil {
+				return nil, err
+			}
+
+			investmentSummary.Amount = investmentSummary.Amount.Add(positionValuation.Amount)
+			investmentSummary.Value = investmentSummary.Value.Add(positionValuation.Value)
+			investmentSummary.CostBasis = investmentSummary.CostBasis.Add(positionValuation.CostBasis)
+			investmentSummary.RealizedProfit = investmentSummary.RealizedProfit.Add(positionValuation.RealizedProfit)
+			investmentSummary.UnrealizedProfit = investmentSummary.UnrealizedProfit.Add(positionValuation.UnrealizedProfit)
+			investmentSummary.Income = investmentSummary.Income.Add(positionValuation.Income)
+
+			holdings, err := valuer.client.HoldingStore.GetByPositionID(ctx, position.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			// Holdings are not historized, so past amounts are assigned to
+			// wallets in order, up to what the position held back then
+			unassigned := positionValuation.Amount
+			for _, holding := range *holdings {
+				amount := decimal.Min(holding.Amount, unassigned)
+				if !amount.IsPositive() {
+					continue
+				}
+
+				name := ""
+				if _, ok := wallets[holding.WalletID]; !ok {
+					wallet, err := valuer.client.WalletStore.Get(ctx, holding.WalletID)
+					if err != nil {
+						return nil, err
+					}
+					name = wallet.Name
+				}
+
+				addToWallet(holding.WalletID, name, amount.Mul(positionValuation.Price))
+				unassigned = unassigned.Sub(amount)
+			}
+
+			if unassigned.IsPositive() {
+				addToWallet(UnassignedWalletID, "Unassigned", unassigned.Mul(positionValuation.Price))
+			}
+		}
+
+		summary.Value = summary.Value.Add(investmentSummary.Value)
+		summary.CostBasis = summary.CostBasis.Add(investmentSummary.CostBasis)
+		summary.RealizedProfit = summary.RealizedProfit.Add(investmentSummary.RealizedProfit)
+		summary.UnrealizedProfit = summary.UnrealizedProfit.Add(investmentSummary.UnrealizedProfit)
+		summary.Income = summary.Income.Add(investmentSummary.Income)
+		summary.Investments = append(summary.Investments, investmentSummary)
+	}
+
+	for i := range summary.Investments {
+		summary.Investments[i].Allocation = percentage(summary.Investments[i].Value, summary.Value)
+	}
+
+	for _, walletId := range walletIds {
+		wallet := wallets[walletId]
+		wallet.Allocation = percentage(wallet.Value, summary.Value)
+		summary.Wallets = append(summary.Wallets, *wallet)
+	}
+
+	return summary, nil
+}
+
+// Returns part as a percentage of total, rounded to two decimal places
+func percentage(part decimal.Decimal, total decimal.Decimal) decimal.Decimal {
+	if total.IsZero() {
+		return decimal.Zero
+	}
+
+	return part.Mul(decimal.NewFromInt(100)).DivRound(total, 2)
+}
@@ -0,0 +1,236 @@
+// Package valuation prices positions and wallets using the client's
+// PriceSource, converting currencies with its FXRateStore.
+package valuation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leoschet/gaivota"
+	"github.com/leoschet/gaivota/accounting"
+	"github.com/leoschet/gaivota/fx"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoPriceSource is returned when the client has no PriceSource configured.
+var ErrNoPriceSource = errors.New("no price source configured")
+
+type Valuer struct {
+	client *gaivota.Client
+}
+
+func New(client *gaivota.Client) *Valuer {
+	return &Valuer{client: client}
+}
+
+type PositionValuation struct {
+	PositionID       int             `json:"position"`
+	Symbol           string          `json:"symbol"`
+	Quote            string          `json:"quote"`
+	Amount           decimal.Decimal `json:"amount"`
+	Price            decimal.Decimal `json:"price"`
+	Value            decimal.Decimal `json:"value"`
+	CostBasis        decimal.Decimal `json:"costBasis"`
+	RealizedProfit   decimal.Decimal `json:"realizedProfit"`
+	UnrealizedProfit decimal.Decimal `json:"unrealizedProfit"`
+	Income           decimal.Decimal `json:"income"`
+	PricedAt         time.Time       `json:"pricedAt"`
+}
+
+type HoldingValuation struct {
+	HoldingID  int             `json:"holding"`
+	PositionID int             `json:"position"`
+	Symbol     string          `json:"symbol"`
+	Amount     decimal.Decimal `json:"amount"`
+	Price      decimal.Decimal `json:"price"`
+	Value      decimal.Decimal `json:"value"`
+}
+
+type WalletValuation struct {
+	WalletID int                `json:"wallet"`
+	Quote    string             `json:"quote"`
+	Value    decimal.Decimal    `json:"value"`
+	Holdings []HoldingValuation `json:"holdings"`
+}
+
+// Price quotes the token now when `at` is zero, or at that time otherwise.
+// Tokens not quoted in `quote` are priced in the default quote currency and
+// converted.
+func (valuer *Valuer) Price(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	price, err := valuer.quote(ctx, symbol, quote, at)
+	if !errors.Is(err, gaivota.ErrPriceNotFound) || quote == gaivota.DefaultQuoteCurrency {
+		return price, err
+	}
+
+	price, err = valuer.quote(ctx, symbol, gaivota.DefaultQuoteCurrency, at)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := valuer.Convert(ctx, price.Value, price.QuoteCurrency, quote, price.At)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sources may share the price they return, so it is copied before converting
+	converted := *price
+	converted.ID = 0
+	converted.QuoteCurrency = quote
+	converted.Value = value
+
+	return &converted, nil
+}
+
+func (valuer *Valuer) quote(ctx context.Context, symbol string, quote string, at time.Time) (*gaivota.Price, error) {
+	if valuer.client.PriceSource == nil {
+		return nil, ErrNoPriceSource
+	}
+
+	if at.IsZero() {
+		return valuer.client.PriceSource.Current(ctx, symbol, quote)
+	}
+
+	return valuer.client.PriceSource.At(ctx, symbol, quote, at)
+}
+
+// Convert converts the amount at the given time, or now when `at` is zero.
+func (valuer *Valuer) Convert(ctx context.Context, amount decimal.Decimal, from string, to string, at time.Time) (decimal.Decimal, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	return fx.NewConverter(valuer.client.FXRateStore).Convert(ctx, amount, from, to, at)
+}
+
+// Position replays the position's orders and prices what is left of it, in
+// `currency` or in the position's own currency when it is empty.
+func (valuer *Valuer) Position(ctx context.Context, positionId int, at time.Time, currency string) (*PositionValuation, error) {
+	position, err := valuer.client.PositionStore.Get(ctx, positionId)
+	if err != nil {
+		return nil, err
+	}
+
+	investment, err := valuer.client.InvestmentStore.Get(ctx, position.InvestmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Past valuations only count the orders and events executed by then
+	result, err := accounting.ReplayPositionAt(ctx, valuer.client, positionId, at)
+	if err != nil {
+		return nil, err
+	}
+
+	// Closed positions are worth nothing, whether their token is quoted or not
+	price := &gaivota.Price{At: at}
+	if !result.Amount.IsZero() {
+		price, err = valuer.Price(ctx, investment.TokenSymbol, position.QuoteCurrency, at)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	positionValuation := &PositionValuation{
+		PositionID:       positionId,
+		Symbol:           investment.TokenSymbol,
+		Quote:            position.QuoteCurrency,
+		Amount:           result.Amount,
+		Price:            price.Value,
+		Value:            result.Amount.Mul(price.Value),
+		CostBasis:        result.CostBasis,
+		RealizedProfit:   result.RealizedProfit,
+		UnrealizedProfit: result.UnrealizedProfit(price.Value),
+		Income:           result.Income,
+		PricedAt:         price.At,
+	}
+
+	if currency == "" || currency == position.QuoteCurrency {
+		return positionValuation, nil
+	}
+
+	// Everything is converted at the same rate, so totals keep adding up
+	rate, err := valuer.rate(ctx, position.QuoteCurrency, currency, price.At)
+	if err != nil {
+		return nil, err
+	}
+
+	positionValuation.Quote = currency
+	positionValuation.Price = positionValuation.Price.Mul(rate)
+	positionValuation.Value = positionValuation.Value.Mul(rate)
+	positionValuation.CostBasis = positionValuation.CostBasis.Mul(rate)
+	positionValuation.RealizedProfit = positionValuation.RealizedProfit.Mul(rate)
+	positionValuation.UnrealizedProfit = positionValuation.UnrealizedProfit.Mul(rate)
+	positionValuation.Income = positionValuation.Income.Mul(rate)
+
+	return positionValuation, nil
+}
+
+func (valuer *Valuer) rate(ctx context.Context, from string, to string, at time.Time) (decimal.Decimal, error) {
+	return valuer.Convert(ctx, decimal.NewFromInt(1), from, to, at)
+}
+
+// Wallet prices every holding in the wallet at the current prices, in the
+// reporting currency of the wallet's user.
+func (valuer *Valuer) Wallet(ctx context.Context, walletId int) (*WalletValuation, error) {
+	wallet, err := valuer.client.WalletStore.Get(ctx, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := valuer.client.UserStore.Get(ctx, wallet.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings, err := valuer.client.HoldingStore.GetByWalletID(ctx, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := &WalletValuation{
+		WalletID: walletId,
+		Quote:    user.ReportingCurrency,
+		Holdings: []HoldingValuation{},
+	}
+
+	for _, holding := range *holdings {
+		symbol, err := valuer.symbol(ctx, holding.PositionID)
+		if err != nil {
+			return nil, err
+		}
+
+		price, err := valuer.Price(ctx, symbol, valuation.Quote, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+
+		value := holding.Amount.Mul(price.Value)
+		valuation.Value = valuation.Value.Add(value)
+		valuation.Holdings = append(valuation.Holdings, HoldingValuation{
+			HoldingID:  holding.ID,
+			PositionID: holding.PositionID,
+			Symbol:     symbol,
+			Amount:     holding.Amount,
+			Price:      price.Value,
+			Value:      value,
+		})
+	}
+
+	return valuation, nil
+}
+
+// Returns the token symbol of the position's investment
+func (valuer *Valuer) symbol(ctx context.Context, positionId int) (string, error) {
+	position, err := valuer.client.PositionStore.Get(ctx, positionId)
+	if err != nil {
+		return "", err
+	}
+
+	investment, err := valuer.client.InvestmentStore.Get(ctx, position.InvestmentID)
+	if err != nil {
+		return "", err
+	}
+
+	return investment.TokenSymbol, nil
+}